@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	addressBookFile string
+	resolveNFD      bool
+	nfdAPIAddress   string
+	nfdConcurrency  int
+	nfdRateLimit    float64
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&addressBookFile, "address-book", "", "CSV file of address,name pairs used to label addresses in the output")
+	rootCmd.Flags().BoolVar(&resolveNFD, "nfd", false, "resolve addresses to their NFDomains names in the output")
+	rootCmd.Flags().StringVar(&nfdAPIAddress, "nfd-api", "https://api.nf.domains", "address of the NFDomains API")
+	rootCmd.Flags().IntVar(&nfdConcurrency, "nfd-concurrency", 4,
+		"number of concurrent NFDomains lookups, separate from the indexer lookups of --concurrency")
+	rootCmd.Flags().Float64Var(&nfdRateLimit, "nfd-rate-limit", 10,
+		"maximum number of NFDomains lookups per second (0 for no limit)")
+}
+
+// addressResolver maps addresses to human readable names, using an address book and optionally NFDomains
+type addressResolver struct {
+	book   map[string]string
+	nfdAPI string
+	client *http.Client
+	// pool runs the NFDomains lookups apart from the indexer lookups
+	pool *backendPool
+	mu   sync.Mutex
+	// cache holds NFDomains lookups results, including misses, so each address is queried only once
+	cache map[string]string
+}
+
+// initAddressResolver returns nil if no address enrichment was requested
+func initAddressResolver(addressBookFile string, resolveNFD bool, nfdAPIAddress string) (*addressResolver, error) {
+	if addressBookFile == "" && !resolveNFD {
+		return nil, nil
+	}
+	resolver := &addressResolver{
+		book:  map[string]string{},
+		cache: map[string]string{},
+	}
+	if addressBookFile != "" {
+		book, err := readAddressBook(addressBookFile)
+		if err != nil {
+			return nil, err
+		}
+		resolver.book = book
+	}
+	if resolveNFD {
+		resolver.nfdAPI = strings.TrimSuffix(nfdAPIAddress, "/")
+		resolver.client = &http.Client{Timeout: 10 * time.Second}
+		pool, err := newBackendPool("--nfd", nfdConcurrency, nfdRateLimit)
+		if err != nil {
+			return nil, err
+		}
+		resolver.pool = pool
+	}
+	return resolver, nil
+}
+
+// readAddressBook reads a CSV file of address,name pairs
+// lines whose first column is not a valid address (e.g. a header line) are skipped
+func readAddressBook(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	book := map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error while reading address book %s: %v", filename, err)
+		}
+		if len(record) < 2 {
+			continue
+		}
+		addr := strings.TrimSpace(record[0])
+		if _, err := types.DecodeAddress(addr); err != nil {
+			log.WithField("file", filename).Debugf("skipping address book line with invalid address %q", addr)
+			continue
+		}
+		book[addr] = strings.TrimSpace(record[1])
+	}
+	return book, nil
+}
+
+// name returns the name of the address, or an empty string if it has none
+func (r *addressResolver) name(addr types.Address) string {
+	addrStr := addr.String()
+	if name, ok := r.book[addrStr]; ok {
+		return name
+	}
+	if r.client == nil {
+		return ""
+	}
+	r.resolve([]types.Address{addr})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cache[addrStr]
+}
+
+// resolve looks up the NFDomains names of the addresses missing from the address book and the cache concurrently
+// in the NFDomains pool, so labeling many addresses takes a fraction of the time of looking them up one by one
+func (r *addressResolver) resolve(addrs []types.Address) {
+	if r == nil || r.client == nil {
+		return
+	}
+	var missing []string
+	seen := map[string]bool{}
+	r.mu.Lock()
+	for _, addr := range addrs {
+		addrStr := addr.String()
+		_, inBook := r.book[addrStr]
+		_, cached := r.cache[addrStr]
+		if !addr.IsZero() && !inBook && !cached && !seen[addrStr] {
+			seen[addrStr] = true
+			missing = append(missing, addrStr)
+		}
+	}
+	r.mu.Unlock()
+	r.pool.run(len(missing), func(i int) {
+		name, err := r.lookupNFD(missing[i])
+		if err != nil {
+			// enrichment is best effort, a failing lookup should not fail the run
+			logEntryMessage(log.WithField("address", missing[i]), log.WarnLevel, msgNFDFailed, err)
+		}
+		r.mu.Lock()
+		r.cache[missing[i]] = name
+		r.mu.Unlock()
+	})
+}
+
+// label returns the address along with its name if it has one
+func (r *addressResolver) label(addr types.Address) string {
+	if addr.IsZero() {
+		return ""
+	}
+	if r == nil {
+		return addr.String()
+	}
+	if name := r.name(addr); name != "" {
+		return fmt.Sprintf("%s (%s)", name, addr.String())
+	}
+	return addr.String()
+}
+
+// lookupNFD queries the NFDomains API for the primary name of the address
+func (r *addressResolver) lookupNFD(addr string) (string, error) {
+	query := url.Values{}
+	query.Set("address", addr)
+	query.Set("view", "tiny")
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		fmt.Sprintf("%s/nfd/lookup?%s", r.nfdAPI, query.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// response is a map from the queried address to its NFD
+	var result map[string]struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed decoding response: %v", err)
+	}
+	return result[addr].Name, nil
+}
+
+// txReceiver returns the address receiving funds or assets in the transaction, if there is one
+func txReceiver(txn types.Transaction) types.Address {
+	switch txn.Type {
+	case types.PaymentTx:
+		return txn.Receiver
+	case types.AssetTransferTx:
+		return txn.AssetReceiver
+	}
+	return types.Address{}
+}
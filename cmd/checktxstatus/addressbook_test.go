@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadAddressBookSkipsInvalidLines(t *testing.T) {
+	alice, bob := types.Address{1}, types.Address{2}
+	file := filepath.Join(testDir(t), "book.csv")
+	content := fmt.Sprintf("address,name\n# treasury accounts\n%s, Alice \nnot-an-address,Mallory\n%s\n%s,Bob\n",
+		alice, bob, bob)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	book, err := readAddressBook(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book) != 2 || book[alice.String()] != "Alice" || book[bob.String()] != "Bob" {
+		t.Errorf("expected the names of the valid addresses, got %v", book)
+	}
+}
+
+func TestLabelResolvesNFDOnce(t *testing.T) {
+	alice, bob := types.Address{1}, types.Address{2}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		address := r.URL.Query().Get("address")
+		if address != alice.String() {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{%q:{"name":"alice.algo"}}`, address)
+	}))
+	defer server.Close()
+	resolver, err := initAddressResolver("", true, server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if label := resolver.label(alice); label != "alice.algo ("+alice.String()+")" {
+			t.Errorf("expected the NFD of the address, got %s", label)
+		}
+		if label := resolver.label(bob); label != bob.String() {
+			t.Errorf("expected the address without an NFD as is, got %s", label)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected every address to be looked up once, got %d requests", requests)
+	}
+	if label := (*addressResolver)(nil).label(alice); label != alice.String() {
+		t.Errorf("expected no label without a resolver, got %s", label)
+	}
+}
+
+func TestResolveLooksUpNFDsInItsOwnPool(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak, requests := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		address := r.URL.Query().Get("address")
+		fmt.Fprintf(w, `{%q:{"name":"%s.algo"}}`, address, address[:4])
+	}))
+	defer server.Close()
+	defer func(concurrency int, rate float64) {
+		nfdConcurrency, nfdRateLimit = concurrency, rate
+	}(nfdConcurrency, nfdRateLimit)
+	nfdConcurrency, nfdRateLimit = 3, 0
+	resolver, err := initAddressResolver("", true, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []types.Address
+	for i := byte(1); i <= 8; i++ {
+		addrs = append(addrs, types.Address{i}, types.Address{i}, types.Address{})
+	}
+	resolver.resolve(addrs)
+	if requests != 8 || peak < 2 || peak > 3 {
+		t.Errorf("expected 8 lookups with up to 3 at a time, got %d lookups with up to %d", requests, peak)
+	}
+	addr := types.Address{1}
+	if label := resolver.label(addr); label != fmt.Sprintf("%s.algo (%s)", addr.String()[:4], addr) {
+		t.Errorf("expected the resolved NFD, got %s", label)
+	}
+	if requests != 8 {
+		t.Errorf("expected labeling to use the resolved names, got %d lookups", requests)
+	}
+}
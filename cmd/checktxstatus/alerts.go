@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	alertRuleValues []string
+	alertNotifiers  []string
+)
+
+func init() {
+	watchDirCmd.Flags().StringArrayVar(&alertRuleValues, "alert", nil,
+		"alert rule evaluated after every check, e.g. \"expired > 0 -> critical\" or \"unsent_ratio > 5% for 30m -> "+
+			"warning\": a metric among unsent, expired, total, failed, exit_code and unsent_ratio, a comparison, how long it "+
+			"must hold and the severity among info, warning and critical, can be repeated")
+	watchDirCmd.Flags().StringArrayVar(&alertNotifiers, "alert-notify", nil,
+		"http(s) URL the alerts are POSTed to as JSON when they fire and when they resolve, prefixed by the lowest "+
+			"severity it receives if not all of them, e.g. critical=https://..., can be repeated")
+}
+
+// the severities of the alerts, in increasing order
+const (
+	alertInfo     = "info"
+	alertWarning  = "warning"
+	alertCritical = "critical"
+)
+
+var alertSeverities = []string{alertInfo, alertWarning, alertCritical}
+
+// the metrics of a check the alert rules compare
+const (
+	metricUnsent      = "unsent"
+	metricExpired     = "expired"
+	metricTotal       = "total"
+	metricFailed      = "failed"
+	metricExitCode    = "exit_code"
+	metricUnsentRatio = "unsent_ratio"
+)
+
+// alertRuleRegexp matches an alert rule, e.g. unsent_ratio > 5% for 30m -> warning
+var alertRuleRegexp = regexp.MustCompile(
+	`^\s*([a-z_]+)\s*(>=|<=|==|!=|>|<)\s*([0-9]+(?:\.[0-9]+)?)(%?)\s*(?:for\s+(\S+)\s*)?->\s*([a-z]+)\s*$`)
+
+// alertRule raises an alert of a severity when a metric of the checks compares to a threshold for a while
+type alertRule struct {
+	text      string
+	metric    string
+	op        string
+	threshold float64
+	// duration is how long the comparison must hold before the alert fires, 0 to fire at once
+	duration time.Duration
+	severity string
+	// holding is when the comparison started holding, zero while it does not, and firing whether the alert fired
+	holding time.Time
+	firing  bool
+}
+
+// alertNotifier is a webhook receiving the alerts of severity at least minimum
+type alertNotifier struct {
+	url     string
+	minimum string
+}
+
+// alertEvent is the JSON body POSTed to the notifiers when an alert fires or resolves
+type alertEvent struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	State    string    `json:"state"`
+	Metric   string    `json:"metric"`
+	Value    float64   `json:"value"`
+	File     string    `json:"file"`
+	Since    time.Time `json:"since"`
+}
+
+// alerting evaluates the alert rules after every check of watch-dir
+type alerting struct {
+	rules     []*alertRule
+	notifiers []alertNotifier
+	client    *http.Client
+}
+
+// parseAlerting parses --alert and --alert-notify, it returns nil if there are no rules
+func parseAlerting(rules []string, notifiers []string) (*alerting, error) {
+	if len(rules) == 0 {
+		if len(notifiers) != 0 {
+			return nil, fmt.Errorf("--alert-notify needs --alert rules")
+		}
+		return nil, nil
+	}
+	a := &alerting{client: &http.Client{Timeout: 10 * time.Second}}
+	for _, text := range rules {
+		rule, err := parseAlertRule(text)
+		if err != nil {
+			return nil, err
+		}
+		a.rules = append(a.rules, rule)
+	}
+	for _, value := range notifiers {
+		notifier := alertNotifier{url: value, minimum: alertInfo}
+		if i := strings.Index(value, "="); i > 0 && severityRank(value[:i]) >= 0 {
+			notifier.url, notifier.minimum = value[i+1:], value[:i]
+		}
+		u, err := url.Parse(notifier.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid --alert-notify %q, expected an http(s) URL optionally prefixed by a "+
+				"severity", value)
+		}
+		a.notifiers = append(a.notifiers, notifier)
+	}
+	return a, nil
+}
+
+// parseAlertRule parses a rule of --alert
+func parseAlertRule(text string) (*alertRule, error) {
+	match := alertRuleRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("invalid --alert %q, expected e.g. \"unsent_ratio > 5%% for 30m -> warning\"", text)
+	}
+	rule := &alertRule{text: strings.TrimSpace(text), metric: match[1], op: match[2], severity: match[6]}
+	switch rule.metric {
+	case metricUnsent, metricExpired, metricTotal, metricFailed, metricExitCode, metricUnsentRatio:
+	default:
+		return nil, fmt.Errorf("invalid --alert %q, unknown metric %s", text, rule.metric)
+	}
+	// the regexp only matches numbers
+	rule.threshold, _ = strconv.ParseFloat(match[3], 64)
+	if match[4] != "" {
+		if rule.metric != metricUnsentRatio {
+			return nil, fmt.Errorf("invalid --alert %q, only unsent_ratio is a percentage", text)
+		}
+		rule.threshold /= 100
+	}
+	if match[5] != "" {
+		duration, err := time.ParseDuration(match[5])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("invalid --alert %q, invalid duration %s", text, match[5])
+		}
+		rule.duration = duration
+	}
+	if severityRank(rule.severity) < 0 {
+		return nil, fmt.Errorf("invalid --alert %q, expected the severity among %s", text,
+			strings.Join(alertSeverities, ", "))
+	}
+	return rule, nil
+}
+
+// severityRank returns the rank of a severity in increasing order, -1 if it is unknown
+func severityRank(severity string) int {
+	for i, known := range alertSeverities {
+		if known == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// metric returns the value of a metric of the check of a file
+func (s *runSummary) metric(name string) float64 {
+	switch name {
+	case metricUnsent:
+		return float64(s.unsent)
+	case metricExpired:
+		return float64(s.expired)
+	case metricTotal:
+		return float64(s.total)
+	case metricFailed:
+		if s.failed {
+			return 1
+		}
+		return 0
+	case metricExitCode:
+		return float64(s.exitCode)
+	case metricUnsentRatio:
+		if s.total == 0 {
+			return 0
+		}
+		return float64(s.unsent) / float64(s.total)
+	}
+	return 0
+}
+
+// holds returns whether the comparison of the rule holds for value
+func (r *alertRule) holds(value float64) bool {
+	switch r.op {
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case "==":
+		return value == r.threshold
+	}
+	return value != r.threshold
+}
+
+// evaluate evaluates the rules after the check of filename at now, logging and notifying the alerts that fire, once
+// until they resolve, and the ones that resolve
+func (a *alerting) evaluate(filename string, summary *runSummary, now time.Time) {
+	if a == nil {
+		return
+	}
+	for _, rule := range a.rules {
+		value := summary.metric(rule.metric)
+		if !rule.holds(value) {
+			if rule.firing {
+				logEntryMessage(log.WithField("alert", rule.severity), log.InfoLevel, msgAlertResolved, rule.text,
+					filename, rule.metric, value)
+				a.notify(rule, alertEvent{State: "resolved", Value: value, File: filename, Since: rule.holding})
+			}
+			rule.holding, rule.firing = time.Time{}, false
+			continue
+		}
+		if rule.holding.IsZero() {
+			rule.holding = now
+		}
+		if rule.firing || now.Sub(rule.holding) < rule.duration {
+			continue
+		}
+		rule.firing = true
+		level := log.InfoLevel
+		switch rule.severity {
+		case alertCritical:
+			level = log.ErrorLevel
+		case alertWarning:
+			level = log.WarnLevel
+		}
+		logEntryMessage(log.WithField("alert", rule.severity), level, msgAlertFired, rule.text, filename, rule.metric,
+			value)
+		a.notify(rule, alertEvent{State: "firing", Value: value, File: filename, Since: rule.holding})
+	}
+}
+
+// notify POSTs an event of rule to the notifiers receiving its severity
+// a failed notification is logged, it does not stop the watch
+func (a *alerting) notify(rule *alertRule, event alertEvent) {
+	event.Rule, event.Severity, event.Metric = rule.text, rule.severity, rule.metric
+	// an event of strings, numbers and times always encodes
+	body, _ := json.Marshal(event)
+	for _, notifier := range a.notifiers {
+		if severityRank(rule.severity) < severityRank(notifier.minimum) {
+			continue
+		}
+		err := a.post(notifier.url, body)
+		if err != nil {
+			logMessage(log.WarnLevel, msgAlertNotifyFailed, redactURL(notifier.url, "notifier"), rule.text, err)
+		}
+	}
+}
+
+// post POSTs a JSON body to a notifier
+func (a *alerting) post(target string, body []byte) error {
+	resp, err := a.client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
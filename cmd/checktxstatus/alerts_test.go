@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertRules(t *testing.T) {
+	alerts, err := parseAlerting([]string{"expired > 0 -> critical", "unsent_ratio > 5% for 30m -> warning"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	unsent := &runSummary{total: 100, unsent: 10}
+	alerts.evaluate("a.tx", unsent, start)
+	if alerts.rules[0].firing || alerts.rules[1].firing {
+		t.Error("expected no alert to fire before the ratio held for 30m")
+	}
+	alerts.evaluate("b.tx", unsent, start.Add(30*time.Minute))
+	if !alerts.rules[1].firing {
+		t.Error("expected the ratio alert to fire once it held for 30m")
+	}
+	alerts.evaluate("c.tx", &runSummary{total: 100, unsent: 1}, start.Add(31*time.Minute))
+	if alerts.rules[1].firing || !alerts.rules[1].holding.IsZero() {
+		t.Error("expected the ratio alert to resolve")
+	}
+	for _, rule := range []string{"pending > 0 -> warning", "unsent > 5% -> warning", "expired > 0 -> page"} {
+		if _, err := parseAlertRule(rule); err == nil {
+			t.Errorf("expected %q to be rejected", rule)
+		}
+	}
+}
+
+func TestAlertOnExitCode(t *testing.T) {
+	alerts, err := parseAlerting([]string{"exit_code >= 1 -> critical"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts.evaluate("a.tx", &runSummary{total: 1}, time.Now())
+	if alerts.rules[0].firing {
+		t.Error("expected no alert for a check exiting with 0")
+	}
+	alerts.evaluate("b.tx", &runSummary{failed: true, exitCode: 1}, time.Now())
+	if !alerts.rules[0].firing {
+		t.Error("expected the alert to fire for a failed check")
+	}
+}
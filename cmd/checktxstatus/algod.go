@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+var (
+	algodAddress string
+	algodToken   string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&algodAddress, "algod-addr", "",
+		"address of an algod node whose pending pool is checked for the transactions not found by the indexer, "+
+			"which lags behind the node")
+	rootCmd.Flags().StringVar(&algodToken, "algod-tkn", "", "API token of the --algod-addr node")
+}
+
+// pendingPool looks up transactions in the pending pool of an algod node
+type pendingPool struct {
+	client *algod.Client
+	// concurrency limits the number of concurrent requests to the node, separately from the indexer lookups
+	concurrency *concurrencyController
+}
+
+// initPendingPool returns the pending pool of --algod-addr, nil if not set
+func initPendingPool() (*pendingPool, error) {
+	if algodAddress == "" {
+		return nil, nil
+	}
+	client, err := algod.MakeClientWithHeaders(algodAddress, algodToken, clientHeaders(algodTransport))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating the algod client: %v", err)
+	}
+	return &pendingPool{client: client, concurrency: newConcurrencyController(concurrency)}, nil
+}
+
+// lookupMissing looks up the transactions of txids the indexer did not find in the pending pool, marking the ones
+// the node knows of as sent. Pending transactions may still be dropped, so only the committed ones are recorded in the
+// checkpoint, along with their rounds for the report.
+func (p *pendingPool) lookupMissing(txids []string, sent []bool, backoff *backoffPolicy, checkpoint *txCheckpoint,
+	rounds *confirmedRounds) error {
+	if p == nil {
+		return nil
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i, txid := range txids {
+		if sent[i] {
+			continue
+		}
+		p.concurrency.acquire()
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			p.concurrency.release(0, nil)
+			break
+		}
+		wg.Add(1)
+		go func(i int, txid string) {
+			defer wg.Done()
+			start := time.Now()
+			pending, round, err := p.lookup(txid, backoff)
+			p.concurrency.release(time.Since(start), err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed getting status of tx %s: %v", txid, err)
+				}
+				return
+			}
+			if pending && !confirmedAsOf(txid, round) {
+				return
+			}
+			if pending {
+				log.Debugf("tx %s is not indexed yet but is known to the node", txid)
+				sent[i] = true
+			}
+			if round != 0 {
+				checkpoint.record(txid, true)
+				rounds.record(txid, round)
+			}
+		}(i, txid)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// lookup returns whether the node knows of txid: pending in its pool, or committed recently enough to still be
+// remembered, in which case round is the round it was committed in. Transactions the node dropped from its pool were
+// not sent.
+func (p *pendingPool) lookup(txid string, backoff *backoffPolicy) (sent bool, round uint64, err error) {
+	requestID, headers := nextRequest()
+	log.Debugf("looking up tx %s in the pending pool, request %s", txid, requestID)
+	trace := lookupTrace{start: time.Now(), backend: backendAlgod}
+	err = backoff.retry(fmt.Sprintf("looking up tx %s in the pending pool, request %s,", txid, requestID),
+		func() error {
+			trace.attempts++
+			start := time.Now()
+			resp, _, err := p.client.PendingTransactionInformation(txid).Do(context.Background(), headers...)
+			if err != nil {
+				// the node answers 404 for transactions it does not know of
+				if status, _, ok := txchecker.HTTPStatus(err); ok && status == 404 {
+					bundle.observe(backendAlgod, time.Since(start), nil)
+					return nil
+				}
+				bundle.observe(backendAlgod, time.Since(start), err)
+				return err
+			}
+			bundle.observe(backendAlgod, time.Since(start), nil)
+			if resp.PoolError != "" {
+				log.Debugf("tx %s was dropped from the pending pool: %s", txid, resp.PoolError)
+				return nil
+			}
+			sent, round = true, resp.ConfirmedRound
+			return nil
+		})
+	traceLookup(txid, trace, sent, err)
+	if err != nil {
+		return false, 0, fmt.Errorf("algod request %s: %v", requestID, err)
+	}
+	return sent, round, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestNode returns a fake algod node answering the pending pool lookups, pooled are the transactions of its pool
+// with their pool errors, committed the ones it remembers as committed
+func newTestNode(t *testing.T, pooled map[string]string, committed map[string]uint64) (*algod.Client,
+	*httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txid := strings.TrimPrefix(r.URL.Path, "/v2/transactions/pending/")
+		poolError, ok := pooled[txid]
+		round := committed[txid]
+		if !ok && round == 0 {
+			http.Error(w, `{"message":"txn does not exist"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/msgpack")
+		_, _ = w.Write(msgpack.Encode(map[string]interface{}{"pool-error": poolError, "confirmed-round": round}))
+	}))
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestPendingPoolLookupMissing(t *testing.T) {
+	client, server := newTestNode(t, map[string]string{testTxID(2): "", testTxID(3): "txn dead"},
+		map[string]uint64{testTxID(4): 990})
+	defer server.Close()
+	checkpoint := &txCheckpoint{Sent: map[string]bool{}}
+	pool := &pendingPool{client: client, concurrency: newConcurrencyController(2)}
+	txids := []string{testTxID(1), testTxID(2), testTxID(3), testTxID(4), testTxID(5)}
+	sent := []bool{true, false, false, false, false}
+	err := pool.lookupMissing(txids, sent, newTestBackoff(), checkpoint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []bool{true, true, false, true, false}
+	for i := range expected {
+		if sent[i] != expected[i] {
+			t.Fatalf("expected the pending and committed transactions to be sent, got %v", sent)
+		}
+	}
+	if _, ok := checkpoint.Sent[testTxID(2)]; ok || !checkpoint.Sent[testTxID(4)] {
+		t.Errorf("expected only the committed transaction to be checkpointed, got %v", checkpoint.Sent)
+	}
+}
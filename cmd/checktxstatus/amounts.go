@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+var (
+	maxAmounts   []string
+	confirmLarge bool
+)
+
+func init() {
+	rootCmd.Flags().StringSliceVar(&maxAmounts, "max-amount", nil,
+		"hold back unsent transactions moving more than this amount for approval: "+
+			"Algos (e.g. 1000) or <asset-id>:<base units> (e.g. 31566704:5000000), can be repeated")
+	rootCmd.Flags().BoolVar(&confirmLarge, "confirm-large", false,
+		"approve resubmitting unsent transactions exceeding --max-amount without asking")
+}
+
+// amountLimits are the maximal amounts transactions may move without approval
+type amountLimits struct {
+	// microAlgos is the limit on payments, zero means no limit
+	microAlgos uint64
+	// assets are the limits on asset transfers by asset ID
+	assets map[uint64]uint64
+}
+
+// parseAmountLimits parses the values of --max-amount, it returns nil if there are none
+func parseAmountLimits(values []string) (*amountLimits, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	limits := &amountLimits{assets: map[uint64]uint64{}}
+	for _, value := range values {
+		if parts := strings.SplitN(value, ":", 2); len(parts) == 2 {
+			assetID, err := strconv.ParseUint(parts[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid asset ID in --max-amount %q", value)
+			}
+			amount, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid amount in --max-amount %q", value)
+			}
+			limits.assets[assetID] = amount
+			continue
+		}
+		algos, err := strconv.ParseFloat(value, 64)
+		if err != nil || algos < 0 {
+			return nil, fmt.Errorf("invalid Algo amount in --max-amount %q", value)
+		}
+		limits.microAlgos = uint64(types.ToMicroAlgos(algos))
+	}
+	return limits, nil
+}
+
+// exceeded returns why the transaction exceeds the limits, or an empty string if it does not
+// closing an account moves its whole balance, which is considered exceeding any limit set for it
+func (l *amountLimits) exceeded(txn types.Transaction) string {
+	if l == nil {
+		return ""
+	}
+	switch txn.Type {
+	case types.PaymentTx:
+		if l.microAlgos == 0 {
+			return ""
+		}
+		if !txn.CloseRemainderTo.IsZero() {
+			return fmt.Sprintf("closes the account to %s", txn.CloseRemainderTo)
+		}
+		if uint64(txn.Amount) > l.microAlgos {
+			return fmt.Sprintf("pays %s Algo, more than %s", formatAlgos(uint64(txn.Amount)), formatAlgos(l.microAlgos))
+		}
+	case types.AssetTransferTx:
+		limit, ok := l.assets[uint64(txn.XferAsset)]
+		if !ok {
+			return ""
+		}
+		if !txn.AssetCloseTo.IsZero() {
+			return fmt.Sprintf("closes the asset %d holding to %s", txn.XferAsset, txn.AssetCloseTo)
+		}
+		if txn.AssetAmount > limit {
+			return fmt.Sprintf("transfers %d of asset %d, more than %d", txn.AssetAmount, txn.XferAsset, limit)
+		}
+	}
+	return ""
+}
+
+// holdLarge separates the unsent transactions exceeding the limits that were not approved from the rest
+// approval is given with --confirm-large or interactively; groups are resubmitted as a whole, so all the members
+// of a group with a transaction exceeding the limits are held back
+func (l *amountLimits) holdLarge(filename string, unsentTxs []txRecord) (approved []txRecord, held []txRecord) {
+	if l == nil {
+		return unsentTxs, nil
+	}
+	logger := log.WithField("file", filename)
+	largeGroups := map[types.Digest]bool{}
+	largeCount := 0
+	for _, tx := range unsentTxs {
+		reason := l.exceeded(tx.stx.Txn)
+		if reason == "" {
+			continue
+		}
+		largeCount++
+		logEntryMessage(logger, log.WarnLevel, msgAmountHeld, tx.txID, reason)
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			largeGroups[gid] = true
+		}
+	}
+	if largeCount == 0 || confirmLarge {
+		return unsentTxs, nil
+	}
+	if isInteractive() && ask(fmt.Sprintf("approve resubmitting %d unsent transactions in %s exceeding --max-amount?",
+		largeCount, filename)) {
+		return unsentTxs, nil
+	}
+	for _, tx := range unsentTxs {
+		gid := tx.stx.Txn.Group
+		if (gid != types.Digest{} && largeGroups[gid]) || l.exceeded(tx.stx.Txn) != "" {
+			held = append(held, tx)
+		} else {
+			approved = append(approved, tx)
+		}
+	}
+	logEntryMessage(logger, log.WarnLevel, msgAmountsHeld, len(held))
+	return approved, held
+}
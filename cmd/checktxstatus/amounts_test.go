@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestParseAmountLimits(t *testing.T) {
+	limits, err := parseAmountLimits([]string{"1.5", "31566704:5000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.microAlgos != 1500000 || limits.assets[31566704] != 5000000 {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	for _, value := range []string{"-1", "many", "asset:1", "1:many"} {
+		if _, err := parseAmountLimits([]string{value}); err == nil {
+			t.Errorf("expected --max-amount %q to be invalid", value)
+		}
+	}
+	if limits, err := parseAmountLimits(nil); limits != nil || err != nil {
+		t.Errorf("expected no limits, got %v, %v", limits, err)
+	}
+}
+
+func TestHoldLargeHoldsWholeGroups(t *testing.T) {
+	limits := &amountLimits{microAlgos: 1000, assets: map[uint64]uint64{7: 10}}
+	tx := func(txID string, txType types.TxType, amount uint64, group types.Digest) txRecord {
+		rec := txRecord{txID: txID}
+		rec.stx.Txn.Type = txType
+		rec.stx.Txn.Amount = types.MicroAlgos(amount)
+		rec.stx.Txn.XferAsset = 7
+		rec.stx.Txn.AssetAmount = amount
+		rec.stx.Txn.Group = group
+		return rec
+	}
+	closing := tx(testTxID(5), types.PaymentTx, 1, types.Digest{})
+	closing.stx.Txn.CloseRemainderTo = types.Address{1}
+	if reason := limits.exceeded(closing.stx.Txn); !strings.Contains(reason, "closes the account") {
+		t.Errorf("expected closing an account to exceed the limit, got %q", reason)
+	}
+	unsent := []txRecord{
+		tx(testTxID(1), types.PaymentTx, 1000, types.Digest{}),
+		tx(testTxID(2), types.AssetTransferTx, 11, types.Digest{}),
+		tx(testTxID(3), types.PaymentTx, 1, types.Digest{1}),
+		tx(testTxID(4), types.PaymentTx, 1001, types.Digest{1}),
+	}
+	// tests do not run on a terminal, so the transactions are held back without asking
+	approved, held := limits.holdLarge("test", unsent)
+	if len(approved) != 1 || approved[0].txID != testTxID(1) || len(held) != 3 {
+		t.Errorf("expected the large transactions and their group to be held back, got %v and %v", approved, held)
+	}
+	confirmLarge = true
+	defer func() { confirmLarge = false }()
+	if approved, held := limits.holdLarge("test", unsent); len(approved) != 4 || held != nil {
+		t.Error("expected --confirm-large to approve all transactions")
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	archiveDir    string
+	archiveDelete bool
+)
+
+// the name of the manifest in an archive, beside the archived file and its report, and the suffix of archives
+const (
+	archiveManifestName = "manifest.json"
+	archiveSuffix       = ".archive.tar.gz"
+)
+
+var archiveCmd = &cobra.Command{
+	Use: "archive <file1.tx> <file2.tx> ...",
+	Short: "Archive transactions files once all of their transactions are confirmed, in compressed and checksummed " +
+		"bundles of the files, their reports and a manifest",
+	Example: `  # archive a batch at the end of its life, removing it once the archive is verified
+  checktxstatus archive --archive-dir /srv/archive --delete --idx-addr http://localhost:8980 batch.tx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		if len(ageRecipients) != 0 || len(gpgRecipients) != 0 {
+			logMessage(log.ErrorLevel, msgArchiveEncrypted)
+			exitCode = 1
+			return
+		}
+		if len(args) == 0 {
+			logError(newUserError(msgNoInputs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+		failed := false
+		for _, filename := range args {
+			err := archiveFile(cmd, filepath.Clean(filename))
+			if err != nil {
+				logError(err)
+				failed = true
+			}
+		}
+		exitCode = 0
+		if failed {
+			exitCode = 1
+		}
+	},
+}
+
+func init() {
+	archiveCmd.Flags().StringVar(&archiveDir, "archive-dir", "",
+		"directory the archives are written to (default: the directory of every file)")
+	archiveCmd.Flags().BoolVar(&archiveDelete, "delete", false,
+		"delete the files, their reports and their indexes once their archives are written and verified")
+}
+
+// initArchiveCmd adds the archive subcommand, sharing the flags of the root command that affect checking
+// it must be called after the flags of the root command were registered
+func initArchiveCmd() {
+	for _, name := range checkSharedFlags {
+		archiveCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(archiveCmd)
+}
+
+// archiveManifest describes an archive, its members are hashed so the archive can be verified
+type archiveManifest struct {
+	ToolVersion string     `json:"tool_version"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	// Input is the path of the archived file when it was archived
+	Input        string `json:"input"`
+	Transactions int    `json:"transactions"`
+	// FirstConfirmedRound and LastConfirmedRound bound the rounds the transactions were confirmed in, when known
+	FirstConfirmedRound uint64 `json:"first_confirmed_round,omitempty"`
+	LastConfirmedRound  uint64 `json:"last_confirmed_round,omitempty"`
+	// Members are the other members of the archive, by their names in it
+	Members []manifestFileEntry `json:"members"`
+}
+
+// archiveName returns the name of the archive of filename
+func archiveName(filename string) string {
+	dir := archiveDir
+	if dir == "" {
+		dir = filepath.Dir(filename)
+	}
+	return filepath.Join(dir, filepath.Base(filename)+archiveSuffix)
+}
+
+// archiveFile checks a file with --report and archives it with its report if all of its transactions are confirmed
+// an existing archive is never overwritten, so archiving a file again cannot replace the archive of another version
+func archiveFile(cmd *cobra.Command, filename string) error {
+	if stat, err := os.Stat(filename); err != nil || !stat.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a file, only files can be archived", filename)
+	}
+	archive := archiveName(filename)
+	if _, err := os.Stat(archive); err == nil {
+		return fmt.Errorf("%s is already archived in %s", filename, archive)
+	}
+	savedReport := writeReport
+	writeReport = true
+	exitCode = 0
+	manifest, summary := runCheck(cmd, []string{filename})
+	writeReport = savedReport
+	if manifest == nil || summary.failed {
+		return fmt.Errorf("failed checking %s, not archiving it", filename)
+	}
+	reportPath := ""
+	for _, output := range manifest.Outputs {
+		if strings.HasSuffix(output.Path, ".report.json") {
+			reportPath = output.Path
+		}
+	}
+	if reportPath == "" {
+		return fmt.Errorf("the check of %s wrote no report, not archiving it", filename)
+	}
+	report, err := ioutil.ReadFile(reportPath)
+	if err != nil {
+		return fmt.Errorf("error while reading %s: %v", reportPath, err)
+	}
+	var entries []txReportEntry
+	err = json.Unmarshal(report, &entries)
+	if err != nil {
+		return fmt.Errorf("error while parsing %s: %v", reportPath, err)
+	}
+	description := archiveManifest{ToolVersion: version, Input: filename, Transactions: len(entries)}
+	notConfirmed := 0
+	for _, entry := range entries {
+		if entry.Classification != string(conditionConfirmed) {
+			if notConfirmed == 0 {
+				logMessage(log.WarnLevel, msgArchiveNotConfirmed, entry.TxID, filename, entry.Classification)
+			}
+			notConfirmed++
+			continue
+		}
+		round := entry.ConfirmedRound
+		if round != 0 && (description.FirstConfirmedRound == 0 || round < description.FirstConfirmedRound) {
+			description.FirstConfirmedRound = round
+		}
+		if round > description.LastConfirmedRound {
+			description.LastConfirmedRound = round
+		}
+	}
+	if notConfirmed != 0 {
+		return fmt.Errorf("%d of the %d transactions of %s are not confirmed, not archiving it", notConfirmed,
+			len(entries), filename)
+	}
+	if !deterministic {
+		now := time.Now().UTC()
+		description.ArchivedAt = &now
+	}
+	err = writeArchive(archive, &description, []string{filename, reportPath})
+	if err != nil {
+		return err
+	}
+	err = verifyArchive(archive, description)
+	if err != nil {
+		return fmt.Errorf("archive %s is corrupt, keeping %s: %v", archive, filename, err)
+	}
+	logEntryMessage(log.WithField("file", filename), log.InfoLevel, msgArchived, len(entries), filename, archive)
+	if !archiveDelete {
+		return nil
+	}
+	originals := []string{filename, reportPath}
+	if _, err := os.Stat(indexFilename(filename)); err == nil {
+		originals = append(originals, indexFilename(filename))
+	}
+	for _, original := range originals {
+		err := removeFile(original)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %v", original, err)
+		}
+		logMessage(log.InfoLevel, msgArchiveDeleted, original)
+	}
+	return nil
+}
+
+// writeArchive writes the files and the manifest describing them to a gzipped tar archive, and the SHA-256 digest
+// of the archive to a sidecar file in the format of sha256sum
+// the members of the manifest are set as the files are written
+func writeArchive(archive string, description *archiveManifest, files []string) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	modTime := time.Now()
+	if deterministic {
+		modTime = time.Unix(0, 0)
+	}
+	addMember := func(name string, content []byte) error {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: modTime})
+		if err == nil {
+			_, err = tw.Write(content)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive %s: %v", name, archive, err)
+		}
+		return nil
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %v", file, err)
+		}
+		digest := sha256.Sum256(content)
+		name := filepath.Base(file)
+		description.Members = append(description.Members, manifestFileEntry{Path: name,
+			Size: int64(len(content)), SHA256: hex.EncodeToString(digest[:])})
+		err = addMember(name, content)
+		zeroize(content)
+		if err != nil {
+			return err
+		}
+	}
+	encoded, err := json.MarshalIndent(description, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding the manifest of archive %s: %v", archive, err)
+	}
+	err = addMember(archiveManifestName, append(encoded, '\n'))
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive %s: %v", archive, err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive %s: %v", archive, err)
+	}
+	err = writeFileAtomic(archive, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write archive %s: %v", archive, err)
+	}
+	digest := sha256.Sum256(buf.Bytes())
+	checksum := fmt.Sprintf("%s  %s\n", hex.EncodeToString(digest[:]), filepath.Base(archive))
+	err = writeFileAtomic(archive+".sha256", []byte(checksum))
+	if err != nil {
+		return fmt.Errorf("failed to write %s.sha256: %v", archive, err)
+	}
+	return nil
+}
+
+// verifyArchive reads an archive back, making sure it has the members of its manifest with their digests
+func verifyArchive(archive string, description archiveManifest) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	expected := map[string]string{archiveManifestName: ""}
+	for _, member := range description.Members {
+		expected[member.Path] = member.SHA256
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		digest, ok := expected[header.Name]
+		if !ok {
+			return fmt.Errorf("unexpected member %s", header.Name)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, tr)
+		if err != nil {
+			return err
+		}
+		if digest != "" && hex.EncodeToString(h.Sum(nil)) != digest {
+			return fmt.Errorf("member %s does not match its digest", header.Name)
+		}
+		delete(expected, header.Name)
+	}
+	if len(expected) != 0 {
+		missing := make([]string, 0, len(expected))
+		for name := range expected {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return fmt.Errorf("members %s are missing", strings.Join(missing, ", "))
+	}
+	return nil
+}
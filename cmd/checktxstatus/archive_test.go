@@ -0,0 +1,28 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteArchiveIsVerified(t *testing.T) {
+	dir := testDir(t)
+	file := filepath.Join(dir, "batch.tx")
+	if err := ioutil.WriteFile(file, []byte("transactions"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	archive := file + archiveSuffix
+	description := archiveManifest{Input: file, Transactions: 1}
+	if err := writeArchive(archive, &description, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyArchive(archive, description); err != nil {
+		t.Errorf("expected the archive to be verified, got %v", err)
+	}
+	description.Members[0].SHA256 = strings.Repeat("0", 64)
+	if err := verifyArchive(archive, description); err == nil {
+		t.Error("expected a member not matching its digest to fail the verification")
+	}
+}
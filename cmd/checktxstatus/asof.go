@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+var asOfRound uint64
+
+func init() {
+	rootCmd.Flags().Uint64Var(&asOfRound, "as-of-round", 0,
+		"classify the transactions as they were at the end of this round: the ones confirmed later are unsent, and "+
+			"the ones whose last valid round was reached by then are expired (0 for the latest round)")
+}
+
+// validateAsOfRound makes sure --as-of-round is not mixed with the statuses of a checkpoint, which are the latest ones
+func validateAsOfRound() error {
+	if asOfRound != 0 && checkpointFile != "" {
+		return fmt.Errorf("--as-of-round and --checkpoint are mutually exclusive")
+	}
+	return nil
+}
+
+// confirmedAsOf returns whether a transaction confirmed in round was confirmed by the end of --as-of-round
+// round is 0 for transactions that were not confirmed, e.g. the ones pending in the pool of a node
+func confirmedAsOf(txid string, round uint64) bool {
+	if asOfRound == 0 {
+		return true
+	}
+	if round == 0 || round > asOfRound {
+		log.Debugf("tx %s was not confirmed by round %d", txid, asOfRound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestConfirmedAsOf(t *testing.T) {
+	defer func(round uint64) { asOfRound = round }(asOfRound)
+	asOfRound = 0
+	if !confirmedAsOf(testTxID(1), 0) {
+		t.Error("expected every found transaction to be confirmed without --as-of-round")
+	}
+	asOfRound = 1000
+	if !confirmedAsOf(testTxID(1), 1000) || confirmedAsOf(testTxID(1), 1001) || confirmedAsOf(testTxID(1), 0) {
+		t.Error("expected only the transactions confirmed by --as-of-round to be confirmed")
+	}
+}
+
+func TestAsOfRoundExpiresAgainstTheHistoricalRound(t *testing.T) {
+	defer func(round uint64) { asOfRound = round }(asOfRound)
+	asOfRound = 500
+	checks := 0
+	r := newTestRoundIndexer(t, 1000, &checks)
+	round, err := r.latest()
+	if err != nil || round != 500 || checks != 0 {
+		t.Fatalf("expected --as-of-round without a lookup, got %d, %v after %d lookups", round, err, checks)
+	}
+	rec := txRecord{stx: types.SignedTxn{Txn: types.Transaction{Header: types.Header{LastValid: 400}}}}
+	if detail := r.expiryDetail(rec); !strings.Contains(detail, "reached by --as-of-round 500") {
+		t.Errorf("expected the expiry against --as-of-round, got %q", detail)
+	}
+}
+
+func TestValidateAsOfRound(t *testing.T) {
+	defer func(round uint64, checkpoint string) {
+		asOfRound, checkpointFile = round, checkpoint
+	}(asOfRound, checkpointFile)
+	asOfRound, checkpointFile = 500, "checkpoint.json"
+	if err := validateAsOfRound(); err == nil {
+		t.Error("expected --as-of-round with --checkpoint to be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// backendPool runs the requests to a backend with its own workers and rate limit, so a slow or throttled backend
+// only delays its own requests; the indexer lookups are limited by their concurrencyController instead
+type backendPool struct {
+	name    string
+	slots   chan struct{}
+	limiter *tokenBucket
+}
+
+// newBackendPool returns the pool of a backend with workers concurrent requests and at most rate requests per
+// second, 0 for no rate limit
+func newBackendPool(name string, workers int, rate float64) (*backendPool, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("the concurrency of %s must be at least 1", name)
+	}
+	if rate < 0 {
+		return nil, fmt.Errorf("the rate limit of %s must not be negative", name)
+	}
+	return &backendPool{name: name, slots: make(chan struct{}, workers), limiter: newTokenBucket(rate, 0)}, nil
+}
+
+// run calls request for each of n requests in the pool and waits for all of them to complete
+func (p *backendPool) run(n int, request func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		p.slots <- struct{}{}
+		p.limiter.wait()
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-p.slots
+				wg.Done()
+			}()
+			request(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackendPoolLimitsWorkers(t *testing.T) {
+	if _, err := newBackendPool("test", 0, 0); err == nil {
+		t.Error("expected an error without workers")
+	}
+	if _, err := newBackendPool("test", 1, -1); err == nil {
+		t.Error("expected an error on a negative rate limit")
+	}
+	pool, err := newBackendPool("test", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	done := make([]bool, 6)
+	pool.run(len(done), func(i int) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		done[i] = true
+		mu.Unlock()
+	})
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("expected request %d to run", i)
+		}
+	}
+	if peak != 2 {
+		t.Errorf("expected 2 concurrent requests, got %d", peak)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"time"
+)
+
+var (
+	backoffStrategy string
+	backoffBase     time.Duration
+	backoffMax      time.Duration
+	maxRetries      int
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&backoffStrategy, "backoff", string(backoffExponential),
+		"strategy of the delays between retries of failed requests: exponential, decorrelated-jitter or fixed")
+	rootCmd.Flags().DurationVar(&backoffBase, "backoff-base", 500*time.Millisecond,
+		"delay before the first retry of a failed request (the delay before every retry with --backoff fixed)")
+	rootCmd.Flags().DurationVar(&backoffMax, "backoff-max", 30*time.Second, "maximal delay between retries")
+	rootCmd.Flags().IntVar(&maxRetries, "retries", 3,
+		"number of times a request failing with a network error, 429 or 5xx response is retried (0 to disable)")
+}
+
+// backoffKind is a strategy of the delays between retries
+type backoffKind = txchecker.BackoffKind
+
+// the strategies of --backoff
+const (
+	backoffExponential        = txchecker.BackoffExponential
+	backoffDecorrelatedJitter = txchecker.BackoffDecorrelatedJitter
+	backoffFixed              = txchecker.BackoffFixed
+)
+
+// backoffPolicy retries requests failing with transient errors, logging the retries
+type backoffPolicy struct {
+	*txchecker.Backoff
+}
+
+// initBackoff returns the backoff policy set by the --backoff and --retries flags
+func initBackoff() (*backoffPolicy, error) {
+	kind := backoffKind(backoffStrategy)
+	switch kind {
+	case backoffExponential, backoffDecorrelatedJitter, backoffFixed:
+	default:
+		return nil, fmt.Errorf("unknown --backoff %q", backoffStrategy)
+	}
+	if backoffBase <= 0 {
+		return nil, fmt.Errorf("--backoff-base must be positive")
+	}
+	if backoffMax < backoffBase {
+		return nil, fmt.Errorf("--backoff-max must be at least --backoff-base")
+	}
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("--retries can not be negative")
+	}
+	seed := time.Now().UnixNano()
+	if deterministic {
+		seed = 1
+	}
+	return &backoffPolicy{&txchecker.Backoff{
+		Kind:      kind,
+		Base:      backoffBase,
+		Max:       backoffMax,
+		Retries:   maxRetries,
+		Rand:      rand.New(rand.NewSource(seed)),
+		Transient: isTransient,
+	}}, nil
+}
+
+// retry calls fn until it succeeds, fails with an error that is not transient, or the retries run out
+// what describes the request in the logs
+func (p *backoffPolicy) retry(what string, fn func() error) error {
+	return p.Retry(fn, p.logRetry(what))
+}
+
+// logRetry returns the function logging the retries of the request described by what
+func (p *backoffPolicy) logRetry(what string) func(retry int, delay time.Duration, err error) {
+	return func(retry int, delay time.Duration, err error) {
+		logMessage(log.WarnLevel, msgRequestRetry, what, retry, p.Retries, delay, err)
+	}
+}
+
+// isTransient returns true if a request failing with err may succeed when retried: network errors,
+// 429 (too many requests) and 5xx responses
+func isTransient(err error) bool {
+	if err == errBudgetExhausted || err == errInterrupted {
+		// no request was made
+		return false
+	}
+	return txchecker.IsTransient(err)
+}
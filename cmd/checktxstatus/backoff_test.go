@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	policy := &backoffPolicy{&txchecker.Backoff{Kind: backoffExponential, Base: time.Second, Max: 5 * time.Second}}
+	var delays []time.Duration
+	for retry := 1; retry <= 4; retry++ {
+		delays = append(delays, policy.Delay(retry, 0))
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Fatalf("expected exponential delays %v, got %v", expected, delays)
+		}
+	}
+	policy = &backoffPolicy{&txchecker.Backoff{Kind: backoffDecorrelatedJitter, Base: time.Second, Max: time.Minute,
+		Rand: rand.New(rand.NewSource(1))}}
+	prev := time.Duration(0)
+	for retry := 1; retry <= 20; retry++ {
+		d := policy.Delay(retry, prev)
+		if d < time.Second || d > time.Minute || (prev != 0 && d > 3*prev) {
+			t.Fatalf("delay %s after %s is out of bounds", d, prev)
+		}
+		prev = d
+	}
+}
+
+func TestBackoffRetriesOnlyTransientErrors(t *testing.T) {
+	var slept []time.Duration
+	policy := &backoffPolicy{&txchecker.Backoff{Kind: backoffFixed, Base: time.Second, Max: time.Second, Retries: 2,
+		Sleep: func(d time.Duration) { slept = append(slept, d) }, Transient: isTransient}}
+	calls := 0
+	err := policy.retry("test", func() error {
+		calls++
+		return fmt.Errorf("HTTP 503: unavailable")
+	})
+	if err == nil || calls != 3 || len(slept) != 2 {
+		t.Errorf("expected 2 retries of a 503, got %d calls, %v", calls, err)
+	}
+	calls = 0
+	err = policy.retry("test", func() error {
+		calls++
+		return fmt.Errorf("HTTP 400: bad request")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a 400 not to be retried, got %d calls", calls)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := map[error]bool{
+		fmt.Errorf("HTTP 429: slow down"):        true,
+		fmt.Errorf("HTTP 502: bad gateway"):      true,
+		fmt.Errorf("dial tcp: connection reset"): true,
+		fmt.Errorf("HTTP 404: not found"):        false,
+		io.EOF:                                   false,
+	}
+	for err, expected := range tests {
+		if isTransient(err) != expected {
+			t.Errorf("expected %q transient to be %v", err, expected)
+		}
+	}
+}
+
+func TestInitBackoffValidatesFlags(t *testing.T) {
+	defer func() { backoffStrategy, maxRetries = string(backoffExponential), 3 }()
+	backoffStrategy = "linear"
+	if _, err := initBackoff(); err == nil {
+		t.Error("expected an unknown strategy to fail")
+	}
+	backoffStrategy, maxRetries = string(backoffFixed), -1
+	if _, err := initBackoff(); err == nil {
+		t.Error("expected negative retries to fail")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// batchFiles returns the files of a batch: the file itself, or the regular files of a directory such as the ones
+// written by goal when dumping one transaction per file, in natural order (tx-2 before tx-10)
+// hidden files are ignored
+func batchFiles(path string) ([]string, error) {
+	if path == stdioName {
+		return []string{path}, nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", path, err)
+	}
+	if !stat.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing %s: %v", path, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalLess(files[i], files[j])
+	})
+	return files, nil
+}
+
+// batchDigest returns the SHA-256 digest identifying the content of an input file, or of all the files of an input
+// directory, regardless of its path
+// the digest of a directory is the digest of the hex digests of its files, in batch order
+func batchDigest(path string) (string, error) {
+	files, err := batchFiles(path)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 1 && files[0] == path {
+		entry, err := hashFile(path)
+		return entry.SHA256, err
+	}
+	h := sha256.New()
+	for _, file := range files {
+		entry, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(entry.SHA256))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readBatch reads the transactions of an input file, or of all the files of an input directory as a single batch
+// groups may span several files of a directory as long as their transactions are in consecutive files
+// decoded is called with every decoded transaction and whether it is the first of its group, if it is not nil
+func readBatch(path string, decoded func(rec txRecord, firstOfGroup bool)) (*txBatch, error) {
+	files, err := batchFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 1 || files[0] != path {
+		logMessage(log.InfoLevel, msgBatchDirectory, len(files), path)
+	}
+	batch := &txBatch{groups: map[types.Digest][]txRecord{}}
+	for _, filename := range files {
+		err = readTxFile(filename, batch, decoded)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+// naturalLess compares strings treating runs of digits as numbers
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aNum, aErr := strconv.ParseUint(aDigits, 10, 64)
+			bNum, bErr := strconv.ParseUint(bDigits, 10, 64)
+			if aErr == nil && bErr == nil && aNum != bNum {
+				return aNum < bNum
+			}
+			if aDigits != bDigits {
+				return aDigits < bDigits
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"tx-10", "tx-2", "tx-1b", "tx-1", "tx-01", "a"}
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	expected := []string{"a", "tx-01", "tx-1", "tx-1b", "tx-2", "tx-10"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestReadBatchOfDirectory(t *testing.T) {
+	dir := testDir(t)
+	group := types.Digest{1}
+	for i, name := range []string{"tx-10", "tx-2", "tx-1", ".hidden"} {
+		stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx,
+			Header: types.Header{FirstValid: types.Round(i + 1)}}}
+		if name != "tx-1" {
+			stx.Txn.Group = group
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), msgpack.Encode(stx), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := readBatch(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.count != 3 || len(batch.individual) != 1 || len(batch.groups[group]) != 2 {
+		t.Fatalf("expected the regular files of the directory in a single batch, got %+v", batch)
+	}
+	// the group spans tx-2 and tx-10, in natural order
+	members := batch.groups[group]
+	if members[0].file != filepath.Join(dir, "tx-2") || members[0].index != 1 || members[0].fileIndex != 0 ||
+		members[1].file != filepath.Join(dir, "tx-10") || members[1].index != 2 {
+		t.Errorf("unexpected group members %+v", members)
+	}
+}
+
+func TestBatchDigestIdentifiesContent(t *testing.T) {
+	dir := testDir(t)
+	write := func(name string, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	digest := func(path string) string {
+		d, err := batchDigest(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	first, renamed := write("a.tx", "txs"), write("b.tx", "txs")
+	if digest(first) != digest(renamed) {
+		t.Error("expected files of the same content to have the same digest")
+	}
+	write("batch/tx-1", "txs")
+	write("batch/tx-2", "more")
+	batchDir := filepath.Join(dir, "batch")
+	before := digest(batchDir)
+	if before == digest(first) {
+		t.Error("expected a directory to have the digest of all of its files")
+	}
+	write("batch/tx-2", "changed")
+	if digest(batchDir) == before {
+		t.Error("expected a changed file to change the digest of its directory")
+	}
+}
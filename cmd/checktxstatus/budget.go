@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	maxRequestsStr    string
+	maxRequestCostStr string
+	requestCostsStrs  []string
+	budgetSlowdown    time.Duration
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&maxRequestsStr, "max-requests", "",
+		"budget of indexer requests, HARD or SOFT/HARD (e.g. 8000/10000, the soft budget is 80% of the hard one "+
+			"by default): past the soft budget requests are slowed down, at the hard budget the run stops")
+	rootCmd.Flags().StringVar(&maxRequestCostStr, "max-request-cost", "",
+		"budget of the total cost of indexer requests (see --request-cost), HARD or SOFT/HARD like --max-requests")
+	rootCmd.Flags().StringSliceVar(&requestCostsStrs, "request-cost", nil,
+		"cost of a kind of indexer request for --max-request-cost, as kind=cost where kind is tx, account, block "+
+			"or health (1 by default), can be repeated")
+	rootCmd.Flags().DurationVar(&budgetSlowdown, "budget-slowdown", time.Second,
+		"delay added before every indexer request past a soft budget")
+}
+
+// requestKind is a kind of indexer request, with its own cost
+type requestKind string
+
+const (
+	requestTx      requestKind = "tx"
+	requestAccount requestKind = "account"
+	requestBlock   requestKind = "block"
+	requestHealth  requestKind = "health"
+)
+
+// errBudgetExhausted is returned by requests past a hard budget
+var errBudgetExhausted = errors.New("request budget exhausted")
+
+// budgetLimit is a soft and a hard limit, a zero hard limit means no limit
+type budgetLimit struct {
+	soft float64
+	hard float64
+}
+
+// parseBudgetLimit parses HARD or SOFT/HARD, the soft limit is 80% of the hard one unless set
+func parseBudgetLimit(value string) (budgetLimit, error) {
+	if value == "" {
+		return budgetLimit{}, nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) > 2 {
+		return budgetLimit{}, fmt.Errorf("invalid budget %q", value)
+	}
+	var limits []float64
+	for _, part := range parts {
+		limit, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || limit <= 0 {
+			return budgetLimit{}, fmt.Errorf("invalid budget %q", value)
+		}
+		limits = append(limits, limit)
+	}
+	if len(limits) == 1 {
+		return budgetLimit{soft: 0.8 * limits[0], hard: limits[0]}, nil
+	}
+	if limits[0] > limits[1] {
+		return budgetLimit{}, fmt.Errorf("soft budget of %q is larger than the hard one", value)
+	}
+	return budgetLimit{soft: limits[0], hard: limits[1]}, nil
+}
+
+// parseRequestCosts parses the --request-cost kind=cost entries, kinds missing from them cost 1
+func parseRequestCosts(entries []string) (map[requestKind]float64, error) {
+	costs := map[requestKind]float64{requestTx: 1, requestAccount: 1, requestBlock: 1, requestHealth: 1}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --request-cost %q, expected kind=cost", entry)
+		}
+		kind := requestKind(strings.TrimSpace(parts[0]))
+		if _, ok := costs[kind]; !ok {
+			return nil, fmt.Errorf("invalid --request-cost %q, unknown request kind %s", entry, kind)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid --request-cost %q", entry)
+		}
+		costs[kind] = value
+	}
+	return costs, nil
+}
+
+// requestBudget limits the number and the cost of indexer requests, for metered API plans
+type requestBudget struct {
+	mu       sync.Mutex
+	requests budgetLimit
+	cost     budgetLimit
+	costs    map[requestKind]float64
+	// usedRequests and usedCost are the requests made so far and their cost
+	usedRequests float64
+	usedCost     float64
+	// slowed is set once a soft budget was reached
+	slowed bool
+	sleep  func(time.Duration)
+}
+
+// budget limits the indexer requests of the run, nil means no limits
+var budget *requestBudget
+
+// initBudget sets the budget of the run from the --max-requests, --max-request-cost and --request-cost flags
+func initBudget() error {
+	requests, err := parseBudgetLimit(maxRequestsStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max-requests: %v", err)
+	}
+	cost, err := parseBudgetLimit(maxRequestCostStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max-request-cost: %v", err)
+	}
+	costs, err := parseRequestCosts(requestCostsStrs)
+	if err != nil {
+		return err
+	}
+	if requests.hard == 0 && cost.hard == 0 {
+		return nil
+	}
+	budget = &requestBudget{requests: requests, cost: cost, costs: costs, sleep: time.Sleep}
+	return nil
+}
+
+// spend accounts for a request of the given kind before it is made
+// it slows down past a soft budget and returns errBudgetExhausted instead of exceeding a hard budget
+// it returns errInterrupted once the run was interrupted, whether a budget is set or not
+func (b *requestBudget) spend(kind requestKind) error {
+	if isInterrupted() {
+		return errInterrupted
+	}
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	requests, cost := b.usedRequests+1, b.usedCost+b.costs[kind]
+	if (b.requests.hard != 0 && requests > b.requests.hard) || (b.cost.hard != 0 && cost > b.cost.hard) {
+		b.mu.Unlock()
+		return errBudgetExhausted
+	}
+	b.usedRequests, b.usedCost = requests, cost
+	slow := (b.requests.hard != 0 && requests > b.requests.soft) || (b.cost.hard != 0 && cost > b.cost.soft)
+	if slow && !b.slowed {
+		b.slowed = true
+		logMessage(log.WarnLevel, msgBudgetSoft, requests, cost, budgetSlowdown)
+	}
+	b.mu.Unlock()
+	if slow {
+		b.sleep(budgetSlowdown)
+	}
+	return nil
+}
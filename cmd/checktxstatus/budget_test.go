@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBudgetLimit(t *testing.T) {
+	limits := map[string]budgetLimit{"": {}, "100": {soft: 80, hard: 100}, "50/100": {soft: 50, hard: 100}}
+	for value, expected := range limits {
+		if limit, err := parseBudgetLimit(value); err != nil || limit != expected {
+			t.Errorf("expected %q to be %+v, got %+v, %v", value, expected, limit, err)
+		}
+	}
+	for _, value := range []string{"0", "many", "100/50", "1/2/3"} {
+		if _, err := parseBudgetLimit(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestRequestBudgetSpend(t *testing.T) {
+	slept := 0
+	b := &requestBudget{
+		requests: budgetLimit{soft: 2, hard: 10},
+		cost:     budgetLimit{soft: 8, hard: 10},
+		costs:    map[requestKind]float64{requestTx: 1, requestBlock: 5},
+		sleep:    func(time.Duration) { slept++ },
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.spend(requestTx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slept != 0 {
+		t.Error("expected no slowdown within the soft budgets")
+	}
+	if err := b.spend(requestBlock); err != nil || slept != 1 {
+		t.Errorf("expected a slowdown past the soft budgets, got %d, %v", slept, err)
+	}
+	if err := b.spend(requestBlock); err != errBudgetExhausted {
+		t.Errorf("expected the hard cost budget to stop the request, got %v", err)
+	}
+	if b.usedRequests != 3 || b.usedCost != 7 {
+		t.Errorf("expected a stopped request not to be accounted for, got %g requests costing %g",
+			b.usedRequests, b.usedCost)
+	}
+	if err := (*requestBudget)(nil).spend(requestTx); err != nil {
+		t.Errorf("expected no limits without a budget, got %v", err)
+	}
+}
+
+func TestInitBudgetValidatesCosts(t *testing.T) {
+	defer func() { maxRequestCostStr, requestCostsStrs, budget = "", nil, nil }()
+	maxRequestCostStr = "100"
+	for _, cost := range []string{"tx", "blocks=2", "tx=-1"} {
+		requestCostsStrs = []string{cost}
+		if err := initBudget(); err == nil {
+			t.Errorf("expected --request-cost %q to be invalid", cost)
+		}
+	}
+	requestCostsStrs = []string{"block=2.5"}
+	if err := initBudget(); err != nil || budget.costs[requestBlock] != 2.5 || budget.costs[requestTx] != 1 {
+		t.Errorf("unexpected budget %+v, %v", budget, err)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	cachePath          string
+	cacheNegativeTTL   time.Duration
+	cacheBatch         int
+	cacheFlushInterval time.Duration
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&cachePath, "cache-path", "",
+		"bbolt database caching the txids found confirmed, shared by the runs using it one at a time, so the "+
+			"transactions confirmed in a previous run are not looked up again")
+	rootCmd.Flags().DurationVar(&cacheNegativeTTL, "cache-negative-ttl", 2*time.Minute,
+		"how long the transactions not found are cached by --cache-path, since they may be confirmed any time "+
+			"(0 to cache only the confirmed ones)")
+	rootCmd.Flags().IntVar(&cacheBatch, "cache-batch", 1000,
+		"write the lookup results to --cache-path in a single transaction once this many are pending (0 to write "+
+			"them only with --cache-flush and at the end)")
+	rootCmd.Flags().DurationVar(&cacheFlushInterval, "cache-flush", 5*time.Second,
+		"write the pending lookup results to --cache-path at least this often (0 to write them only with "+
+			"--cache-batch and at the end)")
+}
+
+// the buckets of the cache, both keyed by txid
+var (
+	// cacheConfirmedBucket maps the confirmed txids to their rounds
+	cacheConfirmedBucket = []byte("confirmed")
+	// cacheUnsentBucket maps the txids not found to when they were not found, in Unix nanoseconds
+	cacheUnsentBucket = []byte("unsent")
+)
+
+// cacheOpenTimeout is how long a run waits for the run holding the cache open before failing
+const cacheOpenTimeout = 10 * time.Second
+
+// txCache caches the lookup results of all the runs in a bbolt database, the confirmed transactions forever and the
+// ones not found for --cache-negative-ttl
+// every lookup is a read of the B+tree of the database through its memory map, so a large cache neither delays the
+// start of the runs nor grows their memory; bbolt holds the database open for a single process, the other runs
+// waiting up to cacheOpenTimeout for it
+// the cache is read and written locally, without going through the concurrency limits of the lookups
+// the results are written in batches, every --cache-batch results and every --cache-flush, a single transaction of the
+// database taking its write lock for the whole batch; the pending results answer the lookups until they are written
+type txCache struct {
+	mu       sync.Mutex
+	filename string
+	db       *bolt.DB
+	// hits is the number of lookups answered by the cache
+	hits int
+	// pending are the results recorded since the last write, by txid
+	pending map[string]cachedResult
+	// flush wakes up the flusher once --cache-batch results are pending, stop and done stop it
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// cachedResult is the result of looking up a transaction, waiting to be written to the cache
+type cachedResult struct {
+	sent  bool
+	round uint64
+	// at is when it was looked up, the start of --cache-negative-ttl for the transactions not found
+	at time.Time
+}
+
+// openCache opens the cache of --cache-path, creating it if missing and removing its expired unsent transactions
+// it returns nil if --cache-path is not set
+func openCache() (*txCache, error) {
+	if cachePath == "" {
+		return nil, nil
+	}
+	if cacheNegativeTTL < 0 {
+		return nil, fmt.Errorf("--cache-negative-ttl must not be negative")
+	}
+	db, err := bolt.Open(cachePath, 0600, &bolt.Options{Timeout: cacheOpenTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("cache %s is held open by another run, retry once it is done", cachePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %v", cachePath, err)
+	}
+	var confirmed, unsent int
+	err = db.Update(func(tx *bolt.Tx) error {
+		confirmedBucket, err := tx.CreateBucketIfNotExists(cacheConfirmedBucket)
+		if err != nil {
+			return err
+		}
+		unsentBucket, err := tx.CreateBucketIfNotExists(cacheUnsentBucket)
+		if err != nil {
+			return err
+		}
+		// only the unsent transactions are scanned, they are few since they expire
+		var expired [][]byte
+		now := time.Now()
+		err = unsentBucket.ForEach(func(txid []byte, value []byte) error {
+			if !cacheUnsentLive(value, now) {
+				expired = append(expired, txid)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, txid := range expired {
+			if err := unsentBucket.Delete(txid); err != nil {
+				return err
+			}
+		}
+		confirmed, unsent = confirmedBucket.Stats().KeyN, unsentBucket.Stats().KeyN-len(expired)
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open cache %s: %v", cachePath, err)
+	}
+	logMessage(log.InfoLevel, msgCacheLoaded, confirmed, unsent, cachePath)
+	c := &txCache{filename: cachePath, db: db, pending: map[string]cachedResult{}}
+	c.startFlushing()
+	return c, nil
+}
+
+// startFlushing starts writing the pending results in the background, every --cache-flush and once --cache-batch are
+// pending; without either they are written when closing the cache
+func (c *txCache) startFlushing() {
+	if cacheBatch <= 0 && cacheFlushInterval <= 0 {
+		return
+	}
+	c.flush = make(chan struct{}, 1)
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		var tick <-chan time.Time
+		if cacheFlushInterval > 0 {
+			ticker := time.NewTicker(cacheFlushInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-c.flush:
+			case <-tick:
+			}
+			c.write()
+		}
+	}()
+}
+
+// cacheUnsentLive returns true if the entry of a transaction not found was not found for less than
+// --cache-negative-ttl before now
+func cacheUnsentLive(value []byte, now time.Time) bool {
+	if len(value) != 8 {
+		return false
+	}
+	unsent := time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+	return now.Sub(unsent) < cacheNegativeTTL
+}
+
+// lookup returns whether a transaction is cached as found or not found, and its round if found
+// with --as-of-round only the transactions whose round is known to be by then are cached as found
+// a failure to read the cache is a cache miss
+func (c *txCache) lookup(txid string) (sent bool, round uint64, ok bool) {
+	if c == nil {
+		return false, 0, false
+	}
+	c.mu.Lock()
+	result, pending := c.pending[txid]
+	c.mu.Unlock()
+	if pending {
+		if !result.live(time.Now()) {
+			return false, 0, false
+		}
+		c.hit()
+		return result.sent, result.round, true
+	}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(cacheConfirmedBucket).Get([]byte(txid)); len(value) == 8 {
+			round = binary.BigEndian.Uint64(value)
+			sent, ok = true, asOfRound == 0 || round != 0 && round <= asOfRound
+			return nil
+		}
+		value := tx.Bucket(cacheUnsentBucket).Get([]byte(txid))
+		ok = value != nil && cacheUnsentLive(value, time.Now())
+		return nil
+	})
+	if err != nil || !ok {
+		return false, 0, false
+	}
+	c.hit()
+	return sent, round, true
+}
+
+// hit counts a lookup answered by the cache
+func (c *txCache) hit() {
+	c.mu.Lock()
+	c.hits++
+	c.mu.Unlock()
+}
+
+// record caches the result of looking up a transaction, it is written with the next batch
+func (c *txCache) record(txid string, sent bool, round uint64) {
+	if c == nil || !sent && cacheNegativeTTL == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a transaction found stays found, whatever a concurrent lookup did not find
+	if previous, ok := c.pending[txid]; ok && previous.sent && !sent {
+		return
+	}
+	c.pending[txid] = cachedResult{sent: sent, round: round, at: time.Now()}
+	if c.flush != nil && cacheBatch > 0 && len(c.pending) >= cacheBatch {
+		select {
+		case c.flush <- struct{}{}:
+		default:
+			// the flusher is already due to write
+		}
+	}
+}
+
+// write writes the pending results to the database in a single transaction
+// failing to write them only loses them for the next runs, so it is logged
+func (c *txCache) write() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = map[string]cachedResult{}
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		confirmedBucket, unsentBucket := tx.Bucket(cacheConfirmedBucket), tx.Bucket(cacheUnsentBucket)
+		for txid, result := range pending {
+			value := make([]byte, 8)
+			if !result.sent {
+				binary.BigEndian.PutUint64(value, uint64(result.at.UnixNano()))
+				if err := unsentBucket.Put([]byte(txid), value); err != nil {
+					return err
+				}
+				continue
+			}
+			binary.BigEndian.PutUint64(value, result.round)
+			if err := confirmedBucket.Put([]byte(txid), value); err != nil {
+				return err
+			}
+			if err := unsentBucket.Delete([]byte(txid)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logMessage(log.WarnLevel, msgCacheWriteFailed, len(pending), c.filename, err)
+		return
+	}
+	log.Debugf("wrote %d lookup results to cache %s", len(pending), c.filename)
+}
+
+// close writes the pending results and closes the cache, logging how many lookups it answered
+func (c *txCache) close() {
+	if c == nil {
+		return
+	}
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+	}
+	c.write()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logMessage(log.InfoLevel, msgCacheHits, c.hits, c.filename)
+	if err := c.db.Close(); err != nil {
+		logMessage(log.WarnLevel, msgCacheCloseFailed, c.filename, err)
+	}
+}
+
+// cacheStats returns the number of confirmed transactions of the cache filename, opening it read-only
+// a missing cache has none
+func cacheStats(filename string) (int, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return 0, nil
+	}
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err == bolt.ErrTimeout {
+		return 0, fmt.Errorf("cache %s is held open by another run", filename)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error while reading cache %s: %v", filename, err)
+	}
+	// no need to check error on close, the cache is only read
+	defer db.Close()
+	confirmed := 0
+	err = db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(cacheConfirmedBucket); bucket != nil {
+			confirmed = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error while reading cache %s: %v", filename, err)
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestCache opens a cache of --cache-path filename, caching the transactions not found for ttl until the test ends
+func openTestCache(t *testing.T, filename string, ttl time.Duration) *txCache {
+	path, oldTTL := cachePath, cacheNegativeTTL
+	t.Cleanup(func() { cachePath, cacheNegativeTTL = path, oldTTL })
+	cachePath, cacheNegativeTTL = filename, ttl
+	cache, err := openCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestIsTxSentCache(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	filename := filepath.Join(testDir(t), "txids.db")
+	for run := 0; run < 2; run++ {
+		cache := openTestCache(t, filename, time.Minute)
+		lookup := newTestLookup(t, server)
+		lookup.cache = cache
+		for i := byte(1); i <= 2; i++ {
+			sent, err := lookup.isTxSent(testTxID(i))
+			if err != nil || sent != (i == 1) {
+				t.Errorf("expected only the first transaction to be sent, got %v, %v", sent, err)
+			}
+		}
+		cache.close()
+	}
+	if requests := server.Requests(); len(requests) != 2 {
+		t.Errorf("expected the second run to be answered by the cache, got requests %v", requests)
+	}
+	if confirmed, err := cacheStats(filename); err != nil || confirmed != 1 {
+		t.Errorf("expected the confirmed transaction in the cache, got %d, %v", confirmed, err)
+	}
+
+	// the transactions not found are looked up again once their entries expired
+	cache := openTestCache(t, filename, time.Nanosecond)
+	defer cache.close()
+	if _, _, ok := cache.lookup(testTxID(2)); ok {
+		t.Error("expected the expired unsent transaction to be looked up again")
+	}
+	if sent, round, ok := cache.lookup(testTxID(1)); !ok || !sent || round != 900 {
+		t.Errorf("expected the confirmed transaction to stay cached, got %v, %d, %v", sent, round, ok)
+	}
+}
+
+func TestCacheLookupAsOfRound(t *testing.T) {
+	cache := openTestCache(t, filepath.Join(testDir(t), "txids.db"), time.Minute)
+	defer cache.close()
+	cache.record(testTxID(1), true, 900)
+	defer func(round uint64) { asOfRound = round }(asOfRound)
+	for round, cached := range map[uint64]bool{0: true, 950: true, 850: false} {
+		asOfRound = round
+		if _, _, ok := cache.lookup(testTxID(1)); ok != cached {
+			t.Errorf("expected the transaction of round 900 to be cached as of round %d: %v, got %v", round,
+				cached, ok)
+		}
+	}
+}
+
+func TestCacheIsHeldOpenByOneRun(t *testing.T) {
+	filename := filepath.Join(testDir(t), "txids.db")
+	cache := openTestCache(t, filename, time.Minute)
+	defer cache.close()
+	if _, err := cacheStats(filename); err == nil {
+		t.Error("expected the cache held open by a run not to be read by another")
+	}
+}
+
+func TestCacheWritesInBatches(t *testing.T) {
+	defer func(batch int, flush time.Duration) { cacheBatch, cacheFlushInterval = batch, flush }(cacheBatch,
+		cacheFlushInterval)
+	cacheBatch, cacheFlushInterval = 2, 0
+	filename := filepath.Join(testDir(t), "txids.db")
+	cache := openTestCache(t, filename, time.Minute)
+	cache.record(testTxID(1), true, 900)
+	if sent, round, ok := cache.lookup(testTxID(1)); !ok || !sent || round != 900 {
+		t.Errorf("expected the pending result to answer the lookup, got %v, %d, %v", sent, round, ok)
+	}
+	cache.record(testTxID(2), true, 901)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		cache.mu.Lock()
+		pending := len(cache.pending)
+		cache.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a full batch to be written, %d results still pending", pending)
+		}
+	}
+	cache.record(testTxID(3), true, 902)
+	cache.close()
+	if confirmed, err := cacheStats(filename); err != nil || confirmed != 3 {
+		t.Errorf("expected the results of the last batch to be written when closing, got %d, %v", confirmed, err)
+	}
+}
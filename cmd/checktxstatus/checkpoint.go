@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	checkpointFile          string
+	checkpointBatch         int
+	checkpointFlushInterval time.Duration
+	resume                  bool
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&checkpointFile, "checkpoint", "",
+		"JSON file recording the status of every transaction looked up, read at start to skip the transactions "+
+			"already looked up by a previous run, e.g. one stopped by --max-requests")
+	rootCmd.Flags().IntVar(&checkpointBatch, "checkpoint-batch", 1000,
+		"write the checkpoint during the run after every this many new lookup results (0 to write it only at the end)")
+	rootCmd.Flags().DurationVar(&checkpointFlushInterval, "checkpoint-flush", 30*time.Second,
+		"write the checkpoint during the run at least this often while it has new lookup results (0 to disable)")
+	rootCmd.Flags().BoolVar(&resume, "resume", false,
+		"skip the inputs already checked completely by a previous run with the same --checkpoint, recognized by their "+
+			"content so they may have been moved or renamed since")
+}
+
+// txCheckpoint records the results of transaction lookups so a stopped run can be resumed
+type txCheckpoint struct {
+	mu sync.Mutex
+	// Sent maps txids to whether they were found by the indexer
+	Sent map[string]bool `json:"sent"`
+	// Inputs are the inputs checked completely, by the SHA-256 digest of their content
+	Inputs map[string]checkpointInput `json:"inputs,omitempty"`
+	// unwritten is the number of results recorded since the checkpoint was last written
+	unwritten int
+	// flush wakes up the flusher once a batch of results is unwritten, nil if not flushing
+	flush chan struct{}
+	// stop stops the flusher, which closes done when it returns
+	stop chan struct{}
+	done chan struct{}
+}
+
+// checkpointInput is an input checked completely by a run
+type checkpointInput struct {
+	// Path is where the input was when checked, its outputs were written next to it
+	Path string `json:"path"`
+	// Severity is the highest severity of the conditions of its transactions
+	Severity int `json:"severity"`
+}
+
+// loadCheckpoint reads a checkpoint, a missing file is an empty checkpoint
+// it returns nil if filename is empty
+func loadCheckpoint(filename string) (*txCheckpoint, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	checkpoint := &txCheckpoint{Sent: map[string]bool{}, Inputs: map[string]checkpointInput{}}
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading checkpoint %s: %v", filename, err)
+	}
+	err = json.Unmarshal(content, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing checkpoint %s: %v", filename, err)
+	}
+	if checkpoint.Sent == nil {
+		checkpoint.Sent = map[string]bool{}
+	}
+	if checkpoint.Inputs == nil {
+		checkpoint.Inputs = map[string]checkpointInput{}
+	}
+	return checkpoint, nil
+}
+
+// validateResume makes sure --resume has a checkpoint to resume from
+func validateResume() error {
+	if resume && checkpointFile == "" {
+		return fmt.Errorf("--resume requires --checkpoint")
+	}
+	return nil
+}
+
+// completed returns the input with the given content digest if it was checked completely, ok is false otherwise
+func (c *txCheckpoint) completed(digest string) (input checkpointInput, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	input, ok = c.Inputs[digest]
+	return input, ok
+}
+
+// complete records that the input at path with the given content digest was checked completely
+func (c *txCheckpoint) complete(digest string, path string, severity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Inputs[digest] = checkpointInput{Path: path, Severity: severity}
+	c.unwritten++
+}
+
+// lookup returns the recorded status of a transaction, ok is false if it was not looked up yet
+func (c *txCheckpoint) lookup(txid string) (sent bool, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent, ok = c.Sent[txid]
+	return sent, ok
+}
+
+// record records the status of a transaction
+func (c *txCheckpoint) record(txid string, sent bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sent[txid] = sent
+	c.unwritten++
+	if c.flush != nil && checkpointBatch > 0 && c.unwritten >= checkpointBatch {
+		select {
+		case c.flush <- struct{}{}:
+		default:
+			// the flusher is already due to write
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file renamed to filename, so a run stopped while writing leaves the
+// previous version of the file
+func writeFileAtomic(filename string, data []byte) error {
+	tmpFilename := filename + ".tmp"
+	err := ioutil.WriteFile(tmpFilename, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
+// write writes the checkpoint to filename, leaving the previous checkpoint if stopped while writing
+func (c *txCheckpoint) write(filename string) error {
+	c.mu.Lock()
+	encoded, err := json.MarshalIndent(c, "", "  ")
+	written := c.unwritten
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed encoding checkpoint: %v", err)
+	}
+	err = writeFileAtomic(filename, append(encoded, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint to %s: %v", filename, err)
+	}
+	c.mu.Lock()
+	c.unwritten -= written
+	c.mu.Unlock()
+	return nil
+}
+
+// startFlushing writes the checkpoint to filename in the background, after every --checkpoint-batch results and
+// every --checkpoint-flush, so a run that crashes loses at most the results recorded since
+func (c *txCheckpoint) startFlushing(filename string) {
+	if checkpointBatch <= 0 && checkpointFlushInterval <= 0 {
+		return
+	}
+	c.flush = make(chan struct{}, 1)
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		var tick <-chan time.Time
+		if checkpointFlushInterval > 0 {
+			ticker := time.NewTicker(checkpointFlushInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-c.flush:
+			case <-tick:
+			}
+			c.mu.Lock()
+			unwritten := c.unwritten
+			c.mu.Unlock()
+			if unwritten == 0 {
+				continue
+			}
+			if err := c.write(filename); err != nil {
+				logError(err)
+				continue
+			}
+			log.Debugf("wrote %d new lookup results to checkpoint %s", unwritten, filename)
+		}
+	}()
+}
+
+// stopFlushing stops writing the checkpoint in the background, waiting for a write in progress
+func (c *txCheckpoint) stopFlushing() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
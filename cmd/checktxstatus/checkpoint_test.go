@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	filename := filepath.Join(testDir(t), "checkpoint.json")
+	checkpoint, err := loadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := checkpoint.lookup(testTxID(1)); ok {
+		t.Error("expected a missing checkpoint to be empty")
+	}
+	checkpoint.record(testTxID(1), true)
+	checkpoint.record(testTxID(2), false)
+	if err := checkpoint.write(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent, ok := loaded.lookup(testTxID(1)); !ok || !sent {
+		t.Error("expected the sent transaction to be recorded")
+	}
+	if sent, ok := loaded.lookup(testTxID(2)); !ok || sent {
+		t.Error("expected the unsent transaction to be recorded")
+	}
+}
+
+func TestTxLookupSkipsCheckpointedTransactions(t *testing.T) {
+	checkpoint := &txCheckpoint{Sent: map[string]bool{testTxID(1): true}}
+	// the lookup has no indexer client, so any request would panic
+	lookup := &txLookup{checkpoint: checkpoint}
+	if sent, err := lookup.isTxSent(testTxID(1)); err != nil || !sent {
+		t.Errorf("expected the recorded status, got %v, %v", sent, err)
+	}
+}
+
+func TestCheckpointFlushesBatches(t *testing.T) {
+	defer func(batch int, interval time.Duration) {
+		checkpointBatch, checkpointFlushInterval = batch, interval
+	}(checkpointBatch, checkpointFlushInterval)
+	checkpointBatch, checkpointFlushInterval = 2, 0
+	filename := filepath.Join(testDir(t), "checkpoint.json")
+	checkpoint, err := loadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.startFlushing(filename)
+	checkpoint.record(testTxID(1), true)
+	checkpoint.record(testTxID(2), false)
+	var loaded *txCheckpoint
+	for i := 0; i < 100; i++ {
+		if loaded, err = loadCheckpoint(filename); err == nil && len(loaded.Sent) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkpoint.stopFlushing()
+	if len(loaded.Sent) != 2 {
+		t.Fatalf("expected a full batch to be written during the run, got %v", loaded.Sent)
+	}
+	if checkpoint.unwritten != 0 {
+		t.Errorf("expected no unwritten results, got %d", checkpoint.unwritten)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed, got %v", err)
+	}
+}
+
+func TestCheckpointRecordsCompletedInputs(t *testing.T) {
+	defer func(r bool, file string) { resume, checkpointFile = r, file }(resume, checkpointFile)
+	resume, checkpointFile = true, ""
+	if err := validateResume(); err == nil {
+		t.Error("expected --resume to require --checkpoint")
+	}
+	filename := filepath.Join(testDir(t), "checkpoint.json")
+	checkpoint, err := loadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.complete("digest", "batch.tx", 2)
+	if err := checkpoint.write(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input, ok := loaded.completed("digest"); !ok || input.Path != "batch.tx" || input.Severity != 2 {
+		t.Errorf("expected the completed input to be recorded, got %+v %v", input, ok)
+	}
+	if _, ok := loaded.completed("other"); ok {
+		t.Error("expected an input not checked to be missing")
+	}
+}
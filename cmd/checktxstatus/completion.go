@@ -0,0 +1,139 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"strings"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Write the shell completion script of checktxstatus to stdout",
+	Example: `  # load the completions in the current bash shell
+  source <(checktxstatus completion bash)
+
+  # install them for every zsh shell
+  checktxstatus completion zsh > "${fpath[1]}/_checktxstatus"
+
+  # install them for fish
+  checktxstatus completion fish > ~/.config/fish/completions/checktxstatus.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.ExactValidArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		}
+		if err != nil {
+			logMessage(log.ErrorLevel, msgCompletionFailed, args[0], err)
+			exitCode = 1
+		}
+	},
+}
+
+// initCompletionCmd adds the completion subcommand and registers the completions of flag values and arguments of
+// every command. It must run after the other subcommands were added.
+func initCompletionCmd() {
+	rootCmd.AddCommand(completionCmd)
+	completeFiles := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	rootCmd.ValidArgsFunction = completeFiles
+	estimateCmd.ValidArgsFunction = completeFiles
+	doctorCmd.ValidArgsFunction = completeFiles
+	submitCmd.ValidArgsFunction = completeFiles
+	verifyCmd.ValidArgsFunction = completeFiles
+	decodeCmd.ValidArgsFunction = completeFiles
+	encodeCmd.ValidArgsFunction = completeFiles
+	archiveCmd.ValidArgsFunction = completeFiles
+	completeDirs := func(cmd *cobra.Command, args []string, toComplete string) ([]string,
+		cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	pruneCmd.ValidArgsFunction = completeDirs
+	watchDirCmd.ValidArgsFunction = completeDirs
+	completeNothing := func(cmd *cobra.Command, args []string, toComplete string) ([]string,
+		cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	initCmd.ValidArgsFunction = completeNothing
+	updateCmd.ValidArgsFunction = completeNothing
+	serveCmd.ValidArgsFunction = completeNothing
+	statusCmd.ValidArgsFunction = completeNothing
+
+	// shared flags are the same flag in every command, so each is registered once
+	registered := map[*pflag.Flag]bool{}
+	for _, cmd := range append([]*cobra.Command{rootCmd}, rootCmd.Commands()...) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			complete := flagCompletion(flag.Name)
+			if complete == nil || registered[flag] {
+				return
+			}
+			registered[flag] = true
+			err := cmd.RegisterFlagCompletionFunc(flag.Name, complete)
+			if err != nil {
+				log.Debugf("failed registering the completion of --%s: %v", flag.Name, err)
+			}
+		})
+	}
+}
+
+// flagCompletion returns the completion of the values of a flag, nil if the shell's default completes them
+func flagCompletion(name string) func(cmd *cobra.Command, args []string, toComplete string) ([]string,
+	cobra.ShellCompDirective) {
+	var values []string
+	switch name {
+	case "input-format":
+		for _, format := range []inputFormat{formatAuto, formatMsgpack, formatJSON, formatBase64, formatTxIDs} {
+			values = append(values, string(format))
+		}
+	case "group-policy":
+		values = []string{groupPolicyFirst, groupPolicyAll, groupPolicyQuorum}
+	case "backoff":
+		values = []string{string(backoffExponential), string(backoffDecorrelatedJitter), string(backoffFixed)}
+	case "log-level":
+		values = []string{"INFO", "DEBUG"}
+	case "format":
+		values = []string{summaryText, summaryJSON, summaryCSV}
+	case "network":
+		for _, preset := range networkPresets {
+			values = append(values, preset.name)
+		}
+	case "graph":
+		values = []string{graphDOT, graphJSON}
+	case "policy", "overrides", "config":
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+		}
+	case "since":
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		}
+	case "address-book":
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"csv"}, cobra.ShellCompDirectiveFilterFileExt
+		}
+	case "archive-dir":
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+	default:
+		return nil
+	}
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var matching []string
+		for _, value := range values {
+			if strings.HasPrefix(value, toComplete) {
+				matching = append(matching, value)
+			}
+		}
+		return matching, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+func TestFlagCompletionOfValues(t *testing.T) {
+	complete := flagCompletion("group-policy")
+	if complete == nil {
+		t.Fatal("expected --group-policy values to complete")
+	}
+	values, directive := complete(rootCmd, nil, "q")
+	if !reflect.DeepEqual(values, []string{groupPolicyQuorum}) || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("expected quorum to complete q, got %v, %v", values, directive)
+	}
+	_, directive = flagCompletion("config")(rootCmd, nil, "")
+	if directive != cobra.ShellCompDirectiveFilterFileExt {
+		t.Errorf("expected --config to complete YAML files, got %v", directive)
+	}
+	if flagCompletion("idx-addr") != nil {
+		t.Error("expected --idx-addr to be completed by the shell")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"io"
+	"strings"
+)
+
+var compression string
+
+func init() {
+	rootCmd.Flags().StringVar(&compression, "compress", "",
+		"compress the transaction output files with gzip or zstd, adding .gz or .zst to their names before any "+
+			"encryption suffix, compressed inputs are always decompressed")
+}
+
+// the methods of --compress
+const (
+	compressGzip = string(txchecker.CompressionGzip)
+	compressZstd = string(txchecker.CompressionZstd)
+)
+
+// validateCompression validates --compress
+func validateCompression() error {
+	switch compression {
+	case "", compressGzip, compressZstd:
+		return nil
+	}
+	return fmt.Errorf("invalid --compress %q, expected gzip or zstd", compression)
+}
+
+// compressedName returns the name of a transaction output file, which has a suffix when compressing outputs
+func compressedName(filename string) string {
+	switch compression {
+	case compressGzip:
+		return filename + ".gz"
+	case compressZstd:
+		return filename + ".zst"
+	}
+	return filename
+}
+
+// trimCompression returns a file name without its compression suffix
+func trimCompression(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".zst")
+}
+
+// compress compresses what is written to the stream from now on with --compress, before it is encrypted
+func (s *outputStream) compress() error {
+	if compression == "" {
+		return nil
+	}
+	w, err := txchecker.Compress(s.w, txchecker.Compression(compression))
+	if err != nil {
+		return fmt.Errorf("failed to compress %s: %v", s.filename, err)
+	}
+	s.w, s.closeCompression = w, w.Close
+	return nil
+}
+
+// decompressedInput is an input file read through a decompressor
+type decompressedInput struct {
+	io.Reader
+	decompressor io.Closer
+	file         io.Closer
+}
+
+func (d *decompressedInput) Close() error {
+	// no need to check error on close of the decompressor, the file is what holds resources
+	_ = d.decompressor.Close()
+	return d.file.Close()
+}
+
+// openTxInput opens an input file of transactions like openInput, decompressing it with txchecker.Decompress if it is
+// compressed with gzip or zstd, whatever its name
+// it returns the compression of the file, empty if it is not compressed
+func openTxInput(filename string) (io.ReadCloser, string, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	decompressed, compressed, err := txchecker.Decompress(bufio.NewReader(file))
+	if err != nil {
+		// no need to check error on close when reading file
+		_ = file.Close()
+		return nil, "", err
+	}
+	return &decompressedInput{Reader: decompressed, decompressor: decompressed, file: file}, string(compressed), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// testCompressionRoundTrip writes an output compressed with method and reads it back as an input
+func testCompressionRoundTrip(t *testing.T, method string) {
+	dir := testDir(t)
+	defer func() { compression = "" }()
+	compression = method
+	if err := validateCompression(); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, compressedName("batch.tx.unsent"))
+	stream, err := openOutputStream(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.compress(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write([]byte("transactions")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.close(); err != nil {
+		t.Fatal(err)
+	}
+	input, compressed, err := openTxInput(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+	content, err := ioutil.ReadAll(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressed != method || string(content) != "transactions" {
+		t.Errorf("expected the %s output to be decompressed, got %q compressed with %q", method, content, compressed)
+	}
+}
+
+func TestCompressedOutputIsDecompressed(t *testing.T) {
+	testCompressionRoundTrip(t, compressGzip)
+}
+
+func TestZstdOutputIsDecompressed(t *testing.T) {
+	testCompressionRoundTrip(t, compressZstd)
+}
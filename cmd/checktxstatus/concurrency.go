@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+var (
+	concurrency         int
+	adaptiveConcurrency bool
+	maxConcurrency      int
+	targetLatency       time.Duration
+)
+
+func init() {
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 1,
+		"number of concurrent transaction lookups (the initial number with --adaptive-concurrency)")
+	rootCmd.Flags().BoolVar(&adaptiveConcurrency, "adaptive-concurrency", false,
+		"raise the number of concurrent lookups while the indexer is healthy and halve it when lookups fail or "+
+			"are slower than --target-latency")
+	rootCmd.Flags().IntVar(&maxConcurrency, "max-concurrency", 32, "maximal number of concurrent lookups with --adaptive-concurrency")
+	rootCmd.Flags().DurationVar(&targetLatency, "target-latency", 500*time.Millisecond,
+		"lookups slower than this make --adaptive-concurrency back off")
+}
+
+// concurrencyController limits the number of concurrent lookups
+// when adaptive it follows AIMD: the limit grows by one after a full window of healthy lookups (as many as the
+// limit) and is halved when a lookup fails or is slower than the target latency, at most once per window
+type concurrencyController struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	inFlight int
+	adaptive bool
+	max      int
+	target   time.Duration
+	// healthy counts the healthy lookups since the limit last changed
+	healthy int
+	// sinceDecrease counts the lookups completed since the limit was last decreased
+	sinceDecrease int
+	// peak is the highest limit reached
+	peak int
+}
+
+// initConcurrency returns the controller set by the --concurrency flags
+func initConcurrency() (*concurrencyController, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+	if adaptiveConcurrency && maxConcurrency < concurrency {
+		return nil, fmt.Errorf("--max-concurrency must be at least --concurrency")
+	}
+	if adaptiveConcurrency && targetLatency <= 0 {
+		return nil, fmt.Errorf("--target-latency must be positive")
+	}
+	return newConcurrencyController(concurrency), nil
+}
+
+// newConcurrencyController returns a controller starting at limit concurrent lookups, adaptive if
+// --adaptive-concurrency is set
+func newConcurrencyController(limit int) *concurrencyController {
+	c := &concurrencyController{
+		limit:    limit,
+		adaptive: adaptiveConcurrency,
+		max:      maxConcurrency,
+		target:   targetLatency,
+		peak:     limit,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// acquire waits until another lookup may start
+func (c *concurrencyController) acquire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inFlight >= c.limit {
+		c.cond.Wait()
+	}
+	c.inFlight++
+}
+
+// release marks a lookup as completed, adapting the limit to its outcome
+func (c *concurrencyController) release(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	defer c.cond.Broadcast()
+	if !c.adaptive {
+		return
+	}
+	c.sinceDecrease++
+	if err != nil || latency > c.target {
+		c.healthy = 0
+		if c.sinceDecrease < c.limit || c.limit == 1 {
+			return
+		}
+		c.limit /= 2
+		c.sinceDecrease = 0
+		if err != nil {
+			log.Debugf("lookup failed, lowering concurrency to %d: %v", c.limit, err)
+		} else {
+			log.Debugf("lookup took %s, lowering concurrency to %d", latency, c.limit)
+		}
+		return
+	}
+	c.healthy++
+	if c.healthy >= c.limit && c.limit < c.max {
+		c.limit++
+		c.healthy = 0
+		if c.limit > c.peak {
+			c.peak = c.limit
+		}
+		log.Debugf("lookups healthy, raising concurrency to %d", c.limit)
+	}
+}
+
+// summary describes the concurrency reached by the adaptive controller
+func (c *concurrencyController) logSummary() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logMessage(log.InfoLevel, msgConcurrencySummary, c.limit, c.peak)
+}
+
+// lookupAll looks up the status of transactions concurrently, returning whether each of them was sent
+// it stops starting lookups after the first failure
+func (l *txLookup) lookupAll(txids []string) ([]bool, error) {
+	sent := make([]bool, len(txids))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i, txid := range txids {
+		if isSent, ok, err := l.prefetched.result(txid); ok {
+			if err != nil {
+				return nil, fmt.Errorf("failed getting status of tx %s: %v", txid, err)
+			}
+			sent[i] = isSent
+			continue
+		}
+		l.concurrency.acquire()
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			l.concurrency.release(0, nil)
+			break
+		}
+		wg.Add(1)
+		go func(i int, txid string) {
+			defer wg.Done()
+			start := time.Now()
+			isSent, err := l.isTxSent(txid)
+			l.concurrency.release(time.Since(start), err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed getting status of tx %s: %v", txid, err)
+				}
+				return
+			}
+			sent[i] = isSent
+		}(i, txid)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	err := l.pending.lookupMissing(txids, sent, l.backoff, l.checkpoint, l.rounds)
+	if err != nil {
+		return nil, err
+	}
+	return sent, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyControllerAIMD(t *testing.T) {
+	c := &concurrencyController{limit: 2, adaptive: true, max: 3, target: time.Second, peak: 2}
+	c.cond = sync.NewCond(&c.mu)
+	for i := 0; i < 2; i++ {
+		c.acquire()
+		c.release(time.Millisecond, nil)
+	}
+	if c.limit != 3 {
+		t.Fatalf("expected a window of healthy lookups to raise the limit, got %d", c.limit)
+	}
+	for i := 0; i < 3; i++ {
+		c.acquire()
+		c.release(time.Millisecond, nil)
+	}
+	if c.limit != 3 {
+		t.Errorf("expected the limit to stop at the maximum, got %d", c.limit)
+	}
+	c.acquire()
+	c.release(2*time.Second, nil)
+	if c.limit != 1 {
+		t.Errorf("expected a slow lookup to halve the limit, got %d", c.limit)
+	}
+	c.acquire()
+	c.release(0, fmt.Errorf("failed"))
+	if c.limit != 1 || c.peak != 3 {
+		t.Errorf("expected the limit to stay at 1 after peaking at 3, got %d and %d", c.limit, c.peak)
+	}
+}
+
+func TestLookupAllLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		txID := path.Base(r.URL.Path)
+		if txID == testTxID(3) {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q}}`, txID)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	concurrency := &concurrencyController{limit: 3}
+	concurrency.cond = sync.NewCond(&concurrency.mu)
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff(), concurrency: concurrency}
+	var txIDs []string
+	for i := byte(0); i < 10; i++ {
+		txIDs = append(txIDs, testTxID(i))
+	}
+	sent, err := lookup.lookupAll(txIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, isSent := range sent {
+		if isSent != (i != 3) {
+			t.Errorf("expected only the transaction not found to be unsent, got %v", sent)
+			break
+		}
+	}
+	if peak < 2 || peak > 3 {
+		t.Errorf("expected up to 3 concurrent lookups, got %d", peak)
+	}
+}
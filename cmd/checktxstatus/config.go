@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var configFile string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "",
+		"YAML file of flag values, e.g. idx-addr: https://..., used for the flags not given on the command line "+
+			"(default ~/"+defaultConfigName+" if it exists)")
+}
+
+// defaultConfigName is the name of the config file in the home directory
+const defaultConfigName = ".checktxstatus.yaml"
+
+// envFlags are flags set by an environment variable of their own, which takes precedence over the config
+var envFlags = map[string]string{
+	"idx-addr":   "AF_IDX_ADDRESS",
+	"idx-tkn":    "AF_IDX_TOKEN",
+	"algod-addr": "AF_ALGOD_ADDRESS",
+	"algod-tkn":  "AF_ALGOD_TOKEN",
+}
+
+// envPrefix prefixes the environment variables of the other flags, e.g. CHECKTXSTATUS_CONCURRENCY for --concurrency
+const envPrefix = "CHECKTXSTATUS"
+
+// envName returns the environment variable setting a flag
+func envName(flag string) string {
+	if name, ok := envFlags[flag]; ok {
+		return name
+	}
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// configFilename returns the config file of the run: --config, its environment variable, or the default one if it
+// exists. It returns an empty name if there is none.
+func configFilename() string {
+	if configFile != "" {
+		return configFile
+	}
+	if filename := os.Getenv(envName("config")); filename != "" {
+		return filename
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	filename := filepath.Join(home, defaultConfigName)
+	if _, err := os.Stat(filename); err != nil {
+		return ""
+	}
+	return filename
+}
+
+// readConfig returns the values of the flags in the environment and in the config file filename, if not empty, the
+// environment taking precedence over the config. The environment variables of envFlags are left to the caller.
+func readConfig(filename string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+	if filename == "" {
+		return v, nil
+	}
+	v.SetConfigFile(filename)
+	v.SetConfigType("yaml")
+	err := v.ReadInConfig()
+	if _, ok := err.(viper.ConfigParseError); ok {
+		return nil, newUserError(msgConfigParse, filename, err)
+	}
+	if err != nil {
+		return nil, newUserError(msgConfigRead, filename, err)
+	}
+	return v, nil
+}
+
+// loadConfig sets the flags of cmd not given on the command line to the values of their environment variables, or to
+// their values in the config file, and then the endpoints still not set to the ones of --network
+func loadConfig(cmd *cobra.Command) error {
+	filename := configFilename()
+	v, err := readConfig(filename)
+	if err != nil {
+		return err
+	}
+	names := v.AllKeys()
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || !knownFlag(cmd.Root(), name) {
+			return newUserError(msgConfigUnknownFlag, filename, name)
+		}
+	}
+	// bound after the check of the config keys, which would list them
+	for flag, env := range envFlags {
+		// no error with a flag name
+		_ = v.BindEnv(flag, env)
+	}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Name == "config" || flag.Changed || !v.IsSet(flag.Name) {
+			return
+		}
+		// lists are comma separated in the environment
+		if value := os.Getenv(envName(flag.Name)); value != "" {
+			// the defaults of the indexer flags are their environment variables
+			if value == flag.Value.String() {
+				return
+			}
+			if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+				err = newUserError(msgEnvInvalidValue, envName(flag.Name), flag.Name, setErr)
+			}
+			return
+		}
+		// lists are set item by item in the config, as if the flag was repeated
+		items, ok := v.Get(flag.Name).([]interface{})
+		if !ok {
+			items = []interface{}{v.Get(flag.Name)}
+		}
+		for _, item := range items {
+			if setErr := cmd.Flags().Set(flag.Name, fmt.Sprint(item)); setErr != nil {
+				err = newUserError(msgConfigInvalidValue, filename, flag.Name, setErr)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return applyNetwork(cmd)
+}
+
+// knownFlag returns true if name is a flag of the root command or of one of its subcommands
+func knownFlag(root *cobra.Command, name string) bool {
+	if root.Flags().Lookup(name) != nil {
+		return true
+	}
+	for _, cmd := range root.Commands() {
+		if cmd.Flags().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	var address, token, level string
+	var recipients []string
+	root.Flags().StringVar(&address, "idx-addr", "", "")
+	root.Flags().StringVar(&token, "idx-tkn", "", "")
+	root.Flags().StringVar(&level, "log-level", "INFO", "")
+	root.Flags().StringSliceVar(&recipients, "age-recipient", nil, "")
+	sub := &cobra.Command{Use: "sub"}
+	sub.Flags().Int("timeout", 0, "")
+	root.AddCommand(sub)
+
+	filename := filepath.Join(testDir(t), "config.yaml")
+	content := "idx-addr: http://config:8980\nidx-tkn: config-token\nlog-level: DEBUG\ntimeout: 5\n" +
+		"age-recipient: [age1a, age1b]\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(file string) { configFile = file }(configFile)
+	configFile = filename
+	defer os.Unsetenv("AF_IDX_TOKEN")
+	if err := os.Setenv("AF_IDX_TOKEN", "env-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Flags().Set("log-level", "WARN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(root); err != nil {
+		t.Fatal(err)
+	}
+	if address != "http://config:8980" || token != "env-token" || level != "WARN" {
+		t.Errorf("expected flags > env > config, got %s, %q and %s", address, token, level)
+	}
+	if len(recipients) != 2 || recipients[1] != "age1b" {
+		t.Errorf("expected lists to be set item by item, got %v", recipients)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("idx-adr: http://typo:8980\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(root); err == nil {
+		t.Error("expected an unknown flag in the config to fail")
+	}
+}
+
+func TestLoadConfigFromTheEnvironment(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	var concurrency int
+	var recipients []string
+	root.Flags().IntVar(&concurrency, "concurrency", 1, "")
+	root.Flags().StringSliceVar(&recipients, "age-recipient", nil, "")
+
+	filename := filepath.Join(testDir(t), "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("concurrency: 4\nage-recipient: [age1c]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(file string) { configFile = file }(configFile)
+	configFile = filename
+	defer os.Unsetenv("CHECKTXSTATUS_CONCURRENCY")
+	defer os.Unsetenv("CHECKTXSTATUS_AGE_RECIPIENT")
+	if err := os.Setenv("CHECKTXSTATUS_CONCURRENCY", "8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("CHECKTXSTATUS_AGE_RECIPIENT", "age1a,age1b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(root); err != nil {
+		t.Fatal(err)
+	}
+	if concurrency != 8 || len(recipients) != 2 || recipients[1] != "age1b" {
+		t.Errorf("expected the environment over the config, lists comma separated, got %d and %v", concurrency,
+			recipients)
+	}
+
+	if err := os.Setenv("CHECKTXSTATUS_CONCURRENCY", "many"); err != nil {
+		t.Fatal(err)
+	}
+	// a fresh command, the flags set above being given as if on the command line
+	root = &cobra.Command{Use: "root"}
+	root.Flags().IntVar(&concurrency, "concurrency", 1, "")
+	root.Flags().StringSliceVar(&recipients, "age-recipient", nil, "")
+	err := loadConfig(root)
+	if errorCode(err) != msgEnvInvalidValue {
+		t.Errorf("expected an invalid environment value to fail, got %v", err)
+	}
+}
+
+func TestSubcommandsExitNonzeroOnAnInvalidConfig(t *testing.T) {
+	filename := filepath.Join(testDir(t), "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("idx-adr: http://typo:8980\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(file string) { configFile = file }(configFile)
+	configFile = filename
+	defer func(level string) { setLogger(level) }(logLevelStr)
+	defer func(code int) { exitCode = code }(exitCode)
+	for _, cmd := range []*cobra.Command{decodeCmd, encodeCmd, submitCmd, updateCmd, verifyCmd} {
+		exitCode = 0
+		cmd.Run(cmd, []string{"batch.tx"})
+		if exitCode != 1 {
+			t.Errorf("expected %s to exit with 1 on an invalid config, got %d", cmd.Name(), exitCode)
+		}
+	}
+}
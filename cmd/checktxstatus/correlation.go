@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common"
+	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+	"time"
+)
+
+var runID string
+
+func init() {
+	rootCmd.Flags().StringVar(&runID, "run-id", "",
+		"correlation ID of the run, sent to the indexer with every request and included in the logs (random by default)")
+}
+
+const (
+	// correlationIDHeader carries the run ID in every request to the indexer
+	correlationIDHeader = "X-Correlation-ID"
+	// requestIDHeader carries an ID unique to every request to the indexer, made of the run ID and a counter
+	requestIDHeader = "X-Request-ID"
+)
+
+// requestCount is the number of requests tagged so far
+var requestCount uint64
+
+// initRunID generates a random run ID unless --run-id was set, and adds it to every log entry
+func initRunID() {
+	if runID == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			runID = fmt.Sprintf("%x", time.Now().UnixNano())
+		} else {
+			runID = hex.EncodeToString(b)
+		}
+	}
+	log.AddHook(runIDHook{})
+}
+
+// runIDHook adds the run ID to every log entry
+type runIDHook struct{}
+
+func (runIDHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (runIDHook) Fire(entry *log.Entry) error {
+	entry.Data["run"] = runID
+	return nil
+}
+
+// runHeaders are the headers sent with every request to the indexer
+func runHeaders() []*common.Header {
+	return []*common.Header{{Key: correlationIDHeader, Value: runID}}
+}
+
+// nextRequest returns a new request ID and the headers carrying it
+func nextRequest() (string, []*common.Header) {
+	requestID := fmt.Sprintf("%s-%d", runID, atomic.AddUint64(&requestCount, 1))
+	return requestID, []*common.Header{{Key: requestIDHeader, Value: requestID}}
+}
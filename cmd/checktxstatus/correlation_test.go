@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndexerRequestsCarryCorrelationIDs(t *testing.T) {
+	defer func() { runID = "" }()
+	runID = "run1"
+	var correlationIDs, requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationIDs = append(correlationIDs, r.Header.Get(correlationIDHeader))
+		requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClientWithHeaders(server.URL, "", runHeaders())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff()}
+	for i := byte(0); i < 2; i++ {
+		if _, err := lookup.isTxSent(testTxID(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requestIDs) != 2 || correlationIDs[0] != "run1" || correlationIDs[1] != "run1" {
+		t.Fatalf("expected every request to carry the run ID, got %v", correlationIDs)
+	}
+	if !strings.HasPrefix(requestIDs[0], "run1-") || requestIDs[0] == requestIDs[1] {
+		t.Errorf("expected unique request IDs of the run, got %v", requestIDs)
+	}
+}
+
+func TestInitRunIDIsRandom(t *testing.T) {
+	defer func() { runID = "" }()
+	initRunID()
+	first := runID
+	runID = ""
+	initRunID()
+	if len(first) != 16 || first == runID {
+		t.Errorf("expected random run IDs, got %s and %s", first, runID)
+	}
+}
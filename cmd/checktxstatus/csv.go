@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// csvHeader are the columns of the rows of --format csv
+var csvHeader = []string{"txid", "file", "group", "sender", "receiver", "amount", "type", "status"}
+
+// csvRows writes a row per checked transaction to stdout with --format csv, file after file and chunk after chunk
+type csvRows struct {
+	w *csv.Writer
+	// redaction hides the addresses or amounts of the rows, nil without --redact
+	redaction *redaction
+}
+
+// newCSVRows writes the header of the rows to w if --format is csv, it returns nil otherwise
+func newCSVRows(w io.Writer, redaction *redaction) (*csvRows, error) {
+	if outputFormat != summaryCSV {
+		return nil, nil
+	}
+	rows := &csvRows{w: csv.NewWriter(w), redaction: redaction}
+	err := rows.w.Write(csvHeader)
+	if err != nil {
+		return nil, err
+	}
+	rows.w.Flush()
+	return rows, rows.w.Error()
+}
+
+// write writes the rows of transactions of filename by their status, in their order in the file
+func (r *csvRows) write(filename string, statuses map[txCondition][]txRecord) error {
+	if r == nil {
+		return nil
+	}
+	type row struct {
+		tx     txRecord
+		status txCondition
+	}
+	var rows []row
+	for status, txs := range statuses {
+		for _, tx := range txs {
+			rows = append(rows, row{tx: tx, status: status})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].tx.index < rows[j].tx.index
+	})
+	for _, row := range rows {
+		record := csvRecord(filename, row.tx, string(row.status))
+		r.redaction.csvRecord(record)
+		err := r.w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// csvRecord returns the row of a transaction, with only the txid, file and status for transactions read from txid
+// lists. The amount is in microalgos for payments and in base units of the asset for asset transfers.
+func csvRecord(filename string, tx txRecord, status string) []string {
+	record := []string{tx.txID, filename, "", "", "", "", "", status}
+	if tx.txIDOnly {
+		return record
+	}
+	txn := tx.stx.Txn
+	if txn.Group != (types.Digest{}) {
+		record[2] = groupIDString(txn.Group)
+	}
+	record[3] = txn.Sender.String()
+	if receiver := txReceiver(txn); !receiver.IsZero() {
+		record[4] = receiver.String()
+	}
+	switch txn.Type {
+	case types.PaymentTx:
+		record[5] = strconv.FormatUint(uint64(txn.Amount), 10)
+	case types.AssetTransferTx:
+		record[5] = strconv.FormatUint(txn.AssetAmount, 10)
+	}
+	record[6] = string(txn.Type)
+	return record
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestCSVRowsInTheOrderOfTheFile(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = summaryCSV
+	var out strings.Builder
+	rows, err := newCSVRows(&out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payment types.SignedTxn
+	payment.Txn.Type = types.PaymentTx
+	payment.Txn.Amount = 1000
+	payment.Txn.Receiver = types.Address{1}
+	var transfer types.SignedTxn
+	transfer.Txn.Type = types.AssetTransferTx
+	transfer.Txn.AssetAmount = 5
+	transfer.Txn.AssetReceiver = types.Address{2}
+	err = rows.write("batch.tx", map[txCondition][]txRecord{
+		conditionConfirmed: {{txID: testTxID(2), stx: transfer, index: 1}},
+		conditionUnsent:    {{txID: testTxID(1), stx: payment, index: 0}, {txID: testTxID(3), txIDOnly: true, index: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		strings.Join(csvHeader, ","),
+		testTxID(1) + ",batch.tx,," + types.Address{}.String() + "," + types.Address{1}.String() + ",1000,pay,unsent",
+		testTxID(2) + ",batch.tx,," + types.Address{}.String() + "," + types.Address{2}.String() + ",5,axfer,confirmed",
+		testTxID(3) + ",batch.tx,,,,,,unsent",
+	}, "\n") + "\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
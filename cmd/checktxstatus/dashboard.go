@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var watchDirDashboard string
+
+func init() {
+	watchDirCmd.Flags().StringVar(&watchDirDashboard, "dashboard-listen", "",
+		"serve a web dashboard of the watched directory on this address (e.g. localhost:8091): the recent runs, the "+
+			"trend of the backlog, and the runs of every file with links to download their reports")
+}
+
+// dashboardRuns is the number of recent runs the dashboard keeps
+const dashboardRuns = 200
+
+// dashboardRun is a check of a dropped file, as shown by the dashboard
+type dashboardRun struct {
+	File     string
+	Started  time.Time
+	Duration time.Duration
+	Status   string
+	Error    string
+	Unsent   int
+	Expired  int
+	// Waiting is the number of files waiting to be checked when the check completed
+	Waiting int
+	// Outputs are the paths of the outputs of the check relative to the watched directory, after they were moved
+	Outputs []string
+}
+
+// dashboard serves a web dashboard of the checks of watch-dir, nil if --dashboard-listen is not set
+// the checks record their runs from the goroutine of the watcher, while the pages are served from others
+type dashboard struct {
+	dir     string
+	pattern string
+	mu      sync.Mutex
+	// runs are the recent runs, the oldest first
+	runs    []dashboardRun
+	waiting int
+}
+
+// startDashboard serves the dashboard of dir if --dashboard-listen is set, the returned function stops serving it
+func startDashboard(dir string) (*dashboard, func(), error) {
+	if watchDirDashboard == "" {
+		return nil, func() {}, nil
+	}
+	listener, err := net.Listen("tcp", watchDirDashboard)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on --dashboard-listen %s: %v", watchDirDashboard, err)
+	}
+	d := &dashboard{dir: dir, pattern: watchDirPattern}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveOverview)
+	mux.HandleFunc("/file", d.serveFile)
+	mux.HandleFunc("/download/", d.serveDownload)
+	logMessage(log.InfoLevel, msgDashboardServing, dir, listener.Addr())
+	go func() {
+		// Serve returns an error once the listener is closed
+		_ = http.Serve(listener, mux)
+	}()
+	return d, func() {
+		_ = listener.Close()
+	}, nil
+}
+
+// record adds the run of a check, forgetting the oldest one past dashboardRuns
+func (d *dashboard) record(run dashboardRun) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.runs = append(d.runs, run)
+	if len(d.runs) > dashboardRuns {
+		d.runs = d.runs[len(d.runs)-dashboardRuns:]
+	}
+}
+
+// setWaiting records the number of files waiting to be checked
+func (d *dashboard) setWaiting(waiting int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.waiting = waiting
+}
+
+// dashboardPage is what the templates of the dashboard render
+type dashboardPage struct {
+	Dir     string
+	Pattern string
+	Waiting int
+	// File is the file drilled down into, empty on the overview
+	File string
+	// Runs are the runs shown, the most recent first
+	Runs []dashboardRun
+	// UnsentTrend and WaitingTrend are the points of the SVG polylines of the unsent transactions and of the files
+	// waiting over the runs, empty when drilling down
+	UnsentTrend  string
+	WaitingTrend string
+}
+
+// snapshot returns the page of the runs of file, or of all of them if empty
+func (d *dashboard) snapshot(file string) dashboardPage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	page := dashboardPage{Dir: d.dir, Pattern: d.pattern, Waiting: d.waiting, File: file}
+	for i := len(d.runs) - 1; i >= 0; i-- {
+		if file == "" || d.runs[i].File == file {
+			page.Runs = append(page.Runs, d.runs[i])
+		}
+	}
+	if file == "" {
+		page.UnsentTrend = trendPoints(d.runs, func(run dashboardRun) int { return run.Unsent + run.Expired })
+		page.WaitingTrend = trendPoints(d.runs, func(run dashboardRun) int { return run.Waiting })
+	}
+	return page
+}
+
+// trendPoints returns the points of a polyline of value over runs, scaled to the 600x100 box of the trend chart
+func trendPoints(runs []dashboardRun, value func(dashboardRun) int) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	highest := 1
+	for _, run := range runs {
+		if value(run) > highest {
+			highest = value(run)
+		}
+	}
+	points := make([]string, 0, len(runs))
+	for i, run := range runs {
+		x := 0
+		if len(runs) > 1 {
+			x = i * 600 / (len(runs) - 1)
+		}
+		points = append(points, fmt.Sprintf("%d,%d", x, 100-value(run)*100/highest))
+	}
+	return strings.Join(points, " ")
+}
+
+// serveOverview serves the recent runs and the trend of the backlog
+func (d *dashboard) serveOverview(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	d.render(w, d.snapshot(""))
+}
+
+// serveFile serves the runs of the file of the name query parameter
+func (d *dashboard) serveFile(w http.ResponseWriter, r *http.Request) {
+	file := r.URL.Query().Get("name")
+	if file == "" {
+		http.Error(w, "missing the name of the file", http.StatusBadRequest)
+		return
+	}
+	d.render(w, d.snapshot(file))
+}
+
+// serveDownload serves the report of a check moved to done/ or unsent/; the dashboard has no authentication, so the
+// other outputs, which hold signed transactions, cannot be downloaded
+func (d *dashboard) serveDownload(w http.ResponseWriter, r *http.Request) {
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(r.URL.Path, "/download/")))
+	top := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+	if rel == top || top != watchDirDone && top != watchDirUnsent || !isReportFile(rel) {
+		http.NotFound(w, r)
+		return
+	}
+	path := filepath.Join(d.dir, rel)
+	if stat, err := os.Stat(path); err != nil || !stat.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+	http.ServeFile(w, r, path)
+}
+
+// isReportFile returns true if a file name is the name of a --report, compressed or encrypted
+func isReportFile(name string) bool {
+	return strings.HasSuffix(trimCompression(trimEncryption(name)), ".report.json")
+}
+
+// render writes a page of the dashboard
+func (d *dashboard) render(w http.ResponseWriter, page dashboardPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := dashboardTemplate.Execute(w, page)
+	if err != nil {
+		logMessage(log.WarnLevel, msgDashboardRenderFailed, err)
+	}
+}
+
+// the page of the dashboard is part of the binary, so it needs nothing beside it to be served
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time":   func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+	"base":   filepath.Base,
+	"link":   filepath.ToSlash,
+	"report": isReportFile,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>checktxstatus: {{.Dir}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #ddd; text-align: left; }
+.OK { color: #2a7a2a; } .UNSENT { color: #b06000; } .ERROR { color: #b00020; }
+svg { border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+{{if .File}}
+<p><a href="/">all runs</a></p>
+<h1>{{.File}}</h1>
+{{else}}
+<h1>{{.Dir}}</h1>
+<p>Watching for {{.Pattern}} files, {{.Waiting}} waiting to be checked.</p>
+{{if .UnsentTrend}}
+<h2>Backlog</h2>
+<svg width="600" height="100" viewBox="-5 -5 610 110">
+<polyline fill="none" stroke="#b06000" stroke-width="2" points="{{.UnsentTrend}}"/>
+<polyline fill="none" stroke="#888" stroke-dasharray="4" points="{{.WaitingTrend}}"/>
+</svg>
+<p>Unsent and expired transactions found by every run (solid), and files waiting after it (dashed).</p>
+{{end}}
+<h2>Recent runs</h2>
+{{end}}
+{{if .Runs}}
+<table>
+<tr><th>Started</th><th>File</th><th>Status</th><th>Unsent</th><th>Expired</th><th>Duration</th><th>Outputs</th></tr>
+{{range .Runs}}
+<tr>
+<td>{{time .Started}}</td>
+<td><a href="/file?name={{.File}}">{{.File}}</a></td>
+<td class="{{.Status}}">{{.Status}}{{if .Error}}: {{.Error}}{{end}}</td>
+<td>{{.Unsent}}</td>
+<td>{{.Expired}}</td>
+<td>{{.Duration}}</td>
+<td>{{range .Outputs}}{{if report .}}<a href="/download/{{link .}}">{{base .}}</a>{{else}}{{base .}}{{end}} {{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No files were checked yet.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDashboardOnlyServesMovedOutputs(t *testing.T) {
+	dir := testDir(t)
+	if err := os.Mkdir(filepath.Join(dir, watchDirDone), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join(watchDirDone, "batch.tx.report.json"), "waiting.tx"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("output"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := &dashboard{dir: dir}
+	for path, status := range map[string]int{
+		watchDirDone + "/batch.tx.report.json": http.StatusOK,
+		"waiting.tx":                           http.StatusNotFound,
+		watchDirDone + "/../waiting.tx":        http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		d.serveDownload(w, httptest.NewRequest(http.MethodGet, "/download/"+path, nil))
+		if w.Code != status {
+			t.Errorf("expected downloading %s to answer %d, got %d", path, status, w.Code)
+		}
+	}
+}
+
+func TestDashboardOnlyServesReports(t *testing.T) {
+	dir := testDir(t)
+	if err := os.Mkdir(filepath.Join(dir, watchDirUnsent), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"batch.tx.report.json.gz", "batch.tx.unsent"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, watchDirUnsent, name), []byte("output"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := &dashboard{dir: dir}
+	for name, status := range map[string]int{
+		"batch.tx.report.json.gz": http.StatusOK,
+		"batch.tx.unsent":         http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		d.serveDownload(w, httptest.NewRequest(http.MethodGet, "/download/"+watchDirUnsent+"/"+name, nil))
+		if w.Code != status {
+			t.Errorf("expected downloading %s to answer %d, got %d", name, status, w.Code)
+		}
+	}
+}
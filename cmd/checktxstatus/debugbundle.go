@@ -0,0 +1,280 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+var debugBundleFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&debugBundleFile, "debug-bundle", "",
+		"write a .tar.gz of diagnostics of the run to attach to bug reports: its logs, manifest, config with secrets "+
+			"redacted, records that failed decoding and backend latencies")
+}
+
+const (
+	// maxBundleLog is the size of the logs kept in the debug bundle, later logs are dropped
+	maxBundleLog = 16 << 20
+	// redacted replaces secrets in the debug bundle and the manifest
+	redacted = "REDACTED"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram of every backend
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second,
+	10 * time.Second, 30 * time.Second, time.Minute,
+}
+
+// bundle collects the diagnostics of the run for --debug-bundle, nil if not set
+var bundle *debugBundle
+
+// debugBundle collects the diagnostics of a run
+type debugBundle struct {
+	mu       sync.Mutex
+	logs     bytes.Buffer
+	dropped  bool
+	failures []failedRecord
+	backends map[string]*backendLatency
+}
+
+// failedRecord is an input record that failed decoding
+type failedRecord struct {
+	File   string `json:"file"`
+	Index  int    `json:"index"`
+	Offset int64  `json:"offset"`
+	Size   int    `json:"size"`
+	Error  string `json:"error"`
+}
+
+// backendLatency is a histogram of the request latencies of a backend
+type backendLatency struct {
+	requests int
+	errors   int
+	max      time.Duration
+	total    time.Duration
+	// counts are the requests of every latency bucket, the last one counts the requests slower than all buckets
+	counts []int
+}
+
+// initDebugBundle starts collecting diagnostics if --debug-bundle is set, including the logs of the run
+func initDebugBundle() {
+	if debugBundleFile == "" {
+		return
+	}
+	bundle = &debugBundle{failures: []failedRecord{}, backends: map[string]*backendLatency{}}
+	log.AddHook(bundle)
+}
+
+// Levels returns the log levels the bundle collects, all of the ones logged
+func (b *debugBundle) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire collects a log entry
+func (b *debugBundle) Fire(entry *log.Entry) error {
+	formatter := &log.TextFormatter{FullTimestamp: true, DisableColors: true}
+	line, err := formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.logs.Len()+len(line) > maxBundleLog {
+		b.dropped = true
+		return nil
+	}
+	b.logs.Write(line)
+	return nil
+}
+
+// recordFailure collects an input record that failed decoding
+func (b *debugBundle) recordFailure(filename string, index int, offset int64, size int, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = append(b.failures, failedRecord{File: filename, Index: index, Offset: offset, Size: size,
+		Error: err.Error()})
+}
+
+// observe collects the latency of a request to a backend
+func (b *debugBundle) observe(backend string, latency time.Duration, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats, ok := b.backends[backend]
+	if !ok {
+		stats = &backendLatency{counts: make([]int, len(latencyBuckets)+1)}
+		b.backends[backend] = stats
+	}
+	stats.requests++
+	if err != nil {
+		stats.errors++
+	}
+	stats.total += latency
+	if latency > stats.max {
+		stats.max = latency
+	}
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool {
+		return latency <= latencyBuckets[i]
+	})
+	stats.counts[bucket]++
+}
+
+// percentile returns the upper bound of the latency bucket of the p-th percentile of the requests
+func (s *backendLatency) percentile(p float64) time.Duration {
+	rank := int(p / 100 * float64(s.requests))
+	seen := 0
+	for i, count := range s.counts {
+		seen += count
+		if seen > rank {
+			if i == len(latencyBuckets) {
+				return s.max
+			}
+			return latencyBuckets[i]
+		}
+	}
+	return s.max
+}
+
+// MarshalJSON encodes the statistics with human-readable latencies
+func (s *backendLatency) MarshalJSON() ([]byte, error) {
+	summary := map[string]interface{}{
+		"requests": s.requests,
+		"errors":   s.errors,
+		"max":      s.max.String(),
+	}
+	if s.requests != 0 {
+		summary["mean"] = (s.total / time.Duration(s.requests)).String()
+		summary["p50_at_most"] = s.percentile(50).String()
+		summary["p90_at_most"] = s.percentile(90).String()
+		summary["p99_at_most"] = s.percentile(99).String()
+	}
+	return json.Marshal(summary)
+}
+
+// redactedConfig returns the config file of the run with the values of secret flags redacted, nil if there is none
+func redactedConfig() ([]byte, error) {
+	filename := configFilename()
+	if filename == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var values yaml.MapSlice
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing config %s: %v", filename, err)
+	}
+	for i := range values {
+		if name, ok := values[i].Key.(string); ok && secretFlags[name] {
+			values[i].Value = redacted
+		}
+	}
+	return yaml.Marshal(values)
+}
+
+// write writes the bundle of the run described by manifest to filename
+func (b *debugBundle) write(filename string, manifest *runManifest) error {
+	encodedManifest, err := manifest.encode()
+	if err != nil {
+		return err
+	}
+	config, err := redactedConfig()
+	if err != nil {
+		config = []byte(fmt.Sprintf("# failed reading the config: %v\n", err))
+	}
+	environment, err := json.MarshalIndent(map[string]string{
+		"tool_version": version,
+		"go_version":   runtime.Version(),
+		"os":           runtime.GOOS,
+		"arch":         runtime.GOARCH,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	logs := append([]byte{}, b.logs.Bytes()...)
+	if b.dropped {
+		logs = append(logs, fmt.Sprintf("# later logs were dropped, the bundle keeps %d bytes\n", maxBundleLog)...)
+	}
+	failures, err := json.MarshalIndent(b.failures, "", "  ")
+	if err != nil {
+		return err
+	}
+	latencies, err := json.MarshalIndent(b.backends, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	compressed := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(compressed)
+	modTime := time.Now()
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{"environment.json", append(environment, '\n')},
+		{"manifest.json", encodedManifest},
+		{"config.yaml", config},
+		{"run.log", logs},
+		{"failed-records.json", append(failures, '\n')},
+		{"latency.json", append(latencies, '\n')},
+	} {
+		if file.content == nil {
+			continue
+		}
+		err = tw.WriteHeader(&tar.Header{Name: "debug-bundle/" + file.name, Mode: 0600,
+			Size: int64(len(file.content)), ModTime: modTime})
+		if err == nil {
+			_, err = tw.Write(file.content)
+		}
+		if err != nil {
+			return fmt.Errorf("failed writing debug bundle: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed writing debug bundle: %v", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("failed writing debug bundle: %v", err)
+	}
+	err = ioutil.WriteFile(filename, archive.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write debug bundle to %s: %v", filename, err)
+	}
+	return nil
+}
+
+// writeDebugBundle writes the bundle of the run to --debug-bundle, if set
+func writeDebugBundle(manifest *runManifest) {
+	if bundle == nil {
+		return
+	}
+	err := bundle.write(debugBundleFile, manifest)
+	if err != nil {
+		logError(err)
+		return
+	}
+	logMessage(log.InfoLevel, msgDebugBundleWritten, debugBundleFile)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackendLatencyPercentiles(t *testing.T) {
+	b := &debugBundle{backends: map[string]*backendLatency{}}
+	for i := 0; i < 9; i++ {
+		b.observe("indexer", 20*time.Millisecond, nil)
+	}
+	b.observe("indexer", 2*time.Minute, fmt.Errorf("HTTP 503: unavailable"))
+	stats := b.backends["indexer"]
+	if stats.requests != 10 || stats.errors != 1 {
+		t.Errorf("expected 10 requests and 1 error, got %d and %d", stats.requests, stats.errors)
+	}
+	if p50 := stats.percentile(50); p50 != 25*time.Millisecond {
+		t.Errorf("expected the median in the 25ms bucket, got %s", p50)
+	}
+	if p99 := stats.percentile(99); p99 != 2*time.Minute {
+		t.Errorf("expected the 99th percentile to be the slowest request, got %s", p99)
+	}
+}
+
+func TestDebugBundleRedactsTheConfig(t *testing.T) {
+	dir := testDir(t)
+	config := filepath.Join(dir, "config.yaml")
+	err := ioutil.WriteFile(config, []byte("idx-addr: http://localhost:8980\nidx-tkn: secret-token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(file string) { configFile = file }(configFile)
+	configFile = config
+
+	b := &debugBundle{failures: []failedRecord{}, backends: map[string]*backendLatency{}}
+	b.recordFailure("batch.tx", 3, 120, 40, fmt.Errorf("msgpack decode error"))
+	filename := filepath.Join(dir, "bundle.tar.gz")
+	err = b.write(filename, newRunManifest(rootCmd, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	tr := tar.NewReader(compressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = string(content)
+	}
+	if config := entries["debug-bundle/config.yaml"]; strings.Contains(config, "secret-token") ||
+		!strings.Contains(config, redacted) {
+		t.Errorf("expected the token to be redacted from the config, got %q", config)
+	}
+	if !strings.Contains(entries["debug-bundle/failed-records.json"], `"offset": 120`) {
+		t.Errorf("expected the failed record, got %q", entries["debug-bundle/failed-records.json"])
+	}
+	if _, ok := entries["debug-bundle/environment.json"]; !ok {
+		t.Errorf("expected the environment in the bundle, got %v", entries)
+	}
+}
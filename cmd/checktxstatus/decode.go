@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	decodeWorkers   int
+	maxBufferedTxns int
+)
+
+func init() {
+	rootCmd.Flags().IntVar(&decodeWorkers, "decode-workers", 1,
+		"number of input files decoded in parallel, ahead of the file being checked")
+	rootCmd.Flags().IntVar(&maxBufferedTxns, "max-buffered-txns", 100000,
+		"maximal number of transactions decoded ahead of the file being checked with --decode-workers")
+}
+
+// txAccountant caps the total number of transactions decoded ahead of the file being checked
+// the file being checked is never held back, so a file larger than the cap can still be read
+type txAccountant struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	max  int
+	// buffered is the number of transactions decoded and not checked yet
+	buffered int
+	// head is the position of the file being checked
+	head int
+	// stopped releases all waiting decoders when the run stops
+	stopped bool
+}
+
+// acquire waits until a transaction of the file at position seq may be decoded
+func (a *txAccountant) acquire(seq int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for a.buffered >= a.max && seq != a.head && !a.stopped {
+		a.cond.Wait()
+	}
+	a.buffered++
+}
+
+// release marks transactions as checked
+func (a *txAccountant) release(n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.buffered -= n
+	a.cond.Broadcast()
+}
+
+// advance marks the file at position seq as the one being checked
+func (a *txAccountant) advance(seq int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.head = seq
+	a.cond.Broadcast()
+}
+
+// stop stops holding decoders back
+func (a *txAccountant) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	a.cond.Broadcast()
+}
+
+// decodedBatch is an input file decoded ahead of being checked
+type decodedBatch struct {
+	batch *txBatch
+	err   error
+	// acquired is the number of transactions of the batch counted by the accountant
+	acquired int
+	// done is closed once the file is decoded
+	done chan struct{}
+}
+
+// batchDecoder decodes input files in parallel workers, in order, ahead of the file being checked
+type batchDecoder struct {
+	accountant *txAccountant
+	filenames  []string
+	batches    []*decodedBatch
+}
+
+// startDecoding starts decoding filenames in --decode-workers parallel workers
+// it returns nil if files are decoded one at a time, when being checked
+// the size of all the files is confirmed first, so confirmations are not asked from parallel workers
+func startDecoding(filenames []string) (*batchDecoder, error) {
+	if decodeWorkers < 1 {
+		return nil, fmt.Errorf("--decode-workers must be at least 1")
+	}
+	if decodeWorkers == 1 {
+		return nil, nil
+	}
+	if prefetchSize > 0 {
+		return nil, fmt.Errorf("--prefetch and --decode-workers are mutually exclusive")
+	}
+	if maxBufferedTxns < 1 {
+		return nil, fmt.Errorf("--max-buffered-txns must be at least 1")
+	}
+	for _, filename := range filenames {
+		err := checkFileSizeLimit(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d := &batchDecoder{
+		accountant: &txAccountant{max: maxBufferedTxns},
+		filenames:  filenames,
+		batches:    make([]*decodedBatch, len(filenames)),
+	}
+	d.accountant.cond = sync.NewCond(&d.accountant.mu)
+	jobs := make(chan int, len(filenames))
+	for seq := range filenames {
+		d.batches[seq] = &decodedBatch{done: make(chan struct{})}
+		jobs <- seq
+	}
+	close(jobs)
+	for i := 0; i < decodeWorkers; i++ {
+		go func() {
+			for seq := range jobs {
+				d.decode(seq)
+			}
+		}()
+	}
+	return d, nil
+}
+
+// decode decodes the file at position seq, counting its transactions with the accountant
+func (d *batchDecoder) decode(seq int) {
+	decoded := d.batches[seq]
+	defer close(decoded.done)
+	decoded.batch, decoded.err = readBatch(d.filenames[seq], func(txRecord, bool) {
+		d.accountant.acquire(seq)
+		decoded.acquired++
+	})
+}
+
+// check waits for the file at position seq to be decoded and checks it
+func (d *batchDecoder) check(c *checker, seq int) (fileResult, error) {
+	d.accountant.advance(seq)
+	decoded := d.batches[seq]
+	<-decoded.done
+	defer d.accountant.release(decoded.acquired)
+	if decoded.err != nil {
+		return fileResult{}, decoded.err
+	}
+	return c.checkBatch(d.filenames[seq], decoded.batch)
+}
+
+// stop stops holding back the workers when the run stops before checking all files
+func (d *batchDecoder) stop() {
+	if d == nil {
+		return
+	}
+	d.accountant.stop()
+}
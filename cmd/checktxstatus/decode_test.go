@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTxAccountantHoldsBackFilesAhead(t *testing.T) {
+	a := &txAccountant{max: 1}
+	a.cond = sync.NewCond(&a.mu)
+	a.acquire(1)
+	acquired := make(chan struct{})
+	go func() {
+		a.acquire(1)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected a file ahead to wait past the cap")
+	case <-time.After(20 * time.Millisecond):
+	}
+	// the file being checked is never held back
+	a.acquire(0)
+	a.release(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected releasing transactions to let the file ahead be decoded")
+	}
+}
+
+func TestStartDecodingValidatesFlags(t *testing.T) {
+	defer func(workers, buffered, prefetch int) {
+		decodeWorkers, maxBufferedTxns, prefetchSize = workers, buffered, prefetch
+	}(decodeWorkers, maxBufferedTxns, prefetchSize)
+	decodeWorkers, maxBufferedTxns, prefetchSize = 1, 10, 0
+	if decoder, err := startDecoding(nil); decoder != nil || err != nil {
+		t.Errorf("expected files to be decoded when checked with a single worker, got %v %v", decoder, err)
+	}
+	decodeWorkers = 0
+	if _, err := startDecoding(nil); err == nil {
+		t.Error("expected an error without decode workers")
+	}
+	decodeWorkers, prefetchSize = 2, 10
+	if _, err := startDecoding(nil); err == nil {
+		t.Error("expected --prefetch and --decode-workers to be mutually exclusive")
+	}
+	prefetchSize, maxBufferedTxns = 0, 0
+	if _, err := startDecoding(nil); err == nil {
+		t.Error("expected an error without buffered transactions")
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the formats decode prints the transactions in
+const (
+	decodeTable = "table"
+	decodeJSON  = "json"
+)
+
+var (
+	decodeOutput string
+	decodeTxIDs  []string
+)
+
+// decodeSharedFlags are the flags of the root command that affect decoding transactions
+var decodeSharedFlags = []string{"log-level", "input-format", "permissive", "redact"}
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode <file1.tx> <file2.tx> ...",
+	Short: "Print the fields of the transactions of files, as a table or as JSON lines",
+	Example: `  # see what is in a batch
+  checktxstatus decode batch.tx
+
+  # inspect a transaction of a large indexed file as JSON
+  checktxstatus decode --output json --txid J5A2KYMUQD53LOMJZ5QB6ZB2HOYQL25UGCY5CABZ5S3BOWNZYGMQ large.tx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		err := validateInputFormat()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		redaction, err := parseRedaction(redactValues)
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		if decodeOutput != decodeTable && decodeOutput != decodeJSON {
+			logMessage(log.ErrorLevel, msgDecodeInvalidOutput, decodeOutput, decodeTable, decodeJSON)
+			exitCode = 1
+			return
+		}
+		for _, txid := range decodeTxIDs {
+			if !txchecker.IsTxID(txid) {
+				logMessage(log.ErrorLevel, msgDecodeInvalidTxID, txid)
+				exitCode = 1
+				return
+			}
+		}
+		if len(args) == 0 {
+			logError(newUserError(msgNoInputs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+
+		for _, filename := range args {
+			err := decodeFile(filepath.Clean(filename), redaction, cmd.OutOrStdout())
+			if err != nil {
+				logError(err)
+				exitCode = 1
+			}
+		}
+	},
+}
+
+func init() {
+	decodeCmd.Flags().StringVar(&decodeOutput, "output", decodeTable,
+		"format of the transactions printed: table, or json for a JSON object per line")
+	decodeCmd.Flags().StringSliceVar(&decodeTxIDs, "txid", nil,
+		"only print the transaction of this txid, read directly from the file if it has a current sidecar index "+
+			"(see --index-files-above), can be repeated")
+}
+
+// initDecodeCmd adds the decode subcommand, sharing the flags of the root command that affect decoding transactions
+// it must be called after the flags of the root command were registered
+func initDecodeCmd() {
+	for _, name := range decodeSharedFlags {
+		decodeCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(decodeCmd)
+}
+
+// decodedTx is a transaction as printed by decode
+type decodedTx struct {
+	recordSource
+	Type     types.TxType `json:"type"`
+	Sender   string       `json:"sender"`
+	Receiver string       `json:"receiver,omitempty"`
+	CloseTo  string       `json:"close_to,omitempty"`
+	// Amount is in microAlgos for payments, and in base units of the asset for asset transfers, missing if redacted
+	Amount     *uint64 `json:"amount,omitempty"`
+	AssetID    uint64  `json:"asset_id,omitempty"`
+	AssetName  string  `json:"asset_name,omitempty"`
+	AppID      uint64  `json:"app_id,omitempty"`
+	OnComplete string  `json:"on_complete,omitempty"`
+	// AppArgs are base64 encoded
+	AppArgs [][]byte `json:"app_args,omitempty"`
+	// Fee is missing if redacted
+	Fee        *uint64 `json:"fee,omitempty"`
+	FirstValid uint64  `json:"first_valid"`
+	LastValid  uint64  `json:"last_valid"`
+	Group      string  `json:"group,omitempty"`
+	RekeyTo    string  `json:"rekey_to,omitempty"`
+	// Note is the note as text if it is printable UTF-8, and base64 encoded otherwise, as told by NoteEncoding
+	Note         string `json:"note,omitempty"`
+	NoteEncoding string `json:"note_encoding,omitempty"`
+	// Signature is sig, msig, lsig, or none for unsigned transactions
+	Signature string `json:"signature"`
+	AuthAddr  string `json:"auth_addr,omitempty"`
+}
+
+// onCompletions are the names of the on-completion actions of application calls, as goal names them
+var onCompletions = map[types.OnCompletion]string{
+	types.NoOpOC:              "noop",
+	types.OptInOC:             "optin",
+	types.CloseOutOC:          "closeout",
+	types.ClearStateOC:        "clearstate",
+	types.UpdateApplicationOC: "update",
+	types.DeleteApplicationOC: "delete",
+}
+
+// decodeFile prints the transactions of a file to out in --output, only the ones of --txid if set, redacted by
+// redaction
+func decodeFile(filename string, redaction *redaction, out io.Writer) error {
+	records, err := decodeRecords(filename)
+	if err != nil {
+		return err
+	}
+	defer zeroizeRecords(records)
+	decoded := make([]decodedTx, 0, len(records))
+	for _, rec := range records {
+		if rec.txIDOnly {
+			return fmt.Errorf("%s lists txids only, it has no transactions to decode", filename)
+		}
+		tx := newDecodedTx(rec)
+		redaction.decodedTx(&tx)
+		decoded = append(decoded, tx)
+	}
+	if decodeOutput == decodeJSON {
+		for _, tx := range decoded {
+			line, err := json.Marshal(tx)
+			if err != nil {
+				return fmt.Errorf("failed encoding tx %s: %v", tx.TxID, err)
+			}
+			fmt.Fprintf(out, "%s\n", line)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tTXID\tTYPE\tSENDER\tRECEIVER\tDETAILS\tFEE\tVALID\tNOTE")
+	for _, tx := range decoded {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d-%d\t%s\n", tx.File, tx.TxID, tx.Type, tx.Sender, tx.Receiver,
+			tx.details(), tx.fee(), tx.FirstValid, tx.LastValid, tx.shortNote())
+	}
+	return w.Flush()
+}
+
+// decodeRecords reads the transactions of a file in file order, only the ones of --txid if set
+// the transactions of --txid are read directly from the file if it has a current sidecar index
+func decodeRecords(filename string) ([]txRecord, error) {
+	if len(decodeTxIDs) != 0 {
+		index, err := loadTxIndex(filename)
+		if err == nil {
+			return readIndexedRecords(filename, index)
+		}
+		log.Debugf("reading the whole of %s: %v", filename, err)
+	}
+	batch, err := readBatch(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.corrupt) != 0 {
+		logEntryMessage(log.WithField("file", filename), log.WarnLevel, msgCorruptSkipped, len(batch.corrupt))
+	}
+	records := allRecords(batch.groups, batch.individual)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].index < records[j].index
+	})
+	if len(decodeTxIDs) == 0 {
+		return records, nil
+	}
+	wanted := map[string]bool{}
+	for _, txid := range decodeTxIDs {
+		wanted[txid] = true
+	}
+	var selected []txRecord
+	for _, rec := range records {
+		if wanted[rec.txID] {
+			selected = append(selected, rec)
+			delete(wanted, rec.txID)
+		}
+	}
+	for _, txid := range decodeTxIDs {
+		if wanted[txid] {
+			zeroizeRecords(selected)
+			return nil, fmt.Errorf("tx %s is not in %s", txid, filename)
+		}
+	}
+	return selected, nil
+}
+
+// readIndexedRecords reads the transactions of --txid from a file with its sidecar index, in file order
+func readIndexedRecords(filename string, index map[string]txIndexEntry) ([]txRecord, error) {
+	// the position of a transaction in the file is the rank of its offset
+	offsets := make([]int64, 0, len(index))
+	for _, entry := range index {
+		offsets = append(offsets, entry.offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+	var records []txRecord
+	for _, txid := range decodeTxIDs {
+		entry, ok := index[txid]
+		if !ok {
+			zeroizeRecords(records)
+			return nil, fmt.Errorf("tx %s is not in %s", txid, filename)
+		}
+		raw, err := readIndexedTx(filename, entry)
+		if err != nil {
+			zeroizeRecords(records)
+			return nil, err
+		}
+		tx, err := txchecker.DecodeTransaction(raw, permissive)
+		if err != nil {
+			zeroize(raw)
+			zeroizeRecords(records)
+			return nil, fmt.Errorf("failed decoding tx %s of %s: %v", txid, filename, err)
+		}
+		position := sort.Search(len(offsets), func(i int) bool {
+			return offsets[i] >= entry.offset
+		})
+		records = append(records, txRecord{stx: tx.SignedTxn, txID: tx.TxID, file: filename, fileIndex: position,
+			index: position, unknown: tx.Unknown, raw: raw})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].index < records[j].index
+	})
+	log.Debugf("read %d transactions of %s with its index", len(records), filename)
+	return records, nil
+}
+
+// newDecodedTx returns the fields of a transaction printed by decode
+func newDecodedTx(rec txRecord) decodedTx {
+	txn := rec.stx.Txn
+	tx := decodedTx{
+		recordSource: rec.source(),
+		Type:         txn.Type,
+		Sender:       txn.Sender.String(),
+		FirstValid:   uint64(txn.FirstValid),
+		LastValid:    uint64(txn.LastValid),
+		Signature:    "none",
+	}
+	fee := uint64(txn.Fee)
+	tx.Fee = &fee
+	if receiver := txReceiver(txn); !receiver.IsZero() {
+		tx.Receiver = receiver.String()
+	}
+	switch txn.Type {
+	case types.PaymentTx:
+		amount := uint64(txn.Amount)
+		tx.Amount = &amount
+		if !txn.CloseRemainderTo.IsZero() {
+			tx.CloseTo = txn.CloseRemainderTo.String()
+		}
+	case types.AssetTransferTx:
+		amount := txn.AssetAmount
+		tx.Amount, tx.AssetID = &amount, uint64(txn.XferAsset)
+		if !txn.AssetCloseTo.IsZero() {
+			tx.CloseTo = txn.AssetCloseTo.String()
+		}
+	case types.AssetConfigTx:
+		tx.AssetID, tx.AssetName = uint64(txn.ConfigAsset), txn.AssetParams.AssetName
+	case types.AssetFreezeTx:
+		tx.AssetID = uint64(txn.FreezeAsset)
+	case types.ApplicationCallTx:
+		tx.AppID, tx.AppArgs = uint64(txn.ApplicationID), txn.ApplicationArgs
+		tx.OnComplete = onCompletions[txn.OnCompletion]
+		if tx.OnComplete == "" {
+			tx.OnComplete = fmt.Sprint(uint64(txn.OnCompletion))
+		}
+	}
+	if gid := txn.Group; gid != (types.Digest{}) {
+		tx.Group = groupIDString(gid)
+	}
+	if !txn.RekeyTo.IsZero() {
+		tx.RekeyTo = txn.RekeyTo.String()
+	}
+	if len(txn.Note) != 0 {
+		tx.Note, tx.NoteEncoding = string(txn.Note), "text"
+		if !printable(txn.Note) {
+			tx.Note, tx.NoteEncoding = base64.StdEncoding.EncodeToString(txn.Note), "base64"
+		}
+	}
+	switch {
+	case rec.stx.Sig != (types.Signature{}):
+		tx.Signature = "sig"
+	case !rec.stx.Msig.Blank():
+		tx.Signature = "msig"
+	case !rec.stx.Lsig.Blank():
+		tx.Signature = "lsig"
+	}
+	if !rec.stx.AuthAddr.IsZero() {
+		tx.AuthAddr = rec.stx.AuthAddr.String()
+	}
+	return tx
+}
+
+// printable returns true if note is UTF-8 text without control characters other than whitespace
+func printable(note []byte) bool {
+	if !utf8.Valid(note) {
+		return false
+	}
+	for _, r := range string(note) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// details returns the column of the table describing what the transaction does, e.g. "5 Algo" for a payment
+func (tx decodedTx) details() string {
+	switch {
+	case tx.Type == types.PaymentTx && tx.Amount != nil:
+		return formatAlgos(*tx.Amount) + " Algo"
+	case tx.Type == types.AssetTransferTx && tx.Amount != nil:
+		return fmt.Sprintf("%d of asset %d", *tx.Amount, tx.AssetID)
+	}
+	switch tx.Type {
+	case types.AssetTransferTx:
+		return fmt.Sprintf("asset %d", tx.AssetID)
+	case types.AssetConfigTx:
+		if tx.AssetID == 0 {
+			return fmt.Sprintf("create asset %q", tx.AssetName)
+		}
+		return fmt.Sprintf("asset %d", tx.AssetID)
+	case types.AssetFreezeTx:
+		return fmt.Sprintf("asset %d", tx.AssetID)
+	case types.ApplicationCallTx:
+		return fmt.Sprintf("app %d %s, %d args", tx.AppID, tx.OnComplete, len(tx.AppArgs))
+	}
+	return ""
+}
+
+// fee returns the fee column of the table, - if it is redacted
+func (tx decodedTx) fee() string {
+	if tx.Fee == nil {
+		return "-"
+	}
+	return formatAlgos(*tx.Fee)
+}
+
+// shortNote returns the note of the table, on a single line and cut at 32 characters
+func (tx decodedTx) shortNote() string {
+	note := strings.Join(strings.Fields(tx.Note), " ")
+	switch tx.NoteEncoding {
+	case "base64":
+		note = "base64:" + note
+	case "redacted":
+		note = "[redacted]"
+	}
+	if runes := []rune(note); len(runes) > 32 {
+		note = string(runes[:31]) + "…"
+	}
+	return note
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestNewDecodedTx(t *testing.T) {
+	rec := txRecord{file: "batch.tx", txID: testTxID(1)}
+	rec.stx.Txn.Type = types.PaymentTx
+	rec.stx.Txn.Amount = 5000000
+	rec.stx.Txn.Note = []byte("invoice 42")
+	tx := newDecodedTx(rec)
+	if tx.Note != "invoice 42" || tx.NoteEncoding != "text" || tx.details() != "5 Algo" || tx.Signature != "none" {
+		t.Errorf("unexpected decoding of a payment with a text note: %+v", tx)
+	}
+	rec.stx.Txn.Note = []byte{0xff, 0x00}
+	tx = newDecodedTx(rec)
+	if tx.Note != "/wA=" || tx.NoteEncoding != "base64" || tx.shortNote() != "base64:/wA=" {
+		t.Errorf("expected a binary note to be base64 encoded, got %q as %s", tx.Note, tx.NoteEncoding)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// derivedStatus refines a condition with an expression over the fields of its transactions, e.g. the unsent
+// transactions that were signed long ago. It is a condition of its own in the policy, the outputs and the reports.
+type derivedStatus struct {
+	// Name is the condition of the transactions matching When
+	Name txCondition `yaml:"name"`
+	// From is the condition refined, the derived status inherits its rule unless the policy has one for Name
+	From txCondition `yaml:"from"`
+	// When is the expression matching the transactions, see statusFields for the fields it can use
+	When string `yaml:"when"`
+	expr statusExpr
+}
+
+// parseDerivedStatuses validates the derived statuses of the policy filename and parses their expressions
+func parseDerivedStatuses(filename string, derived []*derivedStatus) error {
+	names := map[txCondition]bool{}
+	for _, status := range derived {
+		if status.Name == "" || !bucketRegexp.MatchString(string(status.Name)) {
+			return fmt.Errorf("policy %s has invalid derived status name %q", filename, status.Name)
+		}
+		if knownConditions[status.Name] || names[status.Name] {
+			return fmt.Errorf("policy %s derives status %s more than once, or derives a built-in condition", filename,
+				status.Name)
+		}
+		if !knownConditions[status.From] {
+			return fmt.Errorf("policy %s derives status %s from unknown condition %q", filename, status.Name,
+				status.From)
+		}
+		expr, err := parseStatusExpr(status.When)
+		if err != nil {
+			return fmt.Errorf("policy %s has invalid expression for status %s: %v", filename, status.Name, err)
+		}
+		if expr.kind() != exprBool {
+			return fmt.Errorf("policy %s has an expression of kind %s for status %s, expected a boolean one",
+				filename, expr.kind(), status.Name)
+		}
+		status.expr = expr
+		names[status.Name] = true
+	}
+	return nil
+}
+
+// deriveStatuses moves the transactions of classified matching a derived status of the policy to it, the first one
+// matching applies. A group can only be resubmitted as a whole, so all the members of a unit get the status if one of
+// them matches. Transactions read from txid lists have no fields and keep their condition.
+func (c *checker) deriveStatuses(classified map[txCondition][]txRecord) (map[txCondition][]txRecord, error) {
+	if len(c.policy.Derived) == 0 {
+		return classified, nil
+	}
+	statuses := map[txCondition][]txRecord{}
+	for condition, txs := range classified {
+		for _, unit := range submissionUnits(txs) {
+			status, err := c.deriveStatus(condition, unit)
+			if err != nil {
+				return nil, err
+			}
+			statuses[status] = append(statuses[status], unit...)
+		}
+	}
+	return statuses, nil
+}
+
+// deriveStatus returns the status of a unit of transactions of condition
+func (c *checker) deriveStatus(condition txCondition, unit []txRecord) (txCondition, error) {
+	if unit[0].txIDOnly {
+		return condition, nil
+	}
+	for _, status := range c.policy.Derived {
+		if status.From != condition {
+			continue
+		}
+		for _, tx := range unit {
+			fields := &statusFields{tx: tx, round: c.round}
+			matched, err := status.expr.eval(fields)
+			if err != nil {
+				return "", err
+			}
+			if matched.b {
+				return status.Name, nil
+			}
+		}
+	}
+	return condition, nil
+}
+
+// statusFields are the fields of a transaction the expressions of derived statuses can use
+type statusFields struct {
+	tx txRecord
+	// round looks up the latest round only if an expression uses it
+	round *indexerRound
+}
+
+// statusFieldKinds are the fields of statusFields and their kinds
+var statusFieldKinds = map[string]exprKind{
+	"txid":        exprString,
+	"type":        exprString,
+	"sender":      exprString,
+	"receiver":    exprString,
+	"group":       exprString,
+	"amount":      exprInt,
+	"asset":       exprInt,
+	"fee":         exprInt,
+	"first_valid": exprInt,
+	"last_valid":  exprInt,
+	"round":       exprInt,
+	"age":         exprInt,
+}
+
+// field returns the value of a field: amount is in microalgos for payments and in base units of the asset for asset
+// transfers, round is the latest round of the indexer, or --as-of-round, and age is the number of rounds since the
+// first valid round of the transaction
+func (f *statusFields) field(name string) (exprValue, error) {
+	txn := f.tx.stx.Txn
+	switch name {
+	case "txid":
+		return exprValue{s: f.tx.txID}, nil
+	case "type":
+		return exprValue{s: string(txn.Type)}, nil
+	case "sender":
+		return exprValue{s: txn.Sender.String()}, nil
+	case "receiver":
+		if receiver := txReceiver(txn); !receiver.IsZero() {
+			return exprValue{s: receiver.String()}, nil
+		}
+		return exprValue{}, nil
+	case "group":
+		if txn.Group != (types.Digest{}) {
+			return exprValue{s: groupIDString(txn.Group)}, nil
+		}
+		return exprValue{}, nil
+	case "amount":
+		switch txn.Type {
+		case types.PaymentTx:
+			return exprValue{i: clampInt(uint64(txn.Amount))}, nil
+		case types.AssetTransferTx:
+			return exprValue{i: clampInt(txn.AssetAmount)}, nil
+		}
+		return exprValue{}, nil
+	case "asset":
+		return exprValue{i: clampInt(uint64(txn.XferAsset))}, nil
+	case "fee":
+		return exprValue{i: clampInt(uint64(txn.Fee))}, nil
+	case "first_valid":
+		return exprValue{i: clampInt(uint64(txn.FirstValid))}, nil
+	case "last_valid":
+		return exprValue{i: clampInt(uint64(txn.LastValid))}, nil
+	case "round", "age":
+		round, err := f.round.latest()
+		if err != nil {
+			return exprValue{}, err
+		}
+		if name == "age" {
+			return exprValue{i: clampInt(round) - clampInt(uint64(txn.FirstValid))}, nil
+		}
+		return exprValue{i: clampInt(round)}, nil
+	}
+	return exprValue{}, fmt.Errorf("unknown field %s", name)
+}
+
+// clampInt converts an unsigned field to the integers of the expressions, the largest asset amounts are clamped
+func clampInt(v uint64) int64 {
+	if v > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(v)
+}
+
+// exprKind is the type of an expression, checked when parsing it
+type exprKind string
+
+const (
+	exprBool   exprKind = "boolean"
+	exprInt    exprKind = "integer"
+	exprString exprKind = "string"
+)
+
+// exprValue is the value of an expression, of its kind
+type exprValue struct {
+	b bool
+	i int64
+	s string
+}
+
+// statusExpr is a node of a parsed expression
+type statusExpr interface {
+	kind() exprKind
+	eval(fields *statusFields) (exprValue, error)
+}
+
+type exprLiteral struct {
+	k exprKind
+	v exprValue
+}
+
+func (e *exprLiteral) kind() exprKind { return e.k }
+
+func (e *exprLiteral) eval(*statusFields) (exprValue, error) { return e.v, nil }
+
+type exprField struct {
+	name string
+}
+
+func (e *exprField) kind() exprKind { return statusFieldKinds[e.name] }
+
+func (e *exprField) eval(fields *statusFields) (exprValue, error) { return fields.field(e.name) }
+
+type exprNot struct {
+	operand statusExpr
+}
+
+func (e *exprNot) kind() exprKind { return exprBool }
+
+func (e *exprNot) eval(fields *statusFields) (exprValue, error) {
+	v, err := e.operand.eval(fields)
+	return exprValue{b: !v.b}, err
+}
+
+// exprBinary is an operation on two operands, the logical ones evaluate their right operand only if needed
+type exprBinary struct {
+	op          string
+	left, right statusExpr
+}
+
+func (e *exprBinary) kind() exprKind {
+	switch e.op {
+	case "+", "-":
+		return exprInt
+	}
+	return exprBool
+}
+
+func (e *exprBinary) eval(fields *statusFields) (exprValue, error) {
+	left, err := e.left.eval(fields)
+	if err != nil {
+		return exprValue{}, err
+	}
+	if e.op == "&&" && !left.b || e.op == "||" && left.b {
+		return left, nil
+	}
+	right, err := e.right.eval(fields)
+	if err != nil {
+		return exprValue{}, err
+	}
+	switch e.op {
+	case "&&", "||":
+		return right, nil
+	case "+":
+		return exprValue{i: left.i + right.i}, nil
+	case "-":
+		return exprValue{i: left.i - right.i}, nil
+	case "==":
+		return exprValue{b: left == right}, nil
+	case "!=":
+		return exprValue{b: left != right}, nil
+	case "<":
+		return exprValue{b: left.i < right.i}, nil
+	case "<=":
+		return exprValue{b: left.i <= right.i}, nil
+	case ">":
+		return exprValue{b: left.i > right.i}, nil
+	}
+	return exprValue{b: left.i >= right.i}, nil
+}
+
+// parseStatusExpr parses an expression of fields, integer and quoted string literals, true and false, combined with
+// + and - on integers, comparisons, !, && and || and parentheses, checking the kinds of the operands
+func parseStatusExpr(text string) (statusExpr, error) {
+	tokens, err := tokenizeExpr(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// exprOperators are the operators of the expressions, the two-character ones first so they are matched first
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "(", ")"}
+
+// tokenizeExpr splits an expression into operators, identifiers, numbers and quoted strings
+func tokenizeExpr(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		r := rune(text[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			end := strings.IndexRune(text[i+1:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, text[i:i+end+2])
+			i += end + 2
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(text) && (text[i] == '_' || unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i]))) {
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(text[i:], op) {
+					tokens = append(tokens, op)
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", text[i], i)
+			}
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return tokens, nil
+}
+
+// exprParser is a recursive descent parser of expressions, from the lowest precedence to the highest
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// next returns the next token, empty at the end
+func (p *exprParser) next() string {
+	if p.pos == len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// binary parses the chained operations of ops on operands of kind parsed by operand, from left to right
+func (p *exprParser) binary(ops []string, operand func() (statusExpr, error), kind exprKind) (statusExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.next()
+		if op != ops[0] && (len(ops) == 1 || op != ops[1]) {
+			return left, nil
+		}
+		p.pos++
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != kind || right.kind() != kind {
+			return nil, fmt.Errorf("%s needs %s operands, got %s and %s", op, kind, left.kind(), right.kind())
+		}
+		left = &exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (statusExpr, error) {
+	return p.binary([]string{"||"}, p.parseAnd, exprBool)
+}
+
+func (p *exprParser) parseAnd() (statusExpr, error) {
+	return p.binary([]string{"&&"}, p.parseNot, exprBool)
+}
+
+func (p *exprParser) parseNot() (statusExpr, error) {
+	if p.next() != "!" {
+		return p.parseComparison()
+	}
+	p.pos++
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if operand.kind() != exprBool {
+		return nil, fmt.Errorf("! needs a boolean operand, got %s", operand.kind())
+	}
+	return &exprNot{operand: operand}, nil
+}
+
+// parseComparison parses a comparison, which does not chain; any kinds compare for equality, integers only for order
+func (p *exprParser) parseComparison() (statusExpr, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.kind() != right.kind() {
+		return nil, fmt.Errorf("%s compares %s to %s", op, left.kind(), right.kind())
+	}
+	if op != "==" && op != "!=" && left.kind() != exprInt {
+		return nil, fmt.Errorf("%s needs integer operands, got %s", op, left.kind())
+	}
+	return &exprBinary{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseSum() (statusExpr, error) {
+	return p.binary([]string{"+", "-"}, p.parsePrimary, exprInt)
+}
+
+func (p *exprParser) parsePrimary() (statusExpr, error) {
+	token := p.next()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch {
+	case token == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return expr, nil
+	case token == "true" || token == "false":
+		return &exprLiteral{k: exprBool, v: exprValue{b: token == "true"}}, nil
+	case token[0] == '\'' || token[0] == '"':
+		return &exprLiteral{k: exprString, v: exprValue{s: token[1 : len(token)-1]}}, nil
+	case unicode.IsDigit(rune(token[0])):
+		i, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", token)
+		}
+		return &exprLiteral{k: exprInt, v: exprValue{i: i}}, nil
+	case statusFieldKinds[token] != "":
+		return &exprField{name: token}, nil
+	case token[0] == '_' || unicode.IsLetter(rune(token[0])):
+		return nil, fmt.Errorf("unknown field %s", token)
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
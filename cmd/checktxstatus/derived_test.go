@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestParseStatusExpr(t *testing.T) {
+	var payment txRecord
+	payment.txID = testTxID(1)
+	payment.stx.Txn.Type = types.PaymentTx
+	payment.stx.Txn.Amount = 5000000
+	payment.stx.Txn.FirstValid = 100
+	fields := &statusFields{tx: payment}
+	tests := []struct {
+		expr    string
+		matched bool
+	}{
+		{`type == "pay" && amount > 1000000`, true},
+		{`!(type == "pay") || amount - 4000000 < 1000000`, false},
+		{`first_valid + 10 == 110 && receiver == ""`, true},
+		{`txid != "` + testTxID(1) + `"`, false},
+	}
+	for _, test := range tests {
+		expr, err := parseStatusExpr(test.expr)
+		if err != nil {
+			t.Errorf("failed parsing %q: %v", test.expr, err)
+			continue
+		}
+		value, err := expr.eval(fields)
+		if err != nil || value.b != test.matched {
+			t.Errorf("expected %q to be %v, got %v, %v", test.expr, test.matched, value.b, err)
+		}
+	}
+	for _, invalid := range []string{`amount > "1"`, `type +`, `unknown == 1`, `amount == 1 ==  2`, `"open`} {
+		if _, err := parseStatusExpr(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestDeriveStatusesMovesWholeGroups(t *testing.T) {
+	policy, err := loadPolicy(writeTestPolicy(t, `derived:
+  - name: unsent-large
+    from: unsent
+    when: amount >= 1000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Conditions["unsent-large"] != policy.Conditions[conditionUnsent] ||
+		policy.refined("unsent-large") != conditionUnsent {
+		t.Errorf("expected the derived status to inherit the rule of unsent, got %+v", policy.Conditions)
+	}
+	tx := func(txID string, amount uint64, group types.Digest) txRecord {
+		var rec txRecord
+		rec.txID = txID
+		rec.stx.Txn.Type = types.PaymentTx
+		rec.stx.Txn.Amount = types.MicroAlgos(amount)
+		rec.stx.Txn.Group = group
+		return rec
+	}
+	c := &checker{policy: policy}
+	statuses, err := c.deriveStatuses(map[txCondition][]txRecord{
+		conditionUnsent: {tx("small", 1, types.Digest{}), tx("member", 1, types.Digest{1}),
+			tx("large-member", 1000, types.Digest{1}), {txID: "listed", txIDOnly: true}},
+		conditionConfirmed: {tx("confirmed", 1000, types.Digest{})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses["unsent-large"]) != 2 || len(statuses[conditionUnsent]) != 2 ||
+		len(statuses[conditionConfirmed]) != 1 {
+		t.Errorf("expected the group of the large payment to be derived, got %+v", statuses)
+	}
+}
+
+func TestLoadPolicyRejectsInvalidDerivedStatuses(t *testing.T) {
+	for _, content := range []string{
+		"derived:\n  - name: unsent\n    from: unsent\n    when: true\n",
+		"derived:\n  - name: old\n    from: lost\n    when: true\n",
+		"derived:\n  - name: old\n    from: unsent\n    when: age\n",
+	} {
+		if _, err := loadPolicy(writeTestPolicy(t, content)); err == nil {
+			t.Errorf("expected policy %q to be rejected", content)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var doctorTimeout time.Duration
+
+// doctorSharedFlags are the flags of the root command whose configuration the doctor checks
+var doctorSharedFlags = []string{
+	"log-level", "network", "idx-addr", "idx-tkn", "idx-not-found-status", "idx-not-found-body", "idx-not-found-empty",
+	"hedge-idx-addr", "hedge-idx-tkn", "overrides", "checkpoint", "cache-path", "policy", "address-book", "nfd",
+	"nfd-api", "age-recipient", "gpg-recipient", "compress", "input-format", "algod-addr", "algod-tkn",
+	"state-backend", "state-prefix",
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [<file1.tx> <file2.tx> ...]",
+	Short: "Check the configured indexers, endpoints and files, printing how to fix every failure",
+	Example: `  # check the indexer of the config file
+  checktxstatus doctor
+
+  # check the indexer and the hedge indexer serve the network of an input file
+  checktxstatus doctor --idx-addr http://localhost:8980 --hedge-idx-addr https://mainnet-idx.algonode.cloud batch.tx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		initRunID()
+		d := &doctor{out: cmd.OutOrStdout()}
+		d.checkConfig(configErr)
+		genesis := d.checkIndexers()
+		d.checkAlgod(genesis)
+		d.checkInputs(args, genesis)
+		d.checkCheckpoint()
+		d.checkCache()
+		d.checkState()
+		d.checkNFD()
+		if d.failures != 0 {
+			fmt.Fprintf(d.out, "%d checks failed\n", d.failures)
+			exitCode = 1
+			return
+		}
+		fmt.Fprintln(d.out, "all checks passed")
+	},
+}
+
+func init() {
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "timeout of every request of the checks")
+}
+
+// initDoctorCmd adds the doctor subcommand, sharing the flags of the root command it checks
+// it must be called after the flags of the root command were registered
+func initDoctorCmd() {
+	for _, name := range doctorSharedFlags {
+		doctorCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctor runs checks of the configuration, printing their results
+type doctor struct {
+	out      io.Writer
+	failures int
+}
+
+// pass prints a passed check
+func (d *doctor) pass(check string, format string, args ...interface{}) {
+	fmt.Fprintf(d.out, "ok   %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+// fail prints a failed check and how to fix it
+func (d *doctor) fail(check string, err error, fix string) {
+	d.failures++
+	fmt.Fprintf(d.out, "FAIL %s: %v\n", check, err)
+	if fix != "" {
+		fmt.Fprintf(d.out, "     fix: %s\n", fix)
+	}
+}
+
+// checkConfig checks the configuration files and the encryption flags
+// the config file was loaded before the checks, a failure to load it is a failure of the first check
+func (d *doctor) checkConfig(configErr error) {
+	if filename := configFilename(); configErr != nil {
+		d.fail("config", configErr, "fix the config file or the environment variables, see Setup and config file "+
+			"in the README")
+	} else if filename != "" {
+		d.pass("config", "%s is valid", filename)
+	}
+	if err := validateInputFormat(); err != nil {
+		d.fail("input format", err, "use --input-format auto, msgpack, json or txids")
+	}
+	if policyFile != "" {
+		if _, err := loadPolicy(policyFile); err != nil {
+			d.fail("policy", err, "fix the policy file, see Classification policy in the README")
+		} else {
+			d.pass("policy", "%s is valid", policyFile)
+		}
+	}
+	if overridesFile != "" {
+		if _, err := loadOverrides(overridesFile); err != nil {
+			d.fail("overrides", err, "fix the overrides file, see Per-input overrides in the README")
+		} else {
+			d.pass("overrides", "%s is valid", overridesFile)
+		}
+	}
+	if addressBookFile != "" {
+		if book, err := readAddressBook(addressBookFile); err != nil {
+			d.fail("address book", err, "the address book must be a CSV file of address,name lines")
+		} else {
+			d.pass("address book", "%d addresses in %s", len(book), addressBookFile)
+		}
+	}
+	if err := validateCompression(); err != nil {
+		d.fail("compression", err, "pass --compress gzip or --compress zstd")
+	} else if compression != "" {
+		d.pass("compression", "outputs compressed with %s", compression)
+	}
+	if err := validateEncryptionFlags(); err != nil {
+		d.fail("encryption", err, "pass age recipients as age1... public keys, and either age or gpg recipients")
+		return
+	}
+	if len(gpgRecipients) != 0 {
+		if _, err := exec.LookPath("gpg"); err != nil {
+			d.fail("encryption", err, "install gnupg, or encrypt to --age-recipient instead")
+			return
+		}
+		d.pass("encryption", "gpg is installed")
+	}
+}
+
+// doctorIndexer is an indexer of the configuration
+type doctorIndexer struct {
+	name    string
+	flag    string
+	address string
+	token   string
+}
+
+// configuredIndexers returns the indexers of the configuration: --idx-addr, --hedge-idx-addr and the overrides
+func configuredIndexers() []doctorIndexer {
+	indexers := []doctorIndexer{{
+		name:    "indexer",
+		flag:    "--idx-addr and --idx-tkn (or AF_IDX_ADDRESS and AF_IDX_TOKEN)",
+		address: indexerAddress,
+		token:   indexerToken,
+	}}
+	if hedgeIndexerAddress != "" {
+		indexers = append(indexers, doctorIndexer{
+			name:    "hedge indexer",
+			flag:    "--hedge-idx-addr and --hedge-idx-tkn",
+			address: hedgeIndexerAddress,
+			token:   hedgeIndexerToken,
+		})
+	}
+	if overrides, err := loadOverrides(overridesFile); err == nil && overrides != nil {
+		for _, o := range overrides.Overrides {
+			if o.IndexerAddress != "" {
+				indexers = append(indexers, doctorIndexer{
+					name:    fmt.Sprintf("indexer of %q", o.Match),
+					flag:    fmt.Sprintf("idx-addr and idx-tkn of %q in %s", o.Match, overridesFile),
+					address: o.IndexerAddress,
+					token:   o.IndexerToken,
+				})
+			}
+		}
+	}
+	return indexers
+}
+
+// checkIndexers checks every configured indexer, and that they are all of the same network
+// it returns the genesis hash of the network, nil if unknown
+func (d *doctor) checkIndexers() []byte {
+	if indexerAddress == "" {
+		d.fail("indexer", fmt.Errorf("no indexer address"), "set --idx-addr or AF_IDX_ADDRESS")
+		return nil
+	}
+	notFound, err := initIndexerNotFoundRules()
+	if err != nil {
+		d.fail("indexer not found rules", err, "fix the --idx-not-found-* flags")
+		return nil
+	}
+	var genesis []byte
+	var genesisOf string
+	for _, idx := range configuredIndexers() {
+		genesisID, genesisHash, ok := d.checkIndexer(idx, notFound)
+		if !ok || genesisHash == nil {
+			continue
+		}
+		if genesis == nil {
+			genesis, genesisOf = genesisHash, idx.name
+			if preset, _ := selectedNetwork(); preset != nil && preset.genesisID != genesisID {
+				d.fail(idx.name+" network", fmt.Errorf("genesis %s is not the one of --network %s", genesisID,
+					preset.name), fmt.Sprintf("point %s to an indexer of %s", idx.flag, preset.name))
+				continue
+			}
+			d.pass(idx.name+" network", "%s", genesisID)
+			continue
+		}
+		if !bytes.Equal(genesis, genesisHash) {
+			d.fail(idx.name+" network", fmt.Errorf("genesis %s differs from the genesis of the %s",
+				genesisID, genesisOf), fmt.Sprintf("point %s to an indexer of the same network", idx.flag))
+			continue
+		}
+		d.pass(idx.name+" network", "%s, the same as the %s", genesisID, genesisOf)
+	}
+	return genesis
+}
+
+// checkIndexer checks an indexer is reachable, healthy, accepts the token and answers lookups of missing
+// transactions as not found, returning the genesis of its network
+func (d *doctor) checkIndexer(idx doctorIndexer, notFound *notFoundRules) (genesisID string, genesisHash []byte,
+	ok bool) {
+	client, err := indexer.MakeClientWithHeaders(idx.address, idx.token, clientHeaders(indexerTransport))
+	if err != nil {
+		d.fail(idx.name, err, fmt.Sprintf("fix the address in %s, e.g. https://mainnet-idx.algonode.cloud", idx.flag))
+		return "", nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	health, err := client.HealthCheck().Do(ctx)
+	if err != nil {
+		d.fail(idx.name+" "+idx.address, err, indexerFix(err, idx.flag))
+		return "", nil, false
+	}
+	if !health.DbAvailable || health.IsMigrating {
+		d.fail(idx.name+" "+idx.address, fmt.Errorf("unhealthy, database available %t, migrating %t: %s",
+			health.DbAvailable, health.IsMigrating, health.Message),
+			"wait for the indexer to finish migrating, or use another indexer")
+		return "", nil, false
+	}
+	d.pass(idx.name+" "+idx.address, "version %s at round %d", health.Version, health.Round)
+
+	// a random txid is never found
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+	resp, err := client.LookupTransaction(txchecker.TxIDFromRawTxn(random)).Do(ctx)
+	if err == nil && resp.Transaction.Id == "" {
+		err = io.EOF
+	}
+	switch {
+	case err == nil:
+		d.fail(idx.name+" not found detection", fmt.Errorf("a random txid was found"),
+			"this is not an indexer, check the address in "+idx.flag)
+	case notFound.IsNotFound(err):
+		d.pass(idx.name+" not found detection", "missing transactions are recognized")
+	case err == io.EOF:
+		d.fail(idx.name+" not found detection", fmt.Errorf("a missing transaction got an empty response"),
+			"add --idx-not-found-empty if the indexer answers missing transactions with empty responses")
+	default:
+		fix := indexerFix(err, idx.flag)
+		if status, _, ok := txchecker.HTTPStatus(err); ok && status != 401 && status != 403 && status < 500 {
+			fix = fmt.Sprintf("add --idx-not-found-status %d, or --idx-not-found-body matching the response, "+
+				"if the indexer or a proxy in front of it answers missing transactions this way", status)
+		}
+		d.fail(idx.name+" not found detection", err, fix)
+	}
+
+	block, err := client.LookupBlock(0).Do(ctx)
+	if err != nil {
+		d.fail(idx.name+" genesis", err, indexerFix(err, idx.flag))
+		return "", nil, false
+	}
+	return block.GenesisId, block.GenesisHash, true
+}
+
+// checkAlgod checks the --algod-addr node is reachable, accepts the token and serves the network of the indexers
+func (d *doctor) checkAlgod(genesis []byte) {
+	if algodAddress == "" {
+		return
+	}
+	client, err := algod.MakeClientWithHeaders(algodAddress, algodToken, clientHeaders(algodTransport))
+	if err != nil {
+		d.fail("algod", err, "fix the address in --algod-addr, e.g. http://localhost:8080")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	versions, err := client.Versions().Do(ctx)
+	if err != nil {
+		d.fail("algod "+algodAddress, err, backendFix(err, "node", "algod", "--algod-addr"))
+		return
+	}
+	d.pass("algod "+algodAddress, "version %d.%d.%d", versions.Build.Major, versions.Build.Minor,
+		versions.Build.BuildNumber)
+	if genesis != nil && !bytes.Equal(genesis, versions.GenesisHash) {
+		d.fail("algod network", fmt.Errorf("genesis %s differs from the genesis of the indexer", versions.GenesisID),
+			"point --algod-addr to a node of the network of the indexer")
+		return
+	}
+	d.pass("algod network", "%s", versions.GenesisID)
+}
+
+// indexerFix suggests how to fix a failed indexer request
+func indexerFix(err error, flag string) string {
+	return backendFix(err, "indexer", "indexer", flag)
+}
+
+// backendFix suggests how to fix a failed request to a backend serving api, whose address is set by flag
+func backendFix(err error, backend string, api string, flag string) string {
+	status, _, ok := txchecker.HTTPStatus(err)
+	if !ok {
+		if err == context.DeadlineExceeded || strings.Contains(err.Error(), "deadline exceeded") {
+			return fmt.Sprintf("the %s did not answer within --timeout, check the address in %s and the network",
+				backend, flag)
+		}
+		return fmt.Sprintf("check the address in %s is reachable from this host, including its scheme and port", flag)
+	}
+	switch {
+	case status == 401 || status == 403:
+		return fmt.Sprintf("the %s rejected the API token, check the token in %s", backend, flag)
+	case status == 404:
+		return fmt.Sprintf("the address in %s does not serve the %s v2 API, check its path", flag, api)
+	case status == 429:
+		return fmt.Sprintf("the %s is rate limiting this host, lower --concurrency, set --rate-limit or use "+
+			"another %s", backend, backend)
+	case status >= 500:
+		return fmt.Sprintf("the %s is failing, retry later or use another %s", backend, backend)
+	}
+	return ""
+}
+
+// checkInputs checks the inputs are readable, their directories are writable for outputs, and their transactions
+// are of the network of the indexers
+func (d *doctor) checkInputs(filenames []string, genesis []byte) {
+	for _, filename := range filenames {
+		filename = filepath.Clean(filename)
+		batch, err := readBatch(filename, nil)
+		if err != nil {
+			d.fail("input "+filename, err, "make sure the file exists, is readable and has the --input-format")
+			continue
+		}
+		txs := allRecords(batch.groups, batch.individual)
+		d.pass("input "+filename, "%d transactions", len(txs))
+		d.checkWritable("outputs of "+filename, filepath.Dir(filename))
+		if genesis == nil {
+			continue
+		}
+		networks := map[string]int{}
+		for _, rec := range txs {
+			hash := rec.stx.Txn.GenesisHash
+			if hash != (types.Digest{}) && !bytes.Equal(hash[:], genesis) {
+				networks[base64.StdEncoding.EncodeToString(hash[:])]++
+			}
+		}
+		if len(networks) == 0 {
+			continue
+		}
+		var hashes []string
+		for hash, count := range networks {
+			hashes = append(hashes, fmt.Sprintf("%d of genesis %s", count, hash))
+		}
+		sort.Strings(hashes)
+		d.fail("input "+filename+" network", fmt.Errorf("transactions of another network: %s",
+			strings.Join(hashes, ", ")), "check the file against an indexer of the network it was signed for")
+	}
+}
+
+// checkCheckpoint checks the checkpoint can be read and written
+func (d *doctor) checkCheckpoint() {
+	if checkpointFile == "" {
+		return
+	}
+	checkpoint, err := loadCheckpoint(checkpointFile)
+	if err != nil {
+		d.fail("checkpoint", err, "move the corrupt checkpoint aside; its lookups will be repeated")
+		return
+	}
+	d.pass("checkpoint", "%d lookups and %d inputs recorded in %s", len(checkpoint.Sent), len(checkpoint.Inputs),
+		checkpointFile)
+	d.checkWritable("checkpoint", filepath.Dir(checkpointFile))
+}
+
+// checkCache checks the --cache-path cache can be read and written to
+func (d *doctor) checkCache() {
+	if cachePath == "" {
+		return
+	}
+	confirmed, err := cacheStats(cachePath)
+	if err != nil {
+		d.fail("cache", err, "move the unreadable cache aside; its transactions will be looked up again")
+		return
+	}
+	d.pass("cache", "%d confirmed transactions cached in %s", confirmed, cachePath)
+	d.checkWritable("cache", filepath.Dir(cachePath))
+}
+
+// checkState checks the --state-backend is reachable
+func (d *doctor) checkState() {
+	state, err := openSharedState()
+	if err != nil {
+		d.fail("state backend", err, "pass the redis:// URL of a reachable Redis server, with its password if it has one")
+		return
+	}
+	if state != nil {
+		d.pass("state backend", "%s is reachable", redactURL(stateBackend, "URL"))
+	}
+}
+
+// checkWritable checks files can be created in dir
+func (d *doctor) checkWritable(check string, dir string) {
+	file, err := ioutil.TempFile(dir, ".checktxstatus-doctor-")
+	if err != nil {
+		d.fail(check, err, fmt.Sprintf("make %s writable by the user running the tool", dir))
+		return
+	}
+	// the file is removed regardless of errors on close
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+	d.pass(check, "%s is writable", dir)
+}
+
+// checkNFD checks the NFDomains API is reachable when --nfd is set
+func (d *doctor) checkNFD() {
+	if !resolveNFD {
+		return
+	}
+	api := strings.TrimSuffix(nfdAPIAddress, "/")
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/nfd/lookup?address=%s&view=tiny", api, types.ZeroAddress.String()), nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+				err = fmt.Errorf("unexpected status %s", resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		d.fail("nfd "+api, err, "check --nfd-api is reachable from this host, or run without --nfd")
+		return
+	}
+	d.pass("nfd "+api, "reachable")
+}
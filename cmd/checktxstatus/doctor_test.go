@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndexerFix(t *testing.T) {
+	for err, expected := range map[string]string{
+		"HTTP 401: unauthorized":    "rejected the API token",
+		"HTTP 404: not found":       "does not serve the indexer v2 API",
+		"HTTP 429: slow down":       "rate limiting",
+		"HTTP 503: unavailable":     "the indexer is failing",
+		"context deadline exceeded": "did not answer within --timeout",
+		"connection refused":        "is reachable from this host",
+	} {
+		if fix := indexerFix(fmt.Errorf(err), "--idx-addr"); !strings.Contains(fix, expected) {
+			t.Errorf("expected the fix of %q to contain %q, got %q", err, expected, fix)
+		}
+	}
+}
+
+func TestDoctorChecksIndexer(t *testing.T) {
+	for _, test := range []struct {
+		notFoundStatus int
+		failures       int
+		expected       string
+	}{
+		{http.StatusNotFound, 0, "missing transactions are recognized"},
+		{http.StatusBadRequest, 1, "add --idx-not-found-status 400"},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/health":
+				fmt.Fprint(w, `{"db-available":true,"version":"2.15.0","round":1000}`)
+			case strings.HasPrefix(r.URL.Path, "/v2/transactions/"):
+				w.WriteHeader(test.notFoundStatus)
+				fmt.Fprint(w, `{"message":"no transaction found"}`)
+			case r.URL.Path == "/v2/blocks/0":
+				fmt.Fprint(w, `{"genesis-id":"testnet-v1.0","genesis-hash":"SGO1GKSzyE7IEPItTxCByw9x8FmnrCDexi9/cOUJOiI="}`)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		d := &doctor{out: &out}
+		genesisID, genesisHash, ok := d.checkIndexer(doctorIndexer{name: "indexer", flag: "--idx-addr",
+			address: server.URL}, notFound)
+		server.Close()
+		if !ok || genesisID != "testnet-v1.0" || len(genesisHash) != 32 {
+			t.Errorf("expected the genesis of the indexer, got %s %v %v", genesisID, genesisHash, ok)
+		}
+		if d.failures != test.failures || !strings.Contains(out.String(), test.expected) {
+			t.Errorf("expected %d failures and %q, got %d:\n%s", test.failures, test.expected, d.failures, out.String())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// paymentKey identifies payments and asset transfers that move the same amount between the same accounts
+type paymentKey struct {
+	txType   types.TxType
+	sender   types.Address
+	receiver types.Address
+	assetID  uint64
+	amount   uint64
+}
+
+// findDuplicatePayments returns clusters of distinct payments and asset transfers sharing sender, receiver and
+// amount whose validity windows overlap, which are likely accidental double submissions
+func findDuplicatePayments(records []txRecord) [][]txRecord {
+	byKey := map[paymentKey][]txRecord{}
+	for _, rec := range records {
+		txn := rec.stx.Txn
+		key := paymentKey{txType: txn.Type, sender: txn.Sender, receiver: txReceiver(txn)}
+		switch txn.Type {
+		case types.PaymentTx:
+			key.amount = uint64(txn.Amount)
+		case types.AssetTransferTx:
+			key.assetID = uint64(txn.XferAsset)
+			key.amount = txn.AssetAmount
+		default:
+			continue
+		}
+		byKey[key] = append(byKey[key], rec)
+	}
+
+	var clusters [][]txRecord
+	for _, txs := range byKey {
+		clusters = append(clusters, overlappingClusters(txs)...)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i][0].index < clusters[j][0].index
+	})
+	return clusters
+}
+
+// overlappingClusters returns the clusters of at least two distinct transactions of txs whose validity windows
+// overlap, sorting txs by first valid round
+func overlappingClusters(txs []txRecord) [][]txRecord {
+	if len(txs) < 2 {
+		return nil
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].stx.Txn.FirstValid < txs[j].stx.Txn.FirstValid
+	})
+	var clusters [][]txRecord
+	// sweep over the transactions by first valid round, merging the ones whose windows overlap
+	cluster := []txRecord{txs[0]}
+	clusterLastValid := txs[0].stx.Txn.LastValid
+	flush := func() {
+		if len(distinctTxIDs(cluster)) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	for _, tx := range txs[1:] {
+		if tx.stx.Txn.FirstValid <= clusterLastValid {
+			cluster = append(cluster, tx)
+			if tx.stx.Txn.LastValid > clusterLastValid {
+				clusterLastValid = tx.stx.Txn.LastValid
+			}
+			continue
+		}
+		flush()
+		cluster = []txRecord{tx}
+		clusterLastValid = tx.stx.Txn.LastValid
+	}
+	flush()
+	return clusters
+}
+
+// distinctTxIDs returns the txids of the records without repetitions, in order
+func distinctTxIDs(records []txRecord) []string {
+	seen := map[string]bool{}
+	var txIDs []string
+	for _, rec := range records {
+		if !seen[rec.txID] {
+			seen[rec.txID] = true
+			txIDs = append(txIDs, rec.txID)
+		}
+	}
+	return txIDs
+}
+
+// warnDuplicatePayments logs a warning for every cluster of likely duplicate payments in the file
+func warnDuplicatePayments(filename string, records []txRecord) {
+	logger := log.WithField("file", filename)
+	for _, cluster := range findDuplicatePayments(records) {
+		txn := cluster[0].stx.Txn
+		logEntryMessage(logger, log.WarnLevel, msgPossibleDuplicate, txn.Type, txn.Sender, txReceiver(txn),
+			strings.Join(distinctTxIDs(cluster), ", "))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestFindDuplicatePayments(t *testing.T) {
+	alice, bob := types.Address{1}, types.Address{2}
+	payment := func(index int, txID string, amount, firstValid, lastValid uint64) txRecord {
+		rec := txRecord{index: index, txID: txID}
+		rec.stx.Txn.Type = types.PaymentTx
+		rec.stx.Txn.Sender = alice
+		rec.stx.Txn.Receiver = bob
+		rec.stx.Txn.Amount = types.MicroAlgos(amount)
+		rec.stx.Txn.FirstValid = types.Round(firstValid)
+		rec.stx.Txn.LastValid = types.Round(lastValid)
+		return rec
+	}
+	records := []txRecord{
+		payment(0, testTxID(1), 100, 1, 1000),
+		// overlaps the first one
+		payment(1, testTxID(2), 100, 500, 1500),
+		// a repetition of the same transaction is not a duplicate payment
+		payment(2, testTxID(3), 200, 1, 1000),
+		payment(3, testTxID(3), 200, 1, 1000),
+		// does not overlap any payment of the same amount
+		payment(4, testTxID(4), 100, 2000, 3000),
+	}
+	clusters := findDuplicatePayments(records)
+	if len(clusters) != 1 {
+		t.Fatalf("expected a single cluster of duplicates, got %v", clusters)
+	}
+	if txIDs := distinctTxIDs(clusters[0]); len(txIDs) != 2 || txIDs[0] != testTxID(1) || txIDs[1] != testTxID(2) {
+		t.Errorf("expected the overlapping payments, got %v", txIDs)
+	}
+}
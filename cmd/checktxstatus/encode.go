@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	encodeOut   string
+	encodeForce bool
+)
+
+// encodeSharedFlags are the flags of the root command that affect encoding transactions
+var encodeSharedFlags = []string{"log-level", "permissive", "shred"}
+
+var encodeCmd = &cobra.Command{
+	Use:   "encode <file1.json> <file2.json> ...",
+	Short: "Encode the JSON signed transactions of files, as printed by goal clerk inspect, to msgpack .tx files",
+	Example: `  # fix the note of a transaction by hand, then check the edited file
+  goal clerk inspect batch.tx > batch.json
+  vi batch.json
+  checktxstatus encode batch.json
+  checktxstatus batch.tx
+
+  # encode to stdout
+  checktxstatus encode --out - batch.json > edited.tx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		if len(args) == 0 {
+			logError(newUserError(msgNoInputs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+		if encodeOut != "" && len(args) > 1 {
+			logMessage(log.ErrorLevel, msgEncodeSingleInput)
+			exitCode = 1
+			return
+		}
+
+		for _, filename := range args {
+			err := encodeFile(filepath.Clean(filename), encodeOut)
+			if err != nil {
+				logError(err)
+				exitCode = 1
+			}
+		}
+	},
+}
+
+func init() {
+	encodeCmd.Flags().StringVar(&encodeOut, "out", "",
+		"file the msgpack transactions are written to, - for stdout (default: the input file with its .json "+
+			"extension replaced by .tx)")
+	encodeCmd.Flags().BoolVar(&encodeForce, "force", false, "overwrite the output file if it exists")
+}
+
+// initEncodeCmd adds the encode subcommand, sharing the flags of the root command that affect encoding transactions
+// it must be called after the flags of the root command were registered
+func initEncodeCmd() {
+	for _, name := range encodeSharedFlags {
+		encodeCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(encodeCmd)
+}
+
+// inspectHeaderRegexp matches the lines of goal clerk inspect naming the transactions, e.g. batch.tx[0]
+var inspectHeaderRegexp = regexp.MustCompile(`(?m)^\S+\[\d+\]\s*$`)
+
+// encodedName returns the default output of encoding filename: filename with its .json extension replaced by .tx
+func encodedName(filename string) string {
+	return strings.TrimSuffix(filename, ".json") + ".tx"
+}
+
+// encodeFile encodes the JSON transactions of filename to msgpack in out, or in the default output if empty
+// the transactions not validly signed are only warned about, they may be signed again after being encoded
+func encodeFile(filename string, out string) error {
+	if out == "" {
+		if filename == stdioName {
+			return fmt.Errorf("encoding stdin needs --out")
+		}
+		out = encodedName(filename)
+	}
+	if out != stdioName && !encodeForce {
+		if _, err := os.Stat(out); err == nil {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", out)
+		}
+	}
+	input, err := openInput(filename)
+	if err != nil {
+		return fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	content, err := ioutil.ReadAll(input)
+	// no need to check error on close when reading file
+	_ = input.Close()
+	if err != nil {
+		return fmt.Errorf("error while reading %s: %v", filename, err)
+	}
+	// goal clerk inspect prints the name and position of every transaction before it
+	stripped := inspectHeaderRegexp.ReplaceAll(content, nil)
+	zeroize(content)
+	defer zeroize(stripped)
+	logger := log.WithField("file", filename)
+	var encoded bytes.Buffer
+	defer func() {
+		zeroize(encoded.Bytes())
+	}()
+	count, invalid := 0, 0
+	add := func(raw []byte) error {
+		tx, err := txchecker.DecodeTransaction(raw, permissive)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %v", count, err)
+		}
+		rec := txRecord{stx: tx.SignedTxn, txID: tx.TxID, file: filename, fileIndex: count, index: count, raw: raw}
+		if err := verifyTxRecord(rec); err != nil {
+			invalid++
+			logEntryMessage(logger, log.WarnLevel, msgEncodeInvalidSignature, tx.TxID, err)
+		}
+		encoded.Write(raw)
+		zeroize(raw)
+		count++
+		return nil
+	}
+	err = txchecker.ReadRecords(bufio.NewReader(bytes.NewReader(stripped)), formatJSON, permissive, add, nil)
+	if err != nil {
+		return fmt.Errorf("error while decoding %s: %v", filename, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s has no transactions", filename)
+	}
+	if out == stdioName {
+		_, err = os.Stdout.Write(encoded.Bytes())
+	} else {
+		err = writeFileAtomic(out, encoded.Bytes())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", out, err)
+	}
+	logEntryMessage(logger, log.InfoLevel, msgEncoded, count, filename, out, invalid)
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeFileStripsInspectHeaders(t *testing.T) {
+	dir := testDir(t)
+	sender := "FYD3GWODP4ZI4EZA5HTXWF7MPYBCR3HXC4YUYKAXAZWGU2X2KKVWRTFAM4"
+	input := filepath.Join(dir, "batch.json")
+	content := "batch.tx[0]\n" +
+		`{"txn": {"type": "pay", "snd": "` + sender + `", "fee": 1000, "fv": 1, "lv": 2, "amt": 5}}` + "\n"
+	if err := ioutil.WriteFile(input, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := encodeFile(input, ""); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadFile(filepath.Join(dir, "batch.tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stx types.SignedTxn
+	if err := msgpack.Decode(encoded, &stx); err != nil {
+		t.Fatal(err)
+	}
+	if stx.Txn.Sender.String() != sender || stx.Txn.Amount != 5 {
+		t.Errorf("expected the payment of %s to be encoded, got %+v", sender, stx.Txn)
+	}
+	if err := encodeFile(input, ""); err == nil {
+		t.Error("expected an existing output not to be overwritten without --force")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+var (
+	ageRecipients []string
+	gpgRecipients []string
+)
+
+func init() {
+	rootCmd.Flags().StringSliceVar(&ageRecipients, "age-recipient", nil,
+		"encrypt output files to this age recipient (age1...), can be repeated")
+	rootCmd.Flags().StringSliceVar(&gpgRecipients, "gpg-recipient", nil,
+		"encrypt output files to this GPG key ID or email using the gpg binary, can be repeated")
+}
+
+// validateEncryptionFlags makes sure at most one encryption method was requested and that age recipients are valid
+func validateEncryptionFlags() error {
+	if len(ageRecipients) != 0 && len(gpgRecipients) != 0 {
+		return fmt.Errorf("--age-recipient and --gpg-recipient are mutually exclusive")
+	}
+	for _, recipient := range ageRecipients {
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			return fmt.Errorf("invalid age recipient %s: %v", recipient, err)
+		}
+	}
+	return nil
+}
+
+// outputName returns the name of the output file written for filename, which has a suffix when encrypting outputs
+func outputName(filename string) string {
+	switch {
+	case len(ageRecipients) != 0:
+		return filename + ".age"
+	case len(gpgRecipients) != 0:
+		return filename + ".gpg"
+	}
+	return filename
+}
+
+// outputStream is an output file being written, encrypted to the configured recipients
+type outputStream struct {
+	filename string
+	// file is nil for stdout, which is flushed but never synced nor closed
+	file *os.File
+	// digest hashes what is written to stdout, nil for files
+	digest *streamDigest
+	// buf buffers the writes to file, nil when gpg writes to file
+	buf *bufio.Writer
+	// w is where the plaintext is written
+	w io.Writer
+	// closeEncryption completes the encryption, nil when not encrypting
+	closeEncryption func() error
+	// closeCompression completes the compression, nil when not compressing
+	closeCompression func() error
+}
+
+// openOutputStream creates filename, truncating it if it exists
+// filename is expected to be the result of outputName
+// stdout is opened for -
+func openOutputStream(filename string) (*outputStream, error) {
+	if filename == stdioName {
+		return openStdoutStream(), nil
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", filename, err)
+	}
+	s := &outputStream{filename: filename, file: file}
+	switch {
+	case len(ageRecipients) != 0:
+		s.buf = bufio.NewWriter(file)
+		w, err := encryptAge(s.buf)
+		if err != nil {
+			// nothing was written yet, nothing to lose on close
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to encrypt %s: %v", filename, err)
+		}
+		s.w, s.closeEncryption = w, w.Close
+	case len(gpgRecipients) != 0:
+		w, wait, err := encryptGPG(file)
+		if err != nil {
+			// nothing was written yet, nothing to lose on close
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to encrypt %s: %v", filename, err)
+		}
+		s.w = w
+		s.closeEncryption = func() error {
+			if err := w.Close(); err != nil {
+				return err
+			}
+			return wait()
+		}
+	default:
+		s.buf = bufio.NewWriter(file)
+		s.w = s.buf
+	}
+	return s, nil
+}
+
+// Write writes plaintext to the stream
+func (s *outputStream) Write(p []byte) (int, error) {
+	if faults != nil {
+		if n := faults.truncateWrite(len(p)); n < len(p) {
+			n, _ = s.w.Write(p[:n])
+			return n, fmt.Errorf("failed to write to %s: injected partial write", s.filename)
+		}
+	}
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to %s: %v", s.filename, err)
+	}
+	return n, nil
+}
+
+// sync flushes what was written so far to disk
+// data still buffered by the compression or the encryption is flushed only when closing
+func (s *outputStream) sync() error {
+	if s.buf != nil {
+		if err := s.buf.Flush(); err != nil {
+			return fmt.Errorf("failed to write to %s: %v", s.filename, err)
+		}
+	}
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", s.filename, err)
+	}
+	return nil
+}
+
+// close completes the compression and the encryption and closes the file
+func (s *outputStream) close() error {
+	if s.closeCompression != nil {
+		if err := s.closeCompression(); err != nil {
+			// the error of the compression is more informative than the ones of the encryption and of closing the file
+			if s.closeEncryption != nil {
+				_ = s.closeEncryption()
+			}
+			_ = s.file.Close()
+			return fmt.Errorf("failed to compress %s: %v", s.filename, err)
+		}
+	}
+	if s.closeEncryption != nil {
+		if err := s.closeEncryption(); err != nil {
+			// the error of the encryption is more informative than the one of closing the file
+			_ = s.file.Close()
+			return fmt.Errorf("failed to encrypt %s: %v", s.filename, err)
+		}
+	}
+	if err := s.sync(); err != nil {
+		// the error of the sync is more informative than the one of closing the file
+		if s.file != nil {
+			_ = s.file.Close()
+		}
+		return err
+	}
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", s.filename, err)
+	}
+	return nil
+}
+
+// encryptAge returns a writer encrypting to all age recipients into dst, it must be closed to complete the encryption
+func encryptAge(dst io.Writer) (io.WriteCloser, error) {
+	var recipients []age.Recipient
+	for _, r := range ageRecipients {
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return age.Encrypt(dst, recipients...)
+}
+
+// encryptGPG starts the gpg binary encrypting to all GPG recipients into file, returning the writer piping the
+// plaintext to it and a function waiting for gpg to exit once the writer was closed
+// the plaintext is passed on stdin so it is never written to disk
+func encryptGPG(file *os.File) (io.WriteCloser, func() error, error) {
+	args := []string{"--batch", "--encrypt"}
+	for _, recipient := range gpgRecipients {
+		args = append(args, "--recipient", recipient)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdout = file
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("gpg failed: %v", err)
+	}
+	wait := func() error {
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("gpg failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil
+	}
+	return stdin, wait, nil
+}
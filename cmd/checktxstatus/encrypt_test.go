@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateEncryptionFlags(t *testing.T) {
+	defer func() { ageRecipients, gpgRecipients = nil, nil }()
+	ageRecipients = []string{"age1invalid"}
+	if err := validateEncryptionFlags(); err == nil {
+		t.Error("expected an invalid age recipient to fail")
+	}
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageRecipients = []string{identity.Recipient().String()}
+	if err := validateEncryptionFlags(); err != nil {
+		t.Errorf("expected a valid age recipient, got %v", err)
+	}
+	gpgRecipients = []string{"ops@example.com"}
+	if err := validateEncryptionFlags(); err == nil {
+		t.Error("expected age and GPG recipients to be mutually exclusive")
+	}
+}
+
+func TestOutputStreamEncryptsToAgeRecipients(t *testing.T) {
+	defer func() { ageRecipients = nil }()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageRecipients = []string{identity.Recipient().String()}
+	filename := outputName(filepath.Join(testDir(t), "batch.tx.unsent"))
+	if filepath.Ext(filename) != ".age" {
+		t.Errorf("expected an age suffix, got %s", filename)
+	}
+	plaintext := []byte("signed transactions")
+	stream, err := openOutputStream(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plaintext is written in two parts, as chunks are streamed
+	if _, err := stream.Write(plaintext[:6]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write(plaintext[6:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.close(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := age.Decrypt(file, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("expected the output to decrypt to the plaintext, got %q", decrypted)
+	}
+}
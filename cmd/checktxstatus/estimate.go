@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"math/bits"
+	"path/filepath"
+	"time"
+)
+
+var estimateLatency time.Duration
+
+// estimateSharedFlags are the flags of the root command that affect estimates
+var estimateSharedFlags = []string{
+	"log-level", "input-format", "permissive", "submitted-after", "submitted-before",
+	"max-requests", "max-request-cost", "request-cost", "concurrency",
+	"group-policy", "check-all-group-members",
+}
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate <file1.tx> <file2.tx> ...",
+	Short: "Estimate the indexer requests and the time needed to check transaction files, without any network calls",
+	Example: `  # estimate a run of 8 concurrent lookups
+  checktxstatus estimate --concurrency 8 batch1.tx batch2.tx
+
+  # estimate whether a run looking up every group member fits a budget of 10000 requests
+  checktxstatus estimate --group-policy all --max-requests 10000 batch.tx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		err := validateInputFormat()
+		if err != nil {
+			logError(err)
+			return
+		}
+		costs, err := parseRequestCosts(requestCostsStrs)
+		if err != nil {
+			logError(err)
+			return
+		}
+		if concurrency < 1 {
+			logMessage(log.ErrorLevel, msgInvalidConcurrency)
+			return
+		}
+		if err := validateGroupPolicy(); err != nil {
+			logError(err)
+			return
+		}
+		maxRequests, err := parseBudgetLimit(maxRequestsStr)
+		if err != nil {
+			logMessage(log.ErrorLevel, msgInvalidMaxRequests, err)
+			return
+		}
+		maxCost, err := parseBudgetLimit(maxRequestCostStr)
+		if err != nil {
+			logMessage(log.ErrorLevel, msgInvalidMaxRequestCost, err)
+			return
+		}
+		if len(args) == 0 {
+			logMessage(log.ErrorLevel, msgNoInputs)
+			cmd.HelpFunc()(cmd, args)
+			return
+		}
+
+		total := requestEstimate{requests: map[requestKind]int{}}
+		for _, filename := range args {
+			batch, err := readBatch(filepath.Clean(filename), nil)
+			if err != nil {
+				logError(err)
+				return
+			}
+			estimate := estimateBatch(batch)
+			fmt.Printf("%s: %d transactions in %d groups and %d individual transactions, %s\n",
+				filename, estimate.txs, estimate.groups, estimate.individual, estimate.describeRequests())
+			total.add(estimate)
+		}
+		if (submittedAfterStr != "" || submittedBeforeStr != "") && total.maxLastValid != 0 {
+			// a binary search over the rounds for every bound, after getting the latest round
+			bounds := 0
+			for _, bound := range []string{submittedAfterStr, submittedBeforeStr} {
+				if bound != "" {
+					bounds++
+				}
+			}
+			total.requests[requestHealth]++
+			total.requests[requestBlock] += bounds * bits.Len64(total.maxLastValid)
+		}
+
+		requests, cost := 0, 0.0
+		for kind, count := range total.requests {
+			requests += count
+			cost += float64(count) * costs[kind]
+		}
+		fmt.Printf("total: %d transactions, %s\n", total.txs, total.describeRequests())
+		duration := time.Duration(requests) * estimateLatency / time.Duration(concurrency)
+		fmt.Printf("estimated at most %d indexer requests costing %g, taking about %s at %s per request "+
+			"and %d concurrent lookups\n", requests, cost, duration.Round(time.Second), estimateLatency, concurrency)
+		if maxRequests.hard != 0 && float64(requests) > maxRequests.soft {
+			fmt.Printf("the requests may exceed the --max-requests budget of %g/%g\n", maxRequests.soft, maxRequests.hard)
+		}
+		if maxCost.hard != 0 && cost > maxCost.soft {
+			fmt.Printf("the cost may exceed the --max-request-cost budget of %g/%g\n", maxCost.soft, maxCost.hard)
+		}
+	},
+}
+
+func init() {
+	estimateCmd.Flags().DurationVar(&estimateLatency, "latency", 150*time.Millisecond,
+		"assumed average time of an indexer request")
+}
+
+// initEstimateCmd adds the estimate subcommand, sharing the flags of the root command that affect estimates
+// it must be called after the flags of the root command were registered
+func initEstimateCmd() {
+	for _, name := range estimateSharedFlags {
+		estimateCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(estimateCmd)
+}
+
+// requestEstimate is the number of transactions of a batch and the indexer requests needed to check them
+type requestEstimate struct {
+	txs        int
+	groups     int
+	individual int
+	// requests are upper bounds of the number of requests of every kind
+	requests map[requestKind]int
+	// maxLastValid is the highest last valid round, a rough lower bound of the latest round
+	maxLastValid uint64
+}
+
+// estimateBatch estimates the requests needed to check a batch: a lookup for every group (or every member of it
+// with --group-policy all or quorum) and individual transaction that can be submitted, an account lookup for
+// every keyreg transaction in case it is unsent, and a lookup of the latest round in case any transaction is unsent
+func estimateBatch(batch *txBatch) requestEstimate {
+	estimate := requestEstimate{
+		txs:        len(allRecords(batch.groups, batch.individual)),
+		groups:     len(batch.groups),
+		individual: len(batch.individual),
+		requests:   map[requestKind]int{},
+	}
+	groups, individual, _ := splitPlaceholders(batch.groups, batch.individual)
+	estimate.requests[requestTx] = len(individual)
+	for _, group := range groups {
+		if groupPolicy == groupPolicyFirst && !checkAllGroupMembers {
+			estimate.requests[requestTx]++
+		} else {
+			// all and quorum may need to look up every member, and --check-all-group-members looks them all up
+			estimate.requests[requestTx] += len(group)
+		}
+	}
+	if len(groups)+len(individual) != 0 {
+		estimate.requests[requestHealth]++
+	}
+	for _, rec := range append(flattenGroupsMap(groups), individual...) {
+		if rec.stx.Txn.Type == types.KeyRegistrationTx {
+			estimate.requests[requestAccount]++
+		}
+		if lv := uint64(rec.stx.Txn.LastValid); lv > estimate.maxLastValid {
+			estimate.maxLastValid = lv
+		}
+	}
+	return estimate
+}
+
+// add adds the transactions and requests of another estimate
+func (e *requestEstimate) add(other requestEstimate) {
+	e.txs += other.txs
+	e.groups += other.groups
+	e.individual += other.individual
+	for kind, count := range other.requests {
+		e.requests[kind] += count
+	}
+	if other.maxLastValid > e.maxLastValid {
+		e.maxLastValid = other.maxLastValid
+	}
+}
+
+// describeRequests returns a short description of the requests, e.g. "12 tx lookups, 1 account lookups"
+func (e *requestEstimate) describeRequests() string {
+	msg := fmt.Sprintf("%d tx lookups", e.requests[requestTx])
+	for _, kind := range []requestKind{requestAccount, requestBlock, requestHealth} {
+		if e.requests[kind] != 0 {
+			msg += fmt.Sprintf(", %d %s lookups", e.requests[kind], kind)
+		}
+	}
+	return msg
+}
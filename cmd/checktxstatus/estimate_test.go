@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestEstimateBatch(t *testing.T) {
+	tx := func(txType types.TxType, lastValid uint64, signed bool) txRecord {
+		var rec txRecord
+		rec.stx.Txn.Type = txType
+		rec.stx.Txn.LastValid = types.Round(lastValid)
+		if signed {
+			rec.stx.Sig = types.Signature{1}
+		}
+		return rec
+	}
+	batch := &txBatch{
+		groups: map[types.Digest][]txRecord{
+			{1}: {tx(types.PaymentTx, 100, true), tx(types.KeyRegistrationTx, 300, true)},
+			// unsigned groups are never looked up
+			{2}: {tx(types.PaymentTx, 900, true), tx(types.PaymentTx, 900, false)},
+		},
+		individual: []txRecord{tx(types.KeyRegistrationTx, 200, true), tx(types.PaymentTx, 100, false)},
+	}
+	estimate := estimateBatch(batch)
+	if estimate.txs != 6 || estimate.groups != 2 || estimate.individual != 2 {
+		t.Errorf("unexpected counts %+v", estimate)
+	}
+	if estimate.requests[requestTx] != 2 || estimate.requests[requestAccount] != 2 ||
+		estimate.requests[requestHealth] != 1 || estimate.maxLastValid != 300 {
+		t.Errorf("expected a lookup of every signed group and transaction, got %+v", estimate)
+	}
+	total := requestEstimate{requests: map[requestKind]int{}}
+	total.add(estimate)
+	total.add(estimate)
+	if description := total.describeRequests(); description != "4 tx lookups, 4 account lookups, 2 health lookups" {
+		t.Errorf("unexpected description %q", description)
+	}
+}
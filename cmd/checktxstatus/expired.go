@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// indexerRound returns the latest round of the indexer, looked up once per file and only if needed
+// a transaction the indexer did not find although it indexed the rounds past its last valid round can never be
+// confirmed, whatever the lag of the indexer behind the network
+type indexerRound struct {
+	indexerClient *indexer.Client
+	backoff       *backoffPolicy
+	// round is 0 until it was looked up
+	round uint64
+	// node is set when round is the last round committed by the node followed by --watch-algod
+	node bool
+}
+
+// latest returns the latest round of the indexer, looking it up on the first call, or --as-of-round or the
+// --current-round of --offline if set
+func (r *indexerRound) latest() (uint64, error) {
+	if asOfRound != 0 {
+		r.round = asOfRound
+	}
+	if offline {
+		r.round = currentRound
+	}
+	if r.round != 0 {
+		return r.round, nil
+	}
+	requestID, headers := nextRequest()
+	var health models.HealthCheckResponse
+	err := r.backoff.retry(fmt.Sprintf("checking the indexer health, request %s,", requestID), func() error {
+		if err := budget.spend(requestHealth); err != nil {
+			return err
+		}
+		var err error
+		health, err = r.indexerClient.HealthCheck().Do(context.Background(), headers...)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed getting the latest round from the indexer, request %s: %v", requestID, err)
+	}
+	r.round = health.Round
+	return r.round, nil
+}
+
+// splitExpired separates the unsent transactions whose last valid round the latest round reached from the ones that
+// can still be resubmitted. A group can only be confirmed as a whole, so all the members of a group with an expired
+// transaction are expired. Transactions read from txid lists have no last valid round and are never expired.
+func (r *indexerRound) splitExpired(unsentTxs []txRecord) (unsent []txRecord, expired []txRecord, err error) {
+	var lastValid uint64
+	for _, tx := range unsentTxs {
+		if !tx.txIDOnly && (lastValid == 0 || uint64(tx.stx.Txn.LastValid) < lastValid) {
+			lastValid = uint64(tx.stx.Txn.LastValid)
+		}
+	}
+	if lastValid == 0 {
+		return unsentTxs, nil, nil
+	}
+	round, err := r.latest()
+	if err != nil {
+		return nil, nil, err
+	}
+	// a transaction cannot be confirmed after its last valid round, which is expired once the latest round reached it
+	if lastValid > round {
+		return unsentTxs, nil, nil
+	}
+	isExpired := func(tx txRecord) bool {
+		return !tx.txIDOnly && uint64(tx.stx.Txn.LastValid) <= round
+	}
+	expiredGroups := map[types.Digest]bool{}
+	for _, tx := range unsentTxs {
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) && isExpired(tx) {
+			expiredGroups[gid] = true
+		}
+	}
+	for _, tx := range unsentTxs {
+		gid := tx.stx.Txn.Group
+		if (gid != types.Digest{} && expiredGroups[gid]) || isExpired(tx) {
+			expired = append(expired, tx)
+		} else {
+			unsent = append(unsent, tx)
+		}
+	}
+	return unsent, expired, nil
+}
+
+// expiryDetail explains why a transaction expired, empty for the members of an expired group that did not
+func (r *indexerRound) expiryDetail(rec txRecord) string {
+	if uint64(rec.stx.Txn.LastValid) > r.round {
+		return ""
+	}
+	if asOfRound != 0 {
+		return fmt.Sprintf("last valid round %d reached by --as-of-round %d", rec.stx.Txn.LastValid, r.round)
+	}
+	if offline {
+		return fmt.Sprintf("last valid round %d reached by --current-round %d", rec.stx.Txn.LastValid, r.round)
+	}
+	if r.node {
+		return fmt.Sprintf("last valid round %d reached, the node is at round %d", rec.stx.Txn.LastValid, r.round)
+	}
+	return fmt.Sprintf("last valid round %d reached, the indexer is at round %d", rec.stx.Txn.LastValid, r.round)
+}
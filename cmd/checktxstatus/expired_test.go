@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestRoundIndexer returns the latest round of a fake indexer at round, counting its health checks
+func newTestRoundIndexer(t *testing.T, round uint64, checks *int) *indexerRound {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*checks++
+		fmt.Fprintf(w, `{"db-available":true,"is-migrating":false,"message":"%d","round":%d}`, round, round)
+	}))
+	t.Cleanup(server.Close)
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &indexerRound{indexerClient: client, backoff: newTestBackoff()}
+}
+
+func TestSplitExpiredExpiresWholeGroups(t *testing.T) {
+	checks := 0
+	r := newTestRoundIndexer(t, 1000, &checks)
+	group := types.Digest{1}
+	tx := func(txID string, lastValid uint64, gid types.Digest) txRecord {
+		return txRecord{txID: txID, stx: types.SignedTxn{Txn: types.Transaction{
+			Header: types.Header{LastValid: types.Round(lastValid), Group: gid}}}}
+	}
+	txs := []txRecord{
+		tx("live", 2000, types.Digest{}),
+		tx("dead", 900, types.Digest{}),
+		tx("dead-member", 900, group),
+		tx("live-member", 2000, group),
+		{txID: "listed", txIDOnly: true},
+	}
+	unsent, expired, err := r.splitExpired(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsent) != 2 || unsent[0].txID != "live" || unsent[1].txID != "listed" {
+		t.Errorf("expected the live transaction and the txid to stay unsent, got %v", unsent)
+	}
+	if len(expired) != 3 || expired[2].txID != "live-member" {
+		t.Errorf("expected the expired transactions and their group, got %v", expired)
+	}
+	if detail := r.expiryDetail(expired[2]); detail != "" {
+		t.Errorf("expected no detail of a member that did not expire, got %q", detail)
+	}
+	if _, _, err := r.splitExpired(txs[:1]); err != nil || checks != 1 {
+		t.Errorf("expected the round to be looked up once, got %d lookups, %v", checks, err)
+	}
+}
+
+func TestSplitExpiredSkipsTheLookupOfTxIDs(t *testing.T) {
+	checks := 0
+	r := newTestRoundIndexer(t, 1000, &checks)
+	unsent, expired, err := r.splitExpired([]txRecord{{txID: "listed", txIDOnly: true}})
+	if err != nil || len(unsent) != 1 || len(expired) != 0 || checks != 0 {
+		t.Errorf("expected txids to stay unsent without a lookup, got %v, %v, %d lookups, %v", unsent, expired, checks,
+			err)
+	}
+}
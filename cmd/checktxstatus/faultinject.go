@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var faultInjectSpec string
+
+func init() {
+	rootCmd.Flags().StringVar(&faultInjectSpec, "fault-inject", "",
+		"inject faults to validate the retry, checkpoint and alerting settings before running production batches, "+
+			"as comma separated probabilities, e.g. lookup-error=0.1,delay=0.05:2s,partial-write=0.01,seed=7")
+	_ = rootCmd.Flags().MarkHidden("fault-inject")
+}
+
+// faultInjector draws the faults injected into the requests and the outputs of a run
+type faultInjector struct {
+	// lookupError is the probability of a lookup failing with a transient error without being sent
+	lookupError float64
+	// delayRate is the probability of a request being held back for delay before being sent
+	delayRate float64
+	delay     time.Duration
+	// partialWrite is the probability of a write to an output writing half of its data and failing
+	partialWrite float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+	// the numbers of faults injected so far
+	lookupErrors  int
+	delays        int
+	partialWrites int
+}
+
+// faults injects the faults of --fault-inject, nil when not injecting any
+var faults *faultInjector
+
+// initFaultInjection starts injecting the faults of --fault-inject
+// the faults are drawn with a fixed seed in deterministic mode, so runs over the same files fail the same way
+func initFaultInjection() error {
+	faults = nil
+	if faultInjectSpec == "" {
+		return nil
+	}
+	f, seed, err := parseFaultSpec(faultInjectSpec)
+	if err != nil {
+		return err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		if deterministic {
+			seed = 1
+		}
+	}
+	f.rng = rand.New(rand.NewSource(seed))
+	logMessage(log.WarnLevel, msgFaultsInjecting, faultInjectSpec)
+	faults = f
+	return nil
+}
+
+// parseFaultSpec parses --fault-inject, returning the seed it sets, 0 if none
+func parseFaultSpec(spec string) (*faultInjector, int64, error) {
+	f := &faultInjector{delay: time.Second}
+	var seed int64
+	for _, field := range strings.Split(spec, ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, 0, fmt.Errorf("invalid --fault-inject %q, expected name=value", field)
+		}
+		name, value := kv[0], kv[1]
+		var err error
+		switch name {
+		case "lookup-error":
+			f.lookupError, err = parseProbability(value)
+		case "delay":
+			parts := strings.SplitN(value, ":", 2)
+			f.delayRate, err = parseProbability(parts[0])
+			if err == nil && len(parts) == 2 {
+				f.delay, err = time.ParseDuration(parts[1])
+				if err == nil && f.delay <= 0 {
+					err = fmt.Errorf("the delay must be positive")
+				}
+			}
+		case "partial-write":
+			f.partialWrite, err = parseProbability(value)
+		case "seed":
+			seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return nil, 0, fmt.Errorf("unknown fault %q in --fault-inject", name)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid --fault-inject %s: %v", name, err)
+		}
+	}
+	return f, seed, nil
+}
+
+// parseProbability parses a probability between 0 and 1
+func parseProbability(value string) (float64, error) {
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("%v is not a probability between 0 and 1", p)
+	}
+	return p, nil
+}
+
+// draw returns whether a fault of probability p is injected, counting it in count
+func (f *faultInjector) draw(p float64, count *int) bool {
+	if p == 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rng.Float64() >= p {
+		return false
+	}
+	*count++
+	return true
+}
+
+// report logs the numbers of faults injected so far
+func (f *faultInjector) report() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	logMessage(log.WarnLevel, msgFaultsInjected, f.lookupErrors, f.delays, f.partialWrites)
+}
+
+// truncateWrite returns how much of a write of n bytes to write before failing it, n if it does not fail
+func (f *faultInjector) truncateWrite(n int) int {
+	if n == 0 || !f.draw(f.partialWrite, &f.partialWrites) {
+		return n
+	}
+	return n / 2
+}
+
+// faultInjectingTransport delays the requests of the SDK clients and fails their lookups as drawn by faults
+type faultInjectingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *faultInjectingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := faults
+	if f == nil {
+		return t.next.RoundTrip(req)
+	}
+	if f.draw(f.delayRate, &f.delays) {
+		log.Debugf("injected fault: delaying %s %s by %s", req.Method, req.URL.Path, f.delay)
+		timer := time.NewTimer(f.delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	// submissions are never failed, a failed submission cannot be told apart from a rejected one
+	if req.Method == http.MethodGet && f.draw(f.lookupError, &f.lookupErrors) {
+		log.Debugf("injected fault: failing %s %s", req.Method, req.URL.Path)
+		body := `{"message":"injected fault"}`
+		return &http.Response{
+			Status:        "503 Service Unavailable",
+			StatusCode:    http.StatusServiceUnavailable,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
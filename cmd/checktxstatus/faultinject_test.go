@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"testing"
+)
+
+func TestIsTxSentRetriesInjectedFaults(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	// the seed draws failures on the first attempts of some lookups, and never on the 4 attempts of one
+	faultInjectSpec = "lookup-error=0.5,seed=4"
+	defer func() {
+		faultInjectSpec, faults = "", nil
+	}()
+	if err := initFaultInjection(); err != nil {
+		t.Fatal(err)
+	}
+	lookup := newTestLookup(t, server)
+	for i := byte(1); i <= 5; i++ {
+		sent, err := lookup.isTxSent(testTxID(i))
+		if err != nil || sent != (i == 1) {
+			t.Errorf("expected the injected failures to be retried, got %v, %v", sent, err)
+		}
+	}
+	if faults.lookupErrors == 0 || len(server.Requests()) != 5 {
+		t.Errorf("expected the injected failures not to reach the server, got %d failures and requests %v",
+			faults.lookupErrors, server.Requests())
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var checkFees bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&checkFees, "check-fees", false,
+		"compare the fees of the unsent transactions to the suggested parameters of the --algod-addr node, warning "+
+			"about the ones it would reject as too low along with the bump they need")
+}
+
+// feeCheck compares the fees of unsent transactions to the current minimum and congestion fees of the network
+type feeCheck struct {
+	client  *algod.Client
+	backoff *backoffPolicy
+	// params are nil until they were looked up, once per file
+	params *types.SuggestedParams
+}
+
+// initFeeCheck returns a fee check using the client of the pending pool if --check-fees is set, nil otherwise
+func initFeeCheck(pending *pendingPool, backoff *backoffPolicy) (*feeCheck, error) {
+	if !checkFees {
+		return nil, nil
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("--check-fees needs the algod node to get the suggested fees from, please supply " +
+			"--algod-addr")
+	}
+	return &feeCheck{client: pending.client, backoff: backoff}, nil
+}
+
+// forFile returns a fee check looking up the suggested parameters again, for the next file
+func (f *feeCheck) forFile() *feeCheck {
+	if f == nil {
+		return nil
+	}
+	return &feeCheck{client: f.client, backoff: f.backoff}
+}
+
+// suggestedParams returns the suggested parameters of the node, looking them up on the first call
+func (f *feeCheck) suggestedParams() (types.SuggestedParams, error) {
+	if f.params != nil {
+		return *f.params, nil
+	}
+	requestID, headers := nextRequest()
+	var params types.SuggestedParams
+	err := f.backoff.retry(fmt.Sprintf("getting the suggested parameters, request %s,", requestID), func() error {
+		var err error
+		params, err = f.client.SuggestedParams().Do(context.Background(), headers...)
+		return err
+	})
+	if err != nil {
+		return types.SuggestedParams{}, fmt.Errorf("failed getting the suggested parameters from algod, request %s: %v",
+			requestID, err)
+	}
+	f.params = &params
+	return params, nil
+}
+
+// warnLowFees warns about the units of the unsent transactions whose fees are too low for the node to accept them
+// the fees of a group are pooled, so a group needs the sum of the fees of its transactions
+func (f *feeCheck) warnLowFees(filename string, unsent []txRecord) error {
+	if f == nil {
+		return nil
+	}
+	logger := log.WithField("file", filename)
+	for _, unit := range submissionUnits(unsent) {
+		if unit[0].txIDOnly {
+			continue
+		}
+		params, err := f.suggestedParams()
+		if err != nil {
+			return err
+		}
+		var paid, minimum, required uint64
+		for _, tx := range unit {
+			// state proofs are free
+			if tx.stx.Txn.Type == stateProofTx {
+				continue
+			}
+			encoded, err := encodeTxRecord(tx)
+			if err != nil {
+				return err
+			}
+			size := len(encoded)
+			if tx.raw == nil {
+				zeroize(encoded)
+			}
+			paid += uint64(tx.stx.Txn.Fee)
+			minimum += params.MinFee
+			required += txRequiredFee(params, size)
+		}
+		if paid >= required {
+			continue
+		}
+		what := "tx " + unit[0].txID
+		if len(unit) > 1 {
+			what = fmt.Sprintf("the group of %d transactions of tx %s", len(unit), unit[0].txID)
+		}
+		cause := fmt.Sprintf("below the minimum fee of %d microalgos per transaction", params.MinFee)
+		if paid >= minimum {
+			cause = fmt.Sprintf("below the congestion fee of %d microalgos per byte", params.Fee)
+		}
+		logEntryMessage(logger, log.WarnLevel, msgFeeTooLow, what, paid, cause, required-paid, required)
+	}
+	return nil
+}
+
+// txRequiredFee returns the fee the node requires for a signed transaction of size bytes: its size times the fee per
+// byte, which is 0 unless the network is congested, and at least the minimum fee
+func txRequiredFee(params types.SuggestedParams, size int) uint64 {
+	fee := uint64(params.Fee) * uint64(size)
+	if fee < params.MinFee {
+		return params.MinFee
+	}
+	return fee
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTxRequiredFee(t *testing.T) {
+	params := types.SuggestedParams{MinFee: 1000}
+	if fee := txRequiredFee(params, 250); fee != 1000 {
+		t.Errorf("expected the minimum fee without congestion, got %d", fee)
+	}
+	params.Fee = 10
+	if fee := txRequiredFee(params, 250); fee != 2500 {
+		t.Errorf("expected the fee per byte under congestion, got %d", fee)
+	}
+}
+
+func TestWarnLowFeesPoolsTheFeesOfGroups(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"consensus-version":"v1","fee":0,"genesis-hash":"","genesis-id":"testnet-v1.0",`+
+			`"last-round":1000,"min-fee":1000}`)
+	}))
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(level log.Level, out io.Writer) {
+		log.SetLevel(level)
+		log.SetOutput(out)
+	}(log.GetLevel(), log.StandardLogger().Out)
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetLevel(log.WarnLevel)
+
+	record := func(fee uint64, group types.Digest) txRecord {
+		stx, _ := testSignedPayment(fee, group)
+		return txRecord{txID: fmt.Sprintf("tx-%d", fee), stx: stx}
+	}
+	unsent := []txRecord{
+		// the group pays 2000 in total for its 2 transactions
+		record(0, types.Digest{1}),
+		record(2000, types.Digest{1}),
+		record(999, types.Digest{}),
+		{txID: "listed", txIDOnly: true},
+	}
+	f := &feeCheck{client: client, backoff: newTestBackoff()}
+	if err := f.warnLowFees("batch.tx", unsent); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the suggested parameters to be looked up once, got %d lookups", requests)
+	}
+	if strings.Contains(out.String(), "tx-0") || !strings.Contains(out.String(), "tx tx-999 pays a fee of 999") ||
+		!strings.Contains(out.String(), "bump of 1 microalgos") {
+		t.Errorf("expected only the underpaying transaction to be warned about, got %q", out.String())
+	}
+}
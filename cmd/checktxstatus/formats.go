@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+)
+
+var inputFormatStr string
+
+func init() {
+	rootCmd.Flags().StringVar(&inputFormatStr, "input-format", string(formatAuto),
+		"format of the input files: auto, msgpack, json, base64 (one or more msgpack-encoded transactions per line) "+
+			"or txids (one txid per line)")
+}
+
+// inputFormat is the encoding of an input file
+type inputFormat = txchecker.Format
+
+const (
+	// formatAuto detects the format of every input file from its first bytes
+	formatAuto = txchecker.FormatAuto
+	// formatMsgpack is a concatenation of msgpack-encoded signed transactions, as written by goal
+	formatMsgpack = txchecker.FormatMsgpack
+	// formatJSON is a JSON array or a stream of JSON-encoded signed transactions
+	formatJSON = txchecker.FormatJSON
+	// formatBase64 is a file of lines of base64-encoded msgpack signed transactions
+	formatBase64 = txchecker.FormatBase64
+	// formatTxIDs is a file of txids, one per line, which can be checked but not resubmitted
+	formatTxIDs = txchecker.FormatTxIDs
+)
+
+var inputFormats = map[inputFormat]bool{
+	formatAuto:    true,
+	formatMsgpack: true,
+	formatJSON:    true,
+	formatBase64:  true,
+	formatTxIDs:   true,
+}
+
+// validateInputFormat makes sure --input-format is a known format
+func validateInputFormat() error {
+	if !inputFormats[inputFormat(inputFormatStr)] {
+		return newUserError(msgUnknownInputFormat, inputFormatStr)
+	}
+	return nil
+}
+
+// sniffSize is the number of bytes looked at when detecting the format of an input
+const sniffSize = txchecker.SniffSize
+
+// detectInputFormat detects the format of an input from its first bytes without consuming them
+func detectInputFormat(r *bufio.Reader) (inputFormat, error) {
+	format, err := txchecker.DetectFormat(r)
+	if err == txchecker.ErrUndetectedFormat {
+		return "", fmt.Errorf("%v, use --input-format", err)
+	}
+	return format, err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"strings"
+	"testing"
+)
+
+// testInputs returns a payment in every input format
+func testInputs() (types.SignedTxn, map[inputFormat]string) {
+	stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx,
+		Header: types.Header{FirstValid: 1, Fee: 1000}}}
+	encoded := msgpack.Encode(stx)
+	return stx, map[inputFormat]string{
+		formatMsgpack: string(encoded),
+		formatJSON:    "[" + string(json.Encode(stx)) + "]",
+		formatBase64:  base64.StdEncoding.EncodeToString(encoded) + "\n",
+		formatTxIDs:   testTxID(1) + "\n\n",
+	}
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	_, inputs := testInputs()
+	for format, input := range inputs {
+		detected, err := detectInputFormat(bufio.NewReader(strings.NewReader(input)))
+		if err != nil || detected != format {
+			t.Errorf("expected %s to be detected, got %s, %v", format, detected, err)
+		}
+	}
+	if _, err := detectInputFormat(bufio.NewReader(strings.NewReader("\x01garbage"))); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestReadRecordsOfEveryFormat(t *testing.T) {
+	stx, inputs := testInputs()
+	for format, input := range inputs {
+		var raws [][]byte
+		var txIDs []string
+		err := txchecker.ReadRecords(bufio.NewReader(strings.NewReader(input)), format, false, func(raw []byte) error {
+			raws = append(raws, raw)
+			return nil
+		}, func(txID string) error {
+			txIDs = append(txIDs, txID)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("failed reading %s: %v", format, err)
+			continue
+		}
+		if format == formatTxIDs {
+			if len(raws) != 0 || len(txIDs) != 1 || txIDs[0] != testTxID(1) {
+				t.Errorf("expected the txid of the list, got %v", txIDs)
+			}
+			continue
+		}
+		if len(raws) != 1 || string(raws[0]) != string(msgpack.Encode(stx)) {
+			t.Errorf("expected the transaction of %s, got %v", format, raws)
+		}
+	}
+	err := txchecker.ReadRecords(bufio.NewReader(strings.NewReader("not-a-txid\n")), formatTxIDs, false, nil,
+		func(string) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected an invalid txid to fail with its line, got %v", err)
+	}
+}
+
+func TestValidateInputFormat(t *testing.T) {
+	defer func() { inputFormatStr = string(formatAuto) }()
+	inputFormatStr = "xml"
+	if err := validateInputFormat(); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"strconv"
+	"strings"
+)
+
+var graphFormat string
+
+// the formats of --graph
+const (
+	graphDOT  = "dot"
+	graphJSON = "json"
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&graphFormat, "graph", "",
+		"write a graph of the groups, accounts, assets and applications of the unsent transactions of each input to "+
+			"<file>.graph.dot or <file>.graph.json (dot or json), to visualize multi-group operations before "+
+			"resubmitting them")
+}
+
+// validateGraph makes sure --graph is a known format
+func validateGraph() error {
+	if graphFormat != "" && graphFormat != graphDOT && graphFormat != graphJSON {
+		return fmt.Errorf("unknown --graph %q, expected dot or json", graphFormat)
+	}
+	return nil
+}
+
+// the kinds of the nodes of the graph
+const (
+	graphNodeGroup   = "group"
+	graphNodeTx      = "tx"
+	graphNodeAccount = "account"
+	graphNodeAsset   = "asset"
+	graphNodeApp     = "app"
+)
+
+// graphNode is a unit of unsent transactions, a group or an individual transaction, or something they involve
+type graphNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	// Txns is the number of transactions of a unit, or of the ones involving an account, asset or application
+	Txns int `json:"txns"`
+}
+
+// graphEdge links a unit to an account, asset or application its transactions involve, from the sender to the unit
+// and from the unit to everything else
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Role is how the unit involves the other end, e.g. sender, receiver or transfers
+	Role string `json:"role"`
+	Txns int    `json:"txns"`
+}
+
+// graphEdgeKey identifies the edges merged into one
+type graphEdgeKey struct {
+	from, to, role string
+}
+
+// unsentGraph collects the unsent transactions of a file, chunk after chunk, in the order of the file
+// accounts, assets and applications involved in several units link them together, e.g. the groups of batched swaps
+// sharing a pool
+type unsentGraph struct {
+	resolver *addressResolver
+	Nodes    []*graphNode `json:"nodes"`
+	Edges    []*graphEdge `json:"edges"`
+	nodes    map[string]*graphNode
+	edges    map[graphEdgeKey]*graphEdge
+}
+
+// newUnsentGraph returns a graph labeling accounts with resolver if --graph is set, nil otherwise
+func newUnsentGraph(resolver *addressResolver) *unsentGraph {
+	if graphFormat == "" {
+		return nil
+	}
+	return &unsentGraph{
+		resolver: resolver,
+		Nodes:    []*graphNode{},
+		Edges:    []*graphEdge{},
+		nodes:    map[string]*graphNode{},
+		edges:    map[graphEdgeKey]*graphEdge{},
+	}
+}
+
+// add adds the units of unsent transactions, the ones read from txid lists are left out as nothing is known of them
+func (g *unsentGraph) add(unsent []txRecord) {
+	if g == nil {
+		return
+	}
+	for _, unit := range submissionUnits(unsent) {
+		if unit[0].txIDOnly {
+			continue
+		}
+		var id string
+		if gid := unit[0].stx.Txn.Group; gid != (types.Digest{}) {
+			id = g.node(graphNodeGroup, groupIDString(gid),
+				fmt.Sprintf("group %s\n%d transactions", groupIDString(gid), len(unit)), len(unit))
+		} else {
+			id = g.node(graphNodeTx, unit[0].txID, fmt.Sprintf("%s %s", unit[0].stx.Txn.Type, unit[0].txID), 1)
+		}
+		for _, tx := range unit {
+			g.addTx(id, tx.stx.Txn)
+		}
+	}
+}
+
+// addTx links the unit id to what its transaction txn involves
+func (g *unsentGraph) addTx(id string, txn types.Transaction) {
+	g.edge(g.account(txn.Sender), id, "sender")
+	for _, involved := range []struct {
+		role string
+		addr types.Address
+	}{
+		{"receiver", txReceiver(txn)},
+		{"close-to", txn.CloseRemainderTo},
+		{"asset-close-to", txn.AssetCloseTo},
+		{"freezes", txn.FreezeAccount},
+	} {
+		if !involved.addr.IsZero() {
+			g.edge(id, g.account(involved.addr), involved.role)
+		}
+	}
+	switch {
+	case txn.Type == types.AssetTransferTx:
+		g.edge(id, g.asset(uint64(txn.XferAsset)), "transfers")
+	case txn.Type == types.AssetConfigTx && txn.ConfigAsset != 0:
+		g.edge(id, g.asset(uint64(txn.ConfigAsset)), "configures")
+	case txn.Type == types.AssetFreezeTx:
+		g.edge(id, g.asset(uint64(txn.FreezeAsset)), "freezes")
+	case txn.Type == types.ApplicationCallTx && txn.ApplicationID != 0:
+		app := strconv.FormatUint(uint64(txn.ApplicationID), 10)
+		g.edge(id, g.node(graphNodeApp, app, "app "+app, 1), "calls")
+	}
+}
+
+// account returns the node of an account involved in a transaction, labeled with its name if it has one
+func (g *unsentGraph) account(addr types.Address) string {
+	return g.node(graphNodeAccount, addr.String(), g.resolver.label(addr), 1)
+}
+
+// asset returns the node of an asset involved in a transaction
+func (g *unsentGraph) asset(assetID uint64) string {
+	id := strconv.FormatUint(assetID, 10)
+	return g.node(graphNodeAsset, id, "asset "+id, 1)
+}
+
+// node adds txns transactions to the node of kind identified by key, adding the node if it is new, and returns its ID
+func (g *unsentGraph) node(kind string, key string, label string, txns int) string {
+	id := kind + ":" + key
+	node, ok := g.nodes[id]
+	if !ok {
+		node = &graphNode{ID: id, Kind: kind, Label: label}
+		g.nodes[id] = node
+		g.Nodes = append(g.Nodes, node)
+	}
+	node.Txns += txns
+	return id
+}
+
+// edge adds a transaction to the edge from one node to another, adding the edge if it is new
+func (g *unsentGraph) edge(from string, to string, role string) {
+	key := graphEdgeKey{from: from, to: to, role: role}
+	edge, ok := g.edges[key]
+	if !ok {
+		edge = &graphEdge{From: from, To: to, Role: role}
+		g.edges[key] = edge
+		g.Edges = append(g.Edges, edge)
+	}
+	edge.Txns++
+}
+
+// graphShapes are the shapes of the kinds of nodes in DOT
+var graphShapes = map[string]string{
+	graphNodeGroup:   "box",
+	graphNodeTx:      "box",
+	graphNodeAccount: "ellipse",
+	graphNodeAsset:   "diamond",
+	graphNodeApp:     "hexagon",
+}
+
+// encode returns the graph in the format of --graph
+func (g *unsentGraph) encode() ([]byte, error) {
+	if graphFormat == graphJSON {
+		encoded, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(encoded, '\n'), nil
+	}
+	var b strings.Builder
+	b.WriteString("digraph unsent {\n  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", strconv.Quote(node.ID), strconv.Quote(node.Label),
+			graphShapes[node.Kind])
+	}
+	for _, edge := range g.Edges {
+		label := edge.Role
+		if edge.Txns > 1 {
+			label = fmt.Sprintf("%s x%d", edge.Role, edge.Txns)
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To),
+			strconv.Quote(label))
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// write writes the graph of the input filename to <file>.graph.dot or <file>.graph.json and returns its name
+func (g *unsentGraph) write(filename string) (string, error) {
+	output := outputName(fmt.Sprintf("%s.graph.%s", outputBase(filename), graphFormat))
+	encoded, err := g.encode()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the graph of %s: %v", filename, err)
+	}
+	stream, err := openOutputStream(output)
+	if err != nil {
+		return "", err
+	}
+	_, err = stream.Write(encoded)
+	if err != nil {
+		// the error of the write is more informative than the one of closing the file
+		_ = stream.close()
+		return "", err
+	}
+	return output, stream.close()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestUnsentGraphLinksGroupsSharingAnAsset(t *testing.T) {
+	defer func(format string) { graphFormat = format }(graphFormat)
+	graphFormat = graphJSON
+	swap := func(txID string, group byte) txRecord {
+		var rec txRecord
+		rec.txID = txID
+		rec.stx.Txn.Type = types.AssetTransferTx
+		rec.stx.Txn.Sender = types.Address{group}
+		rec.stx.Txn.AssetReceiver = types.Address{9}
+		rec.stx.Txn.XferAsset = 31566704
+		rec.stx.Txn.Group = types.Digest{group}
+		return rec
+	}
+	g := newUnsentGraph(nil)
+	g.add([]txRecord{swap("a", 1), swap("b", 1), swap("c", 2), {txID: "listed", txIDOnly: true}})
+
+	encoded, err := g.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Nodes []graphNode `json:"nodes"`
+		Edges []graphEdge `json:"edges"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	asset := g.nodes["asset:31566704"]
+	if asset == nil || asset.Txns != 3 {
+		t.Errorf("expected the asset to be involved in the 3 transactions, got %+v", asset)
+	}
+	// 2 groups, 3 accounts and the asset
+	if len(decoded.Nodes) != 6 {
+		t.Errorf("expected 6 nodes, got %+v", decoded.Nodes)
+	}
+	transfers := g.edges[graphEdgeKey{from: "group:" + groupIDString(types.Digest{1}), to: "asset:31566704",
+		role: "transfers"}]
+	if transfers == nil || transfers.Txns != 2 {
+		t.Errorf("expected the transfers of a group to be merged into an edge, got %+v", transfers)
+	}
+
+	graphFormat = graphDOT
+	dot, err := g.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(dot), "digraph unsent {") ||
+		!strings.Contains(string(dot), `"asset:31566704" [label="asset 31566704", shape=diamond];`) ||
+		!strings.Contains(string(dot), `[label="transfers x2"]`) {
+		t.Errorf("unexpected DOT graph %s", dot)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+)
+
+// the policies of --group-policy
+const (
+	groupPolicyFirst  = string(txchecker.GroupPolicyFirst)
+	groupPolicyAll    = string(txchecker.GroupPolicyAll)
+	groupPolicyQuorum = string(txchecker.GroupPolicyQuorum)
+)
+
+var (
+	groupPolicy          string
+	checkAllGroupMembers bool
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&groupPolicy, "group-policy", groupPolicyFirst,
+		"how the status of a group is derived from its transactions: first (look up its first transaction only), "+
+			"all (sent only if all its transactions are found) or quorum (sent if a majority of them are found)")
+	rootCmd.Flags().BoolVar(&checkAllGroupMembers, "check-all-group-members", false,
+		"look up every transaction of every group whatever --group-policy, and classify the groups of which only some "+
+			"transactions are found as partial, an error, instead of sent or unsent")
+}
+
+// validateGroupPolicy checks --group-policy
+func validateGroupPolicy() error {
+	switch groupPolicy {
+	case groupPolicyFirst, groupPolicyAll, groupPolicyQuorum:
+		return nil
+	}
+	return fmt.Errorf("invalid --group-policy %q, expected first, all or quorum", groupPolicy)
+}
+
+// lookupAllGroupMembers returns whether every transaction of every group is looked up upfront
+func lookupAllGroupMembers() bool {
+	return checkAllGroupMembers || groupPolicy == groupPolicyAll
+}
+
+// lookupUnits looks up the status of units of transactions according to --group-policy with txchecker.LookupUnits
+// with --check-all-group-members every transaction is looked up, and partial is set for the groups of which only some
+// transactions were found
+func (l *txLookup) lookupUnits(units [][]txRecord) (sent []bool, partial []bool, err error) {
+	policy := txchecker.GroupPolicy(groupPolicy)
+	txids := make([][]string, len(units))
+	// urgent are the units with a transaction matching --priority, whose transactions are looked up first
+	urgent := make([]bool, len(units))
+	for i, unit := range units {
+		for _, rec := range unit {
+			txids[i] = append(txids[i], rec.txID)
+			urgent[i] = urgent[i] || l.priority.matches(rec)
+		}
+	}
+	found, looked, err := txchecker.LookupUnits(txids, policy, checkAllGroupMembers,
+		func(txids []string, owners []int) ([]bool, error) {
+			return l.lookupPrioritized(txids, ownersUrgent(owners, urgent))
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sent = make([]bool, len(units))
+	partial = make([]bool, len(units))
+	for i, unit := range units {
+		sent[i] = found[i] >= policy.Quorum(len(unit))
+		switch {
+		case found[i] == 0 || found[i] == looked[i]:
+		case checkAllGroupMembers:
+			partial[i] = true
+			logMessage(log.ErrorLevel, msgGroupPartial, groupIDString(unit[0].stx.Txn.Group), found[i], len(unit))
+		case groupPolicy != groupPolicyFirst:
+			logMessage(log.WarnLevel, msgGroupPartialPolicy, groupIDString(unit[0].stx.Txn.Group), found[i], looked[i],
+				sentOrUnsent(sent[i]))
+		}
+	}
+	return sent, partial, nil
+}
+
+// ownersUrgent returns whether the units owning transactions are urgent, for every transaction
+func ownersUrgent(owners []int, urgent []bool) []bool {
+	ownerUrgent := make([]bool, len(owners))
+	for j, owner := range owners {
+		ownerUrgent[j] = urgent[owner]
+	}
+	return ownerUrgent
+}
+
+func sentOrUnsent(sent bool) string {
+	if sent {
+		return "sent"
+	}
+	return "unsent"
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestLookupUnitsByGroupPolicy(t *testing.T) {
+	found := map[string]bool{testTxID(1): true, testTxID(3): true, testTxID(4): true, testTxID(6): true}
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		txID := path.Base(r.URL.Path)
+		if !found[txID] {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q}}`, txID)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	concurrency := &concurrencyController{limit: 1}
+	concurrency.cond = sync.NewCond(&concurrency.mu)
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff(), concurrency: concurrency}
+	group := func(gid byte, txids ...byte) []txRecord {
+		var unit []txRecord
+		for _, n := range txids {
+			rec := txRecord{txID: testTxID(n)}
+			rec.stx.Txn.Group[0] = gid
+			unit = append(unit, rec)
+		}
+		return unit
+	}
+	// a partially sent group, a sent one and one sent by a majority of its members
+	units := [][]txRecord{group(1, 1, 2), group(2, 3, 4), group(3, 5, 6, 7)}
+	defer func(policy string) { groupPolicy = policy }(groupPolicy)
+	for _, test := range []struct {
+		policy   string
+		sent     []bool
+		requests int
+	}{
+		{groupPolicyFirst, []bool{true, true, false}, 3},
+		{groupPolicyAll, []bool{false, true, false}, 7},
+		// the quorum of the partially sent group is decided by its first lookups
+		{groupPolicyQuorum, []bool{false, true, false}, 7},
+	} {
+		groupPolicy = test.policy
+		requests = 0
+		if err := validateGroupPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		sent, _, err := lookup.lookupUnits(units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(sent) != fmt.Sprint(test.sent) || requests != test.requests {
+			t.Errorf("expected %v with %d requests under %s, got %v with %d", test.sent, test.requests, test.policy,
+				sent, requests)
+		}
+	}
+	groupPolicy = "most"
+	if err := validateGroupPolicy(); err == nil {
+		t.Error("expected an invalid group policy to fail")
+	}
+}
+
+func TestLookupUnitsPartialGroups(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	server.Confirm(testTxID(3), 900)
+	server.Confirm(testTxID(4), 900)
+	checkAllGroupMembers = true
+	defer func() {
+		checkAllGroupMembers = false
+	}()
+	group := func(gid byte, txids ...string) []txRecord {
+		var unit []txRecord
+		for _, txid := range txids {
+			rec := txRecord{txID: txid}
+			rec.stx.Txn.Group[0] = gid
+			unit = append(unit, rec)
+		}
+		return unit
+	}
+	units := [][]txRecord{
+		group(1, testTxID(1), testTxID(2)),
+		group(2, testTxID(3), testTxID(4)),
+		group(3, testTxID(5), testTxID(6)),
+	}
+	lookup := newTestLookup(t, server)
+	lookup.concurrency = newConcurrencyController(1)
+	sent, partial, err := lookup.lookupUnits(units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !partial[0] || partial[1] || partial[2] || !sent[1] || sent[2] {
+		t.Errorf("expected only the first group to be partial, the second sent and the third unsent, got %v and %v",
+			partial, sent)
+	}
+	// the first transaction decides the groups under --group-policy first, unless every member is looked up
+	if requests := server.Requests(); len(requests) != 6 {
+		t.Errorf("expected every member to be looked up, got %v", requests)
+	}
+}
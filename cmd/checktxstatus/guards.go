@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	maxFileSizeStr string
+	maxTxns        int
+	assumeYes      bool
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&maxFileSizeStr, "max-file-size", "100MB",
+		"ask for confirmation before processing a larger input file, e.g. 500KB, 1GB (0 for no limit)")
+	rootCmd.Flags().IntVar(&maxTxns, "max-txns", 100000,
+		"ask for confirmation before checking a file with more transactions (0 for no limit)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "answer yes to all confirmations")
+}
+
+// parseByteSize parses a size such as 512, 100KB, 20MB or 1GB (powers of 1024)
+func parseByteSize(size string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	upper := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	value, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return value * multiplier, nil
+}
+
+// confirm asks the user to confirm an action when stdin is a terminal
+// it returns an error if the user declined or can not be asked, unless --yes was set
+func confirm(question string) error {
+	if assumeYes {
+		return nil
+	}
+	if !isInteractive() {
+		return fmt.Errorf("%s: aborting, pass --yes to proceed in non-interactive runs", question)
+	}
+	if !ask(fmt.Sprintf("%s, continue?", question)) {
+		return fmt.Errorf("%s: aborted by user", question)
+	}
+	return nil
+}
+
+// noPrompts is set by the commands whose prompts nobody would answer, e.g. serve answering over HTTP
+var noPrompts bool
+
+// isInteractive returns true if the user can be asked questions on stdin
+func isInteractive() bool {
+	return !noPrompts && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ask asks the user a yes or no question on the terminal, anything but yes is a no
+func ask(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// checkFileSizeLimit requires confirmation before processing a file (or all files of a directory) larger than
+// --max-file-size
+func checkFileSizeLimit(filename string) error {
+	maxFileSize, err := parseByteSize(maxFileSizeStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max-file-size: %v", err)
+	}
+	if maxFileSize == 0 {
+		return nil
+	}
+	files, err := batchFiles(filename)
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, file := range files {
+		fileSize, err := inputSize(file)
+		if err != nil {
+			return err
+		}
+		size += fileSize
+	}
+	if size <= maxFileSize {
+		return nil
+	}
+	return confirm(fmt.Sprintf("%s is %d bytes, larger than --max-file-size %s", filename, size, maxFileSizeStr))
+}
+
+// checkTxCountLimit requires confirmation before checking more than --max-txns transactions of a file
+func checkTxCountLimit(filename string, count int) error {
+	if maxTxns == 0 || count <= maxTxns {
+		return nil
+	}
+	return confirm(fmt.Sprintf("%s has %d transactions, more than --max-txns %d", filename, count, maxTxns))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	sizes := map[string]int64{"512": 512, "100KB": 100 << 10, " 20 mb": 20 << 20, "1GB": 1 << 30, "0": 0}
+	for size, expected := range sizes {
+		if parsed, err := parseByteSize(size); err != nil || parsed != expected {
+			t.Errorf("expected %q to be %d bytes, got %d, %v", size, expected, parsed, err)
+		}
+	}
+	for _, size := range []string{"", "-1MB", "1TB", "MB"} {
+		if _, err := parseByteSize(size); err == nil {
+			t.Errorf("expected %q to be invalid", size)
+		}
+	}
+}
+
+func TestLimitsNeedConfirmation(t *testing.T) {
+	defer func() { maxFileSizeStr, assumeYes = "100MB", false }()
+	filename := filepath.Join(testDir(t), "batch.tx")
+	if err := ioutil.WriteFile(filename, make([]byte, 2048), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFileSizeLimit(filename); err != nil {
+		t.Errorf("expected a small file to be processed, got %v", err)
+	}
+	maxFileSizeStr = "1KB"
+	// tests do not run on a terminal, so nobody can confirm
+	if err := checkFileSizeLimit(filename); err == nil {
+		t.Error("expected a large file to need confirmation")
+	}
+	if err := checkTxCountLimit(filename, maxTxns+1); err == nil {
+		t.Error("expected a large number of transactions to need confirmation")
+	}
+	assumeYes = true
+	if err := checkFileSizeLimit(filename); err != nil {
+		t.Errorf("expected --yes to confirm, got %v", err)
+	}
+}
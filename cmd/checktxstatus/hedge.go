@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	hedgeIndexerAddress string
+	hedgeIndexerToken   string
+	hedgePercentile     float64
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&hedgeIndexerAddress, "hedge-idx-addr", "",
+		"address of a second indexer to send a duplicate of transaction lookups slower than --hedge-percentile to, "+
+			"taking whichever response comes first")
+	rootCmd.Flags().StringVar(&hedgeIndexerToken, "hedge-idx-tkn", "", "API token of the --hedge-idx-addr indexer")
+	rootCmd.Flags().Float64Var(&hedgePercentile, "hedge-percentile", 95,
+		"percentile of recent lookup latencies after which a lookup is hedged")
+}
+
+const (
+	// hedgeMinSamples is the number of lookups timed before hedging starts
+	hedgeMinSamples = 20
+	// hedgeWindow is the number of recent lookup latencies the hedging threshold is computed from
+	hedgeWindow = 1000
+)
+
+// hedger sends a duplicate of slow lookups to a second indexer
+type hedger struct {
+	client     *indexer.Client
+	percentile float64
+	mu         sync.Mutex
+	// latencies are the latencies of the recent lookups, a ring of up to hedgeWindow entries
+	latencies []time.Duration
+	next      int
+	// lookups, hedged and wonByHedge count the lookups, the hedged ones and the ones answered first by the hedge
+	lookups    int
+	hedged     int
+	wonByHedge int
+}
+
+// initHedger returns the hedger set by --hedge-idx-addr, nil if not set
+func initHedger() (*hedger, error) {
+	if hedgeIndexerAddress == "" {
+		return nil, nil
+	}
+	if hedgePercentile <= 0 || hedgePercentile >= 100 {
+		return nil, fmt.Errorf("--hedge-percentile must be between 0 and 100")
+	}
+	client, err := indexer.MakeClientWithHeaders(hedgeIndexerAddress, hedgeIndexerToken,
+		clientHeaders(indexerTransport))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating the hedge indexer client: %v", err)
+	}
+	return &hedger{client: client, percentile: hedgePercentile}, nil
+}
+
+// threshold returns the latency after which a lookup is hedged, ok is false until enough lookups were timed
+func (h *hedger) threshold() (threshold time.Duration, ok bool) {
+	h.mu.Lock()
+	latencies := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+	if len(latencies) < hedgeMinSamples {
+		return 0, false
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	rank := int(math.Ceil(h.percentile/100*float64(len(latencies)))) - 1
+	return latencies[rank], true
+}
+
+// observe records the latency of a lookup, and whether it was hedged and answered first by the hedge
+func (h *hedger) observe(latency time.Duration, hedged bool, wonByHedge bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % hedgeWindow
+	}
+	h.lookups++
+	if hedged {
+		h.hedged++
+	}
+	if wonByHedge {
+		h.wonByHedge++
+	}
+}
+
+// logSummary logs how many lookups of the run were hedged
+func (h *hedger) logSummary() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	logMessage(log.InfoLevel, msgHedgeSummary, h.hedged, h.lookups, h.wonByHedge)
+}
+
+// hedgedResponse is the response of one of the indexers to a hedged lookup
+type hedgedResponse struct {
+	resp  models.TransactionResponse
+	err   error
+	hedge bool
+}
+
+// lookup looks up a transaction in the primary indexer, and also in the hedge indexer if the primary one did not
+// answer within the threshold. It returns the first response, unless it is a transient error while the other
+// lookup is still in progress, and whether it came from the hedge; the other lookup is canceled.
+func (h *hedger) lookup(primary *indexer.Client, txid string, headers []*common.Header) (
+	resp models.TransactionResponse, fromHedge bool, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	responses := make(chan hedgedResponse, 2)
+	send := func(client *indexer.Client, hedge bool) {
+		resp, err := client.LookupTransaction(txid).Do(ctx, headers...)
+		responses <- hedgedResponse{resp: resp, err: err, hedge: hedge}
+	}
+	start := time.Now()
+	go send(primary, false)
+
+	var timeout <-chan time.Time
+	threshold, ok := h.threshold()
+	if ok {
+		timer := time.NewTimer(threshold)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r := <-responses:
+		h.observe(time.Since(start), false, false)
+		return r.resp, false, r.err
+	case <-timeout:
+	}
+	if err := budget.spend(requestTx); err != nil {
+		// no budget left for the hedged lookup, wait for the primary one
+		r := <-responses
+		h.observe(time.Since(start), false, false)
+		return r.resp, false, r.err
+	}
+	log.Debugf("lookup of tx %s took more than %s, hedging it", txid, threshold)
+	go send(h.client, true)
+	r := <-responses
+	if r.err != nil && isTransient(r.err) {
+		r = <-responses
+	}
+	h.observe(time.Since(start), true, r.hedge)
+	return r.resp, r.hedge, r.err
+}
+
+// lookupTransaction looks up a transaction in the indexer, hedging slow lookups if --hedge-idx-addr is set
+// it returns the backend that answered, backendIndexer or backendHedge
+func (l *txLookup) lookupTransaction(txid string, headers []*common.Header) (models.TransactionResponse, string,
+	error) {
+	if l.hedge == nil {
+		resp, err := l.indexerClient.LookupTransaction(txid).Do(context.Background(), headers...)
+		return resp, backendIndexer, err
+	}
+	resp, fromHedge, err := l.hedge.lookup(l.indexerClient, txid, headers)
+	if fromHedge {
+		return resp, backendHedge, err
+	}
+	return resp, backendIndexer, err
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+)
+
+func newTestTxIndexer(t *testing.T, delay time.Duration) *indexer.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q}}`, path.Base(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestHedgerThresholdIsPercentileOfRecentLatencies(t *testing.T) {
+	h := &hedger{percentile: 90}
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i)*time.Millisecond, false, false)
+	}
+	if _, ok := h.threshold(); ok {
+		t.Error("expected no threshold before enough lookups were timed")
+	}
+	h.observe(hedgeMinSamples*time.Millisecond, false, false)
+	if threshold, ok := h.threshold(); !ok || threshold != 18*time.Millisecond {
+		t.Errorf("expected the 90th percentile of 1ms to 20ms, got %v", threshold)
+	}
+}
+
+func TestHedgerSendsSlowLookupsToSecondIndexer(t *testing.T) {
+	primary := newTestTxIndexer(t, time.Second)
+	h := &hedger{client: newTestTxIndexer(t, 0), percentile: 50}
+	for i := 0; i < hedgeMinSamples; i++ {
+		h.observe(time.Millisecond, false, false)
+	}
+	start := time.Now()
+	resp, fromHedge, err := h.lookup(primary, testTxID(1), nil)
+	if err != nil || resp.Transaction.Id != testTxID(1) {
+		t.Fatalf("expected the transaction, got %+v %v", resp, err)
+	}
+	if !fromHedge {
+		t.Error("expected the answer to come from the hedge indexer")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the hedge indexer to answer before the slow primary one")
+	}
+	if h.hedged != 1 || h.wonByHedge != 1 {
+		t.Errorf("expected the lookup to be hedged and won by the hedge, got %d hedged and %d won", h.hedged,
+			h.wonByHedge)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var indexFilesAboveStr string
+
+func init() {
+	rootCmd.Flags().StringVar(&indexFilesAboveStr, "index-files-above", "0",
+		"write a sidecar index of the txids and byte offsets of the transactions of msgpack input files at least "+
+			"this large, e.g. 100MB, so later operations on them can seek to transactions (0 to disable)")
+}
+
+// indexFilesAbove is the size of the input files from which a sidecar index is written, 0 if disabled
+var indexFilesAbove int64
+
+// txIndexHeader starts every sidecar index, followed by the size and modification time of the indexed file
+const txIndexHeader = "checktxstatus-index v1"
+
+// initTxIndexing parses --index-files-above
+func initTxIndexing() error {
+	var err error
+	indexFilesAbove, err = parseByteSize(indexFilesAboveStr)
+	if err != nil {
+		return fmt.Errorf("invalid --index-files-above: %v", err)
+	}
+	return nil
+}
+
+// txIndexEntry locates a transaction in its file
+type txIndexEntry struct {
+	txID   string
+	offset int64
+	size   int
+}
+
+// indexFilename returns the name of the sidecar index of filename, which is hidden so directory batches skip it
+func indexFilename(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".idx")
+}
+
+// indexHeaderOf returns the header of the sidecar index of a file, which changes whenever the file does
+func indexHeaderOf(stat os.FileInfo) string {
+	return fmt.Sprintf("%s %d %d", txIndexHeader, stat.Size(), stat.ModTime().UnixNano())
+}
+
+// needsIndex returns true if a sidecar index should be written while reading filename: it is a large msgpack file
+// with no index, or with an index of a previous version of the file
+func needsIndex(filename string, format inputFormat) (bool, error) {
+	// stdin has nowhere to write an index next to
+	if indexFilesAbove == 0 || format != formatMsgpack || filename == stdioName {
+		return false, nil
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return false, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	if stat.Size() < indexFilesAbove {
+		return false, nil
+	}
+	index, err := os.Open(indexFilename(filename))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error while opening index %s: %v", indexFilename(filename), err)
+	}
+	// no need to check error on close when reading file
+	defer index.Close()
+	header, _ := bufio.NewReader(index).ReadString('\n')
+	return strings.TrimSuffix(header, "\n") != indexHeaderOf(stat), nil
+}
+
+// writeTxIndex writes the sidecar index of filename, one "<txid> <offset> <size>" line per transaction
+func writeTxIndex(filename string, entries []txIndexEntry) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	var b strings.Builder
+	b.WriteString(indexHeaderOf(stat) + "\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s %d %d\n", entry.txID, entry.offset, entry.size)
+	}
+	indexName := indexFilename(filename)
+	err = writeFileAtomic(indexName, []byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("failed to write index %s: %v", indexName, err)
+	}
+	logMessage(log.InfoLevel, msgIndexWritten, len(entries), filename, indexName)
+	return nil
+}
+
+// loadTxIndex reads the sidecar index of filename, failing if it is missing or was written for another version of
+// the file
+func loadTxIndex(filename string) (map[string]txIndexEntry, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	indexName := indexFilename(filename)
+	index, err := os.Open(indexName)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening index %s: %v", indexName, err)
+	}
+	// no need to check error on close when reading file
+	defer index.Close()
+	scanner := bufio.NewScanner(index)
+	if !scanner.Scan() || scanner.Text() != indexHeaderOf(stat) {
+		return nil, fmt.Errorf("index %s is not an index of the current %s", indexName, filename)
+	}
+	entries := map[string]txIndexEntry{}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid line %q in index %s", scanner.Text(), indexName)
+		}
+		offset, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in index %s: %v", indexName, err)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in index %s: %v", indexName, err)
+		}
+		entries[fields[0]] = txIndexEntry{txID: fields[0], offset: offset, size: size}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading index %s: %v", indexName, err)
+	}
+	return entries, nil
+}
+
+// readIndexedTx reads the encoding of an indexed transaction from its file, without decoding the transactions before it
+func readIndexedTx(filename string, entry txIndexEntry) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+	raw := make([]byte, entry.size)
+	_, err = file.ReadAt(raw, entry.offset)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading tx %s from %s: %v", entry.txID, filename, err)
+	}
+	return raw, nil
+}
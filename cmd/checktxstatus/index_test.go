@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadTxFileWritesSidecarIndex(t *testing.T) {
+	defer func(above int64) { indexFilesAbove = above }(indexFilesAbove)
+	indexFilesAbove = 1
+	filename := filepath.Join(testDir(t), "txs.msgp")
+	var content []byte
+	var encodings [][]byte
+	for i := 1; i <= 3; i++ {
+		stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx,
+			Header: types.Header{FirstValid: types.Round(i)}}}
+		encodings = append(encodings, msgpack.Encode(stx))
+		content = append(content, encodings[i-1]...)
+	}
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	batch := &txBatch{groups: map[types.Digest][]txRecord{}}
+	if err := readTxFile(filename, batch, nil); err != nil {
+		t.Fatal(err)
+	}
+	if indexing, err := needsIndex(filename, formatMsgpack); err != nil || indexing {
+		t.Errorf("expected the file to be indexed, got %v %v", indexing, err)
+	}
+	entries, err := loadTxIndex(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 indexed transactions, got %v", entries)
+	}
+	for i, rec := range batch.individual {
+		raw, err := readIndexedTx(filename, entries[rec.txID])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, encodings[i]) {
+			t.Errorf("expected the encoding of tx %d at its indexed offset", i)
+		}
+	}
+	// a newer version of the file invalidates the index
+	if err := ioutil.WriteFile(filename, content[:len(encodings[0])], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if indexing, err := needsIndex(filename, formatMsgpack); err != nil || !indexing {
+		t.Errorf("expected a changed file to need a new index, got %v %v", indexing, err)
+	}
+	if _, err := loadTxIndex(filename); err == nil {
+		t.Error("expected the index of the previous version of the file to be rejected")
+	}
+}
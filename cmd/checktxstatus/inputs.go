@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	recursive      bool
+	includePattern string
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&recursive, "recursive", false,
+		"check every file matching --include in the directories given, and in their subdirectories, as its own "+
+			"input instead of reading every directory as a single batch")
+	rootCmd.Flags().StringVar(&includePattern, "include", "*.tx",
+		"pattern of the names of the files checked in the directories walked by --recursive")
+}
+
+// expandInputs expands the glob patterns of args, in which ** matches any number of directories, and the directories
+// with --recursive, to the inputs they match in natural order
+// an argument naming an existing file is never a pattern, and inputs matched by several arguments are checked once
+func expandInputs(args []string) ([]string, error) {
+	if _, err := filepath.Match(includePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid --include %q: %v", includePattern, err)
+	}
+	var inputs []string
+	seen := map[string]bool{}
+	for _, arg := range args {
+		matches := []string{arg}
+		if arg != stdioName && isGlob(arg) {
+			if _, err := os.Stat(arg); err != nil {
+				matches, err = globInputs(arg)
+				if err != nil {
+					return nil, err
+				}
+				if len(matches) == 0 {
+					return nil, fmt.Errorf("no inputs match %s", arg)
+				}
+			}
+		}
+		for _, match := range matches {
+			files := []string{match}
+			if stat, err := os.Stat(match); recursive && err == nil && stat.IsDir() {
+				files, err = walkInputs(match)
+				if err != nil {
+					return nil, err
+				}
+				if len(files) == 0 {
+					logMessage(log.WarnLevel, msgIncludeNoMatch, match, includePattern)
+				}
+			}
+			for _, file := range files {
+				if !seen[filepath.Clean(file)] {
+					seen[filepath.Clean(file)] = true
+					inputs = append(inputs, file)
+				}
+			}
+		}
+	}
+	if len(inputs) != len(args) {
+		logMessage(log.InfoLevel, msgInputsExpanded, len(args), len(inputs))
+	}
+	return inputs, nil
+}
+
+// isGlob returns whether a path has glob metacharacters
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// globInputs returns the paths matching a pattern in natural order, hidden files and directories are skipped
+func globInputs(pattern string) ([]string, error) {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	// the walk starts from the parts before the first one with metacharacters
+	static := 0
+	for static < len(parts) && !isGlob(parts[static]) {
+		static++
+	}
+	root := filepath.FromSlash(strings.Join(parts[:static], "/"))
+	if root == "" && strings.HasPrefix(pattern, "/") {
+		root = "/"
+	}
+	if root == "" {
+		root = "."
+	}
+	// without **, the walk does not go deeper than the pattern
+	deep := false
+	for _, part := range parts[static:] {
+		if part == "**" {
+			deep = true
+			continue
+		}
+		if _, err := filepath.Match(part, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+		}
+	}
+	var matches []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		names := strings.Split(filepath.ToSlash(rel), "/")
+		if matchParts(parts[static:], names) {
+			matches = append(matches, path)
+		}
+		if info.IsDir() && !deep && len(names) >= len(parts)-static {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while matching %s: %v", pattern, err)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return naturalLess(matches[i], matches[j])
+	})
+	return matches, nil
+}
+
+// matchParts returns whether the parts of a path match the parts of a pattern, ** matching any number of them
+func matchParts(pattern []string, names []string) bool {
+	if len(pattern) == 0 {
+		return len(names) == 0
+	}
+	if pattern[0] == "**" {
+		return matchParts(pattern[1:], names) || len(names) != 0 && matchParts(pattern, names[1:])
+	}
+	if len(names) == 0 {
+		return false
+	}
+	matched, _ := filepath.Match(pattern[0], names[0])
+	return matched && matchParts(pattern[1:], names[1:])
+}
+
+// walkInputs returns the files of a directory and of its subdirectories matching --include, in natural order
+// hidden files and directories are skipped
+func walkInputs(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if matched, _ := filepath.Match(includePattern, info.Name()); matched {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while walking %s: %v", dir, err)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalLess(files[i], files[j])
+	})
+	return files, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchParts(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"**/*.tx", "a.tx", true},
+		{"**/*.tx", "a/b/c.tx", true},
+		{"a/**/c/*.tx", "a/c/d.tx", true},
+		{"a/**/c/*.tx", "a/b/b/c/d.tx", true},
+		{"a/*/*.tx", "a/b/c/d.tx", false},
+		{"**/*.tx", "a/b.tx.unsent", false},
+	} {
+		if matchParts(strings.Split(c.pattern, "/"), strings.Split(c.path, "/")) != c.matched {
+			t.Errorf("expected %s matching %s to be %v", c.pattern, c.path, c.matched)
+		}
+	}
+}
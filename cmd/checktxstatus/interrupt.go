@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// errInterrupted is returned by the requests made once the run was interrupted
+var errInterrupted = errors.New("interrupted")
+
+// runInterrupted is closed when the run is interrupted by SIGINT or SIGTERM
+var runInterrupted = make(chan struct{})
+
+// stopOnInterrupt makes the first SIGINT or SIGTERM stop the run gracefully: the requests made after it fail, so the
+// run stops once the ones in progress complete, writing the checkpoint and the other outputs of a stopped run
+// a second signal kills the run at once; the returned function stops listening for signals
+func stopOnInterrupt() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			logMessage(log.WarnLevel, msgInterrupted, sig)
+			close(runInterrupted)
+		case <-stopped:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stopped)
+	}
+}
+
+// isInterrupted returns whether the run was interrupted
+func isInterrupted() bool {
+	select {
+	case <-runInterrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepUnlessInterrupted waits for d, returning false if the run was interrupted first
+func sleepUnlessInterrupted(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-runInterrupted:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
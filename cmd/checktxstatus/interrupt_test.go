@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"testing"
+	"time"
+)
+
+func TestInterruptedRunsMakeNoRequests(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	defer func(interrupted chan struct{}) { runInterrupted = interrupted }(runInterrupted)
+	runInterrupted = make(chan struct{})
+	close(runInterrupted)
+
+	_, err := newTestLookup(t, server).isTxSent(testTxID(1))
+	if err != errInterrupted || isTransient(err) {
+		t.Errorf("expected the lookup to fail without retries once interrupted, got %v", err)
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("expected no request once interrupted, got %v", requests)
+	}
+	if sleepUnlessInterrupted(time.Minute) {
+		t.Error("expected the sleep to end with the interruption")
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	jobsDir       string
+	jobChunk      int
+	maxJobUpload  int64
+	maxQueuedJobs int
+	jobSink       string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&jobsDir, "jobs-dir", "",
+		"directory of the txid lists of the jobs and of their reports, a temporary directory removed when the "+
+			"server stops if not set")
+	serveCmd.Flags().IntVar(&jobChunk, "job-chunk", 10000,
+		"number of txids of a job checked at a time, the other requests are served between the chunks")
+	serveCmd.Flags().Int64Var(&maxJobUpload, "max-job-upload", 1<<30, "maximum size in bytes of the txid list of a job")
+	serveCmd.Flags().IntVar(&maxQueuedJobs, "max-queued-jobs", 16,
+		"maximum number of jobs waiting to be processed, more are refused until some are done")
+	serveCmd.Flags().StringVar(&jobSink, "job-sink", "",
+		"deliver the report of every finished job to an http(s) URL, POSTing it, or to s3://bucket/prefix, with the "+
+			"AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL of the "+
+			"environment")
+}
+
+// the statuses of a job
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// batchJob is a txid list checked in the background, chunk after chunk
+type batchJob struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	TxIDs  int    `json:"txids"`
+	Chunks int    `json:"chunks"`
+	// ChunksDone and the counts below grow as the chunks are checked
+	ChunksDone int `json:"chunks_done"`
+	Confirmed  int `json:"confirmed"`
+	Unsent     int `json:"unsent"`
+	Expired    int `json:"expired"`
+	// Other are the transactions of the other conditions, e.g. denied or outside-time-window
+	Other    int        `json:"other"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	// Delivered is set once the report was delivered to --job-sink
+	Delivered     bool   `json:"delivered,omitempty"`
+	DeliveryError string `json:"delivery_error,omitempty"`
+	// dir holds the chunks of the txid list, removed once checked, and the report
+	dir string
+}
+
+// reportFile returns the name of the report of the job
+func (j *batchJob) reportFile() string {
+	return filepath.Join(j.dir, "report.json")
+}
+
+// chunkFile returns the name of the i-th chunk of the txid list of the job
+func (j *batchJob) chunkFile(i int) string {
+	return filepath.Join(j.dir, fmt.Sprintf("chunk-%06d.txids", i))
+}
+
+// jobQueue holds the jobs of the server, processed one at a time in the order they were submitted
+type jobQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*batchJob
+	// pending are the jobs waiting to be processed, closed once the server stops
+	pending chan *batchJob
+	// stop is closed once the server stops, interrupting the job being processed between two chunks
+	stop chan struct{}
+	// done is closed once all the jobs were processed or failed
+	done chan struct{}
+	dir  string
+	// temporary is set if dir is removed when the server stops
+	temporary bool
+	sink      reportSink
+}
+
+// newJobQueue returns the queue of the jobs kept in --jobs-dir
+func newJobQueue() (*jobQueue, error) {
+	if jobChunk <= 0 {
+		return nil, fmt.Errorf("--job-chunk must be positive")
+	}
+	if maxQueuedJobs <= 0 {
+		return nil, fmt.Errorf("--max-queued-jobs must be positive")
+	}
+	sink, err := parseReportSink(jobSink)
+	if err != nil {
+		return nil, err
+	}
+	q := &jobQueue{
+		jobs:    map[string]*batchJob{},
+		pending: make(chan *batchJob, maxQueuedJobs),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		dir:     jobsDir,
+		sink:    sink,
+	}
+	if q.dir == "" {
+		q.dir, err = ioutil.TempDir("", "checktxstatus-jobs")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a directory for the jobs: %v", err)
+		}
+		q.temporary = true
+	} else if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create --jobs-dir %s: %v", q.dir, err)
+	}
+	return q, nil
+}
+
+// snapshot returns a copy of a job, to be encoded while it is processed
+func (q *jobQueue) snapshot(id string) (batchJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return batchJob{}, false
+	}
+	return *job, true
+}
+
+// update changes a job while it is processed
+func (q *jobQueue) update(job *batchJob, change func(job *batchJob)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(job)
+}
+
+// close stops processing the jobs, interrupting the one being processed and failing the queued ones
+// it must only be called once no more jobs are submitted
+func (q *jobQueue) close() {
+	close(q.stop)
+	close(q.pending)
+	<-q.done
+	if q.temporary {
+		if err := os.RemoveAll(q.dir); err != nil {
+			logMessage(log.ErrorLevel, msgJobRemoveFailed, q.dir, err)
+		}
+	}
+}
+
+// fail marks a job as failed
+func (q *jobQueue) fail(job *batchJob, err error) {
+	logEntryMessage(log.WithField("job", job.ID), log.ErrorLevel, msgJobFailed, err)
+	q.update(job, func(job *batchJob) {
+		now := time.Now()
+		job.Status, job.Error, job.Finished = jobFailed, err.Error(), &now
+	})
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a job ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// serveSubmitJob queues the txid list of the body of a POST /jobs, one txid per line, responding with the job
+// the list is split in chunks of --job-chunk txids as it is received, so it never has to fit in memory
+func (s *checkServer) serveSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST to submit the txid list of a job"))
+		return
+	}
+	id, err := newJobID()
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	job := &batchJob{ID: id, Status: jobQueued, Created: time.Now(), dir: filepath.Join(s.jobs.dir, id)}
+	err = os.Mkdir(job.dir, 0700)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("failed to create %s: %v", job.dir, err))
+		return
+	}
+	code, err := job.split(http.MaxBytesReader(w, r.Body, maxJobUpload))
+	if err != nil {
+		_ = os.RemoveAll(job.dir)
+		writeHTTPError(w, code, err)
+		return
+	}
+	s.jobs.mu.Lock()
+	select {
+	case s.jobs.pending <- job:
+		s.jobs.jobs[id] = job
+	default:
+		err = fmt.Errorf("%d jobs are already waiting, retry once some are done", maxQueuedJobs)
+	}
+	response := *job
+	s.jobs.mu.Unlock()
+	if err != nil {
+		_ = os.RemoveAll(job.dir)
+		writeHTTPError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	logEntryMessage(log.WithField("job", id), log.InfoLevel, msgJobQueued, job.TxIDs, job.Chunks)
+	w.Header().Set("Location", "/jobs/"+id)
+	writeHTTPJSON(w, http.StatusAccepted, response)
+}
+
+// split writes the txids of body to the chunks of the job, returning the status code of the response if it failed
+func (j *batchJob) split(body io.Reader) (int, error) {
+	scanner := bufio.NewScanner(body)
+	var chunk *os.File
+	var writer *bufio.Writer
+	closeChunk := func() error {
+		if chunk == nil {
+			return nil
+		}
+		err := writer.Flush()
+		if closeErr := chunk.Close(); err == nil {
+			err = closeErr
+		}
+		chunk = nil
+		if err != nil {
+			return fmt.Errorf("failed to write the chunk of a job: %v", err)
+		}
+		return nil
+	}
+	for line := 1; scanner.Scan(); line++ {
+		txid := strings.TrimSpace(scanner.Text())
+		if txid == "" {
+			continue
+		}
+		if !txchecker.IsTxID(txid) {
+			_ = closeChunk()
+			return http.StatusBadRequest, fmt.Errorf("line %d is not a txid: %q", line, txid)
+		}
+		if j.TxIDs%jobChunk == 0 {
+			if err := closeChunk(); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			var err error
+			chunk, err = os.OpenFile(j.chunkFile(j.Chunks), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return http.StatusInternalServerError, fmt.Errorf("failed to create the chunk of a job: %v", err)
+			}
+			writer = bufio.NewWriter(chunk)
+			j.Chunks++
+		}
+		_, _ = writer.WriteString(txid + "\n")
+		j.TxIDs++
+	}
+	if err := scanner.Err(); err != nil {
+		_ = closeChunk()
+		return http.StatusBadRequest, fmt.Errorf("invalid txid list: %v", err)
+	}
+	if err := closeChunk(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if j.TxIDs == 0 {
+		return http.StatusBadRequest, fmt.Errorf("the txid list is empty")
+	}
+	return 0, nil
+}
+
+// serveJob answers GET /jobs/<id> with the progress of a job, and GET /jobs/<id>/report with its report once done
+func (s *checkServer) serveJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET to follow a job"))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	report := strings.HasSuffix(id, "/report")
+	id = strings.TrimSuffix(id, "/report")
+	job, ok := s.jobs.snapshot(id)
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("no job %q", id))
+		return
+	}
+	if !report {
+		writeHTTPJSON(w, http.StatusOK, job)
+		return
+	}
+	if job.Status != jobDone {
+		writeHTTPError(w, http.StatusConflict, fmt.Errorf("job %s is %s, it has no report", id, job.Status))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, job.reportFile())
+}
+
+// processJobs processes the queued jobs one at a time until the queue is closed
+func (s *checkServer) processJobs() {
+	defer close(s.jobs.done)
+	for job := range s.jobs.pending {
+		select {
+		case <-s.jobs.stop:
+			s.jobs.fail(job, fmt.Errorf("the server stopped before the job was processed"))
+			continue
+		default:
+		}
+		err := s.processJob(job)
+		if err != nil {
+			s.jobs.fail(job, err)
+			continue
+		}
+		s.deliverJob(job)
+	}
+}
+
+// processJob checks the chunks of a job one at a time, appending their entries to the report of the job, and
+// releasing the server between two chunks so the other requests are not held for the whole job
+func (s *checkServer) processJob(job *batchJob) error {
+	logger := log.WithField("job", job.ID)
+	s.jobs.update(job, func(job *batchJob) {
+		now := time.Now()
+		job.Status, job.Started = jobRunning, &now
+	})
+	logEntryMessage(logger, log.InfoLevel, msgJobProcessing, job.TxIDs)
+	report, err := os.OpenFile(job.reportFile(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create the report of the job: %v", err)
+	}
+	writer := bufio.NewWriter(report)
+	err = s.checkChunks(job, writer)
+	if err == nil {
+		_, err = writer.WriteString("\n]\n")
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := report.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	s.jobs.update(job, func(job *batchJob) {
+		now := time.Now()
+		job.Status, job.Finished = jobDone, &now
+	})
+	logEntryMessage(logger, log.InfoLevel, msgJobDone, job.Confirmed, job.Unsent, job.Expired)
+	return nil
+}
+
+// checkChunks checks the chunks of a job, writing their entries as the elements of a JSON array to report
+func (s *checkServer) checkChunks(job *batchJob, report io.Writer) error {
+	_, err := io.WriteString(report, "[")
+	if err != nil {
+		return fmt.Errorf("failed to write the report of the job: %v", err)
+	}
+	for i := 0; i < job.Chunks; i++ {
+		select {
+		case <-s.jobs.stop:
+			return fmt.Errorf("the server stopped after %d of the %d chunks of the job", i, job.Chunks)
+		default:
+		}
+		chunk := job.chunkFile(i)
+		response, err := s.check([]string{chunk}, formatTxIDs)
+		if err != nil {
+			return err
+		}
+		if response.Error != "" {
+			return fmt.Errorf("chunk %d failed: %s", i, response.Error)
+		}
+		// the outputs of the chunk were read, only the report of the job is kept
+		outputs, _ := filepath.Glob(chunk + "*")
+		for _, output := range outputs {
+			_ = os.Remove(output)
+		}
+		counts := map[string]int{}
+		for n, entry := range response.Files[0].Transactions {
+			// the entries refer to the job, and to the position of their txids in its list
+			entry.File, entry.Index = job.ID, i*jobChunk+entry.Index
+			encoded, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("failed to encode the report of the job: %v", err)
+			}
+			separator := ",\n"
+			if i == 0 && n == 0 {
+				separator = "\n"
+			}
+			_, err = io.WriteString(report, separator+string(encoded))
+			if err != nil {
+				return fmt.Errorf("failed to write the report of the job: %v", err)
+			}
+			counts[entry.Classification]++
+		}
+		s.jobs.update(job, func(job *batchJob) {
+			job.ChunksDone++
+			job.Confirmed += counts[string(conditionConfirmed)]
+			job.Unsent += counts[string(conditionUnsent)]
+			job.Expired += counts[string(conditionExpired)]
+			job.Other += len(response.Files[0].Transactions) - counts[string(conditionConfirmed)] -
+				counts[string(conditionUnsent)] - counts[string(conditionExpired)]
+		})
+	}
+	return nil
+}
+
+// deliverJob delivers the report of a finished job to --job-sink, if set
+func (s *checkServer) deliverJob(job *batchJob) {
+	if s.jobs.sink == nil {
+		return
+	}
+	logger := log.WithField("job", job.ID)
+	err := s.lookup.backoff.retry(fmt.Sprintf("delivering the report of job %s to %s", job.ID, s.jobs.sink),
+		func() error {
+			return s.jobs.sink.deliver(job.ID, job.reportFile())
+		})
+	if err != nil {
+		logEntryMessage(logger, log.ErrorLevel, msgJobDeliveryFailed, s.jobs.sink, err)
+		s.jobs.update(job, func(job *batchJob) {
+			job.DeliveryError = err.Error()
+		})
+		return
+	}
+	logEntryMessage(logger, log.InfoLevel, msgJobDelivered, s.jobs.sink)
+	s.jobs.update(job, func(job *batchJob) {
+		job.Delivered = true
+	})
+}
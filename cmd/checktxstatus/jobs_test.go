@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestJobQueue returns a queue of jobs split in chunks of chunk txids, holding at most queued jobs
+func newTestJobQueue(t *testing.T, chunk int, queued int) *jobQueue {
+	dir, oldChunk, oldQueued := jobsDir, jobChunk, maxQueuedJobs
+	t.Cleanup(func() { jobsDir, jobChunk, maxQueuedJobs = dir, oldChunk, oldQueued })
+	jobsDir, jobChunk, maxQueuedJobs = testDir(t), chunk, queued
+	q, err := newJobQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestServeSubmitJobRefusesWhenTheQueueIsFull(t *testing.T) {
+	s := &checkServer{jobs: newTestJobQueue(t, 10, 1)}
+	codes := []int{http.StatusAccepted, http.StatusServiceUnavailable}
+	for i, code := range codes {
+		w := httptest.NewRecorder()
+		s.serveSubmitJob(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(testTxID(byte(i))+"\n")))
+		if w.Code != code {
+			t.Errorf("expected job %d to get %d, got %d: %s", i, code, w.Code, w.Body)
+		}
+	}
+	if len(s.jobs.jobs) != 1 {
+		t.Errorf("expected only the queued job to be kept, got %d", len(s.jobs.jobs))
+	}
+
+	w := httptest.NewRecorder()
+	s.serveSubmitJob(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("not-a-txid\n")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid txid to be refused, got %d", w.Code)
+	}
+}
+
+func TestJobReportIsAnArrayAcrossTheChunks(t *testing.T) {
+	s := newTestCheckServer(t, testTxID(1))
+	s.jobs = newTestJobQueue(t, 2, 4)
+	defer func(code int) { exitCode = code }(exitCode)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(testTxID(0) + "\n" + testTxID(1) + "\n\n" + testTxID(2) + "\n")
+	s.serveSubmitJob(w, httptest.NewRequest(http.MethodPost, "/jobs", body))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the job to be queued, got %d: %s", w.Code, w.Body)
+	}
+	var queued batchJob
+	if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil || queued.TxIDs != 3 || queued.Chunks != 2 {
+		t.Fatalf("expected 3 txids in 2 chunks, got %+v, %v", queued, err)
+	}
+
+	w = httptest.NewRecorder()
+	s.serveJob(w, httptest.NewRequest(http.MethodGet, "/jobs/"+queued.ID+"/report", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected no report of a queued job, got %d", w.Code)
+	}
+
+	close(s.jobs.pending)
+	s.processJobs()
+	job, _ := s.jobs.snapshot(queued.ID)
+	if job.Status != jobDone || job.ChunksDone != 2 || job.Confirmed != 1 || job.Unsent != 2 {
+		t.Fatalf("expected the job done with 1 confirmed and 2 unsent, got %+v", job)
+	}
+	w = httptest.NewRecorder()
+	s.serveJob(w, httptest.NewRequest(http.MethodGet, "/jobs/"+queued.ID+"/report", nil))
+	content, _ := ioutil.ReadAll(w.Body)
+	var entries []txReportEntry
+	if err := json.Unmarshal(content, &entries); err != nil || len(entries) != 3 {
+		t.Fatalf("expected a JSON array of the 3 txids, got %v: %s", err, content)
+	}
+	for i, entry := range entries {
+		if entry.TxID != testTxID(byte(i)) || entry.Index != i || entry.File != queued.ID {
+			t.Errorf("expected entry %d to be the txid at its index in the job, got %+v", i, entry)
+		}
+	}
+}
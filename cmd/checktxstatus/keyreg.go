@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// isOfflineKeyreg returns true if the keyreg transaction takes its sender offline (or marks it nonparticipating)
+func isOfflineKeyreg(txn types.Transaction) bool {
+	return txn.Nonparticipation || txn.VotePK == types.VotePK{}
+}
+
+// reportUnsentKeyregs logs the participation key validity window of every unsent keyreg transaction
+// along with the current status of its sender, and warns when resubmitting it would have unexpected effects:
+// registering participation keys that already expired, taking an online account offline, or replacing
+// participation keys that were registered since the transaction was created
+// the keyregs whose sender fails to be looked up are skipped with a warning, it only fails once the run must stop
+func reportUnsentKeyregs(filename string, txs []txRecord, indexerClient *indexer.Client, backoff *backoffPolicy) error {
+	for _, rec := range txs {
+		tx := rec.stx
+		if tx.Txn.Type != types.KeyRegistrationTx {
+			continue
+		}
+		txID := rec.txID
+		sender := tx.Txn.Sender.String()
+		logger := log.WithFields(log.Fields{"file": filename, "txid": txID, "sender": sender})
+
+		requestID, headers := nextRequest()
+		var currentRound uint64
+		var account models.Account
+		err := backoff.retry(fmt.Sprintf("looking up account %s, request %s,", sender, requestID), func() error {
+			if err := budget.spend(requestAccount); err != nil {
+				return err
+			}
+			var err error
+			currentRound, account, err = indexerClient.LookupAccountByID(sender).
+				Exclude([]string{"all"}).Do(context.Background(), headers...)
+			return err
+		})
+		if err == errBudgetExhausted || err == errInterrupted {
+			return err
+		}
+		if err != nil {
+			// the account only adds context to the keyreg, the other keyregs are still reported
+			logEntryMessage(logger, log.WarnLevel, msgKeyregAccountFailed, txID, sender, requestID, err)
+			continue
+		}
+		online := account.Status == "Online"
+
+		if isOfflineKeyreg(tx.Txn) {
+			logEntryMessage(logger, log.InfoLevel, msgKeyregOffline, account.Status)
+			if online {
+				logEntryMessage(logger, log.WarnLevel, msgKeyregTakesOffline, txID, sender)
+			}
+			continue
+		}
+
+		logEntryMessage(logger, log.InfoLevel, msgKeyregOnline, tx.Txn.VoteFirst, tx.Txn.VoteLast, account.Status,
+			currentRound)
+		if uint64(tx.Txn.VoteLast) < currentRound {
+			logEntryMessage(logger, log.WarnLevel, msgKeyregExpiredKeys, txID, tx.Txn.VoteLast, sender)
+		}
+		registered := account.Participation.VoteParticipationKey
+		if online && len(registered) != 0 && !bytes.Equal(registered, tx.Txn.VotePK[:]) {
+			logEntryMessage(logger, log.WarnLevel, msgKeyregDifferentKeys, sender, account.Participation.VoteFirstValid,
+				account.Participation.VoteLastValid, txID)
+		}
+	}
+	return nil
+}
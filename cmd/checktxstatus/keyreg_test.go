@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestAccountIndexer returns an indexer client answering every account lookup with the given status
+// and registered participation key at round 1000
+func newTestAccountIndexer(t *testing.T, status string, votePK types.VotePK) *indexer.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"current-round":1000,"account":{"status":%q,"participation":`+
+			`{"vote-participation-key":%q,"vote-first-valid":1,"vote-last-valid":2000}}}`,
+			status, base64.StdEncoding.EncodeToString(votePK[:]))
+	}))
+	t.Cleanup(server.Close)
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestIsOfflineKeyreg(t *testing.T) {
+	if !isOfflineKeyreg(types.Transaction{Type: types.KeyRegistrationTx}) {
+		t.Error("expected a keyreg without participation keys to be offline")
+	}
+	if !isOfflineKeyreg(types.Transaction{Type: types.KeyRegistrationTx,
+		KeyregTxnFields: types.KeyregTxnFields{VotePK: types.VotePK{1}, Nonparticipation: true}}) {
+		t.Error("expected a nonparticipating keyreg to be offline")
+	}
+	if isOfflineKeyreg(types.Transaction{Type: types.KeyRegistrationTx,
+		KeyregTxnFields: types.KeyregTxnFields{VotePK: types.VotePK{1}}}) {
+		t.Error("expected a keyreg with participation keys to be online")
+	}
+}
+
+func TestReportUnsentKeyregsWarnings(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	online := func(votePK types.VotePK, voteLast uint64) txRecord {
+		return txRecord{stx: types.SignedTxn{Txn: types.Transaction{Type: types.KeyRegistrationTx,
+			KeyregTxnFields: types.KeyregTxnFields{VotePK: votePK, VoteFirst: 1, VoteLast: types.Round(voteLast)}}}}
+	}
+	offline := txRecord{stx: types.SignedTxn{Txn: types.Transaction{Type: types.KeyRegistrationTx}}}
+	tests := []struct {
+		status string
+		tx     txRecord
+		warn   string
+	}{
+		{"Online", offline, "take the currently online"},
+		{"Offline", offline, ""},
+		{"Offline", online(types.VotePK{1}, 500), "expired at round 500"},
+		{"Online", online(types.VotePK{2}, 2000), "would replace them"},
+		{"Online", online(types.VotePK{1}, 2000), ""},
+	}
+	for i, test := range tests {
+		hook.Reset()
+		client := newTestAccountIndexer(t, test.status, types.VotePK{1})
+		if err := reportUnsentKeyregs("test", []txRecord{test.tx}, client, newTestBackoff()); err != nil {
+			t.Fatal(err)
+		}
+		var warnings []string
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == log.WarnLevel {
+				warnings = append(warnings, entry.Message)
+			}
+		}
+		if test.warn == "" && len(warnings) != 0 {
+			t.Errorf("test %d: expected no warnings, got %v", i, warnings)
+		}
+		if test.warn != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], test.warn)) {
+			t.Errorf("test %d: expected a warning about %q, got %v", i, test.warn, warnings)
+		}
+	}
+}
+
+func TestReportUnsentKeyregsSkipsFailedLookups(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "account lookups are disabled", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyreg := txRecord{txID: testTxID(1), stx: types.SignedTxn{Txn: types.Transaction{Type: types.KeyRegistrationTx}}}
+	if err := reportUnsentKeyregs("test", []txRecord{keyreg, keyreg}, client, newTestBackoff()); err != nil {
+		t.Fatalf("expected the failed lookups to be skipped, got %v", err)
+	}
+	skipped := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == log.WarnLevel && strings.Contains(entry.Message, "skipping keyreg tx") {
+			skipped++
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("expected a warning for each of the 2 keyregs, got %d", skipped)
+	}
+}
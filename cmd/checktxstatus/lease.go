@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/base64"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// leaseKey identifies the transactions of a sender holding the same lease
+type leaseKey struct {
+	sender types.Address
+	lease  [32]byte
+}
+
+// findLeaseCollisions returns clusters of distinct transactions of the same sender with the same lease whose validity
+// windows overlap; the ledger accepts only one of them, the others are rejected for as long as the lease is held
+func findLeaseCollisions(records []txRecord) [][]txRecord {
+	byKey := map[leaseKey][]txRecord{}
+	for _, rec := range records {
+		txn := rec.stx.Txn
+		if rec.txIDOnly || txn.Lease == ([32]byte{}) {
+			continue
+		}
+		key := leaseKey{sender: txn.Sender, lease: txn.Lease}
+		byKey[key] = append(byKey[key], rec)
+	}
+	var clusters [][]txRecord
+	for _, txs := range byKey {
+		clusters = append(clusters, overlappingClusters(txs)...)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i][0].index < clusters[j][0].index
+	})
+	return clusters
+}
+
+// leaseCollisions maps the txids of the transactions with colliding leases to the txids of the other transactions of
+// their clusters
+type leaseCollisions map[string][]string
+
+// warnLeaseCollisions logs a warning for every cluster of transactions of the file with colliding leases, and returns
+// the collisions of every transaction for the report
+func warnLeaseCollisions(filename string, records []txRecord) leaseCollisions {
+	logger := log.WithField("file", filename)
+	collisions := leaseCollisions{}
+	for _, cluster := range findLeaseCollisions(records) {
+		txn := cluster[0].stx.Txn
+		txIDs := distinctTxIDs(cluster)
+		logEntryMessage(logger, log.WarnLevel, msgLeaseCollision, txn.Sender,
+			base64.StdEncoding.EncodeToString(txn.Lease[:]), strings.Join(txIDs, ", "))
+		for i, txID := range txIDs {
+			others := append(append([]string{}, txIDs[:i]...), txIDs[i+1:]...)
+			collisions[txID] = append(collisions[txID], others...)
+		}
+	}
+	return collisions
+}
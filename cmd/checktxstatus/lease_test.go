@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindLeaseCollisions(t *testing.T) {
+	tx := func(txID string, sender byte, lease byte, firstValid, lastValid uint64, index int) txRecord {
+		return txRecord{txID: txID, index: index, stx: types.SignedTxn{Txn: types.Transaction{Header: types.Header{
+			Sender: types.Address{sender}, Lease: [32]byte{lease}, FirstValid: types.Round(firstValid),
+			LastValid: types.Round(lastValid)}}}}
+	}
+	records := []txRecord{
+		tx("a", 1, 1, 100, 200, 0),
+		tx("b", 1, 1, 150, 250, 1),
+		// a later window of the same lease, and the same lease of another sender
+		tx("c", 1, 1, 300, 400, 2),
+		tx("d", 2, 1, 100, 200, 3),
+		// the same transaction twice is not a collision
+		tx("e", 3, 1, 100, 200, 4),
+		tx("e", 3, 1, 100, 200, 5),
+		tx("f", 1, 0, 100, 200, 6),
+	}
+	clusters := findLeaseCollisions(records)
+	if len(clusters) != 1 || len(clusters[0]) != 2 || clusters[0][0].txID != "a" || clusters[0][1].txID != "b" {
+		t.Errorf("expected a single collision of a and b, got %+v", clusters)
+	}
+}
+
+// writeLeasedBatch writes a file of two signed payments holding the same lease in overlapping validity windows
+func writeLeasedBatch(t *testing.T, dir string) string {
+	var raw []byte
+	for i := 0; i < 2; i++ {
+		stx := types.SignedTxn{Sig: types.Signature{1}, Txn: types.Transaction{Type: types.PaymentTx,
+			Header: types.Header{Fee: 1000, FirstValid: types.Round(990 + i), LastValid: 1990,
+				GenesisID: checkertest.GenesisID, Lease: [32]byte{1}},
+			PaymentTxnFields: types.PaymentTxnFields{Amount: types.MicroAlgos(i)}}}
+		raw = append(raw, msgpack.Encode(stx)...)
+	}
+	file := filepath.Join(dir, "batch.tx.unsent")
+	if err := ioutil.WriteFile(file, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestSubmitRefusesCollidingLeases(t *testing.T) {
+	file := writeLeasedBatch(t, testDir(t))
+	server := checkertest.NewServer()
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &submitter{client: client, backoff: &backoffPolicy{}, out: ioutil.Discard}
+
+	err = s.submitFile(file)
+	if err == nil || !strings.Contains(err.Error(), "colliding leases") || len(server.Submitted()) != 0 {
+		t.Errorf("expected the file to be refused without submitting anything, got %v", err)
+	}
+	defer func() { submitForce = false }()
+	submitForce = true
+	// the node accepts both, the ledger would only confirm one of them
+	if err := s.submitFile(file); err != nil || len(server.Submitted()) != 2 {
+		t.Errorf("expected --force to submit both transactions, got %v", err)
+	}
+}
+
+func TestReportListsLeaseCollisions(t *testing.T) {
+	batch, err := readBatch(writeLeasedBatch(t, testDir(t)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{leases: warnLeaseCollisions("batch.tx", batch.individual)}
+	entries := c.reportEntries(string(conditionUnsent), batch.individual)
+	if len(entries) != 2 || len(entries[0].LeaseCollisions) != 1 || entries[0].LeaseCollisions[0] != entries[1].TxID {
+		t.Errorf("expected every transaction to list the other one, got %+v", entries)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// protocol limits enforced by the network when transactions are broadcast, as of consensus version 31
+const (
+	maxTxGroupSize           = 16
+	maxTxnNoteBytes          = 1024
+	maxAppArgs               = 16
+	maxAppTotalArgLen        = 2048
+	maxAppTxnAccounts        = 4
+	maxAppTxnForeignApps     = 8
+	maxAppTxnForeignAssets   = 8
+	maxAppTotalTxnReferences = 8
+	maxAppProgramLen         = 2048
+	maxExtraAppProgramPages  = 3
+	maxLogicSigSize          = 1000
+)
+
+// txLimitViolations returns descriptions of the protocol limits the transaction violates
+func txLimitViolations(stx types.SignedTxn) []string {
+	var violations []string
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+	txn := stx.Txn
+
+	if len(txn.Note) > maxTxnNoteBytes {
+		violate("note of %d bytes exceeds %d bytes", len(txn.Note), maxTxnNoteBytes)
+	}
+
+	lsigSize := len(stx.Lsig.Logic)
+	for _, arg := range stx.Lsig.Args {
+		lsigSize += len(arg)
+	}
+	if lsigSize > maxLogicSigSize {
+		violate("logicsig program and arguments of %d bytes exceed %d bytes", lsigSize, maxLogicSigSize)
+	}
+
+	switch txn.Type {
+	case types.AssetConfigTx:
+		params := txn.AssetParams
+		if len(params.AssetName) > types.AssetNameMaxLen {
+			violate("asset name of %d bytes exceeds %d bytes", len(params.AssetName), types.AssetNameMaxLen)
+		}
+		if len(params.UnitName) > types.AssetUnitNameMaxLen {
+			violate("asset unit name of %d bytes exceeds %d bytes", len(params.UnitName), types.AssetUnitNameMaxLen)
+		}
+		if len(params.URL) > types.AssetURLMaxLen {
+			violate("asset URL of %d bytes exceeds %d bytes", len(params.URL), types.AssetURLMaxLen)
+		}
+	case types.ApplicationCallTx:
+		if len(txn.ApplicationArgs) > maxAppArgs {
+			violate("%d application arguments exceed %d", len(txn.ApplicationArgs), maxAppArgs)
+		}
+		argsLen := 0
+		for _, arg := range txn.ApplicationArgs {
+			argsLen += len(arg)
+		}
+		if argsLen > maxAppTotalArgLen {
+			violate("application arguments of %d bytes exceed %d bytes", argsLen, maxAppTotalArgLen)
+		}
+		if len(txn.Accounts) > maxAppTxnAccounts {
+			violate("%d foreign accounts exceed %d", len(txn.Accounts), maxAppTxnAccounts)
+		}
+		if len(txn.ForeignApps) > maxAppTxnForeignApps {
+			violate("%d foreign applications exceed %d", len(txn.ForeignApps), maxAppTxnForeignApps)
+		}
+		if len(txn.ForeignAssets) > maxAppTxnForeignAssets {
+			violate("%d foreign assets exceed %d", len(txn.ForeignAssets), maxAppTxnForeignAssets)
+		}
+		references := len(txn.Accounts) + len(txn.ForeignApps) + len(txn.ForeignAssets)
+		if references > maxAppTotalTxnReferences {
+			violate("%d total references exceed %d", references, maxAppTotalTxnReferences)
+		}
+		if txn.ExtraProgramPages > maxExtraAppProgramPages {
+			violate("%d extra program pages exceed %d", txn.ExtraProgramPages, maxExtraAppProgramPages)
+		}
+		programsLen := len(txn.ApprovalProgram) + len(txn.ClearStateProgram)
+		maxProgramsLen := maxAppProgramLen * (1 + int(txn.ExtraProgramPages))
+		if programsLen > maxProgramsLen {
+			violate("programs of %d bytes exceed %d bytes", programsLen, maxProgramsLen)
+		}
+	}
+	return violations
+}
+
+// findLimitViolations returns descriptions of all protocol limits violated by the transactions read from a file
+// such transactions would be rejected by the network if broadcast
+func findLimitViolations(groups map[types.Digest][]txRecord, individualTxs []txRecord) []string {
+	var violations []string
+	for _, txs := range groupsInOrder(groups) {
+		if len(txs) > maxTxGroupSize {
+			violations = append(violations, fmt.Sprintf("group %s has %d transactions, more than the allowed %d",
+				groupIDString(txs[0].stx.Txn.Group), len(txs), maxTxGroupSize))
+		}
+	}
+	for _, rec := range allRecords(groups, individualTxs) {
+		for _, violation := range txLimitViolations(rec.stx) {
+			violations = append(violations, fmt.Sprintf("tx %s: %s", rec.txID, violation))
+		}
+	}
+	return violations
+}
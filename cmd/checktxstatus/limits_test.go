@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestTxLimitViolations(t *testing.T) {
+	var stx types.SignedTxn
+	stx.Txn.Type = types.ApplicationCallTx
+	stx.Txn.Note = make([]byte, maxTxnNoteBytes+1)
+	stx.Txn.Accounts = make([]types.Address, maxAppTxnAccounts)
+	stx.Txn.ForeignAssets = make([]types.AssetIndex, maxAppTxnForeignAssets)
+	stx.Txn.ExtraProgramPages = 1
+	stx.Txn.ApprovalProgram = make([]byte, 2*maxAppProgramLen)
+	expected := []string{"note of 1025 bytes", "12 total references"}
+	violations := txLimitViolations(stx)
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %v", len(expected), violations)
+	}
+	for i, violation := range violations {
+		if !strings.Contains(violation, expected[i]) {
+			t.Errorf("expected violation %d to be about %q, got %q", i, expected[i], violation)
+		}
+	}
+	stx.Txn.ClearStateProgram = []byte{1}
+	if violations := txLimitViolations(stx); len(violations) != 3 {
+		t.Errorf("expected the programs to exceed their extra page, got %v", violations)
+	}
+}
+
+func TestFindLimitViolationsOfGroups(t *testing.T) {
+	var group []txRecord
+	for i := 0; i <= maxTxGroupSize; i++ {
+		group = append(group, txRecord{txID: testTxID(byte(i)), index: i})
+	}
+	violations := findLimitViolations(map[types.Digest][]txRecord{{1}: group}, nil)
+	if len(violations) != 1 || !strings.Contains(violations[0], "has 17 transactions") {
+		t.Errorf("expected the oversized group to be reported, got %v", violations)
+	}
+}
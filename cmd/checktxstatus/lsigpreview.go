@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var previewLogicSigs bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&previewLogicSigs, "preview-logicsigs", false,
+		"evaluate the logicsigs of the unsent transactions with a dryrun on the --algod-addr node, which needs its "+
+			"developer API, warning about the ones that would reject their transactions on resubmission and adding "+
+			"the outcome to the --report")
+}
+
+// logicSigPreview is the outcome of evaluating the logicsig of an unsent transaction, in the report
+type logicSigPreview struct {
+	Approved bool `json:"approved"`
+	// Messages are the messages of the evaluation, PASS or REJECT followed by the error it failed with, if any
+	Messages []string `json:"messages,omitempty"`
+}
+
+// dryrunResponse is the part of the algod dryrun response the previews are read from
+type dryrunResponse struct {
+	Error string `json:"error"`
+	Txns  []struct {
+		LogicSigMessages []string `json:"logic-sig-messages"`
+	} `json:"txns"`
+}
+
+// logicSigPreviewer evaluates the logicsigs of unsent transactions on an algod node
+// the programs are evaluated against the current state of the node, as they would be on resubmission
+type logicSigPreviewer struct {
+	client  *http.Client
+	backoff *backoffPolicy
+	mu      sync.Mutex
+	// previews are the outcomes of the evaluated transactions by txid until they are reported
+	previews map[string]*logicSigPreview
+}
+
+// initLogicSigPreviewer returns a previewer if --preview-logicsigs is set, nil otherwise
+func initLogicSigPreviewer(backoff *backoffPolicy) (*logicSigPreviewer, error) {
+	if !previewLogicSigs {
+		return nil, nil
+	}
+	if algodAddress == "" {
+		return nil, fmt.Errorf("--preview-logicsigs needs the algod node to evaluate on, please supply --algod-addr")
+	}
+	return &logicSigPreviewer{
+		client:   &http.Client{Timeout: 30 * time.Second, Transport: clientTransports[algodTransport]},
+		backoff:  backoff,
+		previews: map[string]*logicSigPreview{},
+	}, nil
+}
+
+// preview evaluates the logicsigs of the units of the unsent transactions, logging the ones that would be rejected
+// a failed evaluation is logged and does not fail the check
+func (p *logicSigPreviewer) preview(filename string, unsent []txRecord) {
+	if p == nil {
+		return
+	}
+	logger := log.WithField("file", filename)
+	rejected := 0
+	for _, unit := range submissionUnits(unsent) {
+		if !hasLogicSig(unit) {
+			continue
+		}
+		previews, err := p.dryrun(unit)
+		if err != nil {
+			logEntryMessage(logger, log.WarnLevel, msgLsigEvalFailed, unit[0].txID, filename, err)
+			continue
+		}
+		for i, tx := range unit {
+			preview := previews[i]
+			if preview == nil {
+				continue
+			}
+			if !preview.Approved {
+				rejected++
+				logEntryMessage(logger, log.WarnLevel, msgLsigRejects, tx.txID, strings.Join(preview.Messages, ", "))
+			}
+			if !writeReport {
+				continue
+			}
+			p.mu.Lock()
+			p.previews[tx.txID] = preview
+			p.mu.Unlock()
+		}
+	}
+	if rejected != 0 {
+		logEntryMessage(logger, log.WarnLevel, msgLsigRejectsSummary, rejected, filename)
+	}
+}
+
+// take returns the outcome of evaluating the logicsig of txid and forgets it, nil if it was not evaluated
+func (p *logicSigPreviewer) take(txid string) *logicSigPreview {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	preview := p.previews[txid]
+	delete(p.previews, txid)
+	return preview
+}
+
+// hasLogicSig returns true if a transaction of a unit is signed by a logicsig
+func hasLogicSig(unit []txRecord) bool {
+	for _, tx := range unit {
+		if !tx.txIDOnly && !tx.stx.Lsig.Blank() {
+			return true
+		}
+	}
+	return false
+}
+
+// dryrun evaluates a unit with the dryrun endpoint of the node, the whole unit is sent so the programs can inspect
+// the other transactions of their group
+// it returns the previews of the transactions of the unit in order, nil for the ones without a logicsig
+func (p *logicSigPreviewer) dryrun(unit []txRecord) ([]*logicSigPreview, error) {
+	request, err := dryrunRequest(unit)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(request)
+	requestID, headers := nextRequest()
+	log.Debugf("evaluating the logicsigs of tx %s, request %s", unit[0].txID, requestID)
+	var resp dryrunResponse
+	err = postAlgodMsgpack(p.client, p.backoff, fmt.Sprintf("evaluating tx %s, request %s,", unit[0].txID, requestID),
+		"/v2/teal/dryrun", request, headers, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("algod request %s: %v", requestID, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("algod request %s: %s", requestID, resp.Error)
+	}
+	if len(resp.Txns) != len(unit) {
+		return nil, fmt.Errorf("algod request %s: the response has %d transactions, expected %d", requestID,
+			len(resp.Txns), len(unit))
+	}
+	previews := make([]*logicSigPreview, len(unit))
+	for i, tx := range unit {
+		if tx.txIDOnly || tx.stx.Lsig.Blank() {
+			continue
+		}
+		messages := resp.Txns[i].LogicSigMessages
+		previews[i] = &logicSigPreview{
+			Approved: len(messages) != 0 && messages[0] == "PASS",
+			Messages: messages,
+		}
+	}
+	return previews, nil
+}
+
+// dryrunRequest encodes the dryrun request of a unit in msgpack, embedding the signed transactions as they were read
+// so their logicsigs are evaluated as they are: {"txns": [...]}
+func dryrunRequest(unit []txRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(0x81)
+	writeMsgpackString(&buf, "txns")
+	// array 16 is needed for groups of 16 transactions
+	buf.Write([]byte{0xdc, byte(len(unit) >> 8), byte(len(unit))})
+	for _, tx := range unit {
+		encoded, err := encodeTxRecord(tx)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreviewReportsRejectedLogicSigs(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != "/v2/teal/dryrun" || msgpack.Decode(body, &request) != nil {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"txns":[{"logic-sig-messages":["REJECT","rejected by logic"]},{}]}`)
+	}))
+	defer server.Close()
+	defer func(addr string, report bool) { algodAddress, writeReport = addr, report }(algodAddress, writeReport)
+	algodAddress, writeReport = server.URL, true
+
+	record := func(b byte, stx types.SignedTxn) txRecord {
+		stx.Txn.Group = types.Digest{1}
+		return txRecord{txID: testTxID(b), stx: stx, raw: msgpack.Encode(stx)}
+	}
+	lsig := record(1, types.SignedTxn{Lsig: types.LogicSig{Logic: []byte{0x01, 0x20, 0x01, 0x00, 0x22}},
+		Txn: types.Transaction{Type: types.PaymentTx}})
+	payment := record(2, types.SignedTxn{Sig: types.Signature{1}, Txn: types.Transaction{Type: types.PaymentTx}})
+	p := &logicSigPreviewer{client: server.Client(), backoff: newTestBackoff(), previews: map[string]*logicSigPreview{}}
+	p.preview("batch.tx", []txRecord{lsig, payment})
+
+	if txns, ok := request["txns"].([]interface{}); !ok || len(txns) != 2 {
+		t.Fatalf("expected the whole group in the dryrun request, got %v", request)
+	}
+	preview := p.take(testTxID(1))
+	if preview == nil || preview.Approved || len(preview.Messages) != 2 {
+		t.Errorf("expected the logicsig to be rejected, got %+v", preview)
+	}
+	if p.take(testTxID(2)) != nil {
+		t.Error("expected no preview for the transaction without a logicsig")
+	}
+}
@@ -1,18 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
 	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
-	"github.com/algorand/go-algorand-sdk/crypto"
-	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
 	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"io"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 var (
@@ -20,12 +23,18 @@ var (
 
 	indexerAddress string
 	indexerToken   string
+
+	deterministic bool
+
+	// exitCode is the exit code of the process, set according to the classification policy
+	exitCode int
 )
 
 // setLogger sets the logger level based on the value from --log-level
 // currently excepts INFO and DEBUG, defaults to WARN
+// timestamps are omitted in deterministic mode
 func setLogger(logLevelStr string) {
-	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	log.SetFormatter(&log.TextFormatter{FullTimestamp: true, DisableTimestamp: deterministic})
 	logLevelStr = strings.ToLower(logLevelStr)
 	logLevel := log.WarnLevel
 	if logLevelStr == "debug" {
@@ -40,19 +49,73 @@ func init() {
 	rootCmd.Flags().StringVar(&logLevelStr, "log-level", "INFO", "log level: INFO or DEBUG")
 	rootCmd.Flags().StringVar(&indexerAddress, "idx-addr", os.Getenv("AF_IDX_ADDRESS"), "address of the indexer client")
 	rootCmd.Flags().StringVar(&indexerToken, "idx-tkn", os.Getenv("AF_IDX_TOKEN"), "API token of the indexer client")
+	rootCmd.Flags().BoolVar(&deterministic, "deterministic", false,
+		"produce byte-identical outputs for identical inputs: keep input order and omit timestamps")
 
 }
 
+// validateFlags checks for invalid combinations of flags
+func validateFlags() error {
+	if strict && permissive {
+		return newUserError(msgStrictPermissive)
+	}
+	err := validateMaxMemory()
+	if err != nil {
+		return err
+	}
+	err = validateInputFormat()
+	if err != nil {
+		return err
+	}
+	err = validateResume()
+	if err != nil {
+		return err
+	}
+	err = validateGroupPolicy()
+	if err != nil {
+		return err
+	}
+	err = validateAsOfRound()
+	if err != nil {
+		return err
+	}
+	err = validateOutputFormat()
+	if err != nil {
+		return err
+	}
+	err = validateStdout()
+	if err != nil {
+		return err
+	}
+	err = validateWatch()
+	if err != nil {
+		return err
+	}
+	err = validateGraph()
+	if err != nil {
+		return err
+	}
+	err = validateOffline()
+	if err != nil {
+		return err
+	}
+	err = validateCompression()
+	if err != nil {
+		return err
+	}
+	return validateEncryptionFlags()
+}
+
 // initIndexerClient inits an indexer client
 // indexerAddress comes from --idx-addr flag or AF_IDX_ADDRESS environment variable
 // indexerToken comes from --idx-tkn flag or AF_IDX_TOKEN environment variable
 func initIndexerClient(indexerAddress, indexerToken string) (*indexer.Client, error) {
 
 	if indexerAddress == "" {
-		return nil, fmt.Errorf("please supply an indexer client address using --idx-addr flag or AF_IDX_ADDRESS environment variable")
+		return nil, newUserError(msgMissingIndexer)
 	}
 
-	indexerClient, err := indexer.MakeClient(indexerAddress, indexerToken)
+	indexerClient, err := indexer.MakeClientWithHeaders(indexerAddress, indexerToken, clientHeaders(indexerTransport))
 	if err != nil {
 		return nil, fmt.Errorf("failed creating the indexer client: %v", err)
 	}
@@ -60,170 +123,1249 @@ func initIndexerClient(indexerAddress, indexerToken string) (*indexer.Client, er
 	return indexerClient, nil
 }
 
-// readTxFile reads and decodes trnsactions from a file, separating them to groups and individual transactions
+// txRecord is a transaction read from a transactions file
+type txRecord struct {
+	stx  types.SignedTxn
+	txID string
+	// file is the input file the transaction was read from
+	file string
+	// fileIndex is the position of the transaction in file
+	fileIndex int
+	// index is the position of the transaction in its batch, which is the same as fileIndex unless the batch is a
+	// directory of files
+	index int
+	// unknown is set for transactions of unknown types or with unknown fields, accepted in permissive mode
+	unknown bool
+	// raw is the original encoding of the signed transaction, written to output files as is
+	// stx may not hold all of its fields
+	raw []byte
+	// spilled locates raw in the spill file once it was spilled to keep under --max-memory
+	spilled spillRef
+	// txIDOnly is set for transactions read from a txid list, which have no stx and can be checked but not resubmitted
+	txIDOnly bool
+}
+
+// recordSource locates a transaction in the input files, so outputs and reports can be traced back to it
+type recordSource struct {
+	File  string `json:"file"`
+	Index int    `json:"index"`
+	TxID  string `json:"txid,omitempty"`
+}
+
+// source returns the position of the transaction in the input files
+func (rec txRecord) source() recordSource {
+	return recordSource{File: rec.file, Index: rec.fileIndex, TxID: rec.txID}
+}
+
+// recordSources returns the positions of the transactions in the input files, in the same order
+func recordSources(txs []txRecord) []recordSource {
+	sources := make([]recordSource, 0, len(txs))
+	for _, tx := range txs {
+		sources = append(sources, tx.source())
+	}
+	return sources
+}
+
+// txBatch holds the transactions read from an input file or directory, separated to groups and individual transactions
+type txBatch struct {
+	groups     map[types.Digest][]txRecord
+	individual []txRecord
+	// corrupt are the records that could not be decoded as transactions, in permissive mode
+	corrupt []skippedTx
+	// count is the number of records read
+	count int
+}
+
+// groupIDString returns the base64 encoding of a group ID, as shown by goal and the indexer
+func groupIDString(gid types.Digest) string {
+	return base64.StdEncoding.EncodeToString(gid[:])
+}
+
+// readTxFile reads and decodes trnsactions from a file with txchecker.ReadTransactions, adding them to the batch
+// the format of the file is detected unless --input-format is set
 // it assumes groups of transactions appear consecutively and does not validate them
-func readTxFile(filename string) (map[types.Digest][]types.SignedTxn, []types.SignedTxn, error) {
-	file, err := os.Open(filename)
+// in permissive mode records that can not be decoded as transactions are skipped rather than failing
+// decoded is called with every decoded transaction and whether it is the first of its group, if it is not nil
+func readTxFile(filename string, batch *txBatch, decoded func(rec txRecord, firstOfGroup bool)) error {
+	file, compressed, err := openTxInput(filename)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error while opening %s: %v", filename, err)
+		return fmt.Errorf("error while opening %s: %v", filename, err)
 	}
 	// no need to check error on close when reading file
 	defer file.Close()
 
 	logger := log.WithField("file", filename)
+	if compressed != "" {
+		logger.Debugf("decompressing %s input", compressed)
+	}
 
-	dec := msgpack.NewDecoder(file)
-
-	groups := map[types.Digest][]types.SignedTxn{}
-	var individualTxs []types.SignedTxn
-
-	for {
-		var stx types.SignedTxn
-		err = dec.Decode(&stx) // read next transaction into stx
-		if err == io.EOF {
-			break
-		}
+	reader := bufio.NewReaderSize(file, sniffSize)
+	format := inputFormat(inputFormatStr)
+	if format == formatAuto {
+		format, err = detectInputFormat(reader)
 		if err != nil {
-			logger.Errorf("error while dcoding txn: %v", err)
-			return nil, nil, err
+			return fmt.Errorf("error while reading %s: %v", filename, err)
 		}
+		logger.Debugf("detected input format %s", format)
+	}
 
-		gid := stx.Txn.Group
+	indexing, err := needsIndex(filename, format)
+	if err != nil {
+		return err
+	}
+	// the offsets of the transactions are not offsets in a compressed file
+	indexing = indexing && compressed == ""
+	var indexEntries []txIndexEntry
+
+	add := func(rec txRecord, pos txchecker.Position) {
+		rec.file = filename
+		rec.fileIndex = pos.Index
+		rec.index = batch.count
+		batch.count++
+
+		gid := rec.stx.Txn.Group
+		firstOfGroup := true
 		if (gid == types.Digest{}) {
-			individualTxs = append(individualTxs, stx)
+			batch.individual = append(batch.individual, rec)
 		} else {
-			groups[gid] = append(groups[gid], stx)
+			batch.groups[gid] = append(batch.groups[gid], rec)
+			firstOfGroup = len(batch.groups[gid]) == 1
+		}
+		if decoded != nil {
+			decoded(rec, firstOfGroup)
 		}
 	}
-	return groups, individualTxs, nil
+	corrupt := func(pos txchecker.Position, err error) error {
+		err = describeDecodeError(err)
+		bundle.recordFailure(filename, pos.Index, pos.Offset, pos.Size, err)
+		if !permissive {
+			return err
+		}
+		logEntryMessage(logger, log.WarnLevel, msgRecordSkipped, pos.Index, err)
+		batch.corrupt = append(batch.corrupt, skippedTx{
+			recordSource: recordSource{File: filename, Index: pos.Index},
+			Reason:       reasonCorrupt,
+			Detail:       err.Error(),
+			index:        batch.count,
+		})
+		batch.count++
+		return nil
+	}
+	options := txchecker.ReadOptions{Format: txchecker.Format(format), Lenient: permissive, Corrupt: corrupt}
+	err = txchecker.ReadTransactions(reader, options, func(tx txchecker.Transaction, pos txchecker.Position) error {
+		if tx.TxIDOnly() {
+			add(txRecord{txID: tx.TxID, txIDOnly: true}, pos)
+			return nil
+		}
+		if indexing {
+			indexEntries = append(indexEntries, txIndexEntry{txID: tx.TxID, offset: pos.Offset, size: pos.Size})
+		}
+		rec := newTxRecord(tx)
+		err := memory.spill(&rec)
+		if err != nil {
+			return err
+		}
+		add(rec, pos)
+		return nil
+	})
+	if err != nil {
+		logEntryMessage(logger, log.ErrorLevel, msgDecodeFailed, err)
+		return err
+	}
+	if indexing {
+		return writeTxIndex(filename, indexEntries)
+	}
+	return nil
 }
 
-// isTxSent queries the indexer to check if transaction was sent
-func isTxSent(txid string, indexerClient *indexer.Client) (bool, error) {
-	_, err := indexerClient.LookupTransaction(txid).Do(context.Background())
+// txLookup looks up the status of transactions in the indexer
+type txLookup struct {
+	indexerClient *indexer.Client
+	// notFound decides which indexer responses mean a transaction was not sent
+	notFound *notFoundRules
+	// backoff retries failed requests
+	backoff *backoffPolicy
+	// checkpoint records the status of looked up transactions, nil if not set
+	checkpoint *txCheckpoint
+	// cache is the --cache-path cache of the lookups of all the runs, nil if not set
+	cache *txCache
+	// shared caches the lookups of all the instances sharing --state-backend, nil if not set
+	shared *sharedState
+	// recent keeps the lookups in memory in front of cache and shared, nil if neither is set
+	recent *memoryCache
+	// since are the transactions confirmed in the report of --since, nil if not set
+	since *priorConfirmations
+	// concurrency limits the number of concurrent lookups
+	concurrency *concurrencyController
+	// prefetched are the lookups started while decoding the file being checked, nil if not prefetching
+	prefetched *prefetcher
+	// hedge sends a duplicate of slow lookups to a second indexer, nil if not set
+	hedge *hedger
+	// pending looks up the transactions not found by the indexer in the pending pool of a node, nil if not set
+	pending *pendingPool
+	// rounds records the confirmed rounds of the transactions found for the --report, nil if not set
+	rounds *confirmedRounds
+	// priority selects the transactions looked up ahead of the others, nil if not set
+	priority *priorityRules
+}
+
+// isTxSent queries the indexer to check if transaction was sent, by the end of --as-of-round if set
+// transactions confirmed in the report of --since, recorded in the checkpoint or cached are not looked up again
+func (l *txLookup) isTxSent(txid string) (bool, error) {
+	if round, ok := l.since.confirmed(txid); ok {
+		traceLookup(txid, lookupTrace{cache: cacheSince}, true, nil)
+		l.rounds.record(txid, round)
+		return true, nil
+	}
+	if sent, ok := l.checkpoint.lookup(txid); ok {
+		traceLookup(txid, lookupTrace{cache: cacheHit}, sent, nil)
+		return sent, nil
+	}
+	if sent, round, ok := readThrough(txid, l.recent, l.cache, l.shared); ok {
+		traceLookup(txid, lookupTrace{cache: cacheStored}, sent, nil)
+		l.checkpoint.record(txid, sent)
+		l.rounds.record(txid, round)
+		return sent, nil
+	}
+	requestID, headers := nextRequest()
+	log.Debugf("looking up tx %s, request %s", txid, requestID)
+	trace := lookupTrace{start: time.Now()}
+	if l.checkpoint != nil {
+		trace.cache = cacheMiss
+	}
+	c := txchecker.Checker{
+		NotFound: l.notFound,
+		Backoff:  l.backoff.Backoff,
+		OnRetry: func(txid string, retry int, delay time.Duration, err error) {
+			l.backoff.logRetry(fmt.Sprintf("looking up tx %s, request %s,", txid, requestID))(retry, delay, err)
+		},
+		Lookup: func(ctx context.Context, txid string) (models.TransactionResponse, error) {
+			if err := budget.spend(requestTx); err != nil {
+				return models.TransactionResponse{}, err
+			}
+			trace.attempts++
+			start := time.Now()
+			resp, backend, err := l.lookupTransaction(txid, headers)
+			trace.backend = backend
+			latency := time.Since(start)
+			failure := err
+			if err != nil && l.notFound.IsNotFound(err) {
+				failure = nil
+			}
+			bundle.observe(backendIndexer, latency, failure)
+			metrics.observeLookup(latency, failure)
+			return resp, err
+		},
+	}
+	found, round, err := c.Status(context.Background(), txid)
+	traceLookup(txid, trace, found, err)
+	if err == nil {
+		// the cache records the transactions confirmed by now, whatever --as-of-round
+		writeThrough(txid, found, round, l.recent, l.cache, l.shared)
+	}
+	if found && !confirmedAsOf(txid, round) {
+		found = false
+	}
+	if err == txchecker.ErrEmptyResponse {
+		return false, fmt.Errorf("%v to request %s (use --idx-not-found-empty if it means not found)", err,
+			requestID)
+	}
+	if err == errBudgetExhausted || err == errInterrupted {
+		return false, err
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "404") {
-			return false, nil
+		return false, fmt.Errorf("request %s: %v", requestID, err)
+	}
+	l.checkpoint.record(txid, found)
+	l.rounds.record(txid, round)
+	return found, nil
+}
+
+// flattenGroupsMap return a slice of all transactions in the given map
+// in deterministic mode the transactions are sorted by their position in the file
+func flattenGroupsMap(groups map[types.Digest][]txRecord) []txRecord {
+	var result []txRecord
+	for _, txs := range groups {
+		result = append(result, txs...)
+	}
+	if deterministic {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].index < result[j].index
+		})
+	}
+	return result
+}
+
+// logUnsentTxs logs every unsent transaction, labeling its addresses when a resolver is configured
+// the list is logged at INFO level only when address enrichment was requested, as it is meant for human review
+func logUnsentTxs(filename string, txs []txRecord, resolver *addressResolver) {
+	level := log.DebugLevel
+	if resolver != nil {
+		level = log.InfoLevel
+	}
+	var addrs []types.Address
+	for _, tx := range txs {
+		addrs = append(addrs, tx.stx.Txn.Sender, txReceiver(tx.stx.Txn))
+	}
+	resolver.resolve(addrs)
+	for _, tx := range txs {
+		if tx.txIDOnly {
+			logMessage(level, msgUnsentTxID, tx.txID, tx.index, filename)
+			continue
 		}
-		return false, err
+		sender := resolver.label(tx.stx.Txn.Sender)
+		if receiver := txReceiver(tx.stx.Txn); !receiver.IsZero() {
+			logMessage(level, msgUnsentTxTo, tx.stx.Txn.Type, tx.txID, tx.index, filename, sender,
+				resolver.label(receiver))
+			continue
+		}
+		logMessage(level, msgUnsentTx, tx.stx.Txn.Type, tx.txID, tx.index, filename, sender)
 	}
-	return true, nil
 }
 
-// filterUnsentGroups returns only the groups of transactions that were not sent
-func filterUnsentGroups(groups map[types.Digest][]types.SignedTxn, indexerClient *indexer.Client) (map[types.Digest][]types.SignedTxn, error) {
-	unsentGroups := map[types.Digest][]types.SignedTxn{}
-	logger := log.WithField("function", "filterUnsentGroups")
-	for gid, txs := range groups {
-		if len(txs) == 0 {
-			// this should never happen as we generate `groups` in `readTxFile` only if there's at least 1 tx with `gid`
-			logger.Fatalf("group %s has no transactions in slice", gid)
+// groupsInOrder returns the groups ordered by the position of their first transaction in the file
+func groupsInOrder(groups map[types.Digest][]txRecord) [][]txRecord {
+	result := make([][]txRecord, 0, len(groups))
+	for _, txs := range groups {
+		result = append(result, txs)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0].index < result[j][0].index
+	})
+	return result
+}
+
+// allRecords returns all transactions read from a file, in their original order
+func allRecords(groups map[types.Digest][]txRecord, individualTxs []txRecord) []txRecord {
+	result := append(flattenGroupsMap(groups), individualTxs...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].index < result[j].index
+	})
+	return result
+}
+
+// checker holds the clients and settings used for checking files
+type checker struct {
+	indexerClient *indexer.Client
+	resolver      *addressResolver
+	policy        classificationPolicy
+	// lookup looks up the status of transactions
+	lookup *txLookup
+	// backoff retries failed indexer requests
+	backoff *backoffPolicy
+	// window limits the transactions checked by their first valid round, nil means no limit
+	window *roundWindow
+	// senders aggregates the unsent transactions of all checked files by sender
+	senders senderSummary
+	// rollups aggregates the unsent transactions of all checked files by asset and by application
+	rollups *unsentRollups
+	// screen denies resubmitting transactions from or to certain addresses, nil means all are allowed
+	screen *addressScreen
+	// limits hold back resubmitting transactions moving large amounts, nil means no limits
+	limits *amountLimits
+	// round is the latest round of the indexer, looked up again for every file
+	round *indexerRound
+	// clock maps the confirmed rounds of the --report to the timestamps of their blocks
+	clock *blockClock
+	// simulator explains why the unsent application calls fail, nil without --simulate-unsent
+	simulator *simulator
+	// lsigs warns about the unsent transactions their logicsigs would reject, nil without --preview-logicsigs
+	lsigs *logicSigPreviewer
+	// follower follows the blocks of the algod node for --watch, nil without --watch-algod
+	follower *blockFollower
+	// rows writes the status of every transaction to stdout, nil unless --format is csv
+	rows *csvRows
+	// fees warns about the unsent transactions with fees too low, nil without --check-fees
+	fees *feeCheck
+	// redaction hides the notes, addresses or amounts of the --report, nil without --redact
+	redaction *redaction
+	// leases are the lease collisions of the file being checked
+	leases leaseCollisions
+}
+
+// fileResult is the outcome of checking a file
+type fileResult struct {
+	// outputs are the names of the files written
+	outputs []string
+	// sources are the positions in the input files of the transactions written to each output, in output order
+	sources map[string][]recordSource
+	// severity is the highest severity of the conditions of the transactions in the file
+	severity int
+	// skipped are the transactions excluded from checking or resubmission
+	skipped []skippedTx
+	// unsent is the number of unsent transactions, not counting the ones held back by the address lists or limits
+	unsent int
+	// expired is the number of unsent transactions past their last valid round
+	expired int
+	// total is the number of transactions of the file
+	total int
+	// summary are the counts and txids of the file for the JSON summary, nil with --format text
+	summary *fileSummary
+	// streamed are the manifest entries of the outputs written to stdout, which cannot be hashed once written
+	streamed map[string]manifestFileEntry
+	// pending are the unsent transactions watched with --watch, and settledSeverity the severity of the conditions
+	// of the other transactions
+	pending         []txRecord
+	settledSeverity int
+	// inputTxIDs are the txids of the records of every input file, for the manifest, nil without --manifest or
+	// --compare-manifest
+	inputTxIDs map[string][]string
+}
+
+// checkFile checks the status of all transactions in filename, classifies them and writes each bucket of the
+// classification policy to filename.<bucket> (unsent transactions to filename.unsent by default)
+func (c *checker) checkFile(filename string) (fileResult, error) {
+	err := checkFileSizeLimit(filename)
+	if err != nil {
+		return fileResult{}, err
+	}
+	var prefetch *prefetcher
+	if prefetchSize > 0 {
+		prefetch = startPrefetcher(c.lookup, c.window, prefetchSize)
+	}
+	batch, err := readBatch(filename, prefetch.offer)
+	prefetch.finish()
+	defer prefetch.stop()
+	if err != nil {
+		return fileResult{}, err
+	}
+	c.lookup.prefetched = prefetch
+	defer func() {
+		c.lookup.prefetched = nil
+	}()
+	return c.checkBatch(filename, batch)
+}
+
+// checkBatch checks the status of the transactions of a batch read from filename, classifies them and writes each
+// bucket of the classification policy next to filename
+func (c *checker) checkBatch(filename string, batch *txBatch) (fileResult, error) {
+	var err error
+	groups, indTxs, skipped := batch.groups, batch.individual, batch.corrupt
+	defer zeroizeRecords(indTxs)
+	for _, txs := range groups {
+		defer zeroizeRecords(txs)
+	}
+	total := batch.count
+	var txIDs map[string][]string
+	if manifestFile != "" || priorManifestFile != "" {
+		txIDs = inputTxIDs(batch)
+	}
+	c.round = &indexerRound{indexerClient: c.indexerClient, backoff: c.backoff}
+	c.fees = c.fees.forFile()
+	logMessage(log.InfoLevel, msgFoundTxs, len(groups), len(indTxs), filename)
+	log.Debugf("transaction types in %s: %s", filename, txTypesSummary(allRecords(groups, indTxs)))
+	groups, indTxs, outsideWindow := filterRoundWindow(c.window, groups, indTxs)
+	if len(outsideWindow) != 0 {
+		logMessage(log.InfoLevel, msgSkippedOutsideWindow, len(outsideWindow), filename)
+		skipped = append(skipped, skipRecords(outsideWindow, reasonOutsideWindow, func(rec txRecord) string {
+			return fmt.Sprintf("first valid round %d", rec.stx.Txn.FirstValid)
+		})...)
+	}
+	err = checkTxCountLimit(filename, len(allRecords(groups, indTxs)))
+	if err != nil {
+		return fileResult{}, err
+	}
+	err = validateTxs(filename, groups, indTxs)
+	if err != nil {
+		return fileResult{}, err
+	}
+	warnDuplicatePayments(filename, allRecords(groups, indTxs))
+	c.leases = warnLeaseCollisions(filename, allRecords(groups, indTxs))
+	groups, indTxs, placeholders := splitPlaceholders(groups, indTxs)
+	if len(placeholders) != 0 {
+		logMessage(log.WarnLevel, msgSkippedUnsigned, len(placeholders), filename)
+		skipped = append(skipped, skipRecords(placeholders, reasonUnsigned, func(rec txRecord) string {
+			if isPlaceholder(rec) {
+				return "no signature, multisig or logicsig"
+			}
+			return ""
+		})...)
+	}
+	result := fileResult{sources: map[string][]recordSource{}, streamed: map[string]manifestFileEntry{},
+		inputTxIDs: txIDs}
+	out := newBucketWriter(outputBase(filename), c.policy)
+	// on failure the outputs are completed with the chunks classified so far
+	defer out.close(nil)
+	report, err := openTxReport(filename)
+	if err != nil {
+		return fileResult{}, err
+	}
+	defer func() {
+		if report != nil {
+			if err := report.close(); err != nil {
+				logError(err)
+			}
+		}
+	}()
+	err = report.write(c.reportEntries(reportOutsideWindow, outsideWindow))
+	if err != nil {
+		return fileResult{}, err
+	}
+	err = c.rows.write(filename, map[txCondition][]txRecord{reportOutsideWindow: outsideWindow})
+	if err != nil {
+		return fileResult{}, err
+	}
+	graph := newUnsentGraph(c.resolver)
+	units := unitsInOrder(groups, indTxs, placeholders)
+	chunkSize := streamChunk
+	if chunkSize <= 0 {
+		chunkSize = len(units)
+	}
+	bucketCounts := map[string]int{}
+	var confirmedCount, unsentCount, expiredCount, pendingCount, unsentGroups, unsentIndividualTxs int
+	var unsentTxIDs, expiredTxIDs []string
+	for start := 0; start < len(units); start += chunkSize {
+		end := start + chunkSize
+		if end > len(units) {
+			end = len(units)
+		}
+		classified, err := c.classifyUnits(filename, units[start:end])
+		if err != nil {
+			return fileResult{}, err
+		}
+		// the derived statuses are reported, written and decide the exit code, the counts are of the conditions
+		statuses, err := c.deriveStatuses(classified)
+		if err != nil {
+			return fileResult{}, err
+		}
+		// denied and large transactions were not sent either
+		var notSent []txRecord
+		for _, condition := range []txCondition{conditionUnsent, conditionDenied, conditionLarge} {
+			notSent = append(notSent, classified[condition]...)
+		}
+		unsentGroups += countGroups(notSent)
+		unsentIndividualTxs += len(notSent) - countGroupedTxs(notSent)
+		confirmedCount += len(classified[conditionConfirmed])
+		unsentCount += len(classified[conditionUnsent])
+		expiredCount += len(classified[conditionExpired])
+		pendingCount += len(classified[conditionPending])
+		if outputFormat == summaryJSON {
+			unsentTxIDs = appendTxIDs(unsentTxIDs, classified[conditionUnsent])
+			expiredTxIDs = appendTxIDs(expiredTxIDs, classified[conditionExpired])
+		}
+		skipped = append(skipped, skipRecords(classified[conditionExpired], reasonExpired, c.round.expiryDetail)...)
+		skipped = append(skipped, skipRecords(classified[conditionDenied], reasonDenied, func(rec txRecord) string {
+			return c.screen.violation(rec.stx.Txn)
+		})...)
+		skipped = append(skipped, skipRecords(classified[conditionLarge], reasonLarge, func(rec txRecord) string {
+			return c.limits.exceeded(rec.stx.Txn)
+		})...)
+		skipped = append(skipped, skipRecords(classified[conditionPartial], reasonPartial, func(txRecord) string {
+			return "only some transactions of the group were found"
+		})...)
+		metrics.classified(classified)
+		if severity := c.policy.severity(statuses); severity > result.severity {
+			result.severity = severity
+		}
+		var entries []txReportEntry
+		for status, txs := range statuses {
+			entries = append(entries, c.reportEntries(string(status), txs)...)
+		}
+		err = report.write(entries)
+		if err != nil {
+			return fileResult{}, err
+		}
+		err = c.rows.write(filename, statuses)
+		if err != nil {
+			return fileResult{}, err
 		}
-		firstTxID := crypto.GetTxID(txs[0].Txn)
-		groupSent, err := isTxSent(firstTxID, indexerClient)
+		watchChunk(filename, c.policy, classified, &result)
+		graph.add(classified[conditionUnsent])
+		buckets := c.policy.buckets(statuses)
+		for bucket, txs := range buckets {
+			bucketCounts[bucket] += len(txs)
+		}
+		err = out.write(buckets)
 		if err != nil {
-			return nil, fmt.Errorf("failed getting status of tx %s in group %s", firstTxID, gid)
+			return fileResult{}, err
 		}
-		if !groupSent {
-			unsentGroups[gid] = txs
+	}
+	if offline {
+		logMessage(log.InfoLevel, msgPossiblyPending, pendingCount, filename, currentRound)
+	} else {
+		logMessage(log.InfoLevel, msgUnsentSummary, filename, unsentGroups, unsentIndividualTxs)
+	}
+	if expiredCount != 0 {
+		logMessage(log.WarnLevel, msgExpired, expiredCount, filename)
+	}
+	if !offline && unsentGroups+unsentIndividualTxs == 0 {
+		logMessage(log.InfoLevel, msgNoUnsent)
+	}
+	sort.SliceStable(skipped, func(i, j int) bool {
+		return skipped[i].index < skipped[j].index
+	})
+	// the possibly pending transactions of --offline were not found either
+	logReconciliation(filename, total, confirmedCount, unsentCount+pendingCount, skipped)
+	result.skipped = skipped
+	result.unsent = unsentCount
+	result.expired = expiredCount
+	result.total = total
+	if outputFormat == summaryJSON {
+		result.summary = &fileSummary{
+			Total:        total,
+			Confirmed:    confirmedCount,
+			Unsent:       unsentCount,
+			Expired:      expiredCount,
+			Skipped:      len(skipped),
+			UnsentTxIDs:  append([]string{}, unsentTxIDs...),
+			ExpiredTxIDs: append([]string{}, expiredTxIDs...),
 		}
 	}
-	return unsentGroups, nil
-}
-
-// filterUnsentTxs returns only transactions that were not sent
-func filterUnsentTxs(txs []types.SignedTxn, indexerClient *indexer.Client) ([]types.SignedTxn, error) {
-	var unsentTxs []types.SignedTxn
-	for _, tx := range txs {
-		txID := crypto.GetTxID(tx.Txn)
-		isSent, err := isTxSent(txID, indexerClient)
+	err = out.close(&result)
+	if err != nil {
+		return fileResult{}, err
+	}
+	out.logOutputs(bucketCounts)
+	if report != nil {
+		err = report.close()
+		output, count := report.stream.filename, report.count
+		report = nil
 		if err != nil {
-			return nil, fmt.Errorf("failed getting status of tx %s", txID)
+			return fileResult{}, err
 		}
-		if !isSent {
-			unsentTxs = append(unsentTxs, tx)
+		result.outputs = append(result.outputs, output)
+		logMessage(log.InfoLevel, msgStatusWritten, count, output)
+	}
+	if graph != nil {
+		output, err := graph.write(filename)
+		if err != nil {
+			return fileResult{}, err
 		}
+		result.outputs = append(result.outputs, output)
+		logMessage(log.InfoLevel, msgGraphWritten, output)
 	}
-	return unsentTxs, nil
+	return result, nil
 }
 
-// flattenGroupsMap return a slice of all transactions in the given map
-func flattenGroupsMap(groups map[types.Digest][]types.SignedTxn) []types.SignedTxn {
-	var result []types.SignedTxn
-	for _, txs := range groups {
-		result = append(result, txs...)
+// unitsInOrder returns the groups and individual transactions of a file as units of transactions classified together,
+// in the order of their first transaction in the file
+func unitsInOrder(groups map[types.Digest][]txRecord, individualTxs []txRecord, placeholders []txRecord) [][]txRecord {
+	units := groupsInOrder(groups)
+	for _, tx := range individualTxs {
+		units = append(units, []txRecord{tx})
 	}
-	return result
+	placeholderGroups := map[types.Digest][]txRecord{}
+	for _, tx := range placeholders {
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			placeholderGroups[gid] = append(placeholderGroups[gid], tx)
+		} else {
+			units = append(units, []txRecord{tx})
+		}
+	}
+	units = append(units, groupsInOrder(placeholderGroups)...)
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i][0].index < units[j][0].index
+	})
+	return units
+}
+
+// countGroups returns the number of groups the transactions belong to
+func countGroups(txs []txRecord) int {
+	gids := map[types.Digest]bool{}
+	for _, tx := range txs {
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			gids[gid] = true
+		}
+	}
+	return len(gids)
 }
 
-// writeTxsToFile writes a slice of transactions to a file
-func writeTxsToFile(filename string, txs []types.SignedTxn) error {
-	var toWrite []byte
+// countGroupedTxs returns the number of transactions that belong to groups
+func countGroupedTxs(txs []txRecord) int {
+	count := 0
 	for _, tx := range txs {
-		encoded := msgpack.Encode(tx)
-		toWrite = append(toWrite, encoded...)
+		if tx.stx.Txn.Group != (types.Digest{}) {
+			count++
+		}
+	}
+	return count
+}
+
+// classifyUnits looks up the status of units of transactions and classifies their transactions by condition
+// a unit is unsigned if any of its transactions is, otherwise its status is derived from its transactions according
+// to --group-policy
+func (c *checker) classifyUnits(filename string, units [][]txRecord) (map[txCondition][]txRecord, error) {
+	var signed [][]txRecord
+	var placeholders []txRecord
+	for _, unit := range units {
+		if hasPlaceholder(unit) {
+			placeholders = append(placeholders, unit...)
+		} else {
+			signed = append(signed, unit)
+		}
+	}
+	if offline {
+		return c.classifyOffline(signed, placeholders)
 	}
-	err := ioutil.WriteFile(filename, toWrite, 0600)
+	sent, partial, err := c.lookup.lookupUnits(signed)
 	if err != nil {
-		return fmt.Errorf("failed to write txs to %s", filename)
+		return nil, err
 	}
-	return nil
+	var confirmed, unsent, partiallySent []txRecord
+	for i, unit := range signed {
+		if partial[i] {
+			partiallySent = append(partiallySent, unit...)
+		} else if sent[i] {
+			confirmed = append(confirmed, unit...)
+		} else {
+			unsent = append(unsent, unit...)
+		}
+	}
+	unsent, expired, err := c.round.splitExpired(unsent)
+	if err != nil {
+		return nil, err
+	}
+	logUnsentTxs(filename, unsent, c.resolver)
+	reportIncompleteMultisigs(filename, unsent, c.resolver)
+	c.simulator.explain(filename, unsent)
+	c.lsigs.preview(filename, unsent)
+	err = c.fees.warnLowFees(filename, unsent)
+	if err != nil {
+		return nil, err
+	}
+	c.senders.add(unsent)
+	c.rollups.add(unsent)
+	err = reportUnsentKeyregs(filename, unsent, c.indexerClient, c.backoff)
+	if err != nil {
+		return nil, err
+	}
+	unsent, denied := c.screen.screen(filename, unsent)
+	unsent, large := c.limits.holdLarge(filename, unsent)
+	return map[txCondition][]txRecord{
+		conditionConfirmed: confirmed,
+		conditionUnsent:    unsent,
+		conditionUnsigned:  placeholders,
+		conditionDenied:    denied,
+		conditionLarge:     large,
+		conditionExpired:   expired,
+		conditionPartial:   partiallySent,
+	}, nil
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "checktxstatus <file1.tx> <file2.tx> ...",
 	Short: "CLI for checking if transactions are successfully submitted to the blockchain",
+	Example: `  # check two files of signed transactions, writing the unsent ones to batch1.tx.unsent and batch2.tx.unsent
+  checktxstatus --idx-addr https://mainnet-idx.algonode.cloud batch1.tx batch2.tx
+
+  # check a list of txids, 8 lookups at a time, writing a manifest of the run
+  checktxstatus --input-format txids --concurrency 8 --manifest run.json txids.txt
+
+  # write every unsent group to its own file, encrypted to an age recipient
+  checktxstatus --split-unsent-by-group --age-recipient age1... batch.tx`,
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		setLogger(logLevelStr)
-		indexerClient, err := initIndexerClient(indexerAddress, indexerToken)
+		stop := stopOnInterrupt()
+		defer stop()
+		runCheck(cmd, args)
+	},
+}
+
+// runCheck checks the inputs of args, it returns the manifest of the run, nil if the config could not be loaded, and
+// its summary; exitCode is set to the exit code of the run, nonzero if it failed
+func runCheck(cmd *cobra.Command, args []string) (manifest *runManifest, summary *runSummary) {
+	summary = &runSummary{start: time.Now()}
+	// --stdout may be set by the config file
+	defer func() {
+		summary.print(summaryOutput())
+	}()
+	configErr := loadConfig(cmd)
+	setLogger(logLevelStr)
+	if configErr != nil {
+		summary.failed, summary.err = true, configErr.Error()
+		logError(configErr)
+		exitCode = 1
+		summary.exitCode = exitCode
+		return
+	}
+	initRunID()
+	initDebugBundle()
+	manifest = newRunManifest(cmd, args)
+	defer func() {
+		summary.failed, summary.err = manifest.Error != "", manifest.Error
+		// a failed run exits with 1, unless the transactions checked before it failed call for a higher severity
+		if summary.failed && exitCode == 0 {
+			exitCode = 1
+		}
+		summary.exitCode = exitCode
+	}()
+	// the bundle is written last, to include the logs of the whole run
+	defer writeDebugBundle(manifest)
+	defer func() {
+		if manifestFile == "" {
+			return
+		}
+		if err := manifest.write(manifestFile); err != nil {
+			logError(err)
+		}
+	}()
+	err := validateFlags()
+	if err == nil {
+		err = validateTxIDStream(args)
+	}
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	defer stopProfiling()
+	stopMetrics, err := startMetrics()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	defer stopMetrics()
+	// --offline classifies the transactions without any indexer
+	var indexerClient *indexer.Client
+	if !offline {
+		indexerClient, err = initIndexerClient(indexerAddress, indexerToken)
 		if err != nil {
-			log.Error(err)
+			manifest.fail(err)
+			logError(err)
 			return
 		}
-		if len(args) == 0 {
-			log.Error("supply at least 1 transactions file")
+	}
+	hedge, err := initHedger()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	resolver, err := initAddressResolver(addressBookFile, resolveNFD, nfdAPIAddress)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	policy, err := loadPolicy(policyFile)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	backoff, err := initBackoff()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	err = initBudget()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	err = initRateLimits()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	err = initFaultInjection()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	if faults != nil {
+		defer faults.report()
+	}
+	clock := newBlockClock(indexerClient, backoff)
+	window, err := initRoundWindow(indexerClient, clock, backoff)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	since, err := loadPriorConfirmations(sinceReport, clock)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	prior, err := loadPriorInputs()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	screen, err := initAddressScreen(allowlistFile, denylistFile)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	limits, err := parseAmountLimits(maxAmounts)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	priority, err := parsePriorityRules(priorityValues)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	redaction, err := parseRedaction(redactValues)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	notFound, err := initIndexerNotFoundRules()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	checkpoint, err := loadCheckpoint(checkpointFile)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	if checkpoint != nil {
+		checkpoint.startFlushing(checkpointFile)
+		defer func() {
+			checkpoint.stopFlushing()
+			if err := checkpoint.write(checkpointFile); err != nil {
+				logError(err)
+				return
+			}
+			logMessage(log.InfoLevel, msgCheckpointSaved, len(checkpoint.Sent), checkpointFile)
+			if isInterrupted() {
+				logMessage(log.InfoLevel, msgResumeHint, checkpointFile)
+			}
+		}()
+	}
+	cache, err := openCache()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	defer cache.close()
+	shared, err := openSharedState()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	err = initMemoryGuard()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	defer memory.close()
+	err = initTxIndexing()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	concurrency, err := initConcurrency()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	pending, err := initPendingPool()
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	simulator, err := initSimulator(backoff)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	lsigs, err := initLogicSigPreviewer(backoff)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	follower, err := initBlockFollower(pending, backoff)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	fees, err := initFeeCheck(pending, backoff)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	rows, err := newCSVRows(os.Stdout, redaction)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	overrides, err := loadOverrides(overridesFile)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	for match, address := range overrides.backends() {
+		manifest.Backends[match] = address
+	}
+	lookup := &txLookup{
+		indexerClient: indexerClient,
+		notFound:      notFound,
+		backoff:       backoff,
+		checkpoint:    checkpoint,
+		cache:         cache,
+		shared:        shared,
+		recent:        newMemoryCache(cache, shared),
+		since:         since,
+		concurrency:   concurrency,
+		hedge:         hedge,
+		pending:       pending,
+		rounds:        newConfirmedRounds(),
+		priority:      priority,
+	}
+	c := &checker{
+		indexerClient: indexerClient,
+		lookup:        lookup,
+		backoff:       backoff,
+		resolver:      resolver,
+		policy:        policy,
+		window:        window,
+		senders:       senderSummary{},
+		rollups:       newUnsentRollups(),
+		screen:        screen,
+		limits:        limits,
+		clock:         clock,
+		simulator:     simulator,
+		lsigs:         lsigs,
+		follower:      follower,
+		rows:          rows,
+		fees:          fees,
+		redaction:     redaction,
+	}
+	if txidStream != "" || len(statusTxIDs) != 0 {
+		err = streamTxIDs(lookup, summary)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+		}
+		return
+	}
+	args, err = expandInputs(args)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	if len(args) == 0 {
+		err := newUserError(msgNoInputs)
+		manifest.fail(err)
+		logError(err)
+		// the JSON summary and the CSV rows are the only output on stdout
+		if outputFormat == summaryText {
 			cmd.HelpFunc()(cmd, args)
 		}
+	}
 
-		for _, filename := range args {
-			groups, indTxs, err := readTxFile(filename)
+	// digests identify the content of the inputs in the checkpoint, they are computed only with a checkpoint
+	var filenames, digests []string
+	for _, filename := range priority.sortFiles(args) {
+		// outputs of a directory are written next to it
+		filename = filepath.Clean(filename)
+		if checkpoint == nil {
+			filenames = append(filenames, filename)
+			continue
+		}
+		digest, err := batchDigest(filename)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		if previous, ok := checkpoint.completed(digest); ok && resume {
+			logMessage(log.InfoLevel, msgInputCompleted, filename, previous.Path)
+			err = manifest.addInput(filename)
 			if err != nil {
-				log.Error(err)
+				manifest.fail(err)
+				logError(err)
 				return
 			}
-			log.Infof("found %d groups and %d individual transactions in %s", len(groups), len(indTxs), filename)
-			unsentGroups, err := filterUnsentGroups(groups, indexerClient)
-			if err != nil {
-				log.Error(err)
+			if previous.Severity > exitCode {
+				exitCode = previous.Severity
+			}
+			summary.addResumed(filename)
+			continue
+		}
+		filenames = append(filenames, filename)
+		digests = append(digests, digest)
+	}
+	decoder, err := startDecoding(filenames)
+	if err != nil {
+		manifest.fail(err)
+		logError(err)
+		return
+	}
+	defer decoder.stop()
+
+	// the skipped transactions report is written file after file, and completed if the run stops midway
+	var report *skippedReportWriter
+	if skippedReportFile != "" {
+		report, err = openSkippedReport(outputName(skippedReportFile))
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		defer func() {
+			if report == nil {
 				return
 			}
-			unsentIndividualTxs, err := filterUnsentTxs(indTxs, indexerClient)
+			if err := report.close(); err != nil {
+				logError(err)
+			}
+		}()
+	}
+	// watched are the unsent transactions of all inputs for --watch, and settledCode the exit code without them
+	var watched []txRecord
+	settledCode := exitCode
+	for i, filename := range filenames {
+		inputIndex := len(manifest.Inputs)
+		err = manifest.addInput(filename)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		fileChecker, err := overrides.checkerFor(c, filename)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		var result fileResult
+		if decoder != nil {
+			result, err = decoder.check(fileChecker, i)
+		} else {
+			result, err = fileChecker.checkFile(filename)
+		}
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		manifest.setInputTxIDs(inputIndex, result.inputTxIDs)
+		if changed := prior.compare(manifest.Inputs[inputIndex:]); changed != 0 && strict {
+			err = fmt.Errorf("%s changed since the run of manifest %s while in strict mode", filename, priorManifestFile)
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		summary.add(filename, result)
+		if result.severity > exitCode {
+			exitCode = result.severity
+		}
+		watched = append(watched, result.pending...)
+		if result.settledSeverity > settledCode {
+			settledCode = result.settledSeverity
+		}
+		if checkpoint != nil {
+			checkpoint.complete(digests[i], filename, result.severity)
+		}
+		if report != nil {
+			err = report.write(result.skipped)
 			if err != nil {
-				log.Error(err)
+				manifest.fail(err)
+				logError(err)
 				return
 			}
-			log.Infof("file %s has %d unsent groups and %d unsent individual transactions",
-				filename, len(unsentGroups), len(unsentIndividualTxs))
-			flattenUnsentGroups := flattenGroupsMap(unsentGroups)
-			allUnsent := append(flattenUnsentGroups, unsentIndividualTxs...)
-			if len(allUnsent) != 0 {
-				unsentFilename := fmt.Sprintf("%s.unsent", filename)
-				err = writeTxsToFile(unsentFilename, allUnsent)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-				log.Infof("wrote unsent transactions to %s", unsentFilename)
-			} else {
-				log.Infof("no unsent transaction were found!")
+		}
+		for _, output := range result.outputs {
+			if entry, ok := result.streamed[output]; ok {
+				manifest.addStreamed(entry, result.sources[output])
+				continue
+			}
+			err = manifest.addOutput(output, result.sources[output])
+			if err != nil {
+				manifest.fail(err)
+				logError(err)
+				return
 			}
 		}
-	},
+	}
+	if watchUnsent {
+		severity, err := c.watch(watched, summary)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		exitCode = settledCode
+		if severity > exitCode {
+			exitCode = severity
+		}
+	}
+	if adaptiveConcurrency {
+		concurrency.logSummary()
+	}
+	if hedge != nil {
+		hedge.logSummary()
+	}
+	c.senders.logTopSenders(topSenders, resolver)
+	c.rollups.logTop(topRollups)
+	if topRollups > 0 {
+		summary.rollups = c.rollups
+	}
+	logMessage(log.InfoLevel, msgPeakMemory, formatByteSize(peakMemory()))
+	if report != nil {
+		err = report.close()
+		report = nil
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+		err = manifest.addOutput(outputName(skippedReportFile), nil)
+		if err != nil {
+			manifest.fail(err)
+			logError(err)
+			return
+		}
+	}
+	return
 }
 
 func main() {
+	initEstimateCmd()
+	initPruneCmd()
+	initDoctorCmd()
+	initInitCmd()
+	initUpdateCmd()
+	initSubmitCmd()
+	initVerifyCmd()
+	initDecodeCmd()
+	initEncodeCmd()
+	initArchiveCmd()
+	initStatusCmd()
+	initWatchDirCmd()
+	initServeCmd()
+	initCompletionCmd()
 	err := rootCmd.Execute()
 	if err != nil {
 		panic(err)
 	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/base32"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTxID returns a valid txid made of n
+func testTxID(n byte) string {
+	var digest [32]byte
+	digest[0] = n
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(digest[:])
+}
+
+// testDir returns a temporary directory removed at the end of the test
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "checktxstatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+// newTestBackoff returns a backoff policy retrying failed requests 3 times without waiting
+func newTestBackoff() *backoffPolicy {
+	return &backoffPolicy{&txchecker.Backoff{Kind: backoffFixed, Base: time.Millisecond, Max: time.Millisecond,
+		Retries: 3, Rand: rand.New(rand.NewSource(1)), Sleep: func(time.Duration) {}, Transient: isTransient}}
+}
+
+// newTestLookup returns a lookup of the indexer of server, retrying failed requests 3 times without waiting
+func newTestLookup(t *testing.T, server *checkertest.Server) *txLookup {
+	client, err := indexer.MakeClientWithHeaders(server.URL, "", clientHeaders(indexerTransport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := initIndexerNotFoundRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff()}
+}
+
+func TestFlattenGroupsMapKeepsInputOrderWhenDeterministic(t *testing.T) {
+	deterministic = true
+	defer func() { deterministic = false }()
+	groups := map[types.Digest][]txRecord{}
+	for i := 0; i < 20; i += 2 {
+		gid := types.Digest{byte(i)}
+		groups[gid] = []txRecord{{index: i}, {index: i + 1}}
+	}
+	for i, rec := range flattenGroupsMap(groups) {
+		if rec.index != i {
+			t.Fatalf("expected transaction %d at position %d", rec.index, i)
+		}
+	}
+}
+
+func TestReadTxFileSkipsCorruptRecordsWhenPermissive(t *testing.T) {
+	defer func() { permissive = false }()
+	stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{FirstValid: 1}}}
+	filename := filepath.Join(testDir(t), "batch.tx")
+	content := append(msgpack.Encode(map[string]string{"txn": "not a transaction"}), msgpack.Encode(stx)...)
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := readTxFile(filename, &txBatch{groups: map[types.Digest][]txRecord{}}, nil); err == nil {
+		t.Error("expected a corrupt record to fail")
+	}
+	permissive = true
+	batch := &txBatch{groups: map[types.Digest][]txRecord{}}
+	if err := readTxFile(filename, batch, nil); err != nil {
+		t.Fatal(err)
+	}
+	individualTxs, corrupt := batch.individual, batch.corrupt
+	if len(individualTxs) != 1 || individualTxs[0].source() != (recordSource{filename, 1, crypto.GetTxID(stx.Txn)}) {
+		t.Errorf("expected the transaction after the corrupt record, got %v", individualTxs)
+	}
+	if batch.count != 2 || len(corrupt) != 1 || corrupt[0].Index != 0 || corrupt[0].Reason != reasonCorrupt {
+		t.Errorf("expected the corrupt record to be skipped, got %+v", corrupt)
+	}
+}
+
+func TestIsTxSentRetriesFlappingIndexer(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusServiceUnavailable, Times: 2})
+	lookup := newTestLookup(t, server)
+	sent, err := lookup.isTxSent(testTxID(1))
+	if err != nil || !sent {
+		t.Errorf("expected the transaction to be found after the retries, got %v, %v", sent, err)
+	}
+	if requests := server.Requests(); len(requests) != 3 {
+		t.Errorf("expected 3 attempts, got %v", requests)
+	}
+	sent, err = lookup.isTxSent(testTxID(2))
+	if err != nil || sent {
+		t.Errorf("expected the transaction to be unsent, got %v, %v", sent, err)
+	}
+}
+
+func TestIsTxSentFailsOnceRetriesRunOut(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusBadGateway})
+	_, err := newTestLookup(t, server).isTxSent(testTxID(1))
+	if err == nil {
+		t.Errorf("expected the lookup to fail")
+	}
+	if requests := server.Requests(); len(requests) != 4 {
+		t.Errorf("expected the first attempt and 3 retries, got %v", requests)
+	}
+}
+
+func TestIsTxSentNotFoundRules(t *testing.T) {
+	responses := map[string]int{testTxID(1): 200, testTxID(2): 404, testTxID(3): 500, testTxID(4): 204}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txID := path.Base(r.URL.Path)
+		switch status := responses[txID]; status {
+		case 200:
+			fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q}}`, txID)
+		case 204:
+			fmt.Fprint(w, `{"current-round":1000,"transaction":{}}`)
+		default:
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"message":"error"}`)
+		}
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff()}
+	if sent, err := lookup.isTxSent(testTxID(1)); err != nil || !sent {
+		t.Errorf("expected the transaction to be sent, got %v, %v", sent, err)
+	}
+	if sent, err := lookup.isTxSent(testTxID(2)); err != nil || sent {
+		t.Errorf("expected the transaction not to be found, got %v, %v", sent, err)
+	}
+	if _, err := lookup.isTxSent(testTxID(3)); err == nil {
+		t.Error("expected a server error to fail")
+	}
+	if _, err := lookup.isTxSent(testTxID(4)); err == nil {
+		t.Error("expected an empty response to fail")
+	}
+	lookup.notFound.Empty = true
+	if sent, err := lookup.isTxSent(testTxID(4)); err != nil || sent {
+		t.Errorf("expected an empty response not to be found, got %v, %v", sent, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// version is the version of the tool, set at build time with -ldflags "-X main.version=<version>"
+var version = "dev"
+
+var manifestFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&manifestFile, "manifest", "",
+		"write a JSON manifest of the run (version, settings, inputs and outputs) to this file")
+}
+
+// manifestFileEntry describes a file read or written by the run
+type manifestFileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Sources are the positions in the input files of the transactions in an output file, in the order written
+	Sources []recordSource `json:"sources,omitempty"`
+	// TxIDs are the txids of the transactions of an input file, in file order, to tell what changed when it is
+	// checked again by a run with --compare-manifest
+	TxIDs []string `json:"txids,omitempty"`
+}
+
+// runManifest captures everything needed to reproduce and audit a run
+// timestamps, the run ID and the peak memory are omitted in deterministic mode so identical runs produce identical manifests
+type runManifest struct {
+	ToolVersion string              `json:"tool_version"`
+	RunID       string              `json:"run_id,omitempty"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+	Args        []string            `json:"args"`
+	Flags       map[string]string   `json:"flags"`
+	Backends    map[string]string   `json:"backends"`
+	Inputs      []manifestFileEntry `json:"inputs"`
+	Outputs     []manifestFileEntry `json:"outputs"`
+	Error       string              `json:"error,omitempty"`
+	// ErrorCode is the message ID of Error, if it is a message of the catalog
+	ErrorCode messageID `json:"error_code,omitempty"`
+	// PeakMemory is the memory in bytes obtained from the OS by the run
+	PeakMemory uint64 `json:"peak_memory,omitempty"`
+}
+
+// secretFlags are flags whose values are never written to the manifest
+// --state-backend holds the password of the Redis server in its URL
+var secretFlags = map[string]bool{
+	"idx-tkn":       true,
+	"hedge-idx-tkn": true,
+	"algod-tkn":     true,
+	"state-backend": true,
+}
+
+// newRunManifest starts a manifest for a run of cmd with the given arguments
+// only flags explicitly set by the user are recorded, the rest have their default values
+func newRunManifest(cmd *cobra.Command, args []string) *runManifest {
+	m := &runManifest{
+		ToolVersion: version,
+		Args:        args,
+		Flags:       map[string]string{},
+		Backends: map[string]string{
+			"indexer": indexerAddress,
+		},
+		Inputs:  []manifestFileEntry{},
+		Outputs: []manifestFileEntry{},
+	}
+	if !deterministic {
+		now := time.Now().UTC()
+		m.StartedAt = &now
+		m.RunID = runID
+	}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		if secretFlags[flag.Name] {
+			value = redacted
+		}
+		m.Flags[flag.Name] = value
+	})
+	if hedgeIndexerAddress != "" {
+		m.Backends["hedge-indexer"] = hedgeIndexerAddress
+	}
+	if algodAddress != "" {
+		m.Backends["algod"] = algodAddress
+	}
+	if resolveNFD {
+		m.Backends["nfd"] = strings.TrimSuffix(nfdAPIAddress, "/")
+	}
+	return m
+}
+
+// hashFile returns a manifest entry of the file, with its SHA-256 digest
+func hashFile(filename string) (manifestFileEntry, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return manifestFileEntry{}, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return manifestFileEntry{}, fmt.Errorf("error while hashing %s: %v", filename, err)
+	}
+	return manifestFileEntry{Path: filename, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// addInput records an input file of the run, or all the files of an input directory
+func (m *runManifest) addInput(filename string) error {
+	files, err := batchFiles(filename)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		entry, err := hashFile(file)
+		if err != nil {
+			return err
+		}
+		m.Inputs = append(m.Inputs, entry)
+	}
+	return nil
+}
+
+// setInputTxIDs records the txids of the transactions of the input files from the entry at index on
+func (m *runManifest) setInputTxIDs(index int, txIDs map[string][]string) {
+	for i := index; i < len(m.Inputs); i++ {
+		m.Inputs[i].TxIDs = txIDs[m.Inputs[i].Path]
+	}
+}
+
+// addOutput records a file written by the run along with the input positions of the transactions in it, if any
+func (m *runManifest) addOutput(filename string, sources []recordSource) error {
+	entry, err := hashFile(filename)
+	if err != nil {
+		return err
+	}
+	entry.Sources = sources
+	m.Outputs = append(m.Outputs, entry)
+	return nil
+}
+
+// addStreamed records an output streamed to stdout, hashed as it was written
+func (m *runManifest) addStreamed(entry manifestFileEntry, sources []recordSource) {
+	entry.Sources = sources
+	m.Outputs = append(m.Outputs, entry)
+}
+
+// fail records the error that stopped the run
+func (m *runManifest) fail(err error) {
+	m.Error = err.Error()
+	m.ErrorCode = errorCode(err)
+}
+
+// encode completes the manifest and encodes it as JSON
+func (m *runManifest) encode() ([]byte, error) {
+	if !deterministic {
+		now := time.Now().UTC()
+		m.FinishedAt = &now
+		m.PeakMemory = peakMemory()
+	}
+	encoded, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding manifest: %v", err)
+	}
+	return append(encoded, '\n'), nil
+}
+
+// write completes the manifest and writes it to filename
+func (m *runManifest) write(filename string) error {
+	encoded, err := m.encode()
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename, encoded, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest to %s", filename)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunManifestRecordsSetFlagsAndRedactsSecrets(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("idx-tkn", "", "")
+	cmd.Flags().String("state-backend", "", "")
+	cmd.Flags().String("log-level", "INFO", "")
+	cmd.Flags().Bool("nfd", false, "")
+	args := []string{"--idx-tkn", "secret", "--state-backend", "redis://:secret@redis:6379", "--nfd"}
+	if err := cmd.Flags().Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	m := newRunManifest(cmd, []string{"batch.tx"})
+	if len(m.Flags) != 3 || m.Flags["idx-tkn"] != "REDACTED" || m.Flags["state-backend"] != "REDACTED" ||
+		m.Flags["nfd"] != "true" {
+		t.Errorf("expected the set flags with the secrets redacted, got %v", m.Flags)
+	}
+}
+
+func TestRunManifestWrite(t *testing.T) {
+	dir := testDir(t)
+	input := filepath.Join(dir, "batch.tx")
+	if err := ioutil.WriteFile(input, []byte("abc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := newRunManifest(&cobra.Command{}, []string{input})
+	if err := m.addInput(input); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.addOutput(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("expected a missing output to fail")
+	}
+	sources := []recordSource{{File: input, Index: 2, TxID: testTxID(1)}}
+	if err := m.addOutput(input, sources); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "manifest.json")
+	if err := m.write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written runManifest
+	if err := json.Unmarshal(content, &written); err != nil {
+		t.Fatal(err)
+	}
+	abcDigest := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if len(written.Inputs) != 1 || written.Inputs[0].Size != 3 || written.Inputs[0].SHA256 != abcDigest {
+		t.Errorf("expected the size and digest of the input, got %+v", written.Inputs)
+	}
+	if len(written.Outputs) != 1 || len(written.Outputs[0].Sources) != 1 || written.Outputs[0].Sources[0] != sources[0] {
+		t.Errorf("expected the output with the sources of its transactions, got %+v", written.Outputs)
+	}
+	if written.FinishedAt == nil {
+		t.Errorf("unexpected manifest %+v", written)
+	}
+}
+
+func TestDeterministicManifestHasNoTimestamps(t *testing.T) {
+	deterministic = true
+	defer func() { deterministic = false }()
+	manifest := filepath.Join(testDir(t), "manifest.json")
+	if err := newRunManifest(&cobra.Command{}, nil).write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "_at") {
+		t.Errorf("expected no timestamps in a deterministic manifest, got %s", content)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"sort"
+)
+
+var priorManifestFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&priorManifestFile, "compare-manifest", "",
+		"--manifest of an earlier run: warn about the inputs it checked whose content changed since, with the "+
+			"transactions added and removed, to catch dumps rewritten upstream (an error with --strict)")
+}
+
+// priorInputs are the inputs checked by an earlier run, by path
+type priorInputs struct {
+	filename string
+	inputs   map[string]manifestFileEntry
+}
+
+// loadPriorInputs reads the inputs of the --compare-manifest manifest, it returns nil if the flag is not set
+func loadPriorInputs() (*priorInputs, error) {
+	if priorManifestFile == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(priorManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading manifest %s: %v", priorManifestFile, err)
+	}
+	var manifest runManifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing manifest %s: %v", priorManifestFile, err)
+	}
+	p := &priorInputs{filename: priorManifestFile, inputs: map[string]manifestFileEntry{}}
+	for _, entry := range manifest.Inputs {
+		p.inputs[entry.Path] = entry
+	}
+	return p, nil
+}
+
+// inputTxIDs returns the txids of the records of every input file of a batch, in file order
+func inputTxIDs(batch *txBatch) map[string][]string {
+	records := allRecords(batch.groups, batch.individual)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].index < records[j].index
+	})
+	txIDs := map[string][]string{}
+	for _, rec := range records {
+		txIDs[rec.file] = append(txIDs[rec.file], rec.txID)
+	}
+	return txIDs
+}
+
+// compare logs how the input files of entries changed since the earlier run checked them
+// it returns the number of files that changed
+func (p *priorInputs) compare(entries []manifestFileEntry) int {
+	if p == nil {
+		return 0
+	}
+	changed := 0
+	for _, entry := range entries {
+		prior, ok := p.inputs[entry.Path]
+		if !ok || prior.SHA256 == entry.SHA256 {
+			continue
+		}
+		changed++
+		logger := log.WithField("file", entry.Path)
+		// the manifests of older versions, and the inputs resumed from a checkpoint, have no txids
+		if prior.TxIDs == nil || entry.TxIDs == nil {
+			logEntryMessage(logger, log.WarnLevel, msgManifestSizeChanged, entry.Path, p.filename, entry.Size,
+				prior.Size)
+			continue
+		}
+		added, removed := diffTxIDs(prior.TxIDs, entry.TxIDs)
+		if len(added)+len(removed) == 0 {
+			logEntryMessage(logger, log.WarnLevel, msgManifestReordered, entry.Path, p.filename, len(entry.TxIDs))
+			continue
+		}
+		logEntryMessage(logger, log.WarnLevel, msgManifestTxsChanged, entry.Path, p.filename, len(added), len(removed))
+		for _, txid := range added {
+			logger.Debugf("tx %s was added", txid)
+		}
+		for _, txid := range removed {
+			logger.Debugf("tx %s was removed", txid)
+		}
+	}
+	return changed
+}
+
+// diffTxIDs returns the txids of after missing from before, and the ones of before missing from after, in order
+func diffTxIDs(before []string, after []string) (added []string, removed []string) {
+	counts := map[string]int{}
+	for _, txid := range before {
+		counts[txid]++
+	}
+	for _, txid := range after {
+		if counts[txid] > 0 {
+			counts[txid]--
+		} else {
+			added = append(added, txid)
+		}
+	}
+	for _, txid := range before {
+		if counts[txid] > 0 {
+			counts[txid]--
+			removed = append(removed, txid)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffTxIDs(t *testing.T) {
+	// duplicates count, a txid appearing once more than before was added
+	added, removed := diffTxIDs([]string{"A", "B", "C", "C"}, []string{"C", "A", "D", "A"})
+	if strings.Join(added, ",") != "D,A" || strings.Join(removed, ",") != "B,C" {
+		t.Errorf("expected D and A added and B and C removed, got %v and %v", added, removed)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+)
+
+var maxMemoryStr string
+
+func init() {
+	rootCmd.Flags().StringVar(&maxMemoryStr, "max-memory", "0",
+		"memory ceiling, e.g. 512MB: once the heap grows past it the encodings of decoded transactions are spilled "+
+			"to a temporary file instead of being kept in memory (0 for no limit)")
+}
+
+// spillCheckInterval is the number of transactions decoded between checks of the heap size
+const spillCheckInterval = 256
+
+// spillRef locates the encoding of a transaction in the spill file, size is 0 if it was not spilled
+type spillRef struct {
+	offset int64
+	size   int
+}
+
+// memoryGuard keeps the heap under --max-memory by spilling the encodings of decoded transactions to a temporary file
+type memoryGuard struct {
+	limit uint64
+	mu    sync.Mutex
+	// decoded is the number of transactions decoded before spilling started
+	decoded int
+	// file is the spill file, nil until the heap first grew past the limit
+	file *os.File
+	size int64
+}
+
+// memory is the memory guard of the run, nil if --max-memory is not set
+var memory *memoryGuard
+
+// validateMaxMemory rejects --max-memory with --shred, which never lets signed transactions be written to temporary
+// files
+func validateMaxMemory() error {
+	limit, err := parseByteSize(maxMemoryStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max-memory: %v", err)
+	}
+	if limit != 0 && shred {
+		return newUserError(msgMaxMemoryShred)
+	}
+	return nil
+}
+
+// initMemoryGuard sets up the memory guard from --max-memory
+func initMemoryGuard() error {
+	limit, err := parseByteSize(maxMemoryStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max-memory: %v", err)
+	}
+	if limit != 0 {
+		memory = &memoryGuard{limit: uint64(limit)}
+	}
+	return nil
+}
+
+// spill moves the encoding of a decoded transaction to the spill file once the heap grew past the limit
+// once spilling started, the encodings of all the transactions decoded afterwards are spilled
+func (g *memoryGuard) spill(rec *txRecord) error {
+	if g == nil || rec.raw == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.file == nil {
+		g.decoded++
+		if g.decoded%spillCheckInterval != 0 {
+			return nil
+		}
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc <= g.limit {
+			return nil
+		}
+		file, err := ioutil.TempFile("", "checktxstatus-spill-")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %v", err)
+		}
+		g.file = file
+		logMessage(log.WarnLevel, msgMemorySpilling, formatByteSize(stats.HeapAlloc), maxMemoryStr, file.Name())
+	}
+	n, err := g.file.WriteAt(rec.raw, g.size)
+	if err != nil {
+		return fmt.Errorf("failed to write to spill file %s: %v", g.file.Name(), err)
+	}
+	rec.spilled = spillRef{offset: g.size, size: n}
+	g.size += int64(n)
+	zeroize(rec.raw)
+	rec.raw = nil
+	return nil
+}
+
+// load reads a spilled encoding back from the spill file
+func (g *memoryGuard) load(ref spillRef) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	raw := make([]byte, ref.size)
+	_, err := g.file.ReadAt(raw, ref.offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from spill file %s: %v", g.file.Name(), err)
+	}
+	return raw, nil
+}
+
+// close removes the spill file
+// it is never written with --shred, see validateMaxMemory
+func (g *memoryGuard) close() {
+	if g == nil || g.file == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// the file is removed regardless of errors on close
+	_ = g.file.Close()
+	if err := os.Remove(g.file.Name()); err != nil {
+		logMessage(log.ErrorLevel, msgSpillRemoveFailed, g.file.Name(), err)
+	}
+}
+
+// peakMemory returns the memory obtained from the OS by the run, which never decreases and so is its peak
+func peakMemory() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys
+}
+
+// formatByteSize formats a size in the units parsed by parseByteSize
+func formatByteSize(size uint64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", size)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestMemoryGuardSpillsPastLimit(t *testing.T) {
+	g := &memoryGuard{limit: 1}
+	defer g.close()
+	var recs []txRecord
+	for i := 0; i < spillCheckInterval+1; i++ {
+		rec := txRecord{raw: []byte{byte(i), 1, 2}}
+		if err := g.spill(&rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if recs[spillCheckInterval-2].raw == nil || g.file == nil {
+		t.Fatalf("expected spilling to start after %d transactions", spillCheckInterval)
+	}
+	for _, i := range []int{spillCheckInterval - 1, spillCheckInterval} {
+		if recs[i].raw != nil || recs[i].spilled.size != 3 {
+			t.Fatalf("expected tx %d to be spilled, got %+v", i, recs[i])
+		}
+		raw, err := g.load(recs[i].spilled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, []byte{byte(i), 1, 2}) {
+			t.Errorf("expected the spilled encoding of tx %d, got %v", i, raw)
+		}
+	}
+	name := g.file.Name()
+	g.close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("expected the spill file to be removed, got %v", err)
+	}
+	g.file = nil
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for size, expected := range map[uint64]string{512: "512B", 1536: "1.5KB", 3 << 20: "3.0MB", 5 << 30: "5.0GB"} {
+		if formatted := formatByteSize(size); formatted != expected {
+			t.Errorf("expected %d to format as %s, got %s", size, expected, formatted)
+		}
+	}
+}
+
+func TestValidateMaxMemoryRejectsShred(t *testing.T) {
+	defer func(limit string, isShred bool) { maxMemoryStr, shred = limit, isShred }(maxMemoryStr, shred)
+	maxMemoryStr, shred = "64MB", true
+	if err := validateMaxMemory(); err == nil {
+		t.Error("expected --max-memory with --shred to be rejected")
+	}
+	maxMemoryStr = "0"
+	if err := validateMaxMemory(); err != nil {
+		t.Errorf("expected --shred alone to be valid, got %v", err)
+	}
+}
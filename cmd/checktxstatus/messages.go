@@ -0,0 +1,456 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// messageID identifies a user-facing message. IDs are stable and logged in the code field of the entries, so scripts
+// can match messages whose wording changes or is translated. Every message logged at INFO level or above is in the
+// catalog, the DEBUG logs are meant for developers and are not.
+type messageID string
+
+const (
+	msgConfigRead                messageID = "config-read"
+	msgConfigParse               messageID = "config-parse"
+	msgConfigUnknownFlag         messageID = "config-unknown-flag"
+	msgConfigInvalidValue        messageID = "config-invalid-value"
+	msgEnvInvalidValue           messageID = "env-invalid-value"
+	msgStrictPermissive          messageID = "strict-permissive"
+	msgMaxMemoryShred            messageID = "max-memory-shred"
+	msgUnknownInputFormat        messageID = "unknown-input-format"
+	msgMissingIndexer            messageID = "missing-indexer"
+	msgNoInputs                  messageID = "no-inputs"
+	msgNoTxIDs                   messageID = "no-txids"
+	msgInputCompleted            messageID = "input-completed"
+	msgFoundTxs                  messageID = "found-txs"
+	msgSkippedOutsideWindow      messageID = "skipped-outside-window"
+	msgSkippedUnsigned           messageID = "skipped-unsigned"
+	msgUnsentSummary             messageID = "unsent-summary"
+	msgNoUnsent                  messageID = "no-unsent"
+	msgExpired                   messageID = "expired"
+	msgReconciled                messageID = "reconciled"
+	msgNotReconciled             messageID = "not-reconciled"
+	msgCheckpointSaved           messageID = "checkpoint-saved"
+	msgPeakMemory                messageID = "peak-memory"
+	msgNFDFailed                 messageID = "nfd-failed"
+	msgAlertFired                messageID = "alert-fired"
+	msgAlertResolved             messageID = "alert-resolved"
+	msgAlertNotifyFailed         messageID = "alert-notify-failed"
+	msgAmountHeld                messageID = "amount-held"
+	msgAmountsHeld               messageID = "amounts-held"
+	msgArchiveEncrypted          messageID = "archive-encrypted"
+	msgArchiveNotConfirmed       messageID = "archive-not-confirmed"
+	msgArchived                  messageID = "archived"
+	msgArchiveDeleted            messageID = "archive-deleted"
+	msgRequestRetry              messageID = "request-retry"
+	msgBatchDirectory            messageID = "batch-directory"
+	msgBudgetSoft                messageID = "budget-soft"
+	msgCacheLoaded               messageID = "cache-loaded"
+	msgCacheWriteFailed          messageID = "cache-write-failed"
+	msgCacheHits                 messageID = "cache-hits"
+	msgCacheCloseFailed          messageID = "cache-close-failed"
+	msgCompletionFailed          messageID = "completion-failed"
+	msgDashboardServing          messageID = "dashboard-serving"
+	msgDashboardRenderFailed     messageID = "dashboard-render-failed"
+	msgDebugBundleWritten        messageID = "debug-bundle-written"
+	msgDecodeInvalidOutput       messageID = "decode-invalid-output"
+	msgDecodeInvalidTxID         messageID = "decode-invalid-txid"
+	msgCorruptSkipped            messageID = "corrupt-skipped"
+	msgPossibleDuplicate         messageID = "possible-duplicate"
+	msgEncodeSingleInput         messageID = "encode-single-input"
+	msgEncodeInvalidSignature    messageID = "encode-invalid-signature"
+	msgEncoded                   messageID = "encoded"
+	msgInvalidConcurrency        messageID = "invalid-concurrency"
+	msgInvalidMaxRequests        messageID = "invalid-max-requests"
+	msgInvalidMaxRequestCost     messageID = "invalid-max-request-cost"
+	msgFaultsInjecting           messageID = "faults-injecting"
+	msgFaultsInjected            messageID = "faults-injected"
+	msgFeeTooLow                 messageID = "fee-too-low"
+	msgGroupPartial              messageID = "group-partial"
+	msgGroupPartialPolicy        messageID = "group-partial-policy"
+	msgIndexWritten              messageID = "index-written"
+	msgIncludeNoMatch            messageID = "include-no-match"
+	msgInputsExpanded            messageID = "inputs-expanded"
+	msgInterrupted               messageID = "interrupted"
+	msgJobRemoveFailed           messageID = "job-remove-failed"
+	msgJobFailed                 messageID = "job-failed"
+	msgJobQueued                 messageID = "job-queued"
+	msgJobProcessing             messageID = "job-processing"
+	msgJobDone                   messageID = "job-done"
+	msgJobDeliveryFailed         messageID = "job-delivery-failed"
+	msgJobDelivered              messageID = "job-delivered"
+	msgKeyregOffline             messageID = "keyreg-offline"
+	msgKeyregTakesOffline        messageID = "keyreg-takes-offline"
+	msgKeyregOnline              messageID = "keyreg-online"
+	msgKeyregExpiredKeys         messageID = "keyreg-expired-keys"
+	msgKeyregDifferentKeys       messageID = "keyreg-different-keys"
+	msgKeyregAccountFailed       messageID = "keyreg-account-failed"
+	msgLeaseCollision            messageID = "lease-collision"
+	msgLsigEvalFailed            messageID = "lsig-eval-failed"
+	msgLsigRejects               messageID = "lsig-rejects"
+	msgLsigRejectsSummary        messageID = "lsig-rejects-summary"
+	msgRecordSkipped             messageID = "record-skipped"
+	msgDecodeFailed              messageID = "decode-failed"
+	msgPossiblyPending           messageID = "possibly-pending"
+	msgStatusWritten             messageID = "status-written"
+	msgUnsentTxID                messageID = "unsent-txid"
+	msgUnsentTx                  messageID = "unsent-tx"
+	msgUnsentTxTo                messageID = "unsent-tx-to"
+	msgConcurrencySummary        messageID = "concurrency-summary"
+	msgHedgeSummary              messageID = "hedge-summary"
+	msgGraphWritten              messageID = "graph-written"
+	msgResumeHint                messageID = "resume-hint"
+	msgManifestSizeChanged       messageID = "manifest-size-changed"
+	msgManifestReordered         messageID = "manifest-reordered"
+	msgManifestTxsChanged        messageID = "manifest-txs-changed"
+	msgMemorySpilling            messageID = "memory-spilling"
+	msgSpillRemoveFailed         messageID = "spill-remove-failed"
+	msgMetricsServing            messageID = "metrics-serving"
+	msgMultisigIncomplete        messageID = "multisig-incomplete"
+	msgMultisigIncompleteSummary messageID = "multisig-incomplete-summary"
+	msgPriorityNotFound          messageID = "priority-not-found"
+	msgPriorityLookedUp          messageID = "priority-looked-up"
+	msgPprofServing              messageID = "pprof-serving"
+	msgCPUProfileFailed          messageID = "cpu-profile-failed"
+	msgHeapProfileCreateFailed   messageID = "heap-profile-create-failed"
+	msgHeapProfileWriteFailed    messageID = "heap-profile-write-failed"
+	msgInvalidRetention          messageID = "invalid-retention"
+	msgInvalidPrunePattern       messageID = "invalid-prune-pattern"
+	msgNoDirectories             messageID = "no-directories"
+	msgPruneDeleted              messageID = "prune-deleted"
+	msgPruneArchived             messageID = "prune-archived"
+	msgPruneWouldDelete          messageID = "prune-would-delete"
+	msgPruneWouldArchive         messageID = "prune-would-archive"
+	msgPruneDeleting             messageID = "prune-deleting"
+	msgPruneArchiving            messageID = "prune-archiving"
+	msgRetryAfterCapped          messageID = "retry-after-capped"
+	msgRateLimited               messageID = "rate-limited"
+	msgRoundTimeFailed           messageID = "round-time-failed"
+	msgTopAssets                 messageID = "top-assets"
+	msgTopAsset                  messageID = "top-asset"
+	msgTopApps                   messageID = "top-apps"
+	msgTopApp                    messageID = "top-app"
+	msgTopAppMoving              messageID = "top-app-moving"
+	msgScreenedOut               messageID = "screened-out"
+	msgTopSenders                messageID = "top-senders"
+	msgTopSender                 messageID = "top-sender"
+	msgServeServing              messageID = "serve-serving"
+	msgServeStopped              messageID = "serve-stopped"
+	msgServeRemoveFailed         messageID = "serve-remove-failed"
+	msgServeRequestFailed        messageID = "serve-request-failed"
+	msgTokensReadable            messageID = "tokens-readable"
+	msgSimulateFailed            messageID = "simulate-failed"
+	msgSimulateFails             messageID = "simulate-fails"
+	msgSinceCarried              messageID = "since-carried"
+	msgStateSharing              messageID = "state-sharing"
+	msgStateLookupFailed         messageID = "state-lookup-failed"
+	msgStateRecordFailed         messageID = "state-record-failed"
+	msgStateCacheDisabled        messageID = "state-cache-disabled"
+	msgStateCacheEnabled         messageID = "state-cache-enabled"
+	msgLockRenewFailed           messageID = "lock-renew-failed"
+	msgLockLost                  messageID = "lock-lost"
+	msgLockReleaseFailed         messageID = "lock-release-failed"
+	msgStateMembers              messageID = "state-members"
+	msgStateHeartbeatFailed      messageID = "state-heartbeat-failed"
+	msgStateLeaveFailed          messageID = "state-leave-failed"
+	msgBucketSplitWritten        messageID = "bucket-split-written"
+	msgBucketWritten             messageID = "bucket-written"
+	msgSubmitted                 messageID = "submitted"
+	msgSubmitNotRemoved          messageID = "submit-not-removed"
+	msgSubmitRemoved             messageID = "submit-removed"
+	msgWindowFirst               messageID = "window-first"
+	msgWindowBefore              messageID = "window-before"
+	msgLimitViolation            messageID = "limit-violation"
+	msgTxAnomaly                 messageID = "tx-anomaly"
+	msgVerified                  messageID = "verified"
+	msgWatchingBlocks            messageID = "watching-blocks"
+	msgWatching                  messageID = "watching"
+	msgWatchMaxDuration          messageID = "watch-max-duration"
+	msgWatchDone                 messageID = "watch-done"
+	msgWatchChangedDetail        messageID = "watch-changed-detail"
+	msgWatchChanged              messageID = "watch-changed"
+	msgFollowingBlocks           messageID = "following-blocks"
+	msgPendingPoolFailed         messageID = "pending-pool-failed"
+	msgPendingPoolEntered        messageID = "pending-pool-entered"
+	msgWatchdirWatching          messageID = "watchdir-watching"
+	msgWatchdirStopped           messageID = "watchdir-stopped"
+	msgWatchdirLockFailed        messageID = "watchdir-lock-failed"
+	msgWatchdirChecking          messageID = "watchdir-checking"
+	msgWatchdirCheckFailed       messageID = "watchdir-check-failed"
+	msgWatchdirMoveFailed        messageID = "watchdir-move-failed"
+	msgWatchdirMoved             messageID = "watchdir-moved"
+	msgWizardEncodeFailed        messageID = "wizard-encode-failed"
+	msgWizardWriteFailed         messageID = "wizard-write-failed"
+)
+
+// defaultLanguage is the language of the messages missing from the catalog of the user's language
+const defaultLanguage = "en"
+
+// messageCatalogs are the fmt formats of the messages by language
+var messageCatalogs = map[string]map[messageID]string{
+	defaultLanguage: {
+		msgConfigRead:         "error while reading config %s: %v",
+		msgConfigParse:        "error while parsing config %s: %v",
+		msgConfigUnknownFlag:  "config %s sets unknown flag %q",
+		msgConfigInvalidValue: "config %s has invalid %s: %v",
+		msgEnvInvalidValue:    "environment variable %s has invalid %s: %v",
+		msgStrictPermissive:   "--strict and --permissive are mutually exclusive",
+		msgMaxMemoryShred: "--max-memory spills signed transactions to a temporary file, it can not be used with " +
+			"--shred",
+		msgUnknownInputFormat: "unknown --input-format %q",
+		msgMissingIndexer: "please supply an indexer client address using --idx-addr flag or AF_IDX_ADDRESS " +
+			"environment variable",
+		msgNoInputs:             "supply at least 1 transactions file",
+		msgNoTxIDs:              "supply at least 1 txid, or --txid-file",
+		msgInputCompleted:       "skipping %s, checked completely by a previous run as %s",
+		msgFoundTxs:             "found %d groups and %d individual transactions in %s",
+		msgSkippedOutsideWindow: "skipping %d transactions in %s outside the submission time window",
+		msgSkippedUnsigned:      "skipping %d unsigned transactions in %s, they cannot be submitted",
+		msgUnsentSummary:        "file %s has %d unsent groups and %d unsent individual transactions",
+		msgNoUnsent:             "no unsent transaction were found!",
+		msgExpired: "%d unsent transactions of %s are past their last valid round and can never be confirmed, " +
+			"do not resubmit them",
+		msgReconciled: "%s: %d transactions = %d confirmed + %d unsent + %d skipped%s",
+		msgNotReconciled: "transactions do not reconcile, %s: %d transactions = %d confirmed + %d unsent + " +
+			"%d skipped%s",
+		msgCheckpointSaved:   "saved the status of %d transactions to checkpoint %s",
+		msgPeakMemory:        "peak memory %s",
+		msgNFDFailed:         "failed resolving NFD: %v",
+		msgAlertFired:        "alert %q fired after checking %s, %s is %g",
+		msgAlertResolved:     "alert %q resolved after checking %s, %s is %g",
+		msgAlertNotifyFailed: "failed notifying %s of alert %q: %v",
+		msgAmountHeld:        "unsent tx %s %s",
+		msgAmountsHeld: "holding back %d unsent transactions exceeding --max-amount, pass --confirm-large to " +
+			"approve them",
+		msgArchiveEncrypted: "archive reads the reports of the checks, so they cannot be encrypted, encrypt the " +
+			"archives instead",
+		msgArchiveNotConfirmed: "tx %s of %s is %s",
+		msgArchived:            "archived the %d confirmed transactions of %s to %s",
+		msgArchiveDeleted:      "deleted %s",
+		msgRequestRetry:        "%s failed, retry %d of %d in %s: %v",
+		msgBatchDirectory:      "reading %d files of directory %s as a single batch",
+		msgBudgetSoft: "soft request budget reached after %g requests costing %g, slowing down by %s per " +
+			"request",
+		msgCacheLoaded:           "%d confirmed and %d unsent transactions cached in %s",
+		msgCacheWriteFailed:      "failed to write %d lookup results to cache %s: %v",
+		msgCacheHits:             "%d lookups answered by cache %s",
+		msgCacheCloseFailed:      "failed to close cache %s: %v",
+		msgCompletionFailed:      "failed writing the %s completion script: %v",
+		msgDashboardServing:      "serving the dashboard of %s on http://%s",
+		msgDashboardRenderFailed: "failed rendering the dashboard: %v",
+		msgDebugBundleWritten:    "wrote debug bundle %s, check it before attaching it to a bug report",
+		msgDecodeInvalidOutput:   "invalid --output %q, expected %s or %s",
+		msgDecodeInvalidTxID:     "invalid --txid %q",
+		msgCorruptSkipped:        "skipping %d records that are not transactions",
+		msgPossibleDuplicate: "possible duplicate %s transactions from %s to %s with overlapping validity: %s, " +
+			"review them before resubmitting",
+		msgEncodeSingleInput:      "--out can only be used with a single input file",
+		msgEncodeInvalidSignature: "tx %s is not validly signed, it will be rejected unless signed again: %v",
+		msgEncoded:                "encoded %d transactions of %s to %s, %d of them not validly signed",
+		msgInvalidConcurrency:     "--concurrency must be at least 1",
+		msgInvalidMaxRequests:     "invalid --max-requests: %v",
+		msgInvalidMaxRequestCost:  "invalid --max-request-cost: %v",
+		msgFaultsInjecting:        "injecting faults (%s), the outcome of this run must not be trusted",
+		msgFaultsInjected:         "injected %d lookup failures, %d delays and %d partial writes",
+		msgFeeTooLow: "%s pays a fee of %d microalgos, %s: the node would reject it, it needs a bump of %d " +
+			"microalgos to %d",
+		msgGroupPartial: "group %s is partially sent: %d of its %d transactions were found, it was tampered with " +
+			"or the indexer has gaps",
+		msgGroupPartialPolicy: "group %s is partially sent: %d of %d transactions looked up were found, considered %s",
+		msgIndexWritten:       "wrote index of %d transactions in %s to %s",
+		msgIncludeNoMatch:     "no files of %s match --include %s",
+		msgInputsExpanded:     "expanded %d arguments to %d inputs",
+		msgInterrupted: "received %s, stopping once the requests in progress complete, send it again to stop " +
+			"at once",
+		msgJobRemoveFailed:    "failed to remove %s: %v",
+		msgJobFailed:          "job failed: %v",
+		msgJobQueued:          "queued a job of %d txids in %d chunks",
+		msgJobProcessing:      "processing a job of %d txids",
+		msgJobDone:            "job done: %d confirmed, %d unsent, %d expired",
+		msgJobDeliveryFailed:  "failed to deliver the report to %s: %v",
+		msgJobDelivered:       "delivered the report to %s",
+		msgKeyregOffline:      "unsent offline keyreg, account is currently %s",
+		msgKeyregTakesOffline: "resubmitting keyreg tx %s would take the currently online account %s offline",
+		msgKeyregOnline: "unsent online keyreg with participation keys valid for rounds %d-%d, account is " +
+			"currently %s at round %d",
+		msgKeyregExpiredKeys: "keyreg tx %s registers participation keys that expired at round %d, resubmitting it " +
+			"would leave account %s not participating",
+		msgKeyregDifferentKeys: "account %s is online with different participation keys (valid for rounds %d-%d), " +
+			"resubmitting keyreg tx %s would replace them",
+		msgKeyregAccountFailed: "skipping keyreg tx %s, failed getting its account %s, request %s: %v",
+		msgLeaseCollision: "transactions from %s with lease %s and overlapping validity: %s, only one of them can " +
+			"be confirmed",
+		msgLsigEvalFailed:      "failed evaluating the logicsigs of tx %s of %s: %v",
+		msgLsigRejects:         "the logicsig of unsent tx %s would reject it on resubmission: %s",
+		msgLsigRejectsSummary:  "%d unsent transactions of %s would be rejected by their logicsigs",
+		msgRecordSkipped:       "skipping record %d that is not a transaction: %v",
+		msgDecodeFailed:        "error while dcoding txn: %v",
+		msgPossiblyPending:     "%d transactions of %s are possibly pending at --current-round %d",
+		msgStatusWritten:       "wrote the status of %d transactions to %s",
+		msgUnsentTxID:          "unsent tx %s at index %d of %s",
+		msgUnsentTx:            "unsent %s tx %s at index %d of %s from %s",
+		msgUnsentTxTo:          "unsent %s tx %s at index %d of %s from %s to %s",
+		msgConcurrencySummary:  "adaptive concurrency ended at %d concurrent lookups, peaking at %d",
+		msgHedgeSummary:        "hedged %d of %d lookups, the hedge indexer answered first %d times",
+		msgGraphWritten:        "wrote the graph of the unsent transactions to %s",
+		msgResumeHint:          "rerun with --checkpoint %s --resume to resume where this run stopped",
+		msgManifestSizeChanged: "%s changed since the run of manifest %s: it has %d bytes, it had %d",
+		msgManifestReordered: "%s changed since the run of manifest %s, but has the same %d transactions: they " +
+			"were reordered or re-encoded",
+		msgManifestTxsChanged: "%s changed since the run of manifest %s: %d transactions were added and %d removed",
+		msgMemorySpilling:     "heap of %s exceeds --max-memory %s, spilling transaction encodings to %s",
+		msgSpillRemoveFailed:  "failed to remove spill file %s: %v",
+		msgMetricsServing:     "serving metrics on http://%s/metrics",
+		msgMultisigIncomplete: "unsent tx %s of %s has %d of the %d multisig signatures needed, it cannot be " +
+			"submitted until %d more of %s sign it",
+		msgMultisigIncompleteSummary: "%d unsent transactions of %s miss multisig signatures",
+		msgPriorityNotFound:          "priority tx %s was not found",
+		msgPriorityLookedUp:          "looked up %d priority transactions ahead of %d others: %d found",
+		msgPprofServing:              "serving pprof on http://%s/debug/pprof/",
+		msgCPUProfileFailed:          "failed to write CPU profile %s: %v",
+		msgHeapProfileCreateFailed:   "failed to create heap profile %s: %v",
+		msgHeapProfileWriteFailed:    "failed to write heap profile %s: %v",
+		msgInvalidRetention:          "invalid --retention: %v",
+		msgInvalidPrunePattern:       "invalid --prune-pattern %q: %v",
+		msgNoDirectories:             "supply at least 1 directory",
+		msgPruneDeleted:              "deleted %d files older than %s",
+		msgPruneArchived:             "archived %d files older than %s",
+		msgPruneWouldDelete:          "would have deleted %d files older than %s",
+		msgPruneWouldArchive:         "would have archived %d files older than %s",
+		msgPruneDeleting:             "deleting %s, last modified %s",
+		msgPruneArchiving:            "archiving %s to %s, last modified %s",
+		msgRetryAfterCapped:          "%s asked to retry after %s, retrying after %s instead",
+		msgRateLimited:               "%s is rate limiting, pausing its requests for %s",
+		msgRoundTimeFailed:           "failed getting the time of round %d for the report: %v",
+		msgTopAssets:                 "%d assets have unsent transactions, top %d:",
+		msgTopAsset:                  "  asset %d: %d unsent, %d base units transferred",
+		msgTopApps:                   "%d applications have unsent calls, top %d:",
+		msgTopApp:                    "  application %d: %d unsent calls",
+		msgTopAppMoving:              "  application %d: %d unsent calls, their groups moving %s",
+		msgScreenedOut:               "unsent tx %s will not be resubmitted: %s",
+		msgTopSenders:                "%d senders have unsent transactions, top %d:",
+		msgTopSender:                 "  %s: %s",
+		msgServeServing:              "serving the checks on http://%s",
+		msgServeStopped:              "stopped serving",
+		msgServeRemoveFailed:         "failed to remove %s: %v",
+		msgServeRequestFailed:        "request failed: %v",
+		msgTokensReadable:            "tokens %s can be read by other users, restrict it with chmod 600",
+		msgSimulateFailed:            "failed simulating tx %s of %s: %v",
+		msgSimulateFails:             "simulating tx %s fails: %s",
+		msgSinceCarried: "carrying forward the %d transactions confirmed in %s, only the other ones are " +
+			"looked up",
+		msgStateSharing:         "sharing state in %s as instance %s",
+		msgStateLookupFailed:    "failed to look up tx %s in --state-backend: %v",
+		msgStateRecordFailed:    "failed to cache tx %s in --state-backend: %v",
+		msgStateCacheDisabled:   "not using the cache of --state-backend, which did not answer: %v",
+		msgStateCacheEnabled:    "using the cache of --state-backend again",
+		msgLockRenewFailed:      "failed to renew the lock of %s: %v",
+		msgLockLost:             "lost the lock of %s, another instance may be checking it too",
+		msgLockReleaseFailed:    "failed to release the lock of %s, it expires within %s: %v",
+		msgStateMembers:         "sharing the watched files with %d other instances",
+		msgStateHeartbeatFailed: "%v",
+		msgStateLeaveFailed:     "failed to leave the members, the others take over within %s: %v",
+		msgBucketSplitWritten:   "wrote %d %s transactions to %d files in %s",
+		msgBucketWritten:        "wrote %d %s transactions to %s",
+		msgSubmitted:            "the node accepted %d transactions, rejected %d, and %d were not submitted",
+		msgSubmitNotRemoved:     "not removing %s, some of its transactions were not submitted",
+		msgSubmitRemoved:        "removed %s",
+		msgWindowFirst:          "considering transactions with first valid round %d or later",
+		msgWindowBefore:         "considering transactions with first valid round before %d",
+		msgLimitViolation:       "%s, it would be rejected by the network",
+		msgTxAnomaly:            "%s",
+		msgVerified:             "%d transactions are validly signed and %d are not",
+		msgWatchingBlocks:       "watching %d unsent transactions in the blocks of the node",
+		msgWatching:             "watching %d unsent transactions every %s",
+		msgWatchMaxDuration:     "stopped watching %d unsent transactions after --max-duration %s",
+		msgWatchDone:            "all the watched transactions are confirmed or expired",
+		msgWatchChangedDetail:   "tx %s changed from unsent to %s: %s",
+		msgWatchChanged:         "tx %s changed from unsent to %s",
+		msgFollowingBlocks:      "following the blocks of the node after round %d",
+		msgPendingPoolFailed:    "failed reading the pending pool, algod request %s: %v",
+		msgPendingPoolEntered:   "tx %s entered the pending pool of the node",
+		msgWatchdirWatching:     "watching %s for %s files",
+		msgWatchdirStopped:      "stopped watching %s",
+		msgWatchdirLockFailed:   "failed to lock %s, checking it later: %v",
+		msgWatchdirChecking:     "checking dropped file",
+		msgWatchdirCheckFailed:  "failed checking %s, leaving it in place",
+		msgWatchdirMoveFailed:   "failed to move %s: %v",
+		msgWatchdirMoved:        "moved %s to %s",
+		msgWizardEncodeFailed:   "failed encoding config: %v",
+		msgWizardWriteFailed:    "failed to write config to %s: %v",
+	},
+}
+
+// messageLanguage returns the language of the messages from the locale environment variables, e.g. de for
+// LANG=de_DE.UTF-8
+func messageLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		fields := strings.FieldsFunc(locale, func(r rune) bool {
+			return r == '_' || r == '.' || r == '@'
+		})
+		if len(fields) != 0 {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return defaultLanguage
+}
+
+// message returns the text of a message in the user's language
+func message(id messageID, args ...interface{}) string {
+	format, ok := messageCatalogs[messageLanguage()][id]
+	if !ok {
+		format = messageCatalogs[defaultLanguage][id]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// userError is an error with a message of the catalog
+type userError struct {
+	id   messageID
+	text string
+}
+
+func (e *userError) Error() string {
+	return e.text
+}
+
+// newUserError returns an error with the message id
+func newUserError(id messageID, args ...interface{}) error {
+	return &userError{id: id, text: message(id, args...)}
+}
+
+// errorCode returns the message ID of err, empty if it is not a message of the catalog
+func errorCode(err error) messageID {
+	var userErr *userError
+	if errors.As(err, &userErr) {
+		return userErr.id
+	}
+	return ""
+}
+
+// logMessage logs a message of the catalog with its ID
+func logMessage(level log.Level, id messageID, args ...interface{}) {
+	logEntryMessage(log.NewEntry(log.StandardLogger()), level, id, args...)
+}
+
+// logEntryMessage logs a message of the catalog with its ID and the fields of entry
+func logEntryMessage(entry *log.Entry, level log.Level, id messageID, args ...interface{}) {
+	entry.WithField("code", id).Log(level, message(id, args...))
+}
+
+// logError logs err, with its ID if it is a message of the catalog
+func logError(err error) {
+	if code := errorCode(err); code != "" {
+		log.WithField("code", code).Error(err)
+		return
+	}
+	log.Error(err)
+}
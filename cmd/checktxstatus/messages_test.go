@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMessageFallsBackToEnglish(t *testing.T) {
+	defer func(lang string, catalog map[messageID]string) {
+		os.Setenv("LANG", lang)
+		messageCatalogs["de"] = catalog
+	}(os.Getenv("LANG"), messageCatalogs["de"])
+	os.Unsetenv("LC_ALL")
+	os.Unsetenv("LC_MESSAGES")
+	os.Setenv("LANG", "de_DE.UTF-8")
+	messageCatalogs["de"] = map[messageID]string{msgNoUnsent: "keine nicht gesendeten Transaktionen gefunden!"}
+
+	if language := messageLanguage(); language != "de" {
+		t.Errorf("expected the language of LANG, got %q", language)
+	}
+	if text := message(msgNoUnsent); text != "keine nicht gesendeten Transaktionen gefunden!" {
+		t.Errorf("expected the translated message, got %q", text)
+	}
+	if text := message(msgNoInputs); text != "supply at least 1 transactions file" {
+		t.Errorf("expected the English message missing from the catalog, got %q", text)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	err := newUserError(msgUnknownInputFormat, "xml")
+	if err.Error() != `unknown --input-format "xml"` || errorCode(err) != msgUnknownInputFormat {
+		t.Errorf("expected the catalog error with its code, got %q, %q", err, errorCode(err))
+	}
+	if code := errorCode(fmt.Errorf("wrapped: %w", err)); code != msgUnknownInputFormat {
+		t.Errorf("expected the code of a wrapped error, got %q", code)
+	}
+	if code := errorCode(fmt.Errorf("other")); code != "" {
+		t.Errorf("expected no code of an error out of the catalog, got %q", code)
+	}
+}
+
+func TestMessagesAreInTheCatalog(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, filename := range files {
+		// the helpers of messages.go log the catalogued messages
+		if strings.HasSuffix(filename, "_test.go") || filename == "messages.go" {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			// err.Error() takes no arguments
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "Info", "Infof", "Warn", "Warnf", "Warning", "Warningf", "Error", "Errorf", "Print", "Printf":
+			default:
+				return true
+			}
+			// the messages are logged by the logrus package or an entry of it, not fmt.Errorf or http.Error
+			if x, ok := sel.X.(*ast.Ident); ok && (x.Name == "fmt" || x.Name == "http") {
+				return true
+			}
+			t.Errorf("%s logs a message that is not in the catalog, use logMessage", fset.Position(call.Pos()))
+			return true
+		})
+	}
+	for _, id := range catalogIDs(t, fset) {
+		if _, ok := messageCatalogs[defaultLanguage][messageID(id)]; !ok {
+			t.Errorf("message %s has no %s text", id, defaultLanguage)
+		}
+	}
+}
+
+// catalogIDs returns the IDs of the messageID constants of messages.go
+func catalogIDs(t *testing.T, fset *token.FileSet) []string {
+	file, err := parser.ParseFile(fset, "messages.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); ok && ident.Name == "messageID" {
+				ids = append(ids, strings.Trim(value.Values[0].(*ast.BasicLit).Value, `"`))
+			}
+		}
+	}
+	return ids
+}
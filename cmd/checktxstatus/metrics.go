@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var metricsListen string
+
+func init() {
+	rootCmd.Flags().StringVar(&metricsListen, "metrics-listen", "",
+		"serve Prometheus metrics of the transactions checked and of the indexer lookups on this address (e.g. "+
+			"localhost:9100) at /metrics, for monitoring --watch and watch-dir")
+}
+
+// metrics are the Prometheus metrics of the runs of the process, nil if --metrics-listen is not set
+// they outlive a run so the counters of watch-dir add up over the files it checks
+var metrics *runMetrics
+
+// runMetrics counts the transactions checked and the indexer lookups
+type runMetrics struct {
+	mu        sync.Mutex
+	checked   int
+	confirmed int
+	unsent    int
+	expired   int
+	// watched is the number of unsent transactions --watch is waiting for
+	watched int
+	// errors are the failed indexer lookups, not found transactions are not failures
+	errors int
+	// lookups is a histogram of the latencies of the indexer lookups, in the buckets of the debug bundle
+	lookups backendLatency
+}
+
+// startMetrics serves the metrics if --metrics-listen is set, the returned function stops serving them
+// they are served once per process, by the first caller, and the other callers get a function doing nothing
+func startMetrics() (func(), error) {
+	if metricsListen == "" || metrics != nil {
+		return func() {}, nil
+	}
+	listener, err := net.Listen("tcp", metricsListen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on --metrics-listen %s: %v", metricsListen, err)
+	}
+	metrics = &runMetrics{lookups: backendLatency{counts: make([]int, len(latencyBuckets)+1)}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", metrics.serve)
+	logMessage(log.InfoLevel, msgMetricsServing, listener.Addr())
+	go func() {
+		// Serve returns an error once the listener is closed
+		_ = http.Serve(listener, mux)
+	}()
+	return func() {
+		_ = listener.Close()
+		metrics = nil
+	}, nil
+}
+
+// classified counts the transactions of a chunk by their condition
+func (m *runMetrics) classified(classified map[txCondition][]txRecord) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for condition, txs := range classified {
+		m.checked += len(txs)
+		switch condition {
+		case conditionConfirmed:
+			m.confirmed += len(txs)
+		case conditionUnsent:
+			m.unsent += len(txs)
+		case conditionExpired:
+			m.expired += len(txs)
+		}
+	}
+}
+
+// watch sets the number of unsent transactions --watch is waiting for
+func (m *runMetrics) watch(unsent int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watched = unsent
+}
+
+// transition counts a watched transaction that changed from unsent to condition
+func (m *runMetrics) transition(condition txCondition) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch condition {
+	case conditionConfirmed:
+		m.confirmed++
+	case conditionExpired:
+		m.expired++
+	}
+}
+
+// observeLookup counts an indexer lookup
+func (m *runMetrics) observeLookup(latency time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups.requests++
+	m.lookups.total += latency
+	if err != nil {
+		m.errors++
+	}
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool {
+		return latency <= latencyBuckets[i]
+	})
+	m.lookups.counts[bucket]++
+}
+
+// serve writes the metrics in the Prometheus text format
+func (m *runMetrics) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	var b strings.Builder
+	for _, counter := range []struct {
+		name, help string
+		value      int
+	}{
+		{"checktxstatus_transactions_checked_total", "Transactions checked.", m.checked},
+		{"checktxstatus_transactions_confirmed_total", "Transactions found confirmed, when checked or watched.",
+			m.confirmed},
+		{"checktxstatus_transactions_unsent_total", "Transactions found unsent when checked.", m.unsent},
+		{"checktxstatus_transactions_expired_total", "Transactions found expired, when checked or watched.",
+			m.expired},
+		{"checktxstatus_indexer_errors_total", "Indexer lookups that failed.", m.errors},
+	} {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", counter.name, counter.help, counter.name,
+			counter.name, counter.value)
+	}
+	fmt.Fprintf(&b, "# HELP checktxstatus_transactions_watched Unsent transactions --watch is waiting for.\n"+
+		"# TYPE checktxstatus_transactions_watched gauge\nchecktxstatus_transactions_watched %d\n", m.watched)
+	const histogram = "checktxstatus_indexer_lookup_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Latency of the indexer lookups.\n# TYPE %s histogram\n", histogram, histogram)
+	cumulative := 0
+	for i, bound := range latencyBuckets {
+		cumulative += m.lookups.counts[i]
+		fmt.Fprintf(&b, "%s_bucket{le=\"%g\"} %d\n", histogram, bound.Seconds(), cumulative)
+	}
+	fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", histogram, m.lookups.requests, histogram,
+		m.lookups.total.Seconds(), histogram, m.lookups.requests)
+	m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(b.String()))
+}
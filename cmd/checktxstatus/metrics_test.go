@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsServedInThePrometheusFormat(t *testing.T) {
+	m := &runMetrics{lookups: backendLatency{counts: make([]int, len(latencyBuckets)+1)}}
+	m.classified(map[txCondition][]txRecord{conditionConfirmed: {{}, {}}, conditionUnsent: {{}}})
+	m.watch(1)
+	m.transition(conditionConfirmed)
+	m.observeLookup(3*time.Millisecond, nil)
+	m.observeLookup(2*time.Minute, fmt.Errorf("HTTP 503: unavailable"))
+
+	server := httptest.NewServer(http.HandlerFunc(m.serve))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"checktxstatus_transactions_checked_total 3",
+		"checktxstatus_transactions_confirmed_total 3",
+		"checktxstatus_transactions_unsent_total 1",
+		"checktxstatus_transactions_watched 1",
+		"checktxstatus_indexer_errors_total 1",
+		`checktxstatus_indexer_lookup_duration_seconds_bucket{le="0.01"} 1`,
+		`checktxstatus_indexer_lookup_duration_seconds_bucket{le="+Inf"} 2`,
+		"checktxstatus_indexer_lookup_duration_seconds_count 2",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected %q in the metrics, got\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	var m *runMetrics
+	m.classified(map[txCondition][]txRecord{conditionUnsent: {{}}})
+	m.observeLookup(time.Millisecond, nil)
+	stop, err := startMetrics()
+	if err != nil || metrics != nil {
+		t.Errorf("expected no metrics without --metrics-listen, got %v", err)
+	}
+	stop()
+}
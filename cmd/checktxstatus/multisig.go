@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// multisigStatus is how far the multisig of a transaction is from its threshold, for the coordinators collecting
+// the signatures to know whether it can be submitted
+type multisigStatus struct {
+	Threshold int `json:"threshold"`
+	// Signed is the number of subsignatures present
+	Signed int `json:"signed"`
+	// Missing are the addresses of the signers whose subsignatures are missing, in the order of the multisig
+	Missing []string `json:"missing,omitempty"`
+	missing []types.Address
+}
+
+// complete returns whether the multisig has the signatures needed by its threshold
+func (s *multisigStatus) complete() bool {
+	return s.Signed >= s.Threshold
+}
+
+// multisigOf returns the status of the multisig of a transaction, or of the multisig delegating its logicsig, nil if
+// it has none
+func multisigOf(stx types.SignedTxn) *multisigStatus {
+	msig := stx.Msig
+	if msig.Blank() {
+		msig = stx.Lsig.Msig
+	}
+	if msig.Blank() {
+		return nil
+	}
+	return newMultisigStatus(msig)
+}
+
+// newMultisigStatus returns the status of a multisig, whose subsignatures are counted, not verified
+func newMultisigStatus(msig types.MultisigSig) *multisigStatus {
+	status := &multisigStatus{Threshold: int(msig.Threshold)}
+	for _, subsig := range msig.Subsigs {
+		if subsig.Sig != (types.Signature{}) {
+			status.Signed++
+			continue
+		}
+		var signer types.Address
+		copy(signer[:], subsig.Key)
+		status.Missing = append(status.Missing, signer.String())
+		status.missing = append(status.missing, signer)
+	}
+	return status
+}
+
+// reportIncompleteMultisigs warns about the unsent transactions whose multisigs miss signatures, which cannot be
+// submitted until they are signed by enough of the missing signers
+func reportIncompleteMultisigs(filename string, txs []txRecord, resolver *addressResolver) {
+	incomplete := 0
+	for _, tx := range txs {
+		if tx.txIDOnly {
+			continue
+		}
+		status := multisigOf(tx.stx)
+		if status == nil {
+			continue
+		}
+		if status.complete() {
+			log.Debugf("unsent tx %s of %s has %d of the %d multisig signatures needed", tx.txID, filename,
+				status.Signed, status.Threshold)
+			continue
+		}
+		incomplete++
+		missing := make([]string, 0, len(status.missing))
+		for _, signer := range status.missing {
+			missing = append(missing, resolver.label(signer))
+		}
+		logMessage(log.WarnLevel, msgMultisigIncomplete, tx.txID, filename, status.Signed, status.Threshold,
+			status.Threshold-status.Signed, strings.Join(missing, ", "))
+	}
+	if incomplete != 0 {
+		logMessage(log.WarnLevel, msgMultisigIncompleteSummary, incomplete, filename)
+	}
+}
+
+// describe returns a short description of the multisig, e.g. "1 of the 2 signatures needed, missing <address>"
+func (s *multisigStatus) describe() string {
+	description := fmt.Sprintf("%d of the %d signatures needed", s.Signed, s.Threshold)
+	if len(s.Missing) != 0 {
+		description += ", missing " + strings.Join(s.Missing, ", ")
+	}
+	return description
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestMultisigOf(t *testing.T) {
+	signer, other := crypto.GenerateAccount(), crypto.GenerateAccount()
+	msig, err := crypto.MultisigAccountWithParams(1, 2, []types.Address{signer.Address, other.Address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, _ := msig.Address()
+	txn := types.Transaction{Type: types.PaymentTx, Header: types.Header{Sender: sender, Fee: 1000,
+		FirstValid: 1, LastValid: 1000}}
+	_, stxBytes, err := crypto.SignMultisigTransaction(signer.PrivateKey, msig, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stx types.SignedTxn
+	if err := msgpack.Decode(stxBytes, &stx); err != nil {
+		t.Fatal(err)
+	}
+	status := multisigOf(stx)
+	if status == nil || status.complete() || status.Signed != 1 || status.Threshold != 2 {
+		t.Fatalf("expected 1 of the 2 signatures needed, got %+v", status)
+	}
+	if len(status.Missing) != 1 || status.Missing[0] != other.Address.String() {
+		t.Errorf("expected %s missing, got %v", other.Address, status.Missing)
+	}
+	if multisigOf(types.SignedTxn{Txn: txn}) != nil {
+		t.Error("expected no multisig status for a transaction without a multisig")
+	}
+}
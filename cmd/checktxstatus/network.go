@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var networkName string
+
+func init() {
+	rootCmd.Flags().StringVar(&networkName, "network", "",
+		"public network whose free public indexer and algod node are used, unless --idx-addr or --algod-addr are set: "+
+			"mainnet, testnet or betanet")
+}
+
+// networkPreset is a public network and the free public endpoints serving it
+type networkPreset struct {
+	name      string
+	genesisID string
+	indexer   string
+	algod     string
+}
+
+// networkPresets are the public networks
+var networkPresets = []networkPreset{
+	{name: "mainnet", genesisID: "mainnet-v1.0", indexer: "https://mainnet-idx.algonode.cloud",
+		algod: "https://mainnet-api.algonode.cloud"},
+	{name: "testnet", genesisID: "testnet-v1.0", indexer: "https://testnet-idx.algonode.cloud",
+		algod: "https://testnet-api.algonode.cloud"},
+	{name: "betanet", genesisID: "betanet-v1.0", indexer: "https://betanet-idx.algonode.cloud",
+		algod: "https://betanet-api.algonode.cloud"},
+}
+
+// applyNetwork sets the endpoints of cmd not set by a flag, an environment variable or the config to the ones of
+// --network, if cmd has the flag and it is set
+func applyNetwork(cmd *cobra.Command) error {
+	if flag := cmd.Flags().Lookup("network"); flag == nil || networkName == "" {
+		return nil
+	}
+	preset, err := selectedNetwork()
+	if err != nil {
+		return err
+	}
+	for name, address := range map[string]string{"idx-addr": preset.indexer, "algod-addr": preset.algod} {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Value.String() != "" {
+			continue
+		}
+		err := cmd.Flags().Set(name, address)
+		if err != nil {
+			return fmt.Errorf("failed setting --%s of --network %s: %v", name, networkName, err)
+		}
+	}
+	return nil
+}
+
+// selectedNetwork returns the preset of --network, nil if not set
+func selectedNetwork() (*networkPreset, error) {
+	if networkName == "" {
+		return nil, nil
+	}
+	var names []string
+	for i := range networkPresets {
+		if networkPresets[i].name == networkName {
+			return &networkPresets[i], nil
+		}
+		names = append(names, networkPresets[i].name)
+	}
+	return nil, fmt.Errorf("unknown --network %q, expected %s", networkName, strings.Join(names, ", "))
+}
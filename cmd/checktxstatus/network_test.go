@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"testing"
+)
+
+func TestApplyNetworkKeepsTheEndpointsSet(t *testing.T) {
+	cmd := &cobra.Command{Use: "check"}
+	var network, indexer, algod string
+	cmd.Flags().StringVar(&network, "network", "", "")
+	cmd.Flags().StringVar(&indexer, "idx-addr", "", "")
+	cmd.Flags().StringVar(&algod, "algod-addr", "", "")
+	if err := cmd.Flags().Set("algod-addr", "http://localhost:4001"); err != nil {
+		t.Fatal(err)
+	}
+	defer func(name string) { networkName = name }(networkName)
+	networkName = "testnet"
+
+	if err := applyNetwork(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if indexer != "https://testnet-idx.algonode.cloud" || algod != "http://localhost:4001" {
+		t.Errorf("expected only the endpoint not set to be the preset, got %s and %s", indexer, algod)
+	}
+
+	networkName = "devnet"
+	if err := applyNetwork(cmd); err == nil {
+		t.Error("expected an unknown network to fail")
+	}
+}
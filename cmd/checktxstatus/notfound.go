@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"regexp"
+)
+
+var (
+	idxNotFoundStatuses []int
+	idxNotFoundBodies   []string
+	idxNotFoundEmpty    bool
+)
+
+func init() {
+	rootCmd.Flags().IntSliceVar(&idxNotFoundStatuses, "idx-not-found-status", []int{404},
+		"HTTP status codes of indexer responses meaning a transaction was not found, e.g. 404,400 behind some proxies")
+	rootCmd.Flags().StringSliceVar(&idxNotFoundBodies, "idx-not-found-body", nil,
+		"regular expression matching the body of indexer error responses meaning a transaction was not found, "+
+			"can be repeated")
+	rootCmd.Flags().BoolVar(&idxNotFoundEmpty, "idx-not-found-empty", false,
+		"treat empty successful indexer responses as transactions not found instead of failing")
+}
+
+// notFoundRules decide which responses of a backend mean that a looked up transaction does not exist
+type notFoundRules = txchecker.NotFoundRules
+
+// newNotFoundRules compiles the not found rules of a backend, flag is the prefix of its flags for error messages
+func newNotFoundRules(flag string, statuses []int, bodies []string, empty bool) (*notFoundRules, error) {
+	rules := &notFoundRules{Statuses: map[int]bool{}, Empty: empty}
+	for _, status := range statuses {
+		if status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid --%s-not-found-status %d", flag, status)
+		}
+		rules.Statuses[status] = true
+	}
+	for _, body := range bodies {
+		re, err := regexp.Compile(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s-not-found-body %q: %v", flag, body, err)
+		}
+		rules.Bodies = append(rules.Bodies, re)
+	}
+	return rules, nil
+}
+
+// initIndexerNotFoundRules returns the not found rules of the indexer from the --idx-not-found-* flags
+func initIndexerNotFoundRules() (*notFoundRules, error) {
+	return newNotFoundRules("idx", idxNotFoundStatuses, idxNotFoundBodies, idxNotFoundEmpty)
+}
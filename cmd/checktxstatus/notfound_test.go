@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestNewNotFoundRulesValidatesFlags(t *testing.T) {
+	if _, err := newNotFoundRules("idx", []int{42}, nil, false); err == nil {
+		t.Error("expected an invalid status to fail")
+	}
+	if _, err := newNotFoundRules("idx", nil, []string{"("}, false); err == nil {
+		t.Error("expected an invalid regular expression to fail")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	rules, err := newNotFoundRules("idx", []int{404, 400}, []string{"no such tx"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[error]bool{
+		fmt.Errorf("HTTP 404: {}"):                     true,
+		fmt.Errorf("HTTP 400: bad request"):            true,
+		fmt.Errorf("HTTP 500: no such tx"):             true,
+		fmt.Errorf("HTTP 500: internal error"):         false,
+		fmt.Errorf("dial tcp: connection refused 404"): false,
+		io.EOF: false,
+	}
+	for err, expected := range tests {
+		if rules.IsNotFound(err) != expected {
+			t.Errorf("expected %q not found to be %v", err, expected)
+		}
+	}
+	rules.Empty = true
+	if !rules.IsNotFound(io.EOF) {
+		t.Error("expected an empty response to be not found with --idx-not-found-empty")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	offline      bool
+	currentRound uint64
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&offline, "offline", false,
+		"classify the transactions locally, without any indexer or node, as expired if their last valid round is "+
+			"not after --current-round and possibly pending otherwise, for dumps of air-gapped environments")
+	rootCmd.Flags().Uint64Var(&currentRound, "current-round", 0,
+		"latest round of the network the transactions of --offline are classified against")
+}
+
+// validateOffline makes sure --offline has a --current-round and none of the flags that need an indexer or a node
+func validateOffline() error {
+	if !offline {
+		if currentRound != 0 {
+			return fmt.Errorf("--current-round is only used with --offline")
+		}
+		return nil
+	}
+	if currentRound == 0 {
+		return fmt.Errorf("--offline needs the latest round of the network as --current-round")
+	}
+	incompatible := []struct {
+		name string
+		set  bool
+	}{
+		{"as-of-round", asOfRound != 0},
+		{"submitted-after", submittedAfterStr != ""},
+		{"submitted-before", submittedBeforeStr != ""},
+		{"since", sinceReport != ""},
+		{"algod-addr", algodAddress != ""},
+		{"simulate-unsent", simulateUnsent},
+		{"preview-logicsigs", previewLogicSigs},
+		{"check-fees", checkFees},
+		{"hedge-idx-addr", hedgeIndexerAddress != ""},
+		{"nfd", resolveNFD},
+		{"prefetch", prefetchSize > 0},
+		{"check-all-group-members", checkAllGroupMembers},
+		{"watch", watchUnsent},
+		{"txids", txidStream != ""},
+	}
+	for _, flag := range incompatible {
+		if flag.set {
+			return fmt.Errorf("--offline cannot be combined with --%s, which needs an indexer or a node", flag.name)
+		}
+	}
+	if inputFormat(inputFormatStr) == formatTxIDs {
+		return fmt.Errorf("--offline cannot classify txids, which have no last valid round")
+	}
+	return nil
+}
+
+// classifyOffline classifies units of signed transactions without looking them up: the ones past their last valid
+// round at --current-round are expired, since they can never be confirmed, and the others are possibly pending
+func (c *checker) classifyOffline(signed [][]txRecord, placeholders []txRecord) (map[txCondition][]txRecord, error) {
+	var notExpired []txRecord
+	for _, unit := range signed {
+		notExpired = append(notExpired, unit...)
+	}
+	pending, expired, err := c.round.splitExpired(notExpired)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("%d transactions possibly pending and %d expired at round %d", len(pending), len(expired), currentRound)
+	return map[txCondition][]txRecord{
+		conditionUnsigned: placeholders,
+		conditionExpired:  expired,
+		conditionPending:  pending,
+	}, nil
+}
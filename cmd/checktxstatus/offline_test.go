@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestClassifyOfflineExpiresGroupsPastCurrentRound(t *testing.T) {
+	offline, currentRound = true, 1000
+	defer func() {
+		offline, currentRound = false, 0
+	}()
+	tx := func(gid byte, lastValid uint64) txRecord {
+		rec := txRecord{txID: testTxID(byte(lastValid))}
+		rec.stx.Txn.Group[0] = gid
+		rec.stx.Txn.LastValid = types.Round(lastValid)
+		return rec
+	}
+	// a transaction is expired once the current round reached its last valid round
+	units := [][]txRecord{{tx(1, 1000), tx(1, 1500)}, {tx(0, 1001)}}
+	c := &checker{round: &indexerRound{}}
+	classified, err := c.classifyOffline(units, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a group is expired as a whole once any of its transactions is
+	if len(classified[conditionExpired]) != 2 || len(classified[conditionPending]) != 1 {
+		t.Errorf("expected the group expired and the individual transaction possibly pending, got %v", classified)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var overridesFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&overridesFile, "overrides", "",
+		"YAML file overriding the concurrency and the indexer of the inputs matching file patterns")
+}
+
+// inputOverride overrides how the inputs matching a file pattern are checked
+type inputOverride struct {
+	// Match is a glob matched against the path of an input, or against its base name if it has no separator
+	Match string `yaml:"match"`
+	// Concurrency is the number of concurrent lookups of the matching inputs, 0 keeps --concurrency
+	Concurrency int `yaml:"concurrency"`
+	// IndexerAddress and IndexerToken set the indexer of the matching inputs, empty keeps --idx-addr
+	IndexerAddress string `yaml:"idx-addr"`
+	IndexerToken   string `yaml:"idx-tkn"`
+	// RateLimit and RateBurst limit the requests to the indexer of IndexerAddress, 0 keeps --rate-limit and
+	// --rate-burst
+	RateLimit float64 `yaml:"rate-limit"`
+	RateBurst int     `yaml:"rate-burst"`
+	// checker checks the matching inputs, created on first use
+	checker *checker
+}
+
+// inputOverrides are the overrides of a run, the first one matching an input applies to it
+type inputOverrides struct {
+	Overrides []*inputOverride `yaml:"overrides"`
+}
+
+// loadOverrides reads overrides from a YAML file
+// it returns nil if filename is empty
+func loadOverrides(filename string) (*inputOverrides, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading overrides %s: %v", filename, err)
+	}
+	var overrides inputOverrides
+	err = yaml.UnmarshalStrict(content, &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing overrides %s: %v", filename, err)
+	}
+	for _, o := range overrides.Overrides {
+		if o.Match == "" {
+			return nil, fmt.Errorf("overrides %s has an entry with no match", filename)
+		}
+		if _, err := filepath.Match(o.Match, ""); err != nil {
+			return nil, fmt.Errorf("overrides %s has invalid match %q: %v", filename, o.Match, err)
+		}
+		if o.Concurrency < 0 {
+			return nil, fmt.Errorf("overrides %s has negative concurrency for %q", filename, o.Match)
+		}
+		if adaptiveConcurrency && o.Concurrency > maxConcurrency {
+			return nil, fmt.Errorf("overrides %s has concurrency %d for %q, above --max-concurrency",
+				filename, o.Concurrency, o.Match)
+		}
+		if o.IndexerToken != "" && o.IndexerAddress == "" {
+			return nil, fmt.Errorf("overrides %s sets idx-tkn with no idx-addr for %q", filename, o.Match)
+		}
+		if (o.RateLimit != 0 || o.RateBurst != 0) && o.IndexerAddress == "" {
+			return nil, fmt.Errorf("overrides %s sets a rate limit with no idx-addr for %q", filename, o.Match)
+		}
+		if o.RateLimit < 0 || o.RateBurst < 0 {
+			return nil, fmt.Errorf("overrides %s has a negative rate limit for %q", filename, o.Match)
+		}
+	}
+	return &overrides, nil
+}
+
+// matches returns true if the override applies to filename
+func (o *inputOverride) matches(filename string) bool {
+	name := filename
+	if !strings.ContainsRune(o.Match, filepath.Separator) {
+		name = filepath.Base(filename)
+	}
+	matched, _ := filepath.Match(o.Match, name)
+	return matched
+}
+
+// checkerFor returns the checker of filename: the checker of the first override matching it, or base if none does
+func (overrides *inputOverrides) checkerFor(base *checker, filename string) (*checker, error) {
+	if overrides == nil {
+		return base, nil
+	}
+	for _, o := range overrides.Overrides {
+		if !o.matches(filename) {
+			continue
+		}
+		if o.checker == nil {
+			var err error
+			o.checker, err = o.newChecker(base)
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Debugf("checking %s with the overrides of %q", filename, o.Match)
+		return o.checker, nil
+	}
+	return base, nil
+}
+
+// newChecker returns a copy of base with the concurrency and indexer of the override
+// its lookups have a concurrency controller of their own, so they do not take the slots of other inputs
+func (o *inputOverride) newChecker(base *checker) (*checker, error) {
+	c := *base
+	lookup := *base.lookup
+	if o.Concurrency > 0 {
+		lookup.concurrency = newConcurrencyController(o.Concurrency)
+	}
+	if o.IndexerAddress != "" {
+		client, err := indexer.MakeClientWithHeaders(o.IndexerAddress, o.IndexerToken,
+			clientHeaders(indexerTransport))
+		if err != nil {
+			return nil, fmt.Errorf("failed creating the indexer client of %q: %v", o.Match, err)
+		}
+		rate, burst := o.RateLimit, o.RateBurst
+		if rate == 0 {
+			rate = rateLimit
+		}
+		if burst == 0 {
+			burst = rateBurst
+		}
+		limitIndexer(o.IndexerAddress, rate, burst)
+		c.indexerClient = client
+		c.clock = newBlockClock(client, base.backoff)
+		lookup.indexerClient = client
+		// the hedge indexer is a duplicate of the default indexer
+		lookup.hedge = nil
+	}
+	c.lookup = &lookup
+	return &c, nil
+}
+
+// backends returns the indexers set by the overrides by their match, for the manifest
+func (overrides *inputOverrides) backends() map[string]string {
+	backends := map[string]string{}
+	if overrides == nil {
+		return backends
+	}
+	for _, o := range overrides.Overrides {
+		if o.IndexerAddress != "" {
+			backends["indexer "+o.Match] = o.IndexerAddress
+		}
+	}
+	return backends
+}
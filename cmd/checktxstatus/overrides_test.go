@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOverridesValidatesEntries(t *testing.T) {
+	dir := testDir(t)
+	for content, valid := range map[string]bool{
+		"overrides:\n- match: '*.tx'\n  concurrency: 4\n":            true,
+		"overrides:\n- concurrency: 4\n":                             false,
+		"overrides:\n- match: '[*.tx'\n":                             false,
+		"overrides:\n- match: '*.tx'\n  concurrency: -1\n":           false,
+		"overrides:\n- match: '*.tx'\n  idx-tkn: secret\n":           false,
+		"overrides:\n- match: '*.tx'\n  unknown: 1\n":                false,
+		"overrides:\n- match: '*.tx'\n  idx-addr: http://idx:8980\n": true,
+	} {
+		filename := filepath.Join(dir, "overrides.yaml")
+		if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadOverrides(filename)
+		if (err == nil) != valid {
+			t.Errorf("expected %q to be valid=%v, got %v", content, valid, err)
+		}
+	}
+}
+
+func TestCheckerForAppliesFirstMatchingOverride(t *testing.T) {
+	base := &checker{lookup: &txLookup{concurrency: newConcurrencyController(1)}}
+	overrides := &inputOverrides{Overrides: []*inputOverride{
+		{Match: "archive/*.tx", Concurrency: 8},
+		{Match: "*.tx", IndexerAddress: "http://archive:8980"},
+	}}
+	archived, err := overrides.checkerFor(base, filepath.Join("archive", "a.tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived == base || archived.lookup.concurrency == base.lookup.concurrency || archived.lookup.concurrency.limit != 8 {
+		t.Errorf("expected a checker with its own concurrency of 8, got %+v", archived.lookup)
+	}
+	again, err := overrides.checkerFor(base, filepath.Join("archive", "b.tx"))
+	if err != nil || again != archived {
+		t.Errorf("expected the checker of an override to be reused, got %v", err)
+	}
+	other, err := overrides.checkerFor(base, filepath.Join("daily", "c.tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.indexerClient == nil || other.lookup.concurrency != base.lookup.concurrency {
+		t.Errorf("expected a checker with its own indexer sharing the concurrency, got %+v", other.lookup)
+	}
+	if unmatched, _ := overrides.checkerFor(base, "c.msgp"); unmatched != base {
+		t.Error("expected the base checker for inputs matching no override")
+	}
+	if backends := overrides.backends(); len(backends) != 1 || backends["indexer *.tx"] != "http://archive:8980" {
+		t.Errorf("expected the indexer of the override in the backends, got %v", backends)
+	}
+}
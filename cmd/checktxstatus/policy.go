@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"sort"
+)
+
+var policyFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&policyFile, "policy", "",
+		"YAML file mapping transaction conditions to output buckets and exit code severities")
+}
+
+// txCondition is the outcome of checking a transaction
+type txCondition string
+
+const (
+	// conditionConfirmed transactions were found by the indexer, or by the --algod-addr node
+	conditionConfirmed txCondition = "confirmed"
+	// conditionUnsent transactions were not found and can be resubmitted
+	conditionUnsent txCondition = "unsent"
+	// conditionUnsigned transactions have no signature and can never be submitted
+	conditionUnsigned txCondition = "unsigned"
+	// conditionDenied transactions were not found but may not be resubmitted according to the address lists
+	conditionDenied txCondition = "denied"
+	// conditionLarge transactions were not found but exceed the amount limits and were not approved for resubmission
+	conditionLarge txCondition = "large"
+	// conditionExpired transactions were not found and are past their last valid round, so they can never be confirmed
+	conditionExpired txCondition = "expired"
+	// conditionPartial transactions belong to groups of which only some transactions were found with
+	// --check-all-group-members, which cannot be resubmitted as they are
+	conditionPartial txCondition = "partial"
+	// conditionPending transactions were classified by --offline and are not past their last valid round, so they may
+	// be pending or even confirmed
+	conditionPending txCondition = "pending"
+)
+
+// knownConditions are all the conditions a policy can refer to
+var knownConditions = map[txCondition]bool{
+	conditionConfirmed: true,
+	conditionUnsent:    true,
+	conditionUnsigned:  true,
+	conditionDenied:    true,
+	conditionLarge:     true,
+	conditionExpired:   true,
+	conditionPartial:   true,
+	conditionPending:   true,
+}
+
+// conditionRule decides what to do with transactions of a certain condition
+type conditionRule struct {
+	// Bucket is the suffix of the output file the transactions are written to, they are not written if it is empty
+	Bucket string `yaml:"bucket"`
+	// Severity is the exit code of the run if there are any transactions of the condition
+	// the run exits with the highest severity found
+	Severity int `yaml:"severity"`
+}
+
+// classificationPolicy maps transaction conditions to rules
+type classificationPolicy struct {
+	Conditions map[txCondition]conditionRule `yaml:"conditions"`
+	// Derived are the statuses refining the conditions, tried in order
+	Derived []*derivedStatus `yaml:"derived"`
+}
+
+// defaultPolicy writes unsent transactions to <file>.unsent, the ones held back for exceeding amount limits to
+// <file>.large, the expired ones to <file>.expired, the partially sent groups to <file>.partial and the possibly
+// pending ones of --offline to <file>.pending
+// it exits successfully unless groups are partially sent, an error
+func defaultPolicy() classificationPolicy {
+	return classificationPolicy{
+		Conditions: map[txCondition]conditionRule{
+			conditionUnsent:  {Bucket: "unsent"},
+			conditionLarge:   {Bucket: "large"},
+			conditionExpired: {Bucket: "expired"},
+			conditionPartial: {Bucket: "partial", Severity: 1},
+			conditionPending: {Bucket: "pending"},
+		},
+	}
+}
+
+var bucketRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+
+// loadPolicy reads a policy from a YAML file, conditions missing from the file keep their default rules and derived
+// statuses missing from its conditions get the rules of the conditions they refine
+// it returns the default policy if filename is empty
+func loadPolicy(filename string) (classificationPolicy, error) {
+	policy := defaultPolicy()
+	if filename == "" {
+		return policy, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return policy, fmt.Errorf("error while reading policy %s: %v", filename, err)
+	}
+	var loaded classificationPolicy
+	err = yaml.UnmarshalStrict(content, &loaded)
+	if err != nil {
+		return policy, fmt.Errorf("error while parsing policy %s: %v", filename, err)
+	}
+	err = parseDerivedStatuses(filename, loaded.Derived)
+	if err != nil {
+		return policy, err
+	}
+	derived := map[txCondition]bool{}
+	for _, status := range loaded.Derived {
+		derived[status.Name] = true
+	}
+	for condition, rule := range loaded.Conditions {
+		if !knownConditions[condition] && !derived[condition] {
+			return policy, fmt.Errorf("policy %s refers to unknown condition %q", filename, condition)
+		}
+		if !bucketRegexp.MatchString(rule.Bucket) {
+			return policy, fmt.Errorf("policy %s has invalid bucket name %q for condition %s",
+				filename, rule.Bucket, condition)
+		}
+		if rule.Severity < 0 || rule.Severity > 125 {
+			return policy, fmt.Errorf("policy %s has severity %d for condition %s, must be between 0 and 125",
+				filename, rule.Severity, condition)
+		}
+		policy.Conditions[condition] = rule
+	}
+	for _, status := range loaded.Derived {
+		if _, ok := loaded.Conditions[status.Name]; !ok {
+			policy.Conditions[status.Name] = policy.Conditions[status.From]
+		}
+	}
+	policy.Derived = loaded.Derived
+	return policy, nil
+}
+
+// buckets groups classified transactions by the bucket they should be written to
+// transactions in each bucket are sorted by their position in the file
+func (p classificationPolicy) buckets(classified map[txCondition][]txRecord) map[string][]txRecord {
+	buckets := map[string][]txRecord{}
+	for condition, txs := range classified {
+		bucket := p.Conditions[condition].Bucket
+		if bucket == "" || len(txs) == 0 {
+			continue
+		}
+		buckets[bucket] = append(buckets[bucket], txs...)
+	}
+	for _, txs := range buckets {
+		sort.SliceStable(txs, func(i, j int) bool {
+			return txs[i].index < txs[j].index
+		})
+	}
+	return buckets
+}
+
+// severity returns the highest severity of the conditions that have transactions
+func (p classificationPolicy) severity(classified map[txCondition][]txRecord) int {
+	severity := 0
+	for condition, txs := range classified {
+		if len(txs) != 0 && p.Conditions[condition].Severity > severity {
+			severity = p.Conditions[condition].Severity
+		}
+	}
+	return severity
+}
+
+// refined returns the condition refined by a derived status, or condition itself if it is not derived
+func (p classificationPolicy) refined(condition txCondition) txCondition {
+	for _, status := range p.Derived {
+		if status.Name == condition {
+			return status.From
+		}
+	}
+	return condition
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestPolicy writes content to a policy file and returns its name
+func writeTestPolicy(t *testing.T, content string) string {
+	filename := filepath.Join(testDir(t), "policy.yaml")
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadPolicyKeepsDefaultRules(t *testing.T) {
+	policy, err := loadPolicy(writeTestPolicy(t, "conditions:\n  unsigned:\n    bucket: placeholders\n    severity: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Conditions[conditionUnsent].Bucket != "unsent" {
+		t.Errorf("expected unsent transactions to keep their default bucket, got %+v", policy.Conditions)
+	}
+	if rule := policy.Conditions[conditionUnsigned]; rule.Bucket != "placeholders" || rule.Severity != 2 {
+		t.Errorf("expected the rule of the policy, got %+v", rule)
+	}
+}
+
+func TestLoadPolicyRejectsInvalidRules(t *testing.T) {
+	for _, content := range []string{
+		"conditions:\n  lost:\n    bucket: lost\n",
+		"conditions:\n  unsent:\n    bucket: ../unsent\n",
+		"conditions:\n  unsent:\n    severity: 126\n",
+		"conditions:\n  unsent:\n    exit: 1\n",
+	} {
+		if _, err := loadPolicy(writeTestPolicy(t, content)); err == nil {
+			t.Errorf("expected policy %q to be rejected", content)
+		}
+	}
+}
+
+func TestPolicyBucketsAndSeverity(t *testing.T) {
+	policy := defaultPolicy()
+	policy.Conditions[conditionConfirmed] = conditionRule{Bucket: "done", Severity: 1}
+	policy.Conditions[conditionUnsigned] = conditionRule{Bucket: "done", Severity: 3}
+	classified := map[txCondition][]txRecord{
+		conditionConfirmed: {{index: 2}, {index: 0}},
+		conditionUnsent:    {{index: 1}},
+		conditionUnsigned:  nil,
+	}
+	buckets := policy.buckets(classified)
+	if len(buckets) != 2 || len(buckets["unsent"]) != 1 || len(buckets["done"]) != 2 || buckets["done"][0].index != 0 {
+		t.Errorf("expected the transactions in their buckets by position, got %v", buckets)
+	}
+	if severity := policy.severity(classified); severity != 1 {
+		t.Errorf("expected only conditions with transactions to set the severity, got %d", severity)
+	}
+}
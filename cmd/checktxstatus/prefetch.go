@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var prefetchSize int
+
+// errPrefetchStopped is the error of queued lookups dropped when checking the file stopped
+var errPrefetchStopped = errors.New("prefetching stopped")
+
+func init() {
+	rootCmd.Flags().IntVar(&prefetchSize, "prefetch", 0,
+		"look up transactions while their file is still being decoded, decoding at most this many transactions "+
+			"ahead of the lookups (0 to decode whole files first)")
+}
+
+// prefetchedLookup is the status of a transaction looked up while its file was being decoded
+type prefetchedLookup struct {
+	// done is closed when the lookup completed
+	done chan struct{}
+	sent bool
+	err  error
+}
+
+// prefetcher looks up transactions while their file is still being decoded, overlapping I/O-bound decoding with
+// network-bound lookups. Decoding blocks when the queue of transactions waiting to be looked up is full.
+type prefetcher struct {
+	lookup *txLookup
+	window *roundWindow
+	queue  chan string
+	mu     sync.Mutex
+	// lookups are the prefetched lookups by txid
+	lookups map[string]*prefetchedLookup
+	// stopped is set when the queued lookups should be dropped
+	stopped bool
+	// done is closed when all lookups started by the prefetcher completed
+	done chan struct{}
+}
+
+// startPrefetcher starts looking up the transactions offered to it, with at most size transactions queued
+func startPrefetcher(lookup *txLookup, window *roundWindow, size int) *prefetcher {
+	p := &prefetcher{
+		lookup:  lookup,
+		window:  window,
+		queue:   make(chan string, size),
+		lookups: map[string]*prefetchedLookup{},
+		done:    make(chan struct{}),
+	}
+	go p.dispatch()
+	return p
+}
+
+// dispatch looks up the queued transactions within the concurrency limit of the lookups
+func (p *prefetcher) dispatch() {
+	var wg sync.WaitGroup
+	for txid := range p.queue {
+		p.mu.Lock()
+		lookup, stopped := p.lookups[txid], p.stopped
+		p.mu.Unlock()
+		if stopped {
+			lookup.err = errPrefetchStopped
+			close(lookup.done)
+			continue
+		}
+		p.lookup.concurrency.acquire()
+		wg.Add(1)
+		go func(txid string, lookup *prefetchedLookup) {
+			defer wg.Done()
+			start := time.Now()
+			lookup.sent, lookup.err = p.lookup.isTxSent(txid)
+			p.lookup.concurrency.release(time.Since(start), lookup.err)
+			close(lookup.done)
+		}(txid, lookup)
+	}
+	wg.Wait()
+	close(p.done)
+}
+
+// offer queues the lookup of a decoded transaction, if it is going to be looked up: it is the first of its group
+// (or any member with --group-policy all or --check-all-group-members), signed and within the time window. It blocks
+// while the queue is full.
+func (p *prefetcher) offer(rec txRecord, firstOfGroup bool) {
+	if p == nil || (!firstOfGroup && !lookupAllGroupMembers()) || isPlaceholder(rec) || !p.window.contains(rec) {
+		return
+	}
+	p.mu.Lock()
+	if _, ok := p.lookups[rec.txID]; ok {
+		p.mu.Unlock()
+		return
+	}
+	p.lookups[rec.txID] = &prefetchedLookup{done: make(chan struct{})}
+	p.mu.Unlock()
+	p.queue <- rec.txID
+}
+
+// finish marks the end of decoding, after which nothing is offered
+func (p *prefetcher) finish() {
+	if p == nil {
+		return
+	}
+	close(p.queue)
+}
+
+// stop drops the queued lookups that did not start yet and waits for the started ones
+// it must be called after finish
+func (p *prefetcher) stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	<-p.done
+}
+
+// result returns the prefetched lookup of a transaction once it completed, ok is false if it was not prefetched
+func (p *prefetcher) result(txid string) (sent bool, ok bool, err error) {
+	if p == nil {
+		return false, false, nil
+	}
+	p.mu.Lock()
+	lookup, ok := p.lookups[txid]
+	p.mu.Unlock()
+	if !ok {
+		return false, false, nil
+	}
+	<-lookup.done
+	return lookup.sent, true, lookup.err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrefetcherLooksUpOfferedTransactions(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		txID := path.Base(r.URL.Path)
+		if txID == testTxID(2) {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q}}`, txID)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	concurrency := &concurrencyController{limit: 2}
+	concurrency.cond = sync.NewCond(&concurrency.mu)
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff(), concurrency: concurrency}
+	window := &roundWindow{first: 10}
+	signed := func(n byte, fv uint64) txRecord {
+		rec := txRecord{txID: testTxID(n)}
+		rec.stx.Sig[0] = 1
+		rec.stx.Txn.FirstValid = types.Round(fv)
+		return rec
+	}
+	p := startPrefetcher(lookup, window, 1)
+	p.offer(signed(1, 10), true)
+	p.offer(signed(2, 10), true)
+	p.offer(signed(1, 10), true)
+	p.offer(signed(3, 10), false)
+	p.offer(signed(4, 5), true)
+	p.offer(txRecord{txID: testTxID(5)}, true)
+	p.finish()
+	for n, expected := range map[byte]bool{1: true, 2: false} {
+		sent, ok, err := p.result(testTxID(n))
+		if err != nil || !ok || sent != expected {
+			t.Errorf("expected tx %d to be prefetched as sent=%v, got %v %v %v", n, expected, sent, ok, err)
+		}
+	}
+	for _, n := range []byte{3, 4, 5} {
+		if _, ok, _ := p.result(testTxID(n)); ok {
+			t.Errorf("expected tx %d not to be prefetched", n)
+		}
+	}
+	p.stop()
+	if requests != 2 {
+		t.Errorf("expected 2 lookups, got %d", requests)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var priorityValues []string
+
+func init() {
+	rootCmd.Flags().StringSliceVar(&priorityValues, "priority", nil,
+		"look up the transactions matching this filter ahead of the others, logging the unsent ones as soon as they "+
+			"are known: sender=<address>, receiver=<address>, type=<type> or file=<glob> (whose files are also "+
+			"checked first), can be repeated")
+}
+
+// priorityRules select the transactions looked up ahead of the bulk of a run, any rule matching
+type priorityRules struct {
+	senders   map[types.Address]bool
+	receivers map[types.Address]bool
+	txTypes   map[types.TxType]bool
+	// files are globs matched against the paths of the input files and their base names
+	files []string
+}
+
+// parsePriorityRules parses the values of --priority, it returns nil if there are none
+func parsePriorityRules(values []string) (*priorityRules, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	rules := &priorityRules{senders: map[types.Address]bool{}, receivers: map[types.Address]bool{},
+		txTypes: map[types.TxType]bool{}}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --priority %q, expected <field>=<value>", value)
+		}
+		field, match := parts[0], parts[1]
+		switch field {
+		case "sender", "receiver":
+			addr, err := types.DecodeAddress(match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address in --priority %q: %v", value, err)
+			}
+			if field == "sender" {
+				rules.senders[addr] = true
+			} else {
+				rules.receivers[addr] = true
+			}
+		case "type":
+			rules.txTypes[types.TxType(match)] = true
+		case "file":
+			if _, err := filepath.Match(match, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob in --priority %q: %v", value, err)
+			}
+			rules.files = append(rules.files, match)
+		default:
+			return nil, fmt.Errorf("unknown field %q in --priority %q, expected sender, receiver, type or file", field,
+				value)
+		}
+	}
+	return rules, nil
+}
+
+// matchesFile returns whether an input file is a priority one
+func (r *priorityRules) matchesFile(filename string) bool {
+	if r == nil {
+		return false
+	}
+	for _, glob := range r.files {
+		if ok, _ := filepath.Match(glob, filename); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, filepath.Base(filename)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether a transaction is a priority one
+func (r *priorityRules) matches(rec txRecord) bool {
+	if r == nil {
+		return false
+	}
+	if r.matchesFile(rec.file) {
+		return true
+	}
+	if rec.txIDOnly {
+		return false
+	}
+	txn := rec.stx.Txn
+	return r.senders[txn.Sender] || r.receivers[txReceiver(txn)] || r.txTypes[txn.Type]
+}
+
+// sortFiles moves the priority input files ahead of the others, keeping their order otherwise
+func (r *priorityRules) sortFiles(filenames []string) []string {
+	if r == nil || len(r.files) == 0 {
+		return filenames
+	}
+	sorted := append([]string{}, filenames...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return r.matchesFile(sorted[i]) && !r.matchesFile(sorted[j])
+	})
+	return sorted
+}
+
+// lookupPrioritized looks up the status of transactions as lookupAll does, looking up the urgent ones first and
+// logging the ones not found as soon as they are all known
+func (l *txLookup) lookupPrioritized(txIDs []string, urgent []bool) ([]bool, error) {
+	var urgentIDs, bulkIDs []string
+	var urgentIndexes, bulkIndexes []int
+	for i, txid := range txIDs {
+		if urgent[i] {
+			urgentIDs = append(urgentIDs, txid)
+			urgentIndexes = append(urgentIndexes, i)
+		} else {
+			bulkIDs = append(bulkIDs, txid)
+			bulkIndexes = append(bulkIndexes, i)
+		}
+	}
+	// when all of them are urgent, e.g. in a priority file, there is nothing to look up first
+	if len(urgentIDs) == 0 || len(bulkIDs) == 0 {
+		return l.lookupAll(txIDs)
+	}
+	sent := make([]bool, len(txIDs))
+	urgentSent, err := l.lookupAll(urgentIDs)
+	if err != nil {
+		return nil, err
+	}
+	found := 0
+	for j, isSent := range urgentSent {
+		sent[urgentIndexes[j]] = isSent
+		if isSent {
+			found++
+			continue
+		}
+		logMessage(log.InfoLevel, msgPriorityNotFound, urgentIDs[j])
+	}
+	logMessage(log.InfoLevel, msgPriorityLookedUp, len(urgentIDs), len(bulkIDs), found)
+	bulkSent, err := l.lookupAll(bulkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for j, isSent := range bulkSent {
+		sent[bulkIndexes[j]] = isSent
+	}
+	return sent, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestPriorityRules(t *testing.T) {
+	rules, err := parsePriorityRules([]string{"type=axfer", "file=urgent-*.tx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := rules.sortFiles([]string{"bulk.tx", "dir/urgent-1.tx", "more.tx", "urgent-2.tx"})
+	if strings.Join(sorted, ",") != "dir/urgent-1.tx,urgent-2.tx,bulk.tx,more.tx" {
+		t.Errorf("expected the urgent files first, got %v", sorted)
+	}
+	rec := txRecord{file: "bulk.tx"}
+	rec.stx.Txn.Type = types.AssetTransferTx
+	if !rules.matches(rec) {
+		t.Error("expected the asset transfer to be a priority transaction")
+	}
+	rec.stx.Txn.Type = types.PaymentTx
+	if rules.matches(rec) {
+		t.Error("expected the payment of a bulk file not to be a priority transaction")
+	}
+	if _, err := parsePriorityRules([]string{"sender=TREASURY"}); err == nil {
+		t.Error("expected an invalid address to be rejected")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // registers the profiling endpoints served by --pprof-listen
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+var (
+	pprofListen    string
+	cpuProfileFile string
+	memProfileFile string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&pprofListen, "pprof-listen", "",
+		"serve the pprof profiling endpoints on this address (e.g. localhost:6060) while the run is in progress")
+	rootCmd.Flags().StringVar(&cpuProfileFile, "cpuprofile", "", "write a CPU profile of the run to this file")
+	rootCmd.Flags().StringVar(&memProfileFile, "memprofile", "",
+		"write a heap profile to this file at the end of the run")
+}
+
+// startProfiling starts the profiling requested by the flags, the returned function stops it and writes the profiles
+func startProfiling() (func(), error) {
+	var cpuProfile *os.File
+	if cpuProfileFile != "" {
+		var err error
+		cpuProfile, err = os.Create(cpuProfileFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile %s: %v", cpuProfileFile, err)
+		}
+		err = pprof.StartCPUProfile(cpuProfile)
+		if err != nil {
+			// the profile is empty, nothing to lose on close
+			_ = cpuProfile.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %v", err)
+		}
+	}
+	var listener net.Listener
+	if pprofListen != "" {
+		var err error
+		listener, err = net.Listen("tcp", pprofListen)
+		if err != nil {
+			if cpuProfile != nil {
+				pprof.StopCPUProfile()
+				_ = cpuProfile.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on --pprof-listen %s: %v", pprofListen, err)
+		}
+		logMessage(log.InfoLevel, msgPprofServing, listener.Addr())
+		go func() {
+			// Serve returns an error once the listener is closed at the end of the run
+			_ = http.Serve(listener, nil)
+		}()
+	}
+	return func() {
+		if listener != nil {
+			_ = listener.Close()
+		}
+		if cpuProfile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuProfile.Close(); err != nil {
+				logMessage(log.ErrorLevel, msgCPUProfileFailed, cpuProfileFile, err)
+			}
+		}
+		if memProfileFile != "" {
+			writeHeapProfile(memProfileFile)
+		}
+	}, nil
+}
+
+// writeHeapProfile writes a profile of the live heap to filename
+func writeHeapProfile(filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		logMessage(log.ErrorLevel, msgHeapProfileCreateFailed, filename, err)
+		return
+	}
+	// collect garbage so the profile shows only live objects
+	runtime.GC()
+	err = pprof.WriteHeapProfile(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logMessage(log.ErrorLevel, msgHeapProfileWriteFailed, filename, err)
+	}
+}
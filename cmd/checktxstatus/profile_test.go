@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfilingWritesProfiles(t *testing.T) {
+	dir := testDir(t)
+	defer func(cpu, mem, listen string) {
+		cpuProfileFile, memProfileFile, pprofListen = cpu, mem, listen
+	}(cpuProfileFile, memProfileFile, pprofListen)
+	cpuProfileFile = filepath.Join(dir, "cpu.prof")
+	memProfileFile = filepath.Join(dir, "mem.prof")
+	pprofListen = "127.0.0.1:0"
+	stop, err := startProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	for _, filename := range []string{cpuProfileFile, memProfileFile} {
+		info, err := os.Stat(filename)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("expected a profile written to %s, got %v", filename, err)
+		}
+	}
+}
+
+func TestStartProfilingFailsOnBadListenAddress(t *testing.T) {
+	defer func(listen string) { pprofListen = listen }(pprofListen)
+	pprofListen = "not an address"
+	if _, err := startProfiling(); err == nil {
+		t.Error("expected an error listening on an invalid address")
+	}
+	// the default mux serves the pprof endpoints
+	if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest("GET", "/debug/pprof/", nil)); pattern == "" {
+		t.Error("expected the pprof endpoints to be registered")
+	}
+}
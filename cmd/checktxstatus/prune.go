@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	retentionStr    string
+	pruneArchiveDir string
+	prunePatterns   []string
+	pruneDryRun     bool
+)
+
+// pruneSharedFlags are the flags of the root command that affect pruning
+var pruneSharedFlags = []string{"log-level", "policy", "shred"}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune <dir1> <dir2> ...",
+	Short: "Delete or archive outputs, reports and checkpoints older than --retention in directories",
+	Example: `  # list the outputs older than 30 days without deleting them
+  checktxstatus prune --dry-run runs/
+
+  # move the outputs older than a week to an archive directory
+  checktxstatus prune --retention 7d --archive-dir /srv/archive runs/`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		retention, err := parseRetention(retentionStr)
+		if err != nil {
+			logMessage(log.ErrorLevel, msgInvalidRetention, err)
+			return
+		}
+		for _, pattern := range prunePatterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				logMessage(log.ErrorLevel, msgInvalidPrunePattern, pattern, err)
+				return
+			}
+		}
+		policy, err := loadPolicy(policyFile)
+		if err != nil {
+			logError(err)
+			return
+		}
+		if len(args) == 0 {
+			logMessage(log.ErrorLevel, msgNoDirectories)
+			cmd.HelpFunc()(cmd, args)
+			return
+		}
+		p := pruner{
+			cutoff:    time.Now().Add(-retention),
+			buckets:   policyBuckets(policy),
+			splitDirs: map[string]bool{},
+		}
+		if splitBucket := policy.Conditions[conditionUnsent].Bucket; splitBucket != "" {
+			p.splitDirs[splitBucket] = true
+		}
+		for _, dir := range args {
+			err = p.prune(filepath.Clean(dir))
+			if err != nil {
+				logError(err)
+				return
+			}
+		}
+		id := msgPruneDeleted
+		switch {
+		case pruneDryRun && pruneArchiveDir != "":
+			id = msgPruneWouldArchive
+		case pruneDryRun:
+			id = msgPruneWouldDelete
+		case pruneArchiveDir != "":
+			id = msgPruneArchived
+		}
+		logMessage(log.InfoLevel, id, p.pruned, retention)
+	},
+}
+
+func init() {
+	pruneCmd.Flags().StringVar(&retentionStr, "retention", "30d",
+		"age after which outputs are pruned, e.g. 30d or 12h")
+	pruneCmd.Flags().StringVar(&pruneArchiveDir, "archive-dir", "",
+		"move pruned files to this directory, on the same filesystem, instead of deleting them")
+	pruneCmd.Flags().StringSliceVar(&prunePatterns, "prune-pattern", nil,
+		"glob of other file names to prune, e.g. the names of reports, manifests and checkpoints (repeatable)")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "only log the files that would be pruned")
+}
+
+// initPruneCmd adds the prune subcommand, sharing the flags of the root command that affect pruning
+// it must be called after the flags of the root command were registered
+func initPruneCmd() {
+	for _, name := range pruneSharedFlags {
+		pruneCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(pruneCmd)
+}
+
+// parseRetention parses a duration such as 12h, or a number of days such as 30d
+func parseRetention(value string) (time.Duration, error) {
+	var retention time.Duration
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of days", value)
+		}
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		retention, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if retention <= 0 {
+		return 0, fmt.Errorf("retention must be positive")
+	}
+	return retention, nil
+}
+
+// policyBuckets returns the buckets outputs are written to by a policy
+func policyBuckets(policy classificationPolicy) []string {
+	var buckets []string
+	for _, rule := range policy.Conditions {
+		if rule.Bucket != "" {
+			buckets = append(buckets, rule.Bucket)
+		}
+	}
+	return buckets
+}
+
+// pruner removes the outputs of the tool last modified before a cutoff
+type pruner struct {
+	cutoff time.Time
+	// buckets are the suffixes of the output files
+	buckets []string
+	// splitDirs are the buckets written to a directory per input with --split-unsent-by-group
+	splitDirs map[string]bool
+	// pruned counts the files pruned
+	pruned int
+}
+
+// trimEncryption returns a name without the suffix added by output encryption
+func trimEncryption(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".age"), ".gpg")
+}
+
+// isOutput returns true if a file name is the name of an output of a bucket, or matches --prune-pattern
+func (p *pruner) isOutput(name string) bool {
+	for _, bucket := range p.buckets {
+		if strings.HasSuffix(trimCompression(trimEncryption(name)), "."+bucket) {
+			return true
+		}
+	}
+	for _, pattern := range prunePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// splitBucket returns true if a directory name is the name of a bucket split by group
+func (p *pruner) splitBucket(name string) bool {
+	for bucket := range p.splitDirs {
+		if strings.HasSuffix(name, "."+bucket) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune prunes the stale outputs in dir, including the stale transaction files of split buckets, removing split
+// bucket directories left empty
+func (p *pruner) prune(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error while listing %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && p.splitBucket(entry.Name()) {
+			err = p.pruneSplitDir(path)
+		} else if entry.Mode().IsRegular() && p.isOutput(entry.Name()) {
+			err = p.pruneFile(path, entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneSplitDir prunes the stale transaction files of a split bucket, and the directory if none are left
+func (p *pruner) pruneSplitDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error while listing %s: %v", dir, err)
+	}
+	left := 0
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !strings.HasSuffix(trimCompression(trimEncryption(entry.Name())), ".stxn") ||
+			!entry.ModTime().Before(p.cutoff) {
+			left++
+			continue
+		}
+		err = p.pruneFile(filepath.Join(dir, entry.Name()), entry)
+		if err != nil {
+			return err
+		}
+	}
+	if left != 0 || pruneDryRun {
+		return nil
+	}
+	err = os.Remove(dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %v", dir, err)
+	}
+	return nil
+}
+
+// pruneFile deletes or archives a file if it was last modified before the cutoff
+// archived files keep their absolute path under --archive-dir, so files of different directories do not collide
+func (p *pruner) pruneFile(path string, stat os.FileInfo) error {
+	if !stat.ModTime().Before(p.cutoff) {
+		return nil
+	}
+	p.pruned++
+	if pruneArchiveDir == "" {
+		logMessage(log.InfoLevel, msgPruneDeleting, path, stat.ModTime().Format(time.RFC3339))
+		if pruneDryRun {
+			return nil
+		}
+		err := removeFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %v", path, err)
+		}
+		return nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %v", path, err)
+	}
+	archived := filepath.Join(pruneArchiveDir, absPath)
+	logMessage(log.InfoLevel, msgPruneArchiving, path, archived, stat.ModTime().Format(time.RFC3339))
+	if pruneDryRun {
+		return nil
+	}
+	if _, err := os.Stat(archived); err == nil {
+		return fmt.Errorf("failed to archive %s: %s already exists", path, archived)
+	}
+	err = os.MkdirAll(filepath.Dir(archived), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(archived), err)
+	}
+	err = os.Rename(path, archived)
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "12h": 12 * time.Hour} {
+		if retention, err := parseRetention(value); err != nil || retention != expected {
+			t.Errorf("expected %s to be %v, got %v %v", value, expected, retention, err)
+		}
+	}
+	for _, value := range []string{"xd", "0d", "-1h", "soon"} {
+		if _, err := parseRetention(value); err == nil {
+			t.Errorf("expected %s to be invalid", value)
+		}
+	}
+}
+
+func TestPrunerRemovesStaleOutputs(t *testing.T) {
+	dir := testDir(t)
+	old := time.Now().Add(-48 * time.Hour)
+	write := func(name string, modTime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("txs"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	stale := []string{
+		write("a.tx.unsent", old),
+		write("a.tx.large.age", old),
+		write("a.tx.unsent-groups/g1.stxn", old),
+	}
+	kept := []string{
+		write("b.tx.unsent", time.Now()),
+		write("a.tx", old),
+		write("c.tx.unsent-groups/g1.stxn", time.Now()),
+	}
+	p := pruner{
+		cutoff:    time.Now().Add(-24 * time.Hour),
+		buckets:   policyBuckets(defaultPolicy()),
+		splitDirs: map[string]bool{"unsent-groups": true},
+	}
+	if err := p.prune(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range stale {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be pruned, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.tx.unsent-groups")); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied split directory to be removed, got %v", err)
+	}
+	for _, path := range kept {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept, got %v", path, err)
+		}
+	}
+	if p.pruned != len(stale) {
+		t.Errorf("expected %d files pruned, got %d", len(stale), p.pruned)
+	}
+}
+
+func TestPrunerArchivesStaleOutputs(t *testing.T) {
+	dir, archiveDir := testDir(t), testDir(t)
+	defer func(archive string) { pruneArchiveDir = archive }(pruneArchiveDir)
+	pruneArchiveDir = archiveDir
+	path := filepath.Join(dir, "a.tx.unsent")
+	if err := ioutil.WriteFile(path, []byte("txs"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := pruner{cutoff: time.Now().Add(time.Hour), buckets: []string{"unsent"}}
+	if err := p.prune(dir); err != nil {
+		t.Fatal(err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(archiveDir, absPath)); err != nil || string(content) != "txs" {
+		t.Errorf("expected the output to be moved under the archive directory, got %q %v", content, err)
+	}
+}
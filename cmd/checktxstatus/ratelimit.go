@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	rateLimit float64
+	rateBurst int
+)
+
+func init() {
+	rootCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0,
+		"maximum number of requests per second to every indexer, 0 for no limit; the indexers answering 429 with "+
+			"Retry-After are not sent requests until then whatever the limit")
+	rootCmd.Flags().IntVar(&rateBurst, "rate-burst", 0,
+		"number of requests sent at once to an indexer that was not sent any for a while, under --rate-limit (the "+
+			"rate rounded up by default)")
+}
+
+// maxRetryAfter bounds the pause asked by a Retry-After header, so a bogus one cannot stall the run
+const maxRetryAfter = 10 * time.Minute
+
+// rateLimits are the token buckets of the indexer hosts by host, the requests to other hosts are not limited
+var rateLimits = struct {
+	sync.Mutex
+	buckets map[string]*tokenBucket
+}{buckets: map[string]*tokenBucket{}}
+
+// initRateLimits limits the requests to the indexers of --idx-addr and --hedge-idx-addr with --rate-limit
+func initRateLimits() error {
+	if rateLimit < 0 {
+		return fmt.Errorf("--rate-limit must not be negative")
+	}
+	if rateBurst < 0 {
+		return fmt.Errorf("--rate-burst must not be negative")
+	}
+	for _, address := range []string{indexerAddress, hedgeIndexerAddress} {
+		if address != "" {
+			limitIndexer(address, rateLimit, rateBurst)
+		}
+	}
+	return nil
+}
+
+// limitIndexer limits the requests to the host of an indexer address to rate per second with burst, rate 0 only
+// honoring its Retry-After headers
+// the bucket of the host is kept if its limit did not change, e.g. between the checks of serve
+func limitIndexer(address string, rate float64, burst int) {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		// the client fails on the address anyway
+		return
+	}
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	bucket := newPausableBucket(rate, burst)
+	if b, ok := rateLimits.buckets[u.Host]; ok && b.rate == bucket.rate && b.burst == bucket.burst {
+		return
+	}
+	rateLimits.buckets[u.Host] = bucket
+	if rate != 0 {
+		log.Debugf("limiting the requests to %s to %g per second, %d at once", u.Host, rate, bucket.burst)
+	}
+}
+
+// bucketOf returns the token bucket of a host, nil if its requests are not limited
+func bucketOf(host string) *tokenBucket {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	return rateLimits.buckets[host]
+}
+
+// rateLimitedTransport sends the requests of the SDK clients to the hosts with token buckets through them
+type rateLimitedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := bucketOf(req.URL.Host)
+	if b == nil {
+		return t.next.RoundTrip(req)
+	}
+	err := b.waitContext(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		if d > maxRetryAfter {
+			logMessage(log.WarnLevel, msgRetryAfterCapped, req.URL.Host, d, maxRetryAfter)
+			d = maxRetryAfter
+		}
+		logMessage(log.InfoLevel, msgRateLimited, req.URL.Host, d)
+		b.pause(d)
+	}
+	return resp, nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, either seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now), t.After(now)
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHonorsRetryAfter(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusTooManyRequests,
+		RetryAfter: time.Second, Times: 1})
+	limitIndexer(server.URL, 0, 0)
+	start := time.Now()
+	sent, err := newTestLookup(t, server).isTxSent(testTxID(1))
+	if err != nil || !sent {
+		t.Errorf("expected the transaction to be found after the 429, got %v, %v", sent, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, it was sent after %s", elapsed)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	limitIndexer(server.URL, 20, 1)
+	lookup := newTestLookup(t, server)
+	start := time.Now()
+	for i := byte(0); i < 5; i++ {
+		if _, err := lookup.isTxSent(testTxID(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first lookup takes the token of the burst, the other 4 wait 50ms each
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("expected 5 lookups at 20 per second to take 200ms, they took %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Tue, 01 Jun 2021 12:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 01 Jun 2021 11:00:00 GMT", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		delay, ok := parseRetryAfter(test.value, now)
+		if ok != test.ok || (ok && delay != test.delay) {
+			t.Errorf("expected %q to be %s, %v, got %s, %v", test.value, test.delay, test.ok, delay, ok)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// lookupCache caches the results of the lookups, its methods do nothing on a nil cache
+type lookupCache interface {
+	// lookup returns the cached result of looking up a transaction, ok is false if it is not cached
+	lookup(txid string) (sent bool, round uint64, ok bool)
+	// record caches the result of looking up a transaction
+	record(txid string, sent bool, round uint64)
+}
+
+// readThrough looks up a transaction in the caches in order, caching a hit in the caches before the one it was found in
+// so the next lookups of the transaction stop there
+func readThrough(txid string, caches ...lookupCache) (sent bool, round uint64, ok bool) {
+	for i, c := range caches {
+		if sent, round, ok = c.lookup(txid); ok {
+			for _, before := range caches[:i] {
+				before.record(txid, sent, round)
+			}
+			return sent, round, true
+		}
+	}
+	return false, 0, false
+}
+
+// writeThrough caches the result of looking up a transaction in all the caches
+func writeThrough(txid string, sent bool, round uint64, caches ...lookupCache) {
+	for _, c := range caches {
+		c.record(txid, sent, round)
+	}
+}
+
+// live returns whether a cached result still answers the lookups: the transactions found as of --as-of-round, and the
+// transactions not found for --cache-negative-ttl
+func (r cachedResult) live(now time.Time) bool {
+	if r.sent {
+		return asOfRound == 0 || r.round != 0 && r.round <= asOfRound
+	}
+	return now.Sub(r.at) < cacheNegativeTTL
+}
+
+// memoryCache keeps the results of the lookups in memory, in front of --cache-path and --state-backend, so the
+// transactions looked up again are answered without reading either
+type memoryCache struct {
+	mu      sync.Mutex
+	results map[string]cachedResult
+}
+
+// newMemoryCache returns a memory cache in front of the caches that are set, nil if none is
+func newMemoryCache(cache *txCache, shared *sharedState) *memoryCache {
+	if cache == nil && shared == nil {
+		return nil
+	}
+	return &memoryCache{results: map[string]cachedResult{}}
+}
+
+func (m *memoryCache) lookup(txid string) (sent bool, round uint64, ok bool) {
+	if m == nil {
+		return false, 0, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.results[txid]
+	if !ok {
+		return false, 0, false
+	}
+	if !result.live(time.Now()) {
+		if !result.sent {
+			// the transactions not found are looked up again once expired
+			delete(m.results, txid)
+		}
+		return false, 0, false
+	}
+	return result.sent, result.round, true
+}
+
+func (m *memoryCache) record(txid string, sent bool, round uint64) {
+	if m == nil || !sent && cacheNegativeTTL == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// a transaction found stays found, whatever a concurrent lookup did not find
+	if previous, ok := m.results[txid]; ok && previous.sent && !sent {
+		return
+	}
+	m.results[txid] = cachedResult{sent: sent, round: round, at: time.Now()}
+}
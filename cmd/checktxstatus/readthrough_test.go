@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadThroughKeepsHitsInTheCachesBefore(t *testing.T) {
+	cache := openTestCache(t, filepath.Join(testDir(t), "txids.db"), time.Minute)
+	defer cache.close()
+	recent := newMemoryCache(cache, nil)
+	cache.record(testTxID(1), true, 900)
+	if sent, round, ok := readThrough(testTxID(1), recent, cache); !ok || !sent || round != 900 {
+		t.Fatalf("expected the transaction cached in --cache-path to be found, got %v, %d, %v", sent, round, ok)
+	}
+	if sent, round, ok := recent.lookup(testTxID(1)); !ok || !sent || round != 900 {
+		t.Errorf("expected the hit to be kept in memory, got %v, %d, %v", sent, round, ok)
+	}
+	writeThrough(testTxID(2), false, 0, recent, cache)
+	for _, c := range []lookupCache{recent, cache} {
+		if sent, _, ok := c.lookup(testTxID(2)); !ok || sent {
+			t.Errorf("expected the unsent transaction to be cached in every cache, got %v, %v", sent, ok)
+		}
+	}
+	if _, _, ok := readThrough(testTxID(3), recent, cache); ok {
+		t.Error("expected a transaction in none of the caches not to be found")
+	}
+}
+
+func TestMemoryCacheKeepsFoundTransactions(t *testing.T) {
+	defer func(ttl time.Duration) { cacheNegativeTTL = ttl }(cacheNegativeTTL)
+	cacheNegativeTTL = time.Minute
+	recent := &memoryCache{results: map[string]cachedResult{}}
+	recent.record(testTxID(1), true, 900)
+	recent.record(testTxID(1), false, 0)
+	if sent, _, ok := recent.lookup(testTxID(1)); !ok || !sent {
+		t.Errorf("expected a found transaction to stay found, got %v, %v", sent, ok)
+	}
+	cacheNegativeTTL = time.Nanosecond
+	recent.record(testTxID(2), false, 0)
+	time.Sleep(time.Millisecond)
+	if _, _, ok := recent.lookup(testTxID(2)); ok {
+		t.Error("expected the expired unsent transaction to be looked up again")
+	}
+}
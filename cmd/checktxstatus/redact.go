@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+)
+
+var redactValues []string
+
+func init() {
+	rootCmd.Flags().StringSliceVar(&redactValues, "redact", nil,
+		"redact the --report, and so the reports of the jobs delivered to --job-sink, the rows of --format csv and "+
+			"the output of decode, for sharing them outside the team: notes masks the notes, addresses truncates the "+
+			"addresses and omits their names, and amounts omits the amounts and fees, can be repeated")
+}
+
+// the rules of --redact
+const (
+	redactNotes     = "notes"
+	redactAddresses = "addresses"
+	redactAmounts   = "amounts"
+)
+
+// redaction is what --redact hides from the reports
+type redaction struct {
+	notes     bool
+	addresses bool
+	amounts   bool
+}
+
+// parseRedaction parses the rules of --redact, it returns nil if there are none
+func parseRedaction(values []string) (*redaction, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	r := &redaction{}
+	for _, value := range values {
+		switch value {
+		case redactNotes:
+			r.notes = true
+		case redactAddresses:
+			r.addresses = true
+		case redactAmounts:
+			r.amounts = true
+		default:
+			return nil, fmt.Errorf("invalid --redact %q, expected %s, %s or %s", value, redactNotes, redactAddresses,
+				redactAmounts)
+		}
+	}
+	return r, nil
+}
+
+// address returns addr truncated to its first 6 and last 4 characters if addresses are redacted, as is otherwise
+// the truncated addresses can still be matched by whoever knows the full ones
+func (r *redaction) address(addr string) string {
+	if r == nil || !r.addresses || len(addr) <= 13 {
+		return addr
+	}
+	return addr[:6] + "..." + addr[len(addr)-4:]
+}
+
+// reportEntry redacts an entry of the --report
+func (r *redaction) reportEntry(entry *txReportEntry) {
+	if r == nil {
+		return
+	}
+	entry.Sender = r.address(entry.Sender)
+	// the name of the sender, e.g. its NFD, identifies it as much as its full address
+	if r.addresses {
+		entry.SenderName = ""
+	}
+	if entry.Multisig != nil {
+		for i, signer := range entry.Multisig.Missing {
+			entry.Multisig.Missing[i] = r.address(signer)
+		}
+	}
+	if r.amounts {
+		entry.Fee = nil
+	}
+}
+
+// csvRecord redacts a row of --format csv, whose columns are csvHeader
+func (r *redaction) csvRecord(record []string) {
+	if r == nil {
+		return
+	}
+	record[3], record[4] = r.address(record[3]), r.address(record[4])
+	if r.amounts {
+		record[5] = ""
+	}
+}
+
+// decodedTx redacts a transaction printed by decode, its masked note is left empty with the note_encoding redacted
+func (r *redaction) decodedTx(tx *decodedTx) {
+	if r == nil {
+		return
+	}
+	tx.Sender, tx.Receiver, tx.CloseTo = r.address(tx.Sender), r.address(tx.Receiver), r.address(tx.CloseTo)
+	tx.RekeyTo, tx.AuthAddr = r.address(tx.RekeyTo), r.address(tx.AuthAddr)
+	if r.notes && tx.NoteEncoding != "" {
+		tx.Note, tx.NoteEncoding = "", "redacted"
+	}
+	if r.amounts {
+		tx.Amount, tx.Fee = nil, nil
+	}
+}
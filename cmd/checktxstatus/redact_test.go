@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRedactReportEntry(t *testing.T) {
+	redaction, err := parseRedaction([]string{"addresses", "amounts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := uint64(1000)
+	sender := "FYD3GWODP4ZI4EZA5HTXWF7MPYBCR3HXC4YUYKAXAZWGU2X2KKVWRTFAM4"
+	entry := txReportEntry{Sender: sender, SenderName: "alice.algo", Fee: &fee,
+		Multisig: &multisigStatus{Missing: []string{sender}}}
+	redaction.reportEntry(&entry)
+	if entry.Sender != "FYD3GW...FAM4" || entry.Multisig.Missing[0] != "FYD3GW...FAM4" || entry.Fee != nil {
+		t.Errorf("expected the addresses truncated and the fee omitted, got %+v", entry)
+	}
+	if entry.SenderName != "" {
+		t.Errorf("expected the name of the sender omitted, got %s", entry.SenderName)
+	}
+	if _, err := parseRedaction([]string{"memos"}); err == nil {
+		t.Error("expected an unknown rule to be rejected")
+	}
+}
+
+func TestRedactCSVRecord(t *testing.T) {
+	redaction, err := parseRedaction([]string{"addresses", "amounts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := "FYD3GWODP4ZI4EZA5HTXWF7MPYBCR3HXC4YUYKAXAZWGU2X2KKVWRTFAM4"
+	record := []string{testTxID(1), "batch.tx", "", sender, sender, "1000", "pay", "unsent"}
+	redaction.csvRecord(record)
+	if record[3] != "FYD3GW...FAM4" || record[4] != "FYD3GW...FAM4" || record[5] != "" {
+		t.Errorf("expected the addresses truncated and the amount omitted, got %v", record)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds connecting to Redis and every command
+const redisTimeout = 10 * time.Second
+
+// redisError is an error reply of Redis, which does not break the connection
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisMaxIdle is the number of idle connections kept open for the next commands
+const redisMaxIdle = 16
+
+// redisClient is a minimal client of the Redis protocol (RESP), enough for the commands of the shared state
+// it keeps a pool of connections, so the concurrent lookups send their commands in parallel
+type redisClient struct {
+	address  string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	mu       sync.Mutex
+	// idle are the connections not sending a command, reused by the next ones
+	idle []*redisConn
+}
+
+// redisConn is a connection of the pool
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newRedisClient returns a client of redis://[user:password@]host:port[/db], or rediss:// over TLS
+// it connects on the first command
+func newRedisClient(u *url.URL) (*redisClient, error) {
+	c := &redisClient{address: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		c.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		var err error
+		c.db, err = strconv.Atoi(db)
+		if err != nil || c.db < 0 {
+			return nil, fmt.Errorf("invalid database %q, expected a number", db)
+		}
+	}
+	return c, nil
+}
+
+// redisIdempotent are the commands having the same effect when sent twice, the locks are compare-and-set EVAL scripts
+// SET NX is not: if the first attempt took the lock, the second one would find it taken
+var redisIdempotent = map[string]bool{
+	"PING": true, "GET": true, "SET": true, "EVAL": true, "ZADD": true, "ZREM": true, "ZREMRANGEBYSCORE": true,
+	"ZRANGE": true,
+}
+
+// retryable returns whether a command can be sent again when its connection failed before its reply was read
+func retryable(args []string) bool {
+	if !redisIdempotent[strings.ToUpper(args[0])] {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if strings.EqualFold(arg, "NX") {
+			return false
+		}
+	}
+	return true
+}
+
+// do sends a command and returns its reply: a string, an int64, nil, or a []interface{} of them
+// an error reply is returned as a redisError, a failed connection is closed and, if it was an idle one that the
+// server may have closed, an idempotent command is sent again on another connection
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	for {
+		conn, reused, err := c.get()
+		if err != nil {
+			return nil, err
+		}
+		reply, err := conn.roundTrip(args)
+		if _, ok := err.(redisError); err == nil || ok {
+			c.put(conn)
+			return reply, err
+		}
+		conn.close()
+		if !reused || !retryable(args) {
+			return nil, err
+		}
+	}
+}
+
+// get returns an idle connection, or a new one if there is none, and whether it was idle
+func (c *redisClient) get() (*redisConn, bool, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n != 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, true, nil
+	}
+	c.mu.Unlock()
+	conn, err := c.connect()
+	return conn, false, err
+}
+
+// put returns a connection to the pool, closing it if the pool is full
+func (c *redisClient) put(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) == redisMaxIdle {
+		conn.close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// connect opens a connection, authenticating and selecting the database
+func (c *redisClient) connect() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address, nil)
+	} else {
+		conn, err = dialer.Dial("tcp", c.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	var setup [][]string
+	switch {
+	case c.username != "" && c.password != "":
+		setup = append(setup, []string{"AUTH", c.username, c.password})
+	case c.password != "":
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := rc.roundTrip(args); err != nil {
+			rc.close()
+			return nil, fmt.Errorf("%s failed: %v", args[0], err)
+		}
+	}
+	return rc, nil
+}
+
+// roundTrip sends a command on the connection and reads its reply
+func (c *redisConn) roundTrip(args []string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+// close closes a failed connection
+func (c *redisConn) close() {
+	// the connection failed or is not needed, its error on close is not informative
+	_ = c.conn.Close()
+}
+
+// readRedisReply reads a reply of the Redis protocol
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("invalid empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		content := make([]byte, size+2)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, err
+		}
+		return string(content[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i], err = readRedisReply(r)
+			if _, ok := err.(redisError); err != nil && !ok {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid reply %q", line)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is a Redis server answering the first command of every connection with +OK, or with the value of the
+// GET, and closing the connection on the next one as if it had been idle for too long
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for i := 0; ; i++ {
+		command, err := readRedisReply(reader)
+		if err != nil {
+			return
+		}
+		args := make([]string, 0, 8)
+		for _, arg := range command.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		f.mu.Unlock()
+		if i != 0 {
+			return
+		}
+		if args[0] == "GET" {
+			conn.Write([]byte("$4\r\n1000\r\n"))
+			continue
+		}
+		conn.Write([]byte("+OK\r\n"))
+	}
+}
+
+func (f *fakeRedis) client(t *testing.T) *redisClient {
+	client, err := newRedisClient(&url.URL{Scheme: "redis", Host: f.listener.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (f *fakeRedis) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestRedisOnlyRetriesIdempotentCommands(t *testing.T) {
+	f := newFakeRedis(t)
+	client := f.client(t)
+	if _, err := client.do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	// the idle connection is closed by the server, the lock may have been taken
+	if _, err := client.do("SET", "lock", "me", "NX", "PX", "1000"); err == nil {
+		t.Error("expected SET NX to fail on the closed connection")
+	}
+	if _, err := client.do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := client.do("GET", "tx")
+	if err != nil || reply != "1000" {
+		t.Errorf("expected GET to be sent again on a new connection, got %v, %v", reply, err)
+	}
+	expected := "PING|SET lock me NX PX 1000|PING|GET tx|GET tx"
+	if commands := strings.Join(f.received(), "|"); commands != expected {
+		t.Errorf("expected the commands %s, got %s", expected, commands)
+	}
+}
+
+func TestSharedStateCacheFailsOpen(t *testing.T) {
+	f := newFakeRedis(t)
+	s := &sharedState{client: f.client(t), prefix: "test"}
+	s.record(testTxID(1), true, 1000)
+	commands := f.received()
+	if len(commands) != 1 || !strings.HasSuffix(commands[0], " 1000 PX "+milliseconds(stateCacheTTL)) {
+		t.Errorf("expected the confirmed transaction to be cached with --state-cache-ttl, got %v", commands)
+	}
+	f.listener.Close()
+	// the idle connection is closed by the server, and no new one can be opened
+	if _, _, ok := s.lookup(testTxID(1)); ok || !s.isCacheDown() {
+		t.Fatal("expected the lookup to miss and the cache of the backend to be disabled")
+	}
+	s.lookup(testTxID(1))
+	s.record(testTxID(1), true, 1000)
+	if commands = f.received(); len(commands) != 2 {
+		t.Errorf("expected the backend not to be sent more commands once it failed, got %v", commands)
+	}
+}
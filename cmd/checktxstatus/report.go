@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+var writeReport bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&writeReport, "report", false,
+		"write a JSON report of the status of every transaction of each input to <file>.report.json, next to the "+
+			"unsent transactions")
+}
+
+// reportOutsideWindow classifies in the report the transactions not checked for being outside the time window
+const reportOutsideWindow = "outside-time-window"
+
+// txReportEntry is the status of a transaction in the report of its file
+type txReportEntry struct {
+	recordSource
+	Group string `json:"group,omitempty"`
+	// Sender, SenderName, Type and Fee are missing for transactions read from txid lists
+	Sender     string       `json:"sender,omitempty"`
+	SenderName string       `json:"sender_name,omitempty"`
+	Type       types.TxType `json:"type,omitempty"`
+	Fee        *uint64      `json:"fee,omitempty"`
+	// ConfirmedRound is missing when the round is unknown, e.g. for transactions recorded in the checkpoint
+	ConfirmedRound uint64 `json:"confirmed_round,omitempty"`
+	// ConfirmedTime is the RFC 3339 UTC timestamp of the block of ConfirmedRound
+	ConfirmedTime string `json:"confirmed_time,omitempty"`
+	// Classification is the condition of the transaction, or outside-time-window if it was not checked
+	Classification string `json:"classification"`
+	// Simulation is the outcome of simulating the unit of an unsent application call with --simulate-unsent
+	Simulation *simulationTrace `json:"simulation,omitempty"`
+	// Multisig is how many signatures the multisig of the transaction has and misses, missing if it has none
+	Multisig *multisigStatus `json:"multisig,omitempty"`
+	// LogicSig is the outcome of evaluating the logicsig of an unsent transaction with --preview-logicsigs
+	LogicSig *logicSigPreview `json:"logicsig,omitempty"`
+	// LeaseCollisions are the txids of the transactions of the file holding the lease of the transaction in
+	// overlapping validity windows, of which only one can be confirmed
+	LeaseCollisions []string `json:"lease_collisions,omitempty"`
+	// index is the position of the transaction in its batch
+	index int
+}
+
+// confirmedRounds records the rounds of the transactions found by lookups until they are reported
+type confirmedRounds struct {
+	mu     sync.Mutex
+	rounds map[string]uint64
+}
+
+// newConfirmedRounds returns a recorder of confirmed rounds if --report is set, nil otherwise
+func newConfirmedRounds() *confirmedRounds {
+	if !writeReport {
+		return nil
+	}
+	return &confirmedRounds{rounds: map[string]uint64{}}
+}
+
+// record records the confirmed round of a transaction
+func (r *confirmedRounds) record(txid string, round uint64) {
+	if r == nil || round == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rounds[txid] = round
+}
+
+// take returns the confirmed round of the first transaction of txs that has one, and forgets the rounds of all of
+// them; the transactions of a group are confirmed in the same round, but only some of them may have been looked up
+func (r *confirmedRounds) take(txs []txRecord) uint64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var round uint64
+	for _, tx := range txs {
+		if found, ok := r.rounds[tx.txID]; ok {
+			if round == 0 {
+				round = found
+			}
+			delete(r.rounds, tx.txID)
+		}
+	}
+	return round
+}
+
+// reportEntries returns the report entries of transactions of a classification
+func (c *checker) reportEntries(classification string, txs []txRecord) []txReportEntry {
+	entries := make([]txReportEntry, 0, len(txs))
+	for _, unit := range submissionUnits(txs) {
+		var round uint64
+		var confirmedTime string
+		if c.policy.refined(txCondition(classification)) == conditionConfirmed {
+			round = c.lookup.rounds.take(unit)
+			confirmedTime = c.roundTime(round)
+		}
+		for _, tx := range unit {
+			entry := txReportEntry{recordSource: tx.source(), ConfirmedRound: round, ConfirmedTime: confirmedTime,
+				Classification: classification, Simulation: c.simulator.take(tx.txID), LogicSig: c.lsigs.take(tx.txID),
+				LeaseCollisions: c.leases[tx.txID], index: tx.index}
+			if !tx.txIDOnly {
+				if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+					entry.Group = groupIDString(gid)
+				}
+				entry.Sender = tx.stx.Txn.Sender.String()
+				if c.resolver != nil {
+					entry.SenderName = c.resolver.name(tx.stx.Txn.Sender)
+				}
+				entry.Type = tx.stx.Txn.Type
+				fee := uint64(tx.stx.Txn.Fee)
+				entry.Fee = &fee
+				entry.Multisig = multisigOf(tx.stx)
+			}
+			c.redaction.reportEntry(&entry)
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// roundTime returns the timestamp of the block of round for the report, empty if round is 0 or the block lookup
+// failed, which does not fail the run
+func (c *checker) roundTime(round uint64) string {
+	if round == 0 || c.clock == nil {
+		return ""
+	}
+	t, err := c.clock.timeOf(round)
+	if err != nil {
+		logMessage(log.WarnLevel, msgRoundTimeFailed, round, err)
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// txReportWriter streams the report of a file, chunk after chunk
+type txReportWriter struct {
+	*jsonArrayWriter
+}
+
+// openTxReport creates the report of the input filename if --report is set, it returns nil otherwise
+func openTxReport(filename string) (*txReportWriter, error) {
+	if !writeReport {
+		return nil, nil
+	}
+	output := outputName(fmt.Sprintf("%s.report.json", outputBase(filename)))
+	w, err := openJSONArray(output, "report")
+	if err != nil {
+		return nil, err
+	}
+	return &txReportWriter{w}, nil
+}
+
+// write appends the entries of a classified chunk to the report, in their order in the file
+func (r *txReportWriter) write(entries []txReportEntry) error {
+	if r == nil {
+		return nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].index < entries[j].index
+	})
+	for _, entry := range entries {
+		if err := r.add(entry); err != nil {
+			return err
+		}
+	}
+	return maybeSync(r.stream, &r.lastSync)
+}
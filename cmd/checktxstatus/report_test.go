@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfirmedRoundsTakeTheRoundOfAGroup(t *testing.T) {
+	rounds := &confirmedRounds{rounds: map[string]uint64{}}
+	rounds.record(testTxID(2), 990)
+	rounds.record(testTxID(3), 0)
+	group := []txRecord{{txID: testTxID(1)}, {txID: testTxID(2)}}
+	if round := rounds.take(group); round != 990 {
+		t.Errorf("expected the round of the member looked up, got %d", round)
+	}
+	if round := rounds.take(group); round != 0 || len(rounds.rounds) != 0 {
+		t.Errorf("expected the rounds to be forgotten once taken, got %d, %v", round, rounds.rounds)
+	}
+	var disabled *confirmedRounds
+	disabled.record(testTxID(1), 990)
+	if round := disabled.take(group); round != 0 {
+		t.Errorf("expected no round without --report, got %d", round)
+	}
+}
+
+func TestTxReportKeepsTheOrderOfTheFile(t *testing.T) {
+	defer func(report bool) { writeReport = report }(writeReport)
+	writeReport = true
+	filename := filepath.Join(testDir(t), "batch.tx")
+	report, err := openTxReport(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stx types.SignedTxn
+	stx.Txn.Type = types.PaymentTx
+	c := &checker{lookup: &txLookup{}}
+	confirmed := c.reportEntries(string(conditionConfirmed), []txRecord{{stx: stx, txID: testTxID(2), index: 1}})
+	unsent := c.reportEntries(string(conditionUnsent), []txRecord{{stx: stx, txID: testTxID(1), index: 0}})
+	if err := report.write(append(confirmed, unsent...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.close(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filename + ".report.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []txReportEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		t.Fatalf("expected a JSON array, got %v: %s", err, content)
+	}
+	if len(entries) != 2 || entries[0].TxID != testTxID(1) || entries[1].Classification != string(conditionConfirmed) ||
+		entries[0].Type != types.PaymentTx || entries[0].Fee == nil {
+		t.Errorf("expected the entries in the order of the file, got %+v", entries)
+	}
+}
+
+func TestReportEntriesOfConfirmedTransactionsHaveTheBlockTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/blocks/990" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"round":990,"timestamp":1646370367}`)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rounds := &confirmedRounds{rounds: map[string]uint64{testTxID(1): 990}}
+	c := &checker{lookup: &txLookup{rounds: rounds}, clock: newBlockClock(client, newTestBackoff())}
+	entries := c.reportEntries(string(conditionConfirmed), []txRecord{{txID: testTxID(1), txIDOnly: true}})
+	if len(entries) != 1 || entries[0].ConfirmedRound != 990 || entries[0].ConfirmedTime != "2022-03-04T05:06:07Z" {
+		t.Errorf("expected the round and UTC time of the block, got %+v", entries)
+	}
+	if confirmed := c.roundTime(991); confirmed != "" {
+		t.Errorf("expected no time of a block failing to be looked up, got %q", confirmed)
+	}
+}
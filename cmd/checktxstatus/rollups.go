@@ -0,0 +1,179 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+var topRollups int
+
+func init() {
+	rootCmd.Flags().IntVar(&topRollups, "top-rollups", 5,
+		"number of assets and of applications with the most unsent transactions to list in the run summary, all of "+
+			"them are in the --format json summary (0 to disable)")
+}
+
+// assetRollup aggregates the unsent transactions of an asset: its transfers, configurations and freezes
+type assetRollup struct {
+	AssetID uint64 `json:"asset_id"`
+	Unsent  int    `json:"unsent"`
+	// Amount is the total amount of the unsent transfers, in base units
+	Amount uint64 `json:"amount"`
+}
+
+// appRollup aggregates the unsent calls of an application, and the amounts moved by their groups
+type appRollup struct {
+	AppID  uint64 `json:"app_id"`
+	Unsent int    `json:"unsent"`
+	// MicroAlgos and Assets are the total amounts of the payments and of the asset transfers, by asset ID, of the
+	// groups of the calls, e.g. the deposits of swaps
+	MicroAlgos uint64            `json:"micro_algos"`
+	Assets     map[uint64]uint64 `json:"assets,omitempty"`
+}
+
+// unsentRollups aggregates the unsent transactions of all checked files by asset and by application, so the teams
+// owning them can see which product's transactions are stuck
+type unsentRollups struct {
+	assets map[uint64]*assetRollup
+	apps   map[uint64]*appRollup
+}
+
+// newUnsentRollups returns empty rollups
+func newUnsentRollups() *unsentRollups {
+	return &unsentRollups{assets: map[uint64]*assetRollup{}, apps: map[uint64]*appRollup{}}
+}
+
+// add aggregates unsent transactions
+// application creations have no application ID yet, and are not aggregated
+func (r *unsentRollups) add(txs []txRecord) {
+	// groupApps are the applications called by the group of the transaction, the transactions of a group being
+	// together
+	var groupApps map[uint64]bool
+	var group types.Digest
+	for i, tx := range txs {
+		if tx.txIDOnly {
+			continue
+		}
+		txn := tx.stx.Txn
+		switch txn.Type {
+		case types.AssetTransferTx:
+			r.asset(uint64(txn.XferAsset), txn.AssetAmount)
+		case types.AssetConfigTx:
+			if txn.ConfigAsset != 0 {
+				r.asset(uint64(txn.ConfigAsset), 0)
+			}
+		case types.AssetFreezeTx:
+			r.asset(uint64(txn.FreezeAsset), 0)
+		case types.ApplicationCallTx:
+			if txn.ApplicationID != 0 {
+				r.app(uint64(txn.ApplicationID)).Unsent++
+			}
+		}
+		if txn.Group == (types.Digest{}) {
+			continue
+		}
+		if txn.Group != group {
+			group, groupApps = txn.Group, groupAppIDs(txs[i:])
+		}
+		for appID := range groupApps {
+			app := r.app(appID)
+			switch txn.Type {
+			case types.PaymentTx:
+				app.MicroAlgos += uint64(txn.Amount)
+			case types.AssetTransferTx:
+				app.Assets[uint64(txn.XferAsset)] += txn.AssetAmount
+			}
+		}
+	}
+}
+
+// groupAppIDs returns the applications called by the group of the first transaction, whose transactions follow it
+func groupAppIDs(txs []txRecord) map[uint64]bool {
+	appIDs := map[uint64]bool{}
+	group := txs[0].stx.Txn.Group
+	for _, tx := range txs {
+		if tx.txIDOnly || tx.stx.Txn.Group != group {
+			break
+		}
+		if tx.stx.Txn.Type == types.ApplicationCallTx && tx.stx.Txn.ApplicationID != 0 {
+			appIDs[uint64(tx.stx.Txn.ApplicationID)] = true
+		}
+	}
+	return appIDs
+}
+
+// asset counts an unsent transaction of an asset moving amount
+func (r *unsentRollups) asset(assetID uint64, amount uint64) {
+	rollup, ok := r.assets[assetID]
+	if !ok {
+		rollup = &assetRollup{AssetID: assetID}
+		r.assets[assetID] = rollup
+	}
+	rollup.Unsent++
+	rollup.Amount += amount
+}
+
+// app returns the rollup of an application, adding it if missing
+func (r *unsentRollups) app(appID uint64) *appRollup {
+	rollup, ok := r.apps[appID]
+	if !ok {
+		rollup = &appRollup{AppID: appID, Assets: map[uint64]uint64{}}
+		r.apps[appID] = rollup
+	}
+	return rollup
+}
+
+// sortedAssets returns the asset rollups with the most unsent transactions first
+func (r *unsentRollups) sortedAssets() []assetRollup {
+	assets := make([]assetRollup, 0, len(r.assets))
+	for _, rollup := range r.assets {
+		assets = append(assets, *rollup)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Unsent != assets[j].Unsent {
+			return assets[i].Unsent > assets[j].Unsent
+		}
+		return assets[i].AssetID < assets[j].AssetID
+	})
+	return assets
+}
+
+// sortedApps returns the application rollups with the most unsent calls first
+func (r *unsentRollups) sortedApps() []appRollup {
+	apps := make([]appRollup, 0, len(r.apps))
+	for _, rollup := range r.apps {
+		apps = append(apps, *rollup)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Unsent != apps[j].Unsent {
+			return apps[i].Unsent > apps[j].Unsent
+		}
+		return apps[i].AppID < apps[j].AppID
+	})
+	return apps
+}
+
+// logTop logs the n assets and applications with the most unsent transactions
+func (r *unsentRollups) logTop(n int) {
+	if n <= 0 {
+		return
+	}
+	if assets := r.sortedAssets(); len(assets) != 0 {
+		logMessage(log.InfoLevel, msgTopAssets, len(assets), n)
+		for i := 0; i < len(assets) && i < n; i++ {
+			logMessage(log.InfoLevel, msgTopAsset, assets[i].AssetID, assets[i].Unsent, assets[i].Amount)
+		}
+	}
+	if apps := r.sortedApps(); len(apps) != 0 {
+		logMessage(log.InfoLevel, msgTopApps, len(apps), n)
+		for i := 0; i < len(apps) && i < n; i++ {
+			if amounts := describeAmounts(apps[i].MicroAlgos, apps[i].Assets); len(amounts) != 0 {
+				logMessage(log.InfoLevel, msgTopAppMoving, apps[i].AppID, apps[i].Unsent, strings.Join(amounts, ", "))
+			} else {
+				logMessage(log.InfoLevel, msgTopApp, apps[i].AppID, apps[i].Unsent)
+			}
+		}
+	}
+}
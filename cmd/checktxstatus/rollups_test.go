@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestUnsentRollups(t *testing.T) {
+	tx := func(txn types.Transaction) txRecord {
+		return txRecord{stx: types.SignedTxn{Txn: txn}}
+	}
+	swap := types.Digest{1}
+	r := newUnsentRollups()
+	r.add([]txRecord{
+		tx(types.Transaction{Type: types.PaymentTx, Header: types.Header{Group: swap},
+			PaymentTxnFields: types.PaymentTxnFields{Amount: 12000000}}),
+		tx(types.Transaction{Type: types.AssetTransferTx, Header: types.Header{Group: swap},
+			AssetTransferTxnFields: types.AssetTransferTxnFields{XferAsset: 31566704, AssetAmount: 5000000}}),
+		tx(types.Transaction{Type: types.ApplicationCallTx, Header: types.Header{Group: swap},
+			ApplicationFields: types.ApplicationFields{ApplicationCallTxnFields: types.ApplicationCallTxnFields{
+				ApplicationID: 1002541853}}}),
+		tx(types.Transaction{Type: types.AssetFreezeTx,
+			AssetFreezeTxnFields: types.AssetFreezeTxnFields{FreezeAsset: 31566704}}),
+		tx(types.Transaction{Type: types.ApplicationCallTx}),
+		{txIDOnly: true},
+	})
+	assets := r.sortedAssets()
+	if len(assets) != 1 || assets[0] != (assetRollup{AssetID: 31566704, Unsent: 2, Amount: 5000000}) {
+		t.Errorf("expected the transfer and the freeze of the asset, got %+v", assets)
+	}
+	apps := r.sortedApps()
+	if len(apps) != 1 || apps[0].AppID != 1002541853 || apps[0].Unsent != 1 || apps[0].MicroAlgos != 12000000 ||
+		apps[0].Assets[31566704] != 5000000 {
+		t.Errorf("expected the call with the amounts of its group, not the creation, got %+v", apps)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+var (
+	allowlistFile string
+	denylistFile  string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&allowlistFile, "allowlist", "",
+		"file of addresses (one per line); unsent transactions from or to any other address are not resubmitted")
+	rootCmd.Flags().StringVar(&denylistFile, "denylist", "",
+		"file of addresses (one per line); unsent transactions from or to these addresses are not resubmitted")
+}
+
+// addressScreen decides which addresses transactions may be resubmitted from and to
+type addressScreen struct {
+	// allowed is nil when there is no allowlist
+	allowed map[types.Address]bool
+	denied  map[types.Address]bool
+}
+
+// readAddressList reads a file of addresses, one per line; empty lines and lines starting with # are ignored,
+// and anything after the address on the same line (e.g. a comma separated name) is ignored as well
+func readAddressList(filename string) (map[types.Address]bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+
+	addresses := map[types.Address]bool{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		addr, err := types.DecodeAddress(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q in %s line %d: %v", fields[0], filename, lineNumber, err)
+		}
+		addresses[addr] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading %s: %v", filename, err)
+	}
+	return addresses, nil
+}
+
+// initAddressScreen returns nil if neither an allowlist nor a denylist was given
+func initAddressScreen(allowlistFile, denylistFile string) (*addressScreen, error) {
+	if allowlistFile == "" && denylistFile == "" {
+		return nil, nil
+	}
+	screen := &addressScreen{denied: map[types.Address]bool{}}
+	var err error
+	if allowlistFile != "" {
+		screen.allowed, err = readAddressList(allowlistFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if denylistFile != "" {
+		screen.denied, err = readAddressList(denylistFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return screen, nil
+}
+
+// txCounterparties returns the sender of the transaction and every address receiving funds or assets from it
+func txCounterparties(txn types.Transaction) []types.Address {
+	addresses := []types.Address{txn.Sender}
+	for _, addr := range []types.Address{txReceiver(txn), txn.CloseRemainderTo, txn.AssetCloseTo} {
+		if !addr.IsZero() {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// violation returns why the transaction violates the screening lists, or an empty string if it does not
+func (s *addressScreen) violation(txn types.Transaction) string {
+	if s == nil {
+		return ""
+	}
+	for _, addr := range txCounterparties(txn) {
+		if s.denied[addr] {
+			return fmt.Sprintf("%s is denylisted", addr)
+		}
+		if s.allowed != nil && !s.allowed[addr] {
+			return fmt.Sprintf("%s is not allowlisted", addr)
+		}
+	}
+	return ""
+}
+
+// screen separates the unsent transactions violating the screening lists from the rest
+// groups are resubmitted as a whole, so all the members of a group with a violating transaction are denied
+func (s *addressScreen) screen(filename string, unsentTxs []txRecord) (allowed []txRecord, denied []txRecord) {
+	if s == nil {
+		return unsentTxs, nil
+	}
+	logger := log.WithField("file", filename)
+	deniedGroups := map[types.Digest]bool{}
+	for _, tx := range unsentTxs {
+		if tx.txIDOnly {
+			continue
+		}
+		violation := s.violation(tx.stx.Txn)
+		if violation == "" {
+			continue
+		}
+		logEntryMessage(logger, log.WarnLevel, msgScreenedOut, tx.txID, violation)
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			deniedGroups[gid] = true
+		}
+	}
+	for _, tx := range unsentTxs {
+		gid := tx.stx.Txn.Group
+		if (gid != types.Digest{} && deniedGroups[gid]) || (!tx.txIDOnly && s.violation(tx.stx.Txn) != "") {
+			denied = append(denied, tx)
+		} else {
+			allowed = append(allowed, tx)
+		}
+	}
+	return allowed, denied
+}
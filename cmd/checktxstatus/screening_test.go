@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadAddressList(t *testing.T) {
+	alice, bob := types.Address{1}, types.Address{2}
+	filename := filepath.Join(testDir(t), "allow.txt")
+	content := "# treasury\n" + alice.String() + ",Alice\n\n" + bob.String() + " Bob\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := readAddressList(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 || !addresses[alice] || !addresses[bob] {
+		t.Errorf("expected both addresses, got %v", addresses)
+	}
+	if err := ioutil.WriteFile(filename, []byte("not-an-address\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAddressList(filename); err == nil {
+		t.Error("expected an invalid address to fail")
+	}
+}
+
+func TestScreenDeniesWholeGroups(t *testing.T) {
+	alice, bob, mallory := types.Address{1}, types.Address{2}, types.Address{3}
+	payment := func(txID string, receiver types.Address, group types.Digest) txRecord {
+		rec := txRecord{txID: txID}
+		rec.stx.Txn.Type = types.PaymentTx
+		rec.stx.Txn.Sender = alice
+		rec.stx.Txn.Receiver = receiver
+		rec.stx.Txn.Group = group
+		return rec
+	}
+	screen := &addressScreen{denied: map[types.Address]bool{mallory: true}}
+	unsent := []txRecord{
+		payment(testTxID(1), bob, types.Digest{}),
+		payment(testTxID(2), mallory, types.Digest{}),
+		payment(testTxID(3), bob, types.Digest{1}),
+		payment(testTxID(4), mallory, types.Digest{1}),
+	}
+	allowed, denied := screen.screen("test", unsent)
+	if len(allowed) != 1 || allowed[0].txID != testTxID(1) || len(denied) != 3 {
+		t.Errorf("expected the payments to mallory and their group to be denied, got %v and %v", allowed, denied)
+	}
+
+	screen = &addressScreen{allowed: map[types.Address]bool{alice: true}, denied: map[types.Address]bool{}}
+	if violation := screen.violation(unsent[0].stx.Txn); violation == "" {
+		t.Error("expected a receiver missing from the allowlist to be a violation")
+	}
+	if allowed, denied := (*addressScreen)(nil).screen("test", unsent); len(allowed) != 4 || denied != nil {
+		t.Error("expected all transactions to be allowed without lists")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+var topSenders int
+
+func init() {
+	rootCmd.Flags().IntVar(&topSenders, "top-senders", 5,
+		"number of senders with the most unsent transactions to list in the run summary (0 to disable)")
+}
+
+// senderStats aggregates the unsent transactions of a single sender
+type senderStats struct {
+	sender types.Address
+	count  int
+	// microAlgos is the total amount of the unsent payments
+	microAlgos uint64
+	// assets is the total amount of the unsent asset transfers by asset ID
+	assets map[uint64]uint64
+}
+
+// senderSummary aggregates unsent transactions by sender across all checked files
+type senderSummary map[types.Address]*senderStats
+
+// add aggregates unsent transactions
+func (s senderSummary) add(txs []txRecord) {
+	for _, tx := range txs {
+		if tx.txIDOnly {
+			continue
+		}
+		txn := tx.stx.Txn
+		stats, ok := s[txn.Sender]
+		if !ok {
+			stats = &senderStats{sender: txn.Sender, assets: map[uint64]uint64{}}
+			s[txn.Sender] = stats
+		}
+		stats.count++
+		switch txn.Type {
+		case types.PaymentTx:
+			stats.microAlgos += uint64(txn.Amount)
+		case types.AssetTransferTx:
+			stats.assets[uint64(txn.XferAsset)] += txn.AssetAmount
+		}
+	}
+}
+
+// top returns the n senders with the most unsent transactions
+func (s senderSummary) top(n int) []*senderStats {
+	all := make([]*senderStats, 0, len(s))
+	for _, stats := range s {
+		all = append(all, stats)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].count != all[j].count {
+			return all[i].count > all[j].count
+		}
+		return all[i].sender.String() < all[j].sender.String()
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// describe returns a description of the sender's unsent transactions, e.g. "3 unsent, 1.5 Algo, 10 of asset 31566704"
+func (stats *senderStats) describe() string {
+	parts := []string{fmt.Sprintf("%d unsent", stats.count)}
+	return strings.Join(append(parts, describeAmounts(stats.microAlgos, stats.assets)...), ", ")
+}
+
+// describeAmounts describes amounts of Algos and of assets by asset ID, e.g. "1.5 Algo" and "10 of asset 31566704"
+func describeAmounts(microAlgos uint64, assets map[uint64]uint64) []string {
+	var parts []string
+	if microAlgos != 0 {
+		parts = append(parts, fmt.Sprintf("%s Algo", formatAlgos(microAlgos)))
+	}
+	assetIDs := make([]uint64, 0, len(assets))
+	for assetID := range assets {
+		assetIDs = append(assetIDs, assetID)
+	}
+	sort.Slice(assetIDs, func(i, j int) bool { return assetIDs[i] < assetIDs[j] })
+	for _, assetID := range assetIDs {
+		parts = append(parts, fmt.Sprintf("%d of asset %d", assets[assetID], assetID))
+	}
+	return parts
+}
+
+// formatAlgos formats an amount of microAlgos in Algos without rounding, e.g. 1.000007
+func formatAlgos(microAlgos uint64) string {
+	formatted := fmt.Sprintf("%d.%06d", microAlgos/1000000, microAlgos%1000000)
+	return strings.TrimSuffix(strings.TrimRight(formatted, "0"), ".")
+}
+
+// logTopSenders logs the senders with the most unsent transactions
+func (s senderSummary) logTopSenders(n int, resolver *addressResolver) {
+	if n <= 0 || len(s) == 0 {
+		return
+	}
+	logMessage(log.InfoLevel, msgTopSenders, len(s), n)
+	top := s.top(n)
+	addrs := make([]types.Address, 0, len(top))
+	for _, stats := range top {
+		addrs = append(addrs, stats.sender)
+	}
+	resolver.resolve(addrs)
+	for _, stats := range top {
+		logMessage(log.InfoLevel, msgTopSender, resolver.label(stats.sender), stats.describe())
+	}
+}
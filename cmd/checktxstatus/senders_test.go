@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"testing"
+)
+
+func TestSenderSummary(t *testing.T) {
+	alice, bob := types.Address{1}, types.Address{2}
+	tx := func(sender types.Address, txType types.TxType, amount uint64, assetID uint64) txRecord {
+		var rec txRecord
+		rec.stx.Txn.Sender = sender
+		rec.stx.Txn.Type = txType
+		rec.stx.Txn.Amount = types.MicroAlgos(amount)
+		rec.stx.Txn.AssetAmount = amount
+		rec.stx.Txn.XferAsset = types.AssetIndex(assetID)
+		return rec
+	}
+	summary := senderSummary{}
+	summary.add([]txRecord{
+		tx(alice, types.PaymentTx, 1500000, 0),
+		tx(bob, types.PaymentTx, 1, 0),
+		tx(alice, types.AssetTransferTx, 10, 31566704),
+		tx(alice, types.AssetTransferTx, 5, 7),
+		tx(alice, types.ApplicationCallTx, 0, 0),
+	})
+	top := summary.top(1)
+	if len(top) != 1 || top[0].sender != alice {
+		t.Fatalf("expected the sender with the most unsent transactions, got %v", top)
+	}
+	if description := top[0].describe(); description != "4 unsent, 1.5 Algo, 5 of asset 7, 10 of asset 31566704" {
+		t.Errorf("unexpected description %q", description)
+	}
+}
+
+func TestFormatAlgos(t *testing.T) {
+	amounts := map[uint64]string{0: "0", 1: "0.000001", 1000007: "1.000007", 2000000: "2", 2500000: "2.5"}
+	for microAlgos, expected := range amounts {
+		if formatted := formatAlgos(microAlgos); formatted != expected {
+			t.Errorf("expected %d microAlgos to be %s, got %s", microAlgos, expected, formatted)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	serveListen    string
+	serveMaxUpload int64
+)
+
+var serveCmd = &cobra.Command{
+	Use: "serve",
+	Short: "Serve the checks over HTTP: POST /check checks uploaded files, GET /status/<txid> looks up a transaction, " +
+		"POST /resubmit resubmits uploaded files",
+	Example: `  # serve the checks of internal services
+  checktxstatus serve --listen localhost:8090 --idx-addr http://localhost:8980 --yes
+
+  # check a file, and look up a transaction
+  curl -F file=@batch.tx http://localhost:8090/check
+  curl http://localhost:8090/status/J5A2KYMUQD53LOMJZ5QB6ZB2HOYQL25UGCY5CABZ5S3BOWNZYGMQ
+
+  # serve the tokens of tokens.yaml only, with their roles, and resubmit to a node
+  checktxstatus serve --tokens tokens.yaml --algod-addr http://localhost:8080 --idx-addr http://localhost:8980 --yes
+  curl -H "Authorization: Bearer $TOKEN" -F file=@batch.tx.unsent http://localhost:8090/resubmit`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		initRunID()
+		noPrompts = true
+		s, err := newCheckServer(cmd)
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		err = s.run()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		exitCode = 0
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "localhost:8090", "address to serve the checks on")
+	serveCmd.Flags().Int64Var(&serveMaxUpload, "max-upload", 64<<20,
+		"maximum size in bytes of the files uploaded to a check, all of them together")
+}
+
+// initServeCmd adds the serve subcommand, sharing the flags of the root command that affect checking
+// it must be called after the flags of the root command were registered
+func initServeCmd() {
+	for _, name := range checkSharedFlags {
+		serveCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(serveCmd)
+}
+
+// checkServer serves the checks of uploaded files, the lookups of transactions and the resubmissions
+// the checks share the settings of the process, so requests are served one at a time
+type checkServer struct {
+	cmd    *cobra.Command
+	mu     sync.Mutex
+	lookup *txLookup
+	// tokens are the tokens requests must bear, nil if any request is served
+	tokens *apiTokens
+	// submitter resubmits to the --algod-addr node, nil if it is not set
+	submitter *submitter
+	jobs      *jobQueue
+}
+
+// checkResponse is the response to POST /check
+type checkResponse struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	Unsent   int    `json:"unsent"`
+	Expired  int    `json:"expired"`
+	// Files are the uploaded files, in the order of the upload
+	Files []checkedFile `json:"files"`
+}
+
+// checkedFile is the outcome of checking an uploaded file
+type checkedFile struct {
+	File string `json:"file"`
+	// Transactions are the entries of its --report
+	Transactions []txReportEntry `json:"transactions"`
+	// Outputs are the contents of its other outputs, e.g. the unsent transactions, by their names
+	Outputs map[string][]byte `json:"outputs"`
+}
+
+// statusResponse is the response to GET /status/<txid>
+type statusResponse struct {
+	TxID   string      `json:"txid"`
+	Status txCondition `json:"status"`
+	// ConfirmedRound is missing if the transaction is unsent, or pending in the pool of the --algod-addr node
+	ConfirmedRound uint64 `json:"confirmed_round,omitempty"`
+}
+
+// resubmitResponse is the response to POST /resubmit
+type resubmitResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	// Files are the uploaded files, in the order of the upload
+	Files []resubmittedFile `json:"files"`
+}
+
+// resubmittedFile is the outcome of resubmitting an uploaded file
+type resubmittedFile struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+	// Transactions are the transactions submitted, the ones held back are left out
+	Transactions []submitOutcome `json:"transactions"`
+}
+
+// submitOutcome is how the node answered the submission of a transaction, as printed by submit
+type submitOutcome struct {
+	TxID    string `json:"txid"`
+	Outcome string `json:"outcome"`
+}
+
+// newCheckServer returns a server with the lookup of GET /status configured by the flags
+// the outputs of the uploads are returned, so they cannot be encrypted
+func newCheckServer(cmd *cobra.Command) (*checkServer, error) {
+	if len(ageRecipients) != 0 || len(gpgRecipients) != 0 {
+		return nil, fmt.Errorf("serve cannot encrypt the outputs it returns, use HTTPS instead")
+	}
+	indexerClient, err := initIndexerClient(indexerAddress, indexerToken)
+	if err != nil {
+		return nil, err
+	}
+	notFound, err := initIndexerNotFoundRules()
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := initBackoff()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := initPendingPool()
+	if err != nil {
+		return nil, err
+	}
+	err = initRateLimits()
+	if err != nil {
+		return nil, err
+	}
+	err = initFaultInjection()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return nil, err
+	}
+	lookup := &txLookup{indexerClient: indexerClient, notFound: notFound, backoff: backoff, pending: pending,
+		cache: cache, recent: newMemoryCache(cache, nil)}
+	tokens, err := loadAPITokens(serveTokensFile)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := newJobQueue()
+	if err != nil {
+		return nil, err
+	}
+	s := &checkServer{cmd: cmd, lookup: lookup, tokens: tokens, jobs: jobs}
+	if algodAddress != "" && !serveReadOnly {
+		s.submitter, err = newSubmitter(ioutil.Discard)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// run serves the requests until interrupted
+func (s *checkServer) run() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/check", s.authorized(roleCheck, s.serveCheck))
+	mux.HandleFunc("/status/", s.authorized(roleStatus, s.serveStatus))
+	mux.HandleFunc("/resubmit", s.authorized(roleResubmit, s.serveResubmit))
+	mux.HandleFunc("/jobs", s.authorized(roleCheck, s.serveSubmitJob))
+	mux.HandleFunc("/jobs/", s.authorized(roleStatus, s.serveJob))
+	server := &http.Server{Addr: serveListen, Handler: mux}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	go func() {
+		<-interrupted
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		// the checks in progress complete before the server stops
+		_ = server.Shutdown(ctx)
+	}()
+	go s.processJobs()
+	// the job being processed is interrupted after its current chunk
+	defer s.jobs.close()
+	defer s.lookup.cache.close()
+	logMessage(log.InfoLevel, msgServeServing, serveListen)
+	err := server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		return fmt.Errorf("failed serving on --listen %s: %v", serveListen, err)
+	}
+	logMessage(log.InfoLevel, msgServeStopped)
+	return nil
+}
+
+// serveCheck checks the files uploaded in the file fields of a multipart form, as a run of checktxstatus with all of
+// them would, and responds with the report of every file and its outputs
+func (s *checkServer) serveCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST to upload the files to check"))
+		return
+	}
+	paths, remove, ok := receiveUploads(w, r)
+	if !ok {
+		return
+	}
+	defer remove()
+	response, err := s.check(paths, "")
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, response)
+}
+
+// receiveUploads saves the files uploaded in the file fields of a multipart form to a temporary directory, and
+// returns their paths and a function removing them
+// it writes the error response if they could not be saved
+func receiveUploads(w http.ResponseWriter, r *http.Request) ([]string, func(), bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, serveMaxUpload)
+	// uploads larger than the memory limit are stored in temporary files
+	err := r.ParseMultipartForm(serveMaxUpload)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("invalid upload: %v", err))
+		return nil, nil, false
+	}
+	uploads := r.MultipartForm.File["file"]
+	if len(uploads) == 0 {
+		_ = r.MultipartForm.RemoveAll()
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("no file field in the upload"))
+		return nil, nil, false
+	}
+	dir, err := ioutil.TempDir("", "checktxstatus-serve")
+	if err != nil {
+		_ = r.MultipartForm.RemoveAll()
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("failed to create a directory: %v", err))
+		return nil, nil, false
+	}
+	remove := func() {
+		_ = r.MultipartForm.RemoveAll()
+		if err := os.RemoveAll(dir); err != nil {
+			logMessage(log.ErrorLevel, msgServeRemoveFailed, dir, err)
+		}
+	}
+	var paths []string
+	for i, upload := range uploads {
+		// every file gets its own directory, so uploads of the same name do not overwrite each other
+		name := filepath.Base(upload.Filename)
+		if name == "." || name == ".." || name == string(filepath.Separator) {
+			name = "upload"
+		}
+		path := filepath.Join(dir, fmt.Sprint(i), name)
+		err := saveUpload(upload, path)
+		if err != nil {
+			remove()
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return nil, nil, false
+		}
+		paths = append(paths, path)
+	}
+	return paths, remove, true
+}
+
+// saveUpload writes an uploaded file to path
+func saveUpload(header *multipart.FileHeader, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	upload, err := header.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read the upload of %s: %v", filepath.Base(path), err)
+	}
+	// no need to check error on close, the upload is only read
+	defer upload.Close()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	_, err = io.Copy(file, upload)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// check checks the files saved at paths, with --report forced so their transactions can be returned, and format
+// instead of --input-format unless empty
+func (s *checkServer) check(paths []string, format inputFormat) (checkResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	savedReport, savedFormat := writeReport, inputFormatStr
+	writeReport = true
+	if format != "" {
+		inputFormatStr = string(format)
+	}
+	defer func() {
+		writeReport, inputFormatStr = savedReport, savedFormat
+	}()
+	exitCode = 0
+	manifest, summary := runCheck(s.cmd, paths)
+	response := checkResponse{Status: summary.status(), ExitCode: summary.exitCode, Error: summary.err,
+		Unsent: summary.unsent, Expired: summary.expired, Files: []checkedFile{}}
+	if manifest == nil {
+		return response, nil
+	}
+	for _, path := range paths {
+		file := checkedFile{File: filepath.Base(path), Transactions: []txReportEntry{}, Outputs: map[string][]byte{}}
+		for _, output := range manifest.Outputs {
+			if !strings.HasPrefix(output.Path, path+".") {
+				continue
+			}
+			content, err := ioutil.ReadFile(output.Path)
+			if err != nil {
+				return checkResponse{}, fmt.Errorf("error while reading %s: %v", output.Path, err)
+			}
+			name := filepath.Base(path) + strings.TrimPrefix(output.Path, path)
+			if output.Path != path+".report.json" {
+				file.Outputs[name] = content
+				continue
+			}
+			err = json.Unmarshal(content, &file.Transactions)
+			if err != nil {
+				return checkResponse{}, fmt.Errorf("error while parsing %s: %v", output.Path, err)
+			}
+		}
+		// the report refers to the files where they were saved
+		for i := range file.Transactions {
+			file.Transactions[i].File = file.File
+		}
+		response.Files = append(response.Files, file)
+	}
+	return response, nil
+}
+
+// serveStatus looks up the transaction of GET /status/<txid> in the indexer, and in the pending pool of the
+// --algod-addr node if the indexer did not find it
+func (s *checkServer) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET to look up a transaction"))
+		return
+	}
+	txid := strings.TrimPrefix(r.URL.Path, "/status/")
+	if !txchecker.IsTxID(txid) {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("invalid txid %q", txid))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lookup := *s.lookup
+	lookup.rounds = &confirmedRounds{rounds: map[string]uint64{}}
+	sent, err := lookup.isTxSent(txid)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadGateway, err)
+		return
+	}
+	found := []bool{sent}
+	err = lookup.pending.lookupMissing([]string{txid}, found, lookup.backoff, nil, lookup.rounds)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadGateway, err)
+		return
+	}
+	response := statusResponse{TxID: txid, Status: conditionUnsent}
+	if found[0] {
+		response.Status = conditionConfirmed
+		response.ConfirmedRound = lookup.rounds.take([]txRecord{{txID: txid}})
+	}
+	writeHTTPJSON(w, http.StatusOK, response)
+}
+
+// serveResubmit resubmits the transactions of the files uploaded in the file fields of a multipart form to the
+// --algod-addr node, as submit would, and responds with how the node answered each of them
+func (s *checkServer) serveResubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST to upload the files to resubmit"))
+		return
+	}
+	if s.submitter == nil {
+		writeHTTPError(w, http.StatusNotImplemented, fmt.Errorf("resubmitting needs the server to have --algod-addr"))
+		return
+	}
+	paths, remove, ok := receiveUploads(w, r)
+	if !ok {
+		return
+	}
+	defer remove()
+	writeHTTPJSON(w, http.StatusOK, s.resubmit(paths))
+}
+
+// resubmit resubmits the files saved at paths, the failure of a file does not stop the others from being resubmitted
+func (s *checkServer) resubmit(paths []string) resubmitResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response := resubmitResponse{Files: []resubmittedFile{}}
+	for _, path := range paths {
+		file := resubmittedFile{File: filepath.Base(path), Transactions: []submitOutcome{}}
+		s.submitter.onOutcome = func(_ string, txID string, outcome string) {
+			file.Transactions = append(file.Transactions, submitOutcome{TxID: txID, Outcome: outcome})
+			if strings.HasPrefix(outcome, "rejected") {
+				response.Rejected++
+			} else {
+				response.Accepted++
+			}
+		}
+		err := s.submitter.submitFile(path)
+		if err != nil {
+			// the errors refer to the files where they were saved
+			file.Error = strings.ReplaceAll(err.Error(), path, file.File)
+		}
+		response.Files = append(response.Files, file)
+	}
+	s.submitter.onOutcome = nil
+	return response
+}
+
+// writeHTTPJSON writes a JSON response
+func writeHTTPJSON(w http.ResponseWriter, code int, response interface{}) {
+	encoded, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(encoded, '\n'))
+}
+
+// writeHTTPError writes a JSON error response, logging it
+func writeHTTPError(w http.ResponseWriter, code int, err error) {
+	logMessage(log.WarnLevel, msgServeRequestFailed, err)
+	writeHTTPJSON(w, code, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// newTestCheckServer returns a check server of an indexer at round 999, before the last valid round of
+// testSignedPayment, knowing only of the confirmed txids, confirmed in round 990
+func newTestCheckServer(t *testing.T, confirmed ...string) *checkServer {
+	known := map[string]bool{}
+	for _, txid := range confirmed {
+		known[txid] = true
+	}
+	idx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			fmt.Fprint(w, `{"db-available":true,"is-migrating":false,"message":"999","round":999}`)
+			return
+		}
+		txid := path.Base(r.URL.Path)
+		if !known[txid] {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":999,"transaction":{"id":%q,"confirmed-round":990}}`, txid)
+	}))
+	t.Cleanup(idx.Close)
+	client, err := indexer.MakeClient(idx.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the checks of the uploads look up the transactions as a run would
+	address := indexerAddress
+	t.Cleanup(func() { indexerAddress = address })
+	indexerAddress = idx.URL
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff()}
+	return &checkServer{cmd: rootCmd, lookup: lookup}
+}
+
+func TestServeStatus(t *testing.T) {
+	stx, _ := testSignedPayment(1000, types.Digest{})
+	confirmed := crypto.GetTxID(stx.Txn)
+	s := newTestCheckServer(t, confirmed)
+	tests := []struct {
+		method, txid string
+		code         int
+		expected     statusResponse
+	}{
+		{http.MethodGet, confirmed, http.StatusOK, statusResponse{TxID: confirmed, Status: conditionConfirmed,
+			ConfirmedRound: 990}},
+		{http.MethodGet, testTxID(1), http.StatusOK, statusResponse{TxID: testTxID(1), Status: conditionUnsent}},
+		{http.MethodGet, "not-a-txid", http.StatusBadRequest, statusResponse{}},
+		{http.MethodPost, confirmed, http.StatusMethodNotAllowed, statusResponse{}},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.serveStatus(w, httptest.NewRequest(test.method, "/status/"+test.txid, nil))
+		if w.Code != test.code {
+			t.Errorf("expected %d to %s %s, got %d: %s", test.code, test.method, test.txid, w.Code, w.Body)
+			continue
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+		var response statusResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response != test.expected {
+			t.Errorf("expected %+v, got %+v, %v", test.expected, response, err)
+		}
+	}
+}
+
+func TestServeCheck(t *testing.T) {
+	stx, confirmedRaw := testSignedPayment(1000, types.Digest{})
+	_, unsentRaw := testSignedPayment(1001, types.Digest{})
+	s := newTestCheckServer(t, crypto.GetTxID(stx.Txn))
+	defer func(code int) { exitCode = code }(exitCode)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	// files of the same name do not overwrite each other
+	for _, content := range [][]byte{append(append([]byte{}, confirmedRaw...), unsentRaw...), confirmedRaw} {
+		part, err := form.CreateFormFile("file", "batch.tx")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/check", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.serveCheck(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the check to succeed, got %d: %s", w.Code, w.Body)
+	}
+	var response checkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != statusUnsent || response.Unsent != 1 || len(response.Files) != 2 {
+		t.Fatalf("expected an unsent transaction in the first of 2 files, got %+v", response)
+	}
+	first := response.Files[0]
+	if first.File != "batch.tx" || len(first.Transactions) != 2 || first.Transactions[0].ConfirmedRound != 990 ||
+		!bytes.Equal(first.Outputs["batch.tx.unsent"], unsentRaw) {
+		t.Errorf("expected the report and the unsent transaction of the first file, got %+v", first)
+	}
+	if len(response.Files[1].Transactions) != 1 {
+		t.Errorf("expected the report of the second file, got %+v", response.Files[1])
+	}
+
+	w = httptest.NewRecorder()
+	s.serveCheck(w, httptest.NewRequest(http.MethodGet, "/check", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET /check to be refused, got %d", w.Code)
+	}
+}
+
+func TestServeCheckFailureHasNonzeroExitCode(t *testing.T) {
+	s := newTestCheckServer(t)
+	defer func(code int) { exitCode = code }(exitCode)
+	input := filepath.Join(testDir(t), "batch.tx")
+	// a msgpack map with a field unknown to signed transactions
+	if err := ioutil.WriteFile(input, []byte{0x81, 0xa3, 'f', 'o', 'o', 0x01}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	response, err := s.check([]string{input}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != statusError || response.ExitCode != 1 || response.Error == "" {
+		t.Errorf("expected the failed check to have exit code 1, got %+v", response)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	serveTokensFile string
+	serveReadOnly   bool
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveTokensFile, "tokens", "",
+		"YAML file of the API tokens the requests must bear, and the roles they are granted: status, check or "+
+			"resubmit; without it every request is served but resubmissions")
+	serveCmd.Flags().BoolVar(&serveReadOnly, "read-only", false,
+		"only serve the status lookups, whatever the roles of the tokens")
+}
+
+// apiRole is what the bearer of a token may request
+type apiRole string
+
+const (
+	// roleStatus looks up transactions, GET /status
+	roleStatus apiRole = "status"
+	// roleCheck checks files, POST /check
+	roleCheck apiRole = "check"
+	// roleResubmit resubmits the transactions of files to the --algod-addr node, POST /resubmit
+	roleResubmit apiRole = "resubmit"
+)
+
+// minTokenLength is the length of the shortest token accepted, guessing one must not be practical
+const minTokenLength = 16
+
+// apiToken is a token requests may bear, in the Authorization header as Bearer <token>
+type apiToken struct {
+	// Name identifies the bearer in the logs, the token itself is never logged
+	Name  string    `yaml:"name"`
+	Token string    `yaml:"token"`
+	Roles []apiRole `yaml:"roles"`
+}
+
+// apiTokens are the tokens of the server
+type apiTokens struct {
+	Tokens []*apiToken `yaml:"tokens"`
+}
+
+// loadAPITokens reads the tokens from a YAML file, warning if other users can read it
+// it returns nil if filename is empty
+func loadAPITokens(filename string) (*apiTokens, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading tokens %s: %v", filename, err)
+	}
+	if info, err := os.Stat(filename); err == nil && info.Mode().Perm()&0077 != 0 {
+		logMessage(log.WarnLevel, msgTokensReadable, filename)
+	}
+	var tokens apiTokens
+	err = yaml.UnmarshalStrict(content, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing tokens %s: %v", filename, err)
+	}
+	if len(tokens.Tokens) == 0 {
+		return nil, fmt.Errorf("tokens %s has no tokens", filename)
+	}
+	names := map[string]bool{}
+	values := map[string]bool{}
+	for _, t := range tokens.Tokens {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tokens %s has a token with no name", filename)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tokens %s has several tokens named %q", filename, t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Token) < minTokenLength {
+			return nil, fmt.Errorf("token %q of %s is shorter than %d characters", t.Name, filename, minTokenLength)
+		}
+		if values[t.Token] {
+			return nil, fmt.Errorf("token %q of %s is the token of another name", t.Name, filename)
+		}
+		values[t.Token] = true
+		if len(t.Roles) == 0 {
+			return nil, fmt.Errorf("token %q of %s has no roles", t.Name, filename)
+		}
+		for _, role := range t.Roles {
+			if role != roleStatus && role != roleCheck && role != roleResubmit {
+				return nil, fmt.Errorf("token %q of %s has unknown role %q, expected status, check or resubmit",
+					t.Name, filename, role)
+			}
+		}
+	}
+	return &tokens, nil
+}
+
+// bearer returns the token of a request, nil if it bears none or an unknown one
+func (a *apiTokens) bearer(r *http.Request) *apiToken {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil
+	}
+	presented := []byte(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	var found *apiToken
+	// every token is compared in constant time, so the timing does not tell how close a guess was
+	for _, t := range a.Tokens {
+		if subtle.ConstantTimeCompare(presented, []byte(t.Token)) == 1 {
+			found = t
+		}
+	}
+	return found
+}
+
+// has returns true if the token was granted role
+func (t *apiToken) has(role apiRole) bool {
+	for _, granted := range t.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized serves the requests of handle only if they may request role
+// with no --tokens every request may but the resubmissions, which are only served to tokens granted them
+func (s *checkServer) authorized(role apiRole, handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if serveReadOnly && role != roleStatus {
+			writeHTTPError(w, http.StatusForbidden, fmt.Errorf("the server is read-only, it only serves /status"))
+			return
+		}
+		if s.tokens == nil {
+			if role == roleResubmit {
+				writeHTTPError(w, http.StatusForbidden, fmt.Errorf("resubmitting needs the server to have --tokens"))
+				return
+			}
+			handle(w, r)
+			return
+		}
+		token := s.tokens.bearer(r)
+		if token == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="checktxstatus"`)
+			writeHTTPError(w, http.StatusUnauthorized, fmt.Errorf("missing or unknown bearer token"))
+			return
+		}
+		if !token.has(role) {
+			writeHTTPError(w, http.StatusForbidden, fmt.Errorf("token %q is not granted the %s role", token.Name, role))
+			return
+		}
+		log.WithField("token", token.Name).Debugf("%s %s", r.Method, r.URL.Path)
+		handle(w, r)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthorized(t *testing.T) {
+	s := &checkServer{tokens: &apiTokens{Tokens: []*apiToken{
+		{Name: "dashboard", Token: "dashboard-token-0001", Roles: []apiRole{roleStatus}},
+		{Name: "ops", Token: "ops-token-000000001", Roles: []apiRole{roleStatus, roleCheck, roleResubmit}},
+	}}}
+	served := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	tests := []struct {
+		role   apiRole
+		header string
+		code   int
+	}{
+		{roleStatus, "", http.StatusUnauthorized},
+		{roleStatus, "Bearer unknown-token-00001", http.StatusUnauthorized},
+		{roleStatus, "Basic ZGFzaGJvYXJk", http.StatusUnauthorized},
+		{roleStatus, "Bearer dashboard-token-0001", http.StatusNoContent},
+		{roleCheck, "Bearer dashboard-token-0001", http.StatusForbidden},
+		{roleResubmit, "Bearer ops-token-000000001", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/status/", nil)
+		if test.header != "" {
+			r.Header.Set("Authorization", test.header)
+		}
+		w := httptest.NewRecorder()
+		s.authorized(test.role, served)(w, r)
+		if w.Code != test.code {
+			t.Errorf("expected %d to %q requesting %s, got %d: %s", test.code, test.header, test.role, w.Code, w.Body)
+		}
+		if test.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("expected a challenge to %q", test.header)
+		}
+	}
+}
+
+func TestAuthorizedReadOnly(t *testing.T) {
+	defer func(readOnly bool) { serveReadOnly = readOnly }(serveReadOnly)
+	serveReadOnly = true
+	s := &checkServer{tokens: &apiTokens{Tokens: []*apiToken{
+		{Name: "ops", Token: "ops-token-000000001", Roles: []apiRole{roleStatus, roleCheck}},
+	}}}
+	served := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	for role, code := range map[apiRole]int{roleStatus: http.StatusNoContent, roleCheck: http.StatusForbidden} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer ops-token-000000001")
+		w := httptest.NewRecorder()
+		s.authorized(role, served)(w, r)
+		if w.Code != code {
+			t.Errorf("expected %d requesting %s of a read-only server, got %d", code, role, w.Code)
+		}
+	}
+}
+
+func TestAuthorizedWithoutTokens(t *testing.T) {
+	s := &checkServer{}
+	served := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	for role, code := range map[apiRole]int{roleCheck: http.StatusNoContent, roleResubmit: http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		s.authorized(role, served)(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if w.Code != code {
+			t.Errorf("expected %d requesting %s with no --tokens, got %d", code, role, w.Code)
+		}
+	}
+}
+
+func TestLoadAPITokens(t *testing.T) {
+	dir := testDir(t)
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"tokens:\n  - {name: ops, token: ops-token-000000001, roles: [check]}\n", ""},
+		{"tokens:\n  - {name: ops, token: short, roles: [check]}\n", "shorter than"},
+		{"tokens:\n  - {name: ops, token: ops-token-000000001, roles: [admin]}\n", "unknown role"},
+		{"tokens:\n  - {name: a, token: ops-token-000000001, roles: [check]}\n" +
+			"  - {name: b, token: ops-token-000000001, roles: [check]}\n", "token of another name"},
+	}
+	for i, test := range tests {
+		filename := filepath.Join(dir, "tokens.yaml")
+		if err := ioutil.WriteFile(filename, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		tokens, err := loadAPITokens(filename)
+		if test.err == "" && (err != nil || len(tokens.Tokens) != 1) {
+			t.Errorf("expected tokens %d to load, got %v", i, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("expected tokens %d to fail with %q, got %v", i, test.err, err)
+		}
+	}
+}
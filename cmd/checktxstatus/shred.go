@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"os"
+)
+
+var shred bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&shred, "shred", false,
+		"zeroize buffers holding signed transactions once they are no longer needed")
+}
+
+// zeroize overwrites b with zeros when --shred is set
+func zeroize(b []byte) {
+	if !shred {
+		return
+	}
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// zeroizeRecords wipes the encodings and signatures held by the records when --shred is set
+// the records must not be used afterwards
+func zeroizeRecords(records []txRecord) {
+	if !shred {
+		return
+	}
+	for i := range records {
+		zeroize(records[i].raw)
+		zeroize(records[i].stx.Lsig.Logic)
+		for _, arg := range records[i].stx.Lsig.Args {
+			zeroize(arg)
+		}
+		records[i].stx.Sig = types.Signature{}
+		records[i].stx.Msig = types.MultisigSig{}
+		records[i].stx.Lsig = types.LogicSig{}
+	}
+}
+
+// removeFile removes a file, overwriting it with zeros first when --shred is set
+func removeFile(filename string) error {
+	if shred {
+		err := overwriteFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to overwrite %s: %v", filename, err)
+		}
+	}
+	return os.Remove(filename)
+}
+
+// overwriteFile overwrites the content of a file with zeros
+func overwriteFile(filename string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	zeros := make([]byte, 64*1024)
+	for offset := int64(0); offset < stat.Size(); offset += int64(len(zeros)) {
+		if remaining := stat.Size() - offset; remaining < int64(len(zeros)) {
+			zeros = zeros[:remaining]
+		}
+		if _, err := file.WriteAt(zeros, offset); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
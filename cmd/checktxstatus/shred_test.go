@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestZeroizeOnlyWithShred(t *testing.T) {
+	b := []byte{1, 2, 3}
+	zeroize(b)
+	if b[0] != 1 {
+		t.Error("expected buffers to be kept without --shred")
+	}
+	shred = true
+	defer func() { shred = false }()
+	zeroize(b)
+	if b[0] != 0 || b[1] != 0 || b[2] != 0 {
+		t.Errorf("expected the buffer to be zeroized, got %v", b)
+	}
+}
+
+func TestZeroizeRecords(t *testing.T) {
+	shred = true
+	defer func() { shred = false }()
+	raw, logic, arg := []byte{1}, []byte{2}, []byte{3}
+	records := []txRecord{{
+		raw: raw,
+		stx: types.SignedTxn{Sig: types.Signature{4}, Lsig: types.LogicSig{Logic: logic, Args: [][]byte{arg}}},
+	}}
+	zeroizeRecords(records)
+	if raw[0] != 0 || logic[0] != 0 || arg[0] != 0 {
+		t.Error("expected the encoding, logic and arguments to be zeroized")
+	}
+	if records[0].stx.Sig != (types.Signature{}) || records[0].stx.Lsig.Logic != nil {
+		t.Errorf("expected the signatures to be cleared, got %+v", records[0].stx)
+	}
+}
+
+func TestOverwriteFileZeroesContent(t *testing.T) {
+	filename := filepath.Join(testDir(t), "unsent")
+	content := bytes.Repeat([]byte{7}, 70*1024)
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := overwriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	overwritten, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(overwritten, make([]byte, len(content))) {
+		t.Error("expected the whole file to be overwritten with zeros, keeping its size")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var simulateUnsent bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&simulateUnsent, "simulate-unsent", false,
+		"simulate the unsent groups with application calls on the --algod-addr node with an execution trace, logging "+
+			"why they fail and adding the failing program counter and cost to the --report")
+}
+
+// simulationTrace is the condensed outcome of simulating the unit of an unsent transaction, in the report
+type simulationTrace struct {
+	// FailureMessage is empty if the unit would succeed
+	FailureMessage string `json:"failure_message,omitempty"`
+	// FailedAt is the position in the unit of the failing transaction
+	FailedAt *int `json:"failed_at,omitempty"`
+	// AppBudgetConsumed is the cost of the application calls of the whole unit
+	AppBudgetConsumed uint64 `json:"app_budget_consumed"`
+	// FailingPC is the program counter of the last opcode evaluated by the failing transaction
+	FailingPC *uint64 `json:"failing_pc,omitempty"`
+	// OpcodesEvaluated is the length of the approval program trace of the failing transaction
+	OpcodesEvaluated int `json:"opcodes_evaluated,omitempty"`
+}
+
+// simulateResponse is the part of the algod simulate response the trace is condensed from
+type simulateResponse struct {
+	TxnGroups []struct {
+		FailureMessage    string   `json:"failure-message"`
+		FailedAt          []uint64 `json:"failed-at"`
+		AppBudgetConsumed uint64   `json:"app-budget-consumed"`
+		TxnResults        []struct {
+			ExecTrace *struct {
+				ApprovalProgramTrace []struct {
+					PC uint64 `json:"pc"`
+				} `json:"approval-program-trace"`
+			} `json:"exec-trace"`
+		} `json:"txn-results"`
+	} `json:"txn-groups"`
+}
+
+// simulator simulates unsent application calls on an algod node, the node fails them as it would on resubmission
+type simulator struct {
+	client  *http.Client
+	backoff *backoffPolicy
+	mu      sync.Mutex
+	// traces are the outcomes of the simulated units by txid until they are reported
+	traces map[string]*simulationTrace
+}
+
+// initSimulator returns a simulator if --simulate-unsent is set, nil otherwise
+func initSimulator(backoff *backoffPolicy) (*simulator, error) {
+	if !simulateUnsent {
+		return nil, nil
+	}
+	if algodAddress == "" {
+		return nil, fmt.Errorf("--simulate-unsent needs the algod node to simulate on, please supply --algod-addr")
+	}
+	return &simulator{
+		client:  &http.Client{Timeout: 30 * time.Second, Transport: clientTransports[algodTransport]},
+		backoff: backoff,
+		traces:  map[string]*simulationTrace{},
+	}, nil
+}
+
+// explain simulates the units of the unsent transactions that have application calls, logging the failing ones
+// a failed simulation is logged and does not fail the check
+func (s *simulator) explain(filename string, unsent []txRecord) {
+	if s == nil {
+		return
+	}
+	for _, unit := range submissionUnits(unsent) {
+		if !hasAppCall(unit) {
+			continue
+		}
+		trace, err := s.simulate(unit)
+		if err != nil {
+			logMessage(log.WarnLevel, msgSimulateFailed, unit[0].txID, filename, err)
+			continue
+		}
+		if trace.FailureMessage != "" {
+			logEntryMessage(log.WithField("file", filename), log.WarnLevel, msgSimulateFails, unit[0].txID,
+				trace.FailureMessage)
+		}
+		// the traces are only kept for the report
+		if !writeReport {
+			continue
+		}
+		s.mu.Lock()
+		for _, tx := range unit {
+			s.traces[tx.txID] = trace
+		}
+		s.mu.Unlock()
+	}
+}
+
+// take returns the outcome of simulating the unit of txid and forgets it, nil if it was not simulated
+func (s *simulator) take(txid string) *simulationTrace {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trace := s.traces[txid]
+	delete(s.traces, txid)
+	return trace
+}
+
+// hasAppCall returns true if a unit has an application call; units read from txid lists have none to simulate
+func hasAppCall(unit []txRecord) bool {
+	for _, tx := range unit {
+		if !tx.txIDOnly && tx.stx.Txn.Type == types.ApplicationCallTx {
+			return true
+		}
+	}
+	return false
+}
+
+// simulate runs a unit through the simulate endpoint of the node with the execution trace enabled
+func (s *simulator) simulate(unit []txRecord) (*simulationTrace, error) {
+	request, err := simulateRequest(unit)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(request)
+	requestID, headers := nextRequest()
+	log.Debugf("simulating tx %s, request %s", unit[0].txID, requestID)
+	var resp simulateResponse
+	err = postAlgodMsgpack(s.client, s.backoff, fmt.Sprintf("simulating tx %s, request %s,", unit[0].txID, requestID),
+		"/v2/transactions/simulate?format=json", request, headers, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("algod request %s: %v", requestID, err)
+	}
+	if len(resp.TxnGroups) == 0 {
+		return nil, fmt.Errorf("algod request %s: the response has no group", requestID)
+	}
+	group := resp.TxnGroups[0]
+	trace := &simulationTrace{FailureMessage: group.FailureMessage, AppBudgetConsumed: group.AppBudgetConsumed}
+	// failed-at is the path to the failing transaction, starting with its position in the group
+	if len(group.FailedAt) != 0 {
+		failedAt := int(group.FailedAt[0])
+		trace.FailedAt = &failedAt
+		if failedAt < len(group.TxnResults) && group.TxnResults[failedAt].ExecTrace != nil {
+			pcs := group.TxnResults[failedAt].ExecTrace.ApprovalProgramTrace
+			trace.OpcodesEvaluated = len(pcs)
+			if len(pcs) != 0 {
+				pc := pcs[len(pcs)-1].PC
+				trace.FailingPC = &pc
+			}
+		}
+	}
+	return trace, nil
+}
+
+// postAlgodMsgpack posts a msgpack request to path of the --algod-addr node, retrying it with backoff, and decodes
+// its JSON response into resp
+func postAlgodMsgpack(client *http.Client, backoff *backoffPolicy, description string, path string, request []byte,
+	headers []*common.Header, resp interface{}) error {
+	return backoff.retry(description, func() error {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			strings.TrimRight(algodAddress, "/")+path, bytes.NewReader(request))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set("X-Algo-API-Token", algodToken)
+		for _, header := range append(runHeaders(), headers...) {
+			req.Header.Set(header.Key, header.Value)
+		}
+		httpResp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return err
+		}
+		// same format as the errors of the SDK, so they are retried alike
+		if httpResp.StatusCode != http.StatusOK {
+			return fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, body)
+		}
+		if err := json.Unmarshal(body, resp); err != nil {
+			return fmt.Errorf("failed decoding response: %v", err)
+		}
+		return nil
+	})
+}
+
+// simulateRequest encodes the simulate request of a unit in msgpack, embedding the signed transactions as they were
+// read so their signatures still match:
+// {"exec-trace-config": {"enable": true}, "txn-groups": [{"txns": [...]}]}
+func simulateRequest(unit []txRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(0x82)
+	writeMsgpackString(&buf, "exec-trace-config")
+	buf.WriteByte(0x81)
+	writeMsgpackString(&buf, "enable")
+	buf.WriteByte(0xc3)
+	writeMsgpackString(&buf, "txn-groups")
+	buf.WriteByte(0x91)
+	buf.WriteByte(0x81)
+	writeMsgpackString(&buf, "txns")
+	// array 16 is needed for groups of 16 transactions
+	buf.Write([]byte{0xdc, byte(len(unit) >> 8), byte(len(unit))})
+	for _, tx := range unit {
+		encoded, err := encodeTxRecord(tx)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMsgpackString writes a msgpack fixstr, the keys of the simulate request are all shorter than 32 bytes
+func writeMsgpackString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(0xa0 | byte(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSimulateCondensesTheFailingTransaction(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := msgpack.Decode(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"txn-groups":[{"failure-message":"logic eval error: assert failed","failed-at":[1],`+
+			`"app-budget-consumed":42,"txn-results":[{},{"exec-trace":{"approval-program-trace":`+
+			`[{"pc":1},{"pc":7},{"pc":12}]}}]}]}`)
+	}))
+	defer server.Close()
+	defer func(addr string, report bool) { algodAddress, writeReport = addr, report }(algodAddress, writeReport)
+	algodAddress, writeReport = server.URL, true
+
+	payment := txRecord{txID: testTxID(1), stx: types.SignedTxn{Sig: types.Signature{1},
+		Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{Group: types.Digest{1}}}}}
+	call := txRecord{txID: testTxID(2), stx: types.SignedTxn{Sig: types.Signature{1},
+		Txn: types.Transaction{Type: types.ApplicationCallTx, Header: types.Header{Group: types.Digest{1}}}}}
+	s := &simulator{client: server.Client(), backoff: newTestBackoff(), traces: map[string]*simulationTrace{}}
+	s.explain("batch.tx", []txRecord{payment, call})
+
+	groups, ok := request["txn-groups"].([]interface{})
+	if !ok || len(groups) != 1 {
+		t.Fatalf("expected a single group in the request, got %v", request)
+	}
+	trace := s.take(testTxID(1))
+	if trace == nil || trace.FailureMessage != "logic eval error: assert failed" || *trace.FailedAt != 1 ||
+		*trace.FailingPC != 12 || trace.OpcodesEvaluated != 3 || trace.AppBudgetConsumed != 42 {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	if s.take(testTxID(1)) != nil || s.take(testTxID(2)) == nil {
+		t.Error("expected the trace of every member of the group to be taken once")
+	}
+}
+
+func TestHasAppCall(t *testing.T) {
+	call := txRecord{stx: types.SignedTxn{Txn: types.Transaction{Type: types.ApplicationCallTx}}}
+	if hasAppCall([]txRecord{{txIDOnly: true}}) || !hasAppCall([]txRecord{{txIDOnly: true}, call}) {
+		t.Error("expected only units with an application call to be simulated")
+	}
+}
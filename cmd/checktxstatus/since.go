@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"time"
+)
+
+var sinceReport string
+
+func init() {
+	rootCmd.Flags().StringVar(&sinceReport, "since", "",
+		"JSON report of a previous run written by --report: the transactions it classified as confirmed are carried "+
+			"forward as confirmed, with their rounds, without being looked up again, so only the rest are re-checked")
+}
+
+// priorConfirmations are the transactions confirmed in the report of a previous run, they cannot become unsent
+type priorConfirmations struct {
+	// rounds maps the txids to their confirmed rounds, 0 if the report did not have it
+	rounds map[string]uint64
+}
+
+// loadPriorConfirmations reads the confirmed transactions of a report, seeding clock with the timestamps of their
+// rounds so the new report carries them forward without looking up their blocks
+// it returns nil if filename is empty
+func loadPriorConfirmations(filename string, clock *blockClock) (*priorConfirmations, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading report %s: %v", filename, err)
+	}
+	var entries []txReportEntry
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing report %s: %v", filename, err)
+	}
+	prior := &priorConfirmations{rounds: map[string]uint64{}}
+	for _, entry := range entries {
+		// transactions read from txid lists are reported with their txids too, and only the confirmed ones have
+		// rounds, whatever the status derived from their condition
+		confirmed := entry.Classification == string(conditionConfirmed) || entry.ConfirmedRound != 0
+		if !confirmed || entry.TxID == "" {
+			continue
+		}
+		prior.rounds[entry.TxID] = entry.ConfirmedRound
+		if entry.ConfirmedRound == 0 || entry.ConfirmedTime == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, entry.ConfirmedTime); err == nil {
+			clock.cache[entry.ConfirmedRound] = t
+		}
+	}
+	logMessage(log.InfoLevel, msgSinceCarried, len(prior.rounds), filename)
+	return prior, nil
+}
+
+// confirmed returns whether a transaction was confirmed in the report, and its round if known
+// with --as-of-round only the transactions whose round is known to be by then are carried forward
+func (p *priorConfirmations) confirmed(txid string) (uint64, bool) {
+	if p == nil {
+		return 0, false
+	}
+	round, ok := p.rounds[txid]
+	if !ok || asOfRound != 0 && (round == 0 || round > asOfRound) {
+		return 0, false
+	}
+	return round, true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinceCarriesForwardTheConfirmedTransactions(t *testing.T) {
+	report := filepath.Join(testDir(t), "batch.tx.report.json")
+	err := ioutil.WriteFile(report, []byte(`[
+  {"file": "batch.tx", "index": 0, "txid": "`+testTxID(1)+`", "confirmed_round": 990,
+    "confirmed_time": "2022-03-04T05:06:07Z", "classification": "confirmed"},
+  {"file": "batch.tx", "index": 1, "txid": "`+testTxID(2)+`", "classification": "unsent"},
+  {"file": "batch.tx", "index": 2, "txid": "`+testTxID(3)+`", "classification": "confirmed"}
+]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newBlockClock(nil, newTestBackoff())
+	prior, err := loadPriorConfirmations(report, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clock.cache[990].Equal(time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("expected the time of the round to be seeded, got %v", clock.cache)
+	}
+	// no indexer is needed, the confirmed transactions are not looked up
+	lookup := &txLookup{since: prior, rounds: &confirmedRounds{rounds: map[string]uint64{}}}
+	if sent, err := lookup.isTxSent(testTxID(1)); err != nil || !sent || lookup.rounds.rounds[testTxID(1)] != 990 {
+		t.Errorf("expected the confirmed transaction with its round, got %v, %v", sent, err)
+	}
+	if _, ok := prior.confirmed(testTxID(2)); ok {
+		t.Error("expected the unsent transaction to be looked up again")
+	}
+
+	defer func(round uint64) { asOfRound = round }(asOfRound)
+	asOfRound = 995
+	if _, ok := prior.confirmed(testTxID(3)); ok {
+		t.Error("expected a transaction confirmed in an unknown round to be looked up again with --as-of-round")
+	}
+	if round, ok := prior.confirmed(testTxID(1)); !ok || round != 990 {
+		t.Errorf("expected the transaction confirmed by --as-of-round, got %d, %v", round, ok)
+	}
+}
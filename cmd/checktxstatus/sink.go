@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportSink is where the reports of finished jobs are delivered
+type reportSink interface {
+	// deliver sends the report of job read from filename
+	deliver(job string, filename string) error
+	// String describes the sink in the logs
+	String() string
+}
+
+// parseReportSink returns the sink of --job-sink: an http(s) URL the reports are POSTed to, or s3://bucket/prefix
+// the reports are PUT in with the AWS credentials of the environment
+// it returns nil if sink is empty
+func parseReportSink(sink string) (reportSink, error) {
+	if sink == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --job-sink %q: %v", sink, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &webhookSink{url: sink}, nil
+	case "s3":
+		return newS3Sink(u)
+	}
+	return nil, fmt.Errorf("invalid --job-sink %q, expected an http(s) URL or s3://bucket/prefix", sink)
+}
+
+// sinkClient sends the reports, they may be large so only connecting is bounded in time
+var sinkClient = &http.Client{Transport: &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: time.Minute,
+}}
+
+// sendReport sends the report of filename in a request to target, completed by prepare, failing with an error
+// formatted like the errors of the SDK if the response is not successful, so only the transient failures are retried
+func sendReport(filename string, method string, target string, prepare func(req *http.Request)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error while reading %s: %v", filename, err)
+	}
+	// no need to check error on close, the file is only read
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error while reading %s: %v", filename, err)
+	}
+	req, err := http.NewRequest(method, target, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/json")
+	prepare(req)
+	resp, err := sinkClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// webhookSink POSTs the reports to a URL, with the job ID in the X-Checktxstatus-Job header
+type webhookSink struct {
+	url string
+}
+
+func (s *webhookSink) deliver(job string, filename string) error {
+	return sendReport(filename, http.MethodPost, s.url, func(req *http.Request) {
+		req.Header.Set("X-Checktxstatus-Job", job)
+	})
+}
+
+func (s *webhookSink) String() string {
+	return redactURL(s.url, "webhook")
+}
+
+// redactURL returns a URL without its query and credentials, which may hold secrets, or fallback if it is invalid
+func redactURL(target string, fallback string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fallback
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// s3Sink PUTs the reports to <prefix>/<job>.report.json in an S3 bucket, signing the requests with AWS Signature
+// Version 4
+type s3Sink struct {
+	bucket string
+	prefix string
+	region string
+	// endpoint is AWS_ENDPOINT_URL, e.g. of a MinIO server addressed in path style, empty for AWS
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// newS3Sink returns the sink of s3://bucket/prefix, with the credentials, region and endpoint of the environment
+func newS3Sink(u *url.URL) (*s3Sink, error) {
+	s := &s3Sink{
+		bucket:       u.Host,
+		prefix:       strings.Trim(u.Path, "/"),
+		region:       os.Getenv("AWS_REGION"),
+		endpoint:     strings.TrimSuffix(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.bucket == "" {
+		return nil, fmt.Errorf("invalid --job-sink %q, expected s3://bucket/prefix", u)
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("--job-sink %s needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", u)
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	return s, nil
+}
+
+func (s *s3Sink) deliver(job string, filename string) error {
+	key := path.Join(s.prefix, job+".report.json")
+	target := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+	if s.endpoint != "" {
+		target = fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+	}
+	// the payload is signed too, so the report cannot be altered on the way
+	payload, err := hashFile(filename)
+	if err != nil {
+		return err
+	}
+	return sendReport(filename, http.MethodPut, target, func(req *http.Request) {
+		s.sign(req, payload.SHA256, time.Now())
+	})
+}
+
+func (s *s3Sink) String() string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.prefix)
+}
+
+// sign signs a request with AWS Signature Version 4, with all of its headers and payloadHash, the hex SHA-256 of its
+// payload; it must be the last change to the request
+func (s *s3Sink) sign(req *http.Request, payloadHash string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	date := stamp[:8]
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	headers, signed := canonicalHeaders(req)
+	canonical := strings.Join([]string{req.Method, req.URL.EscapedPath(), canonicalQuery(req.URL), headers, signed,
+		payloadHash}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+// canonicalHeaders returns the canonical headers of a request, its host and all of its headers sorted by lowercase name,
+// and the list of their names
+func canonicalHeaders(req *http.Request) (headers string, signed string) {
+	values := map[string][]string{"host": {req.URL.Host}}
+	if req.Host != "" {
+		values["host"] = []string{req.Host}
+	}
+	for name, v := range req.Header {
+		name = strings.ToLower(name)
+		values[name] = append(values[name], v...)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		trimmed := make([]string, len(values[name]))
+		for i, v := range values[name] {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery returns the query of a URL with its parameters sorted and encoded as Signature Version 4 expects
+func canonicalQuery(u *url.URL) string {
+	// url.Values.Encode sorts by key but encodes spaces as +, which must be %20
+	return strings.ReplaceAll(u.Query().Encode(), "+", "%20")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
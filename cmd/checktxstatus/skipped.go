@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+var skippedReportFile string
+
+func init() {
+	rootCmd.Flags().StringVar(&skippedReportFile, "skipped-report", "",
+		"write a JSON report of every transaction excluded from checking or resubmission, with the reason, to this file")
+}
+
+// skipReason explains why a transaction was excluded from checking or resubmission
+type skipReason string
+
+const (
+	reasonCorrupt       skipReason = "corrupt"
+	reasonOutsideWindow skipReason = "outside-time-window"
+	reasonUnsigned      skipReason = "unsigned"
+	reasonDenied        skipReason = "denied"
+	reasonLarge         skipReason = "exceeds-max-amount"
+	reasonExpired       skipReason = "expired"
+	reasonPartial       skipReason = "partially-sent"
+)
+
+// skippedTx is a transaction excluded from checking or resubmission
+type skippedTx struct {
+	recordSource
+	Group  string     `json:"group,omitempty"`
+	Reason skipReason `json:"reason"`
+	Detail string     `json:"detail,omitempty"`
+	// index is the position of the transaction in its batch
+	index int
+}
+
+// skipRecords records the exclusion of transactions, detail returns the explanation for a single transaction or an
+// empty string if it was excluded only for being in the same group as another excluded transaction
+func skipRecords(records []txRecord, reason skipReason, detail func(txRecord) string) []skippedTx {
+	skipped := make([]skippedTx, 0, len(records))
+	for _, rec := range records {
+		entry := skippedTx{recordSource: rec.source(), Reason: reason, index: rec.index}
+		if gid := rec.stx.Txn.Group; gid != (types.Digest{}) {
+			entry.Group = groupIDString(gid)
+		}
+		if detail != nil {
+			entry.Detail = detail(rec)
+		}
+		if entry.Detail == "" && entry.Group != "" {
+			entry.Detail = fmt.Sprintf("in a group with a transaction skipped as %s", reason)
+		}
+		skipped = append(skipped, entry)
+	}
+	return skipped
+}
+
+// logReconciliation logs how all the transactions read from a file were accounted for
+func logReconciliation(filename string, total int, confirmed int, unsent int, skipped []skippedTx) {
+	reasons := map[skipReason]int{}
+	for _, entry := range skipped {
+		reasons[entry.Reason]++
+	}
+	var parts []string
+	for reason, count := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(parts)
+	var reasonsSummary string
+	if len(parts) != 0 {
+		reasonsSummary = fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+	}
+	if total != confirmed+unsent+len(skipped) {
+		logMessage(log.ErrorLevel, msgNotReconciled, filename, total, confirmed, unsent, len(skipped), reasonsSummary)
+		return
+	}
+	logMessage(log.InfoLevel, msgReconciled, filename, total, confirmed, unsent, len(skipped), reasonsSummary)
+}
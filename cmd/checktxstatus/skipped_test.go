@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestSkipRecordsExplainsGroupMembers(t *testing.T) {
+	skipped := txRecord{txID: testTxID(1), file: "batch.tx", fileIndex: 3, index: 3}
+	skipped.stx.Txn.Group = types.Digest{1}
+	member := txRecord{txID: testTxID(2), file: "batch.tx", fileIndex: 4, index: 4}
+	member.stx.Txn.Group = types.Digest{1}
+	entries := skipRecords([]txRecord{skipped, member}, reasonDenied, func(rec txRecord) string {
+		if rec.txID == skipped.txID {
+			return "denylisted"
+		}
+		return ""
+	})
+	if len(entries) != 2 || entries[0].Detail != "denylisted" || entries[0].File != "batch.tx" || entries[0].Index != 3 || entries[0].Group == "" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if !strings.Contains(entries[1].Detail, "in a group with a transaction skipped as denied") {
+		t.Errorf("expected the group member to be explained, got %q", entries[1].Detail)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var splitUnsentByGroup bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&splitUnsentByGroup, "split-unsent-by-group", false,
+		"write each unsent group to <file>.unsent/<group-id>.stxn and individual unsent transactions to "+
+			"<file>.unsent/individual.stxn instead of a single <file>.unsent")
+}
+
+// individualFileName is the file individual transactions are written to when splitting by group
+const individualFileName = "individual.stxn"
+
+// groupFileName returns the file a group is written to when splitting by group, named by its URL-safe base64 ID
+func groupFileName(gid types.Digest) string {
+	return base64.URLEncoding.EncodeToString(gid[:]) + ".stxn"
+}
+
+// splitByGroup splits transactions to files of dir, one per group and one for all individual transactions
+// it returns the file names in the order of their first transaction, and the transactions of each file
+func splitByGroup(dir string, txs []txRecord) ([]string, map[string][]txRecord) {
+	var names []string
+	parts := map[string][]txRecord{}
+	for _, tx := range txs {
+		name := individualFileName
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			name = groupFileName(gid)
+		}
+		name = filepath.Join(dir, name)
+		if _, ok := parts[name]; !ok {
+			names = append(names, name)
+		}
+		parts[name] = append(parts[name], tx)
+	}
+	return names, parts
+}
+
+// prepareSplitDir creates dir, removing transaction files left in it by previous runs so it only holds the
+// transactions of this run
+func prepareSplitDir(dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error while listing %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Mode().IsRegular() || !strings.HasSuffix(trimCompression(trimEncryption(name)), ".stxn") {
+			continue
+		}
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to remove %s left by a previous run: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitByGroup(t *testing.T) {
+	tx := func(group types.Digest) txRecord {
+		var rec txRecord
+		rec.stx.Txn.Group = group
+		return rec
+	}
+	names, parts := splitByGroup("out", []txRecord{tx(types.Digest{1}), tx(types.Digest{}), tx(types.Digest{1}),
+		tx(types.Digest{})})
+	groupFile := filepath.Join("out", groupFileName(types.Digest{1}))
+	individualFile := filepath.Join("out", individualFileName)
+	if len(names) != 2 || names[0] != groupFile || names[1] != individualFile {
+		t.Fatalf("expected a file of the group and one of individual transactions, got %v", names)
+	}
+	if len(parts[groupFile]) != 2 || len(parts[individualFile]) != 2 {
+		t.Errorf("unexpected parts %v", parts)
+	}
+}
+
+func TestPrepareSplitDirRemovesOnlyTransactionFiles(t *testing.T) {
+	dir := filepath.Join(testDir(t), "batch.tx.unsent")
+	if err := prepareSplitDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"old.stxn", "old.stxn.age", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := prepareSplitDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "notes.txt" {
+		t.Errorf("expected only the files of other tools to be kept, got %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error(err)
+	}
+}
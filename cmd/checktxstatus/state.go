@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	stateBackend  string
+	statePrefix   string
+	stateTTL      time.Duration
+	stateCacheTTL time.Duration
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&stateBackend, "state-backend", "",
+		"redis://[user:password@]host:port[/db] (rediss:// over TLS) holding the state shared by several instances: "+
+			"the cache of the lookups and, for watch-dir, the locks of the files being checked and the instances "+
+			"the files are partitioned among")
+	rootCmd.Flags().StringVar(&statePrefix, "state-prefix", "checktxstatus",
+		"prefix of the keys of --state-backend, distinct for every watched directory sharing the backend")
+	rootCmd.Flags().DurationVar(&stateCacheTTL, "state-cache-ttl", 7*24*time.Hour,
+		"how long the transactions found confirmed are cached in --state-backend, so its keys do not grow without "+
+			"bound")
+	watchDirCmd.Flags().DurationVar(&stateTTL, "state-ttl", 30*time.Second,
+		"how long the locks and the membership of an instance in --state-backend outlive it if it stops without "+
+			"releasing them, they are renewed while it runs")
+}
+
+// the scripts of the locks, which only renew or release a lock still held by the instance
+const (
+	renewLockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then ` +
+		`return redis.call("pexpire", KEYS[1], ARGV[2]) end return 0`
+	unlockLockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) end return 0`
+)
+
+// sharedState is the state shared by the instances using the same --state-backend and --state-prefix
+type sharedState struct {
+	client *redisClient
+	prefix string
+	// instance identifies this instance in the locks and the members
+	instance string
+	mu       sync.Mutex
+	// members are the live instances as of the last heartbeat, sorted, and lastBeat is when it was sent
+	members  []string
+	lastBeat time.Time
+	// cacheDown is true once the backend failed to answer a lookup, which then only uses the local cache
+	cacheDown bool
+}
+
+// openedState is the state of --state-backend, opened once per process and nil if not set
+var openedState *sharedState
+
+// openSharedState connects to --state-backend, once per process, it returns nil if --state-backend is not set
+func openSharedState() (*sharedState, error) {
+	if stateBackend == "" || openedState != nil {
+		return openedState, nil
+	}
+	u, err := url.Parse(stateBackend)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --state-backend %s, expected redis://host:port or rediss://host:port",
+			redactURL(stateBackend, "URL"))
+	}
+	client, err := newRedisClient(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --state-backend %s: %v", redactURL(stateBackend, "URL"), err)
+	}
+	if _, err := client.do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to --state-backend %s: %v", redactURL(stateBackend, "URL"), err)
+	}
+	hostname, _ := os.Hostname()
+	openedState = &sharedState{client: client, prefix: statePrefix, instance: fmt.Sprintf("%s-%d", hostname, os.Getpid())}
+	logMessage(log.InfoLevel, msgStateSharing, redactURL(stateBackend, "URL"), openedState.instance)
+	return openedState, nil
+}
+
+// key returns the key of parts under --state-prefix
+func (s *sharedState) key(parts ...string) string {
+	key := s.prefix
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
+
+// lookup returns whether a transaction is cached in the shared state as found or not found, and its round if found
+// like the --cache-path cache, with --as-of-round only the transactions whose round is known to be by then are
+// cached as found; a failure of the backend is a cache miss
+func (s *sharedState) lookup(txid string) (sent bool, round uint64, ok bool) {
+	if s == nil || s.isCacheDown() {
+		return false, 0, false
+	}
+	reply, err := s.client.do("GET", s.key("tx", txid))
+	if err != nil {
+		s.cacheFailed(msgStateLookupFailed, txid, err)
+		return false, 0, false
+	}
+	value, _ := reply.(string)
+	switch value {
+	case "":
+		return false, 0, false
+	case "unsent":
+		return false, 0, true
+	}
+	round, err = strconv.ParseUint(value, 10, 64)
+	if err != nil || asOfRound != 0 && (round == 0 || round > asOfRound) {
+		return false, 0, false
+	}
+	return true, round, true
+}
+
+// record caches the result of looking up a transaction in the shared state, the transactions found expire after
+// --state-cache-ttl and the ones not found after --cache-negative-ttl
+// failing to record it only loses it for the other instances, so it is logged
+func (s *sharedState) record(txid string, sent bool, round uint64) {
+	if s == nil || !sent && cacheNegativeTTL == 0 || s.isCacheDown() {
+		return
+	}
+	args := []string{"SET", s.key("tx", txid), strconv.FormatUint(round, 10), "PX", milliseconds(stateCacheTTL)}
+	if !sent {
+		args = []string{"SET", s.key("tx", txid), "unsent", "PX", milliseconds(cacheNegativeTTL)}
+	}
+	if _, err := s.client.do(args...); err != nil {
+		s.cacheFailed(msgStateRecordFailed, txid, err)
+	}
+}
+
+// isCacheDown returns whether the cache of the backend is not used since it failed
+func (s *sharedState) isCacheDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cacheDown
+}
+
+// cacheFailed logs a failure of the cache of the backend, which is not used anymore if the backend did not answer, so
+// the lookups do not wait for it every time; an error reply only fails its own command
+func (s *sharedState) cacheFailed(id messageID, txid string, err error) {
+	if _, ok := err.(redisError); ok {
+		logMessage(log.WarnLevel, id, txid, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cacheDown {
+		s.cacheDown = true
+		logMessage(log.WarnLevel, msgStateCacheDisabled, err)
+	}
+}
+
+// lock takes the lock of a watched file, so no other instance checks it at the same time, renewing it every third of
+// --state-ttl until unlock is called
+// it returns false if another instance holds the lock, and always succeeds without a state backend
+func (s *sharedState) lock(name string) (unlock func(), ok bool, err error) {
+	if s == nil {
+		return func() {}, true, nil
+	}
+	key := s.key("lock", name)
+	reply, err := s.client.do("SET", key, s.instance, "NX", "PX", milliseconds(stateTTL))
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(stateTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			reply, err := s.client.do("EVAL", renewLockScript, "1", key, s.instance, milliseconds(stateTTL))
+			if err != nil {
+				logMessage(log.WarnLevel, msgLockRenewFailed, name, err)
+			} else if renewed, _ := reply.(int64); renewed == 0 {
+				logMessage(log.ErrorLevel, msgLockLost, name)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		if _, err := s.client.do("EVAL", unlockLockScript, "1", key, s.instance); err != nil {
+			logMessage(log.WarnLevel, msgLockReleaseFailed, name, stateTTL, err)
+		}
+	}, true, nil
+}
+
+// heartbeat renews the membership of the instance and reads the live members, at most every third of --state-ttl
+// the members are kept in a sorted set scored by when they expire, so the clocks of the instances must agree to a
+// fraction of --state-ttl
+func (s *sharedState) heartbeat(now time.Time) error {
+	if s == nil || now.Sub(s.lastBeat) < stateTTL/3 {
+		return nil
+	}
+	key := s.key("members")
+	if _, err := s.client.do("ZADD", key, strconv.FormatInt(unixMilli(now.Add(stateTTL)), 10), s.instance); err != nil {
+		return fmt.Errorf("failed to renew the membership of %s: %v", s.instance, err)
+	}
+	if _, err := s.client.do("ZREMRANGEBYSCORE", key, "-inf", strconv.FormatInt(unixMilli(now), 10)); err != nil {
+		return fmt.Errorf("failed to expire the members: %v", err)
+	}
+	reply, err := s.client.do("ZRANGE", key, "0", "-1")
+	if err != nil {
+		return fmt.Errorf("failed to read the members: %v", err)
+	}
+	members := []string{s.instance}
+	items, _ := reply.([]interface{})
+	for _, item := range items {
+		if member, ok := item.(string); ok && member != s.instance {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the backend is reachable again
+	if s.cacheDown {
+		s.cacheDown = false
+		logMessage(log.InfoLevel, msgStateCacheEnabled)
+	}
+	if len(members) != len(s.members) {
+		logMessage(log.InfoLevel, msgStateMembers, len(members)-1)
+	}
+	s.members, s.lastBeat = members, now
+	return nil
+}
+
+// leave removes the instance from the members, so the others take over its files without waiting for it to expire
+func (s *sharedState) leave() {
+	if s == nil {
+		return
+	}
+	if _, err := s.client.do("ZREM", s.key("members"), s.instance); err != nil {
+		logMessage(log.WarnLevel, msgStateLeaveFailed, stateTTL, err)
+	}
+}
+
+// owns returns whether the instance is the one checking a file, always true without a state backend
+func (s *sharedState) owns(name string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.members) == 0 || ownerOf(name, s.members) == s.instance
+}
+
+// ownerOf returns the member a file is partitioned to, by rendezvous hashing of its name, so only the files of a
+// member joining or leaving move to another one
+func ownerOf(name string, members []string) string {
+	var owner string
+	var best uint64
+	for _, member := range members {
+		h := fnv.New64a()
+		h.Write([]byte(member))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		if score := h.Sum64(); owner == "" || score > best {
+			owner, best = member, score
+		}
+	}
+	return owner
+}
+
+// milliseconds formats a duration in milliseconds, as expected by Redis
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+// unixMilli returns t in milliseconds since the epoch
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
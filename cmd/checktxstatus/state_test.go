@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestOwnerOfMovesOnlyTheFilesOfALeavingMember(t *testing.T) {
+	members := []string{"a-1", "b-2", "c-3"}
+	owned := map[string]int{}
+	for i := 0; i < 300; i++ {
+		name := "batch" + strconv.Itoa(i) + ".tx"
+		owner := ownerOf(name, members)
+		owned[owner]++
+		if owner == "b-2" {
+			continue
+		}
+		if after := ownerOf(name, []string{"a-1", "c-3"}); after != owner {
+			t.Errorf("expected %s to stay with %s when b-2 leaves, got %s", name, owner, after)
+		}
+	}
+	for _, member := range members {
+		if owned[member] < 50 {
+			t.Errorf("expected the files to be spread among the members, got %v", owned)
+			break
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var statusTxIDFile string
+
+// statusSharedFlags are the flags of the root command that affect looking up txids
+var statusSharedFlags = []string{
+	"log-level", "network", "idx-addr", "idx-tkn", "idx-not-found-status", "idx-not-found-body", "idx-not-found-empty",
+	"hedge-idx-addr", "hedge-idx-tkn", "hedge-percentile", "algod-addr", "algod-tkn", "concurrency",
+	"adaptive-concurrency", "max-concurrency", "target-latency", "backoff", "backoff-base", "backoff-max", "retries",
+	"max-requests", "max-request-cost", "request-cost", "budget-slowdown", "rate-limit", "rate-burst", "fault-inject",
+	"cache-path", "cache-negative-ttl", "cache-batch", "cache-flush", "format", "deterministic", "metrics-listen",
+	"state-backend", "state-prefix", "state-cache-ttl",
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status <txid1> <txid2> ...",
+	Short: "Print the status of transactions by their txids, without the files they were read from",
+	Example: `  # check two transactions
+  checktxstatus status --idx-addr https://mainnet-idx.algonode.cloud \
+    IXCINEKHAGV2DN5VQ5BG4JXEKBGI6MFLGMDSBHXP426DGJER7YXQ QTMZY2MCSCIGT6ZRTGHJ77TXYUSHGLMQOJJ7PCYMQYDXBPHRKXVA
+
+  # check the txids of a file, one per line, as JSON lines
+  checktxstatus status --txid-file txids.txt --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		stop := stopOnInterrupt()
+		defer stop()
+		if len(args) == 0 && statusTxIDFile == "" {
+			setLogger(logLevelStr)
+			logError(newUserError(msgNoTxIDs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+		statusTxIDs, txidStream = args, statusTxIDFile
+		runCheck(cmd, nil)
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusTxIDFile, "txid-file", "",
+		"file of txids to look up too, one per line, - for stdin")
+}
+
+// initStatusCmd adds the status subcommand, sharing the flags of the root command that affect looking up txids
+// it must be called after the flags of the root command were registered
+func initStatusCmd() {
+	for _, name := range statusSharedFlags {
+		statusCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// stdioName is the filename of stdin as an input, and of stdout as the output of --stdout
+const stdioName = "-"
+
+// stdinOutputBase is the name the outputs of stdin are derived from, in the working directory
+const stdinOutputBase = "stdin"
+
+var writeStdout bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&writeStdout, "stdout", false,
+		"write the unsent transactions of all inputs to stdout instead of <file>.unsent, for piping into other "+
+			"tools; the summary is printed to stderr")
+}
+
+// stdin is read once, on first use, and kept in memory so it can be hashed and read like a file
+var stdin struct {
+	once    sync.Once
+	content []byte
+	err     error
+}
+
+// readStdin returns the content of stdin
+func readStdin() ([]byte, error) {
+	stdin.once.Do(func() {
+		stdin.content, stdin.err = ioutil.ReadAll(os.Stdin)
+		if stdin.err != nil {
+			stdin.err = fmt.Errorf("error while reading stdin: %v", stdin.err)
+		}
+	})
+	return stdin.content, stdin.err
+}
+
+// openInput opens an input file for reading, or stdin if filename is -
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename != stdioName {
+		return os.Open(filename)
+	}
+	content, err := readStdin()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// inputSize returns the size of an input file, or of stdin if filename is -
+func inputSize(filename string) (int64, error) {
+	if filename == stdioName {
+		content, err := readStdin()
+		return int64(len(content)), err
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return 0, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	return stat.Size(), nil
+}
+
+// outputBase returns the name the outputs of an input are derived from
+func outputBase(filename string) string {
+	if filename == stdioName {
+		return stdinOutputBase
+	}
+	return filename
+}
+
+// validateStdout makes sure nothing else is written to stdout with --stdout, and that the transactions written
+// there can be read back by the next tool of the pipe
+func validateStdout() error {
+	if !writeStdout {
+		return nil
+	}
+	switch {
+	case outputFormat != summaryText:
+		return fmt.Errorf("--stdout cannot be combined with --format %s, which is printed to stdout too", outputFormat)
+	case splitUnsentByGroup:
+		return fmt.Errorf("--stdout cannot be combined with --split-unsent-by-group")
+	case len(ageRecipients) != 0 || len(gpgRecipients) != 0:
+		return fmt.Errorf("--stdout cannot be combined with encryption, encrypt the output of the pipe instead")
+	}
+	return nil
+}
+
+// summaryOutput returns where the summary is printed, stderr when stdout carries the unsent transactions
+func summaryOutput() io.Writer {
+	if writeStdout || txidStream != "" || len(statusTxIDs) != 0 {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// streamDigest hashes and counts what is written to stdout, for the manifest
+type streamDigest struct {
+	hash hash.Hash
+	size int64
+}
+
+// Write hashes p
+func (d *streamDigest) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// entry returns the manifest entry of what was written
+func (d *streamDigest) entry() manifestFileEntry {
+	return manifestFileEntry{Path: stdioName, Size: d.size, SHA256: hex.EncodeToString(d.hash.Sum(nil))}
+}
+
+// openStdoutStream returns a stream writing the transactions of an input to stdout, it is flushed on close but never
+// closed, so the transactions of the next inputs follow
+func openStdoutStream() *outputStream {
+	s := &outputStream{filename: stdioName, buf: bufio.NewWriter(os.Stdout), digest: &streamDigest{hash: sha256.New()}}
+	s.w = io.MultiWriter(s.buf, s.digest)
+	return s
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestReadTxFileFromStdin(t *testing.T) {
+	_, payment := testSignedPayment(1000, types.Digest{})
+	filename := filepath.Join(testDir(t), "stdin")
+	if err := ioutil.WriteFile(filename, payment, 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	defer func(in *os.File) { os.Stdin = in }(os.Stdin)
+	os.Stdin = file
+	stdin.once, stdin.content, stdin.err = sync.Once{}, nil, nil
+	defer func() { stdin.once, stdin.content, stdin.err = sync.Once{}, nil, nil }()
+
+	for i := 0; i < 2; i++ {
+		// stdin is kept in memory, so it can be read again
+		batch := &txBatch{groups: map[types.Digest][]txRecord{}}
+		if err := readTxFile(stdioName, batch, nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(batch.individual) != 1 {
+			t.Fatalf("expected the transaction read from stdin, got %+v", batch)
+		}
+	}
+	if size, err := inputSize(stdioName); err != nil || size != int64(len(payment)) {
+		t.Errorf("expected the size of stdin, got %d, %v", size, err)
+	}
+	if outputBase(stdioName) != stdinOutputBase || outputBase(filename) != filename {
+		t.Error("expected the outputs of stdin to be named after stdin")
+	}
+}
+
+func TestStdoutStreamDigest(t *testing.T) {
+	defer func(out *os.File) { os.Stdout = out }(os.Stdout)
+	filename := filepath.Join(testDir(t), "stdout")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	os.Stdout = file
+	s := openStdoutStream()
+	if _, err := s.w.Write([]byte("unsent")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("unsent"))
+	entry := s.digest.entry()
+	if entry.Path != stdioName || entry.Size != 6 || entry.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected manifest entry %+v", entry)
+	}
+	if content, err := ioutil.ReadFile(filename); err != nil || string(content) != "unsent" {
+		t.Errorf("expected the stream to be flushed to stdout, got %q, %v", content, err)
+	}
+}
+
+func TestValidateStdout(t *testing.T) {
+	defer func(stdout bool, format string) { writeStdout, outputFormat = stdout, format }(writeStdout, outputFormat)
+	writeStdout, outputFormat = true, summaryJSON
+	if err := validateStdout(); err == nil {
+		t.Error("expected --stdout with --format json to be rejected")
+	}
+	outputFormat = summaryText
+	if err := validateStdout(); err != nil {
+		t.Errorf("expected --stdout with the text summary, got %v", err)
+	}
+}
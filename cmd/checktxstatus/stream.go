@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+var (
+	streamChunk   int
+	fsyncInterval time.Duration
+)
+
+func init() {
+	rootCmd.Flags().IntVar(&streamChunk, "stream-chunk", 1000,
+		"number of groups and individual transactions classified at a time, each chunk is written to the outputs as "+
+			"soon as it is classified (0 to classify whole files at once)")
+	rootCmd.Flags().DurationVar(&fsyncInterval, "fsync-interval", 5*time.Second,
+		"sync outputs to disk at least this often while they are written (0 to sync them only when complete)")
+}
+
+// outputSink streams transactions to an output file, syncing it to disk every --fsync-interval
+type outputSink struct {
+	stream   *outputStream
+	lastSync time.Time
+	// count is the number of transactions written
+	count int
+	// sources are the positions in the input files of the transactions written, in order
+	sources []recordSource
+}
+
+// openOutputSink creates an output file, filename is expected to be the result of outputName and of compressedName
+// the transactions written to stdout are never compressed
+func openOutputSink(filename string) (*outputSink, error) {
+	stream, err := openOutputStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	if filename != stdioName {
+		if err := stream.compress(); err != nil {
+			// nothing was written yet, nothing to lose
+			_ = stream.close()
+			return nil, err
+		}
+	}
+	return &outputSink{stream: stream, lastSync: time.Now()}, nil
+}
+
+// write appends transactions to the output file
+func (s *outputSink) write(txs []txRecord) error {
+	for _, tx := range txs {
+		encoded, err := encodeTxRecord(tx)
+		if err != nil {
+			return fmt.Errorf("failed to write txs to %s: %v", s.stream.filename, err)
+		}
+		_, err = s.stream.Write(encoded)
+		if tx.raw == nil {
+			zeroize(encoded)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	s.count += len(txs)
+	s.sources = append(s.sources, recordSources(txs)...)
+	return maybeSync(s.stream, &s.lastSync)
+}
+
+// maybeSync syncs a stream to disk if it was last synced more than --fsync-interval ago
+func maybeSync(stream *outputStream, lastSync *time.Time) error {
+	if fsyncInterval <= 0 || time.Since(*lastSync) < fsyncInterval {
+		return nil
+	}
+	*lastSync = time.Now()
+	return stream.sync()
+}
+
+// bucketWriter streams the buckets of the classification of a file to their output files, chunk after chunk
+type bucketWriter struct {
+	filename string
+	policy   classificationPolicy
+	// sinks are the output files by name, outputs are their names in the order they were created
+	sinks   map[string]*outputSink
+	outputs []string
+	// bucketOutputs are the names of the output files of every bucket, in the order they were created
+	bucketOutputs map[string][]string
+	// splitDirs are the directories of the buckets split by group that were prepared
+	splitDirs map[string]bool
+}
+
+func newBucketWriter(filename string, policy classificationPolicy) *bucketWriter {
+	return &bucketWriter{
+		filename:      filename,
+		policy:        policy,
+		sinks:         map[string]*outputSink{},
+		bucketOutputs: map[string][]string{},
+		splitDirs:     map[string]bool{},
+	}
+}
+
+// write appends a classified chunk to the output files of its buckets
+// the unsent bucket is split to a file per group with --split-unsent-by-group
+func (w *bucketWriter) write(buckets map[string][]txRecord) error {
+	bucketNames := make([]string, 0, len(buckets))
+	for bucket := range buckets {
+		bucketNames = append(bucketNames, bucket)
+	}
+	sort.Strings(bucketNames)
+	for _, bucket := range bucketNames {
+		if writeStdout && bucket == w.policy.Conditions[conditionUnsent].Bucket {
+			err := w.append(bucket, stdioName, buckets[bucket])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !splitUnsentByGroup || bucket != w.policy.Conditions[conditionUnsent].Bucket {
+			err := w.append(bucket, outputName(compressedName(fmt.Sprintf("%s.%s", w.filename, bucket))), buckets[bucket])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		dir := fmt.Sprintf("%s.%s", w.filename, bucket)
+		if !w.splitDirs[dir] {
+			err := prepareSplitDir(dir)
+			if err != nil {
+				return err
+			}
+			w.splitDirs[dir] = true
+		}
+		names, parts := splitByGroup(dir, buckets[bucket])
+		for _, name := range names {
+			err := w.append(bucket, outputName(compressedName(name)), parts[name])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// append writes transactions of a bucket to an output file, creating it on first use
+func (w *bucketWriter) append(bucket string, output string, txs []txRecord) error {
+	sink, ok := w.sinks[output]
+	if !ok {
+		var err error
+		sink, err = openOutputSink(output)
+		if err != nil {
+			return err
+		}
+		w.sinks[output] = sink
+		w.outputs = append(w.outputs, output)
+		w.bucketOutputs[bucket] = append(w.bucketOutputs[bucket], output)
+	}
+	return sink.write(txs)
+}
+
+// close completes all output files, adding them to result
+// it is safe to call again, later calls do nothing
+func (w *bucketWriter) close(result *fileResult) error {
+	var firstErr error
+	for _, output := range w.outputs {
+		sink := w.sinks[output]
+		if sink == nil {
+			continue
+		}
+		delete(w.sinks, output)
+		if err := sink.stream.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if result != nil {
+			result.outputs = append(result.outputs, output)
+			result.sources[output] = sink.sources
+			if sink.stream.digest != nil {
+				result.streamed[output] = sink.stream.digest.entry()
+			}
+		}
+	}
+	return firstErr
+}
+
+// logOutputs logs the number of transactions written to the output files of every bucket
+func (w *bucketWriter) logOutputs(counts map[string]int) {
+	bucketNames := make([]string, 0, len(w.bucketOutputs))
+	for bucket := range w.bucketOutputs {
+		bucketNames = append(bucketNames, bucket)
+	}
+	sort.Strings(bucketNames)
+	for _, bucket := range bucketNames {
+		outputs := w.bucketOutputs[bucket]
+		if splitUnsentByGroup && bucket == w.policy.Conditions[conditionUnsent].Bucket {
+			logMessage(log.InfoLevel, msgBucketSplitWritten, counts[bucket], bucket, len(outputs),
+				fmt.Sprintf("%s.%s", w.filename, bucket))
+			continue
+		}
+		logMessage(log.InfoLevel, msgBucketWritten, counts[bucket], bucket, outputs[0])
+	}
+}
+
+// jsonArrayWriter streams a report as a JSON array, file after file
+type jsonArrayWriter struct {
+	stream   *outputStream
+	lastSync time.Time
+	count    int
+	// what names the report in errors
+	what string
+}
+
+// openJSONArray creates a report, filename is expected to be the result of outputName
+func openJSONArray(filename string, what string) (*jsonArrayWriter, error) {
+	stream, err := openOutputStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonArrayWriter{stream: stream, lastSync: time.Now(), what: what}, nil
+}
+
+// add appends an entry to the report
+// the report is formatted as an indented JSON array, the same as encoding all the entries at once
+func (r *jsonArrayWriter) add(entry interface{}) error {
+	encoded, err := json.MarshalIndent(entry, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding %s: %v", r.what, err)
+	}
+	separator := ",\n  "
+	if r.count == 0 {
+		separator = "[\n  "
+	}
+	_, err = r.stream.Write(append([]byte(separator), encoded...))
+	if err != nil {
+		return err
+	}
+	r.count++
+	return nil
+}
+
+// close completes the report
+func (r *jsonArrayWriter) close() error {
+	end := "\n]\n"
+	if r.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := r.stream.Write([]byte(end)); err != nil {
+		// the report is incomplete either way
+		_ = r.stream.close()
+		return err
+	}
+	return r.stream.close()
+}
+
+// skippedReportWriter streams the skipped transactions report, file after file
+type skippedReportWriter struct {
+	*jsonArrayWriter
+}
+
+// openSkippedReport creates the skipped transactions report, filename is expected to be the result of outputName
+func openSkippedReport(filename string) (*skippedReportWriter, error) {
+	w, err := openJSONArray(filename, "skipped transactions report")
+	if err != nil {
+		return nil, err
+	}
+	return &skippedReportWriter{w}, nil
+}
+
+// write appends skipped transactions to the report
+func (r *skippedReportWriter) write(skipped []skippedTx) error {
+	for _, entry := range skipped {
+		if err := r.add(entry); err != nil {
+			return err
+		}
+	}
+	return maybeSync(r.stream, &r.lastSync)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBucketWriterAppendsChunks(t *testing.T) {
+	filename := filepath.Join(testDir(t), "batch.tx")
+	w := newBucketWriter(filename, defaultPolicy())
+	chunks := [][]txRecord{
+		{{txID: testTxID(1), raw: []byte("a"), fileIndex: 0}},
+		{{txID: testTxID(2), raw: []byte("bc"), fileIndex: 1}, {txID: testTxID(3), raw: []byte("d"), fileIndex: 2}},
+	}
+	for _, chunk := range chunks {
+		if err := w.write(map[string][]txRecord{"unsent": chunk}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := fileResult{sources: map[string][]recordSource{}}
+	if err := w.close(&result); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.close(&result); err != nil || len(result.outputs) != 1 {
+		t.Fatalf("expected closing again to do nothing, got %v %v", result.outputs, err)
+	}
+	output := filename + ".unsent"
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "abcd" {
+		t.Errorf("expected the chunks to be appended in order, got %q", content)
+	}
+	if len(result.sources[output]) != 3 || result.sources[output][2].Index != 2 {
+		t.Errorf("expected the sources of the 3 transactions, got %+v", result.sources[output])
+	}
+}
+
+func TestSkippedReportWriterWritesJSONArray(t *testing.T) {
+	dir := testDir(t)
+	for _, chunks := range [][][]skippedTx{
+		nil,
+		{{{Reason: reasonCorrupt}}, {{Reason: reasonDenied}, {Reason: reasonUnsigned}}},
+	} {
+		filename := filepath.Join(dir, "skipped.json")
+		r, err := openSkippedReport(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var all []skippedTx
+		for _, chunk := range chunks {
+			if err := r.write(chunk); err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, chunk...)
+		}
+		if err := r.close(); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if all == nil {
+			expected = []byte("[]")
+		}
+		if string(content) != string(expected)+"\n" {
+			t.Errorf("expected the report to be the same as encoding all entries at once, got %s", content)
+		}
+	}
+}
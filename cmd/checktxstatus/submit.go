@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	removeSubmitted bool
+	submitForce     bool
+)
+
+// submitSharedFlags are the flags of the root command that affect resubmitting
+var submitSharedFlags = []string{
+	"log-level", "input-format", "permissive", "network", "algod-addr", "algod-tkn", "idx-addr", "idx-tkn",
+	"backoff", "backoff-base", "backoff-max", "retries", "allowlist", "denylist", "max-amount", "confirm-large",
+	"shred",
+}
+
+var submitCmd = &cobra.Command{
+	Use:   "submit <file1.tx.unsent> <file2.tx.unsent> ...",
+	Short: "Resubmit the transactions of files to an algod node, reporting whether the node accepted each of them",
+	Example: `  # resubmit the unsent transactions found by a check
+  checktxstatus submit --algod-addr http://localhost:8080 --algod-tkn $ALGOD_TOKEN batch.tx.unsent
+
+  # resubmit, warning about stale keyregs, and remove the file once all of its transactions were accepted
+  checktxstatus submit --algod-addr http://localhost:8080 --idx-addr http://localhost:8980 \
+    --remove-submitted --shred batch.tx.unsent`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		initRunID()
+		err := validateInputFormat()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		s, err := newSubmitter(cmd.OutOrStdout())
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		if len(args) == 0 {
+			logError(newUserError(msgNoInputs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+
+		for _, filename := range args {
+			err := s.submitFile(filepath.Clean(filename))
+			if err != nil {
+				logError(err)
+				exitCode = 1
+			}
+		}
+	},
+}
+
+func init() {
+	submitCmd.Flags().BoolVar(&removeSubmitted, "remove-submitted", false,
+		"remove every file once the node accepted all of its transactions, overwriting it first with --shred")
+	submitCmd.Flags().BoolVar(&submitForce, "force", false,
+		"submit files with transactions holding colliding leases, of which only one of each cluster can be confirmed")
+}
+
+// initSubmitCmd adds the submit subcommand, sharing the flags of the root command that affect resubmitting
+// it must be called after the flags of the root command were registered
+func initSubmitCmd() {
+	for _, name := range submitSharedFlags {
+		submitCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(submitCmd)
+}
+
+// submitter resubmits transactions to an algod node
+type submitter struct {
+	client *algod.Client
+	// indexerClient looks up the senders of keyregs, nil if there is no indexer
+	indexerClient *indexer.Client
+	backoff       *backoffPolicy
+	screen        *addressScreen
+	limits        *amountLimits
+	// out receives the outcome of every transaction
+	out io.Writer
+	// onOutcome is called with the outcome of every transaction too, if set
+	onOutcome func(filename string, txID string, outcome string)
+}
+
+// newSubmitter returns a submitter configured by the flags, writing the outcomes to out
+func newSubmitter(out io.Writer) (*submitter, error) {
+	if algodAddress == "" {
+		return nil, fmt.Errorf("please supply the address of the algod node to submit to using --algod-addr")
+	}
+	client, err := algod.MakeClientWithHeaders(algodAddress, algodToken, clientHeaders(algodTransport))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating the algod client: %v", err)
+	}
+	s := &submitter{client: client, out: out}
+	// stale keyregs are only reported when there is an indexer to look up their senders with
+	if indexerAddress != "" {
+		s.indexerClient, err = initIndexerClient(indexerAddress, indexerToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.backoff, err = initBackoff()
+	if err != nil {
+		return nil, err
+	}
+	s.screen, err = initAddressScreen(allowlistFile, denylistFile)
+	if err != nil {
+		return nil, err
+	}
+	s.limits, err = parseAmountLimits(maxAmounts)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// submitFile submits the transactions of a file, every group as a whole and every individual transaction on its own,
+// in the order of the file. It fails if any of them was not submitted.
+func (s *submitter) submitFile(filename string) error {
+	batch, err := readBatch(filename, nil)
+	if err != nil {
+		return err
+	}
+	defer zeroizeRecords(allRecords(batch.groups, batch.individual))
+	logger := log.WithField("file", filename)
+	if len(batch.corrupt) != 0 {
+		logEntryMessage(logger, log.WarnLevel, msgCorruptSkipped, len(batch.corrupt))
+	}
+	groups, individual, placeholders := splitPlaceholders(batch.groups, batch.individual)
+	if len(placeholders) != 0 {
+		logMessage(log.WarnLevel, msgSkippedUnsigned, len(placeholders), filename)
+	}
+	var txs []txRecord
+	for _, unit := range unitsInOrder(groups, individual, nil) {
+		for _, tx := range unit {
+			if tx.txIDOnly {
+				return fmt.Errorf("%s lists txids only, it has no signed transactions to submit", filename)
+			}
+		}
+		txs = append(txs, unit...)
+	}
+	// the node would accept one transaction of every cluster of colliding leases and reject the others, so which one
+	// is confirmed is left to the user
+	if collisions := warnLeaseCollisions(filename, txs); len(collisions) != 0 && !submitForce {
+		return fmt.Errorf("%d transactions of %s hold colliding leases, only one of each cluster can be confirmed, "+
+			"use --force to submit them anyway", len(collisions), filename)
+	}
+	if s.indexerClient != nil {
+		err = reportUnsentKeyregs(filename, txs, s.indexerClient, s.backoff)
+		if err != nil {
+			return err
+		}
+	}
+	txs, denied := s.screen.screen(filename, txs)
+	txs, held := s.limits.holdLarge(filename, txs)
+
+	accepted, rejected := 0, 0
+	for _, unit := range submissionUnits(txs) {
+		outcome := "sent"
+		err := s.submitUnit(unit)
+		switch {
+		case err == nil:
+			accepted += len(unit)
+		case isAlreadyCommitted(err):
+			outcome = "already committed"
+			accepted += len(unit)
+		default:
+			outcome = "rejected: " + poolError(err)
+			rejected += len(unit)
+		}
+		for _, tx := range unit {
+			fmt.Fprintf(s.out, "%s %s: %s\n", filename, tx.txID, outcome)
+			if s.onOutcome != nil {
+				s.onOutcome(filename, tx.txID, outcome)
+			}
+		}
+	}
+	logEntryMessage(logger, log.InfoLevel, msgSubmitted, accepted, rejected, len(placeholders)+len(denied)+len(held))
+	if rejected != 0 {
+		return fmt.Errorf("the node rejected %d transactions of %s", rejected, filename)
+	}
+	if !removeSubmitted {
+		return nil
+	}
+	if len(batch.corrupt)+len(placeholders)+len(denied)+len(held) != 0 {
+		logEntryMessage(logger, log.WarnLevel, msgSubmitNotRemoved, filename)
+		return nil
+	}
+	err = removeFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed removing %s: %v", filename, err)
+	}
+	logEntryMessage(logger, log.InfoLevel, msgSubmitRemoved, filename)
+	return nil
+}
+
+// submissionUnits splits transactions in file order to the units submitted together: whole groups and individual
+// transactions
+func submissionUnits(txs []txRecord) [][]txRecord {
+	groups := map[types.Digest][]txRecord{}
+	var individual []txRecord
+	for _, tx := range txs {
+		if gid := tx.stx.Txn.Group; gid != (types.Digest{}) {
+			groups[gid] = append(groups[gid], tx)
+		} else {
+			individual = append(individual, tx)
+		}
+	}
+	return unitsInOrder(groups, individual, nil)
+}
+
+// submitUnit sends the transactions of a unit to the node in a single request, the concatenation of their encodings
+func (s *submitter) submitUnit(unit []txRecord) error {
+	var raw []byte
+	for _, tx := range unit {
+		encoded, err := encodeTxRecord(tx)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, encoded...)
+	}
+	defer zeroize(raw)
+	what := "tx " + unit[0].txID
+	if len(unit) > 1 {
+		what = fmt.Sprintf("the group of %d transactions of tx %s", len(unit), unit[0].txID)
+	}
+	requestID, headers := nextRequest()
+	log.Debugf("submitting %s, request %s", what, requestID)
+	err := s.backoff.retry(fmt.Sprintf("submitting %s, request %s,", what, requestID), func() error {
+		_, err := s.client.SendRawTransaction(raw).Do(context.Background(), headers...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("algod request %s: %v", requestID, err)
+	}
+	return nil
+}
+
+// isAlreadyCommitted returns true if the node rejected a transaction because it is already in the ledger, e.g. when
+// an earlier attempt timed out after the node accepted it
+func isAlreadyCommitted(err error) bool {
+	return strings.Contains(err.Error(), "already in ledger")
+}
+
+// poolError returns the message of the error the node answered a submission with
+func poolError(err error) string {
+	text := err.Error()
+	if start := strings.Index(text, "{"); start != -1 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal([]byte(text[start:]), &body) == nil && body.Message != "" {
+			return body.Message
+		}
+	}
+	return text
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testSignedPayment returns a signed payment, its fee making it unique, and its encoding
+func testSignedPayment(fee uint64, group types.Digest) (types.SignedTxn, []byte) {
+	stx := types.SignedTxn{Sig: types.Signature{1}, Txn: types.Transaction{Type: types.PaymentTx,
+		Header: types.Header{FirstValid: 1, LastValid: 1000, Fee: types.MicroAlgos(fee), Group: group}}}
+	return stx, msgpack.Encode(stx)
+}
+
+func TestSubmitFile(t *testing.T) {
+	group := types.Digest{7}
+	first, firstRaw := testSignedPayment(1000, group)
+	_, secondRaw := testSignedPayment(1001, group)
+	dead, deadRaw := testSignedPayment(1002, types.Digest{})
+	committed, committedRaw := testSignedPayment(1003, types.Digest{})
+	filename := filepath.Join(testDir(t), "batch.tx.unsent")
+	content := bytes.Join([][]byte{firstRaw, secondRaw, deadRaw, committedRaw}, nil)
+	if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var submitted [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		submitted = append(submitted, body)
+		mu.Unlock()
+		switch {
+		case bytes.Equal(body, deadRaw):
+			http.Error(w, `{"message":"TransactionPool.Remember: txn dead: round 5000 outside of 1--1000"}`,
+				http.StatusBadRequest)
+		case bytes.Equal(body, committedRaw):
+			http.Error(w, `{"message":"transaction already in ledger"}`, http.StatusBadRequest)
+		default:
+			fmt.Fprintf(w, `{"txId":"%s"}`, crypto.GetTxID(first.Txn))
+		}
+	}))
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	s := &submitter{client: client, backoff: newTestBackoff(), out: &out}
+
+	err = s.submitFile(filename)
+	if err == nil || !strings.Contains(err.Error(), "rejected 1 transactions") {
+		t.Errorf("expected the dead transaction to fail the file, got %v", err)
+	}
+	if len(submitted) != 3 || !bytes.Equal(submitted[0], append(append([]byte{}, firstRaw...), secondRaw...)) {
+		t.Errorf("expected the group in a single submission and the others on their own, got %d submissions",
+			len(submitted))
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"sent",
+		"sent",
+		"rejected: TransactionPool.Remember: txn dead: round 5000 outside of 1--1000",
+		"already committed",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected a line per transaction, got %q", out.String())
+	}
+	for i, outcome := range expected {
+		if !strings.HasSuffix(lines[i], ": "+outcome) {
+			t.Errorf("expected line %d to end with %q, got %q", i, outcome, lines[i])
+		}
+	}
+	if !strings.Contains(lines[2], crypto.GetTxID(dead.Txn)) ||
+		!strings.Contains(lines[3], crypto.GetTxID(committed.Txn)) {
+		t.Errorf("expected the txids of the transactions, got %q", out.String())
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("expected the file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var outputFormat string
+
+func init() {
+	rootCmd.Flags().StringVar(&outputFormat, "format", summaryText,
+		"format of the summary printed to stdout: text (a single STATUS line at the end of the run), json "+
+			"(the counts and unsent txids of every file at the end of the run) or csv (a row per transaction as it "+
+			"is checked, with the STATUS line on stderr)")
+}
+
+const (
+	summaryText = "text"
+	summaryJSON = "json"
+	summaryCSV  = "csv"
+)
+
+const (
+	statusOK     = "OK"
+	statusUnsent = "UNSENT"
+	statusError  = "ERROR"
+)
+
+// validateOutputFormat makes sure --format is known
+func validateOutputFormat() error {
+	if outputFormat != summaryText && outputFormat != summaryJSON && outputFormat != summaryCSV {
+		return fmt.Errorf("unknown --format %q, expected text, json or csv", outputFormat)
+	}
+	return nil
+}
+
+// runSummary is the summary of a check run, printed to stdout whatever the log level so cron and email based
+// monitoring can match a single line, and scripts can parse the JSON format
+type runSummary struct {
+	start  time.Time
+	files  int
+	unsent int
+	// expired are the unsent transactions past their last valid round
+	expired int
+	// total is the number of transactions of the checked files
+	total  int
+	failed bool
+	// err is the error the run failed with
+	err string
+	// exitCode is the exit code of the run, at least 1 if it failed
+	exitCode int
+	// fileSummaries are the summaries of the files in the order they were checked, with --format json
+	fileSummaries []fileSummary
+	// rollups aggregates the unsent transactions by asset and by application, nil with --top-rollups 0
+	rollups *unsentRollups
+}
+
+// fileSummary is the outcome of checking a file in the JSON summary
+type fileSummary struct {
+	File string `json:"file"`
+	// Resumed is set for the files skipped by --resume, which have no counts
+	Resumed   bool `json:"resumed,omitempty"`
+	Total     int  `json:"total"`
+	Confirmed int  `json:"confirmed"`
+	Unsent    int  `json:"unsent"`
+	Expired   int  `json:"expired"`
+	Skipped   int  `json:"skipped"`
+	// UnsentTxIDs and ExpiredTxIDs are in their order in the file
+	UnsentTxIDs  []string `json:"unsent_txids"`
+	ExpiredTxIDs []string `json:"expired_txids"`
+}
+
+// jsonSummary is the JSON format of the summary
+type jsonSummary struct {
+	Status  string        `json:"status"`
+	Error   string        `json:"error,omitempty"`
+	Files   []fileSummary `json:"files"`
+	Unsent  int           `json:"unsent"`
+	Expired int           `json:"expired"`
+	// Assets and Apps aggregate the unsent transactions, the ones with the most unsent transactions first
+	Assets   []assetRollup `json:"assets,omitempty"`
+	Apps     []appRollup   `json:"apps,omitempty"`
+	Duration float64       `json:"duration_seconds,omitempty"`
+}
+
+// add counts a checked input file
+func (s *runSummary) add(filename string, result fileResult) {
+	s.files++
+	s.unsent += result.unsent
+	s.expired += result.expired
+	s.total += result.total
+	if result.summary != nil {
+		result.summary.File = filename
+		s.fileSummaries = append(s.fileSummaries, *result.summary)
+	}
+}
+
+// addResumed counts an input file skipped by --resume
+func (s *runSummary) addResumed(filename string) {
+	s.files++
+	if outputFormat == summaryJSON {
+		s.fileSummaries = append(s.fileSummaries, fileSummary{File: filename, Resumed: true})
+	}
+}
+
+// transition counts an unsent transaction of filename that --watch found confirmed or expired
+func (s *runSummary) transition(filename string, txid string, condition txCondition) {
+	s.unsent--
+	if condition == conditionExpired {
+		s.expired++
+	}
+	for i := range s.fileSummaries {
+		file := &s.fileSummaries[i]
+		if file.File != filename || file.Resumed {
+			continue
+		}
+		file.Unsent--
+		for j, unsentTxID := range file.UnsentTxIDs {
+			if unsentTxID == txid {
+				file.UnsentTxIDs = append(file.UnsentTxIDs[:j], file.UnsentTxIDs[j+1:]...)
+				break
+			}
+		}
+		if condition == conditionExpired {
+			file.Expired++
+			file.ExpiredTxIDs = append(file.ExpiredTxIDs, txid)
+		} else {
+			file.Confirmed++
+		}
+		return
+	}
+}
+
+// status is the overall status of the run
+func (s *runSummary) status() string {
+	switch {
+	case s.failed:
+		return statusError
+	case s.unsent+s.expired != 0:
+		return statusUnsent
+	}
+	return statusOK
+}
+
+// print writes the summary in --format, without the duration in deterministic mode
+// the CSV rows were written to w as the files were checked, so the STATUS line goes to stderr for the monitoring
+func (s *runSummary) print(w io.Writer) {
+	if outputFormat == summaryCSV {
+		w = os.Stderr
+	}
+	if outputFormat == summaryJSON {
+		summary := jsonSummary{
+			Status:  s.status(),
+			Error:   s.err,
+			Files:   s.fileSummaries,
+			Unsent:  s.unsent,
+			Expired: s.expired,
+		}
+		if summary.Files == nil {
+			summary.Files = []fileSummary{}
+		}
+		if s.rollups != nil {
+			summary.Assets, summary.Apps = s.rollups.sortedAssets(), s.rollups.sortedApps()
+		}
+		if !deterministic {
+			summary.Duration = time.Since(s.start).Seconds()
+		}
+		encoded, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			fmt.Fprintf(w, "{\"status\": %q}\n", statusError)
+			return
+		}
+		fmt.Fprintln(w, string(encoded))
+		return
+	}
+	line := fmt.Sprintf("STATUS=%s files=%d unsent=%d expired=%d", s.status(), s.files, s.unsent, s.expired)
+	if !deterministic {
+		line += fmt.Sprintf(" duration=%.3fs", time.Since(s.start).Seconds())
+	}
+	fmt.Fprintln(w, line)
+}
+
+// appendTxIDs appends the txids of transactions to txids
+func appendTxIDs(txids []string, txs []txRecord) []string {
+	for _, tx := range txs {
+		txids = append(txids, tx.txID)
+	}
+	return txids
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunSummaryStatus(t *testing.T) {
+	deterministic = true
+	defer func() { deterministic = false }()
+	tests := []struct {
+		summary  runSummary
+		expected string
+	}{
+		{runSummary{files: 2}, "STATUS=OK files=2 unsent=0 expired=0\n"},
+		{runSummary{files: 1, unsent: 3}, "STATUS=UNSENT files=1 unsent=3 expired=0\n"},
+		{runSummary{files: 1, unsent: 3, failed: true}, "STATUS=ERROR files=1 unsent=3 expired=0\n"},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		test.summary.print(&out)
+		if out.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, out.String())
+		}
+	}
+}
+
+func TestRunSummaryAddsFiles(t *testing.T) {
+	s := &runSummary{}
+	s.add("a.tx", fileResult{unsent: 2})
+	s.add("b.tx", fileResult{unsent: 1})
+	if s.files != 2 || s.unsent != 3 {
+		t.Errorf("expected 2 files with 3 unsent transactions, got %d and %d", s.files, s.unsent)
+	}
+}
+
+func TestRunSummaryJSON(t *testing.T) {
+	defer func(format string) { outputFormat = format }(outputFormat)
+	outputFormat = summaryJSON
+	deterministic = true
+	defer func() { deterministic = false }()
+	s := &runSummary{}
+	s.addResumed("a.tx")
+	s.add("b.tx", fileResult{unsent: 1, summary: &fileSummary{Total: 2, Confirmed: 1, Unsent: 1,
+		UnsentTxIDs: []string{testTxID(1)}, ExpiredTxIDs: []string{}}})
+	var out strings.Builder
+	s.print(&out)
+	var summary jsonSummary
+	if err := json.Unmarshal([]byte(out.String()), &summary); err != nil {
+		t.Fatalf("expected a JSON summary, got %v: %s", err, out.String())
+	}
+	if summary.Status != statusUnsent || len(summary.Files) != 2 || !summary.Files[0].Resumed ||
+		summary.Files[1].File != "b.tx" || summary.Files[1].UnsentTxIDs[0] != testTxID(1) || summary.Duration != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestRunSummaryTransition(t *testing.T) {
+	s := &runSummary{unsent: 2, fileSummaries: []fileSummary{
+		{File: "a.tx", Resumed: true},
+		{File: "a.tx", Unsent: 2, UnsentTxIDs: []string{testTxID(1), testTxID(2)}, ExpiredTxIDs: []string{}},
+	}}
+	s.transition("a.tx", testTxID(1), conditionConfirmed)
+	s.transition("a.tx", testTxID(2), conditionExpired)
+	file := s.fileSummaries[1]
+	if s.unsent != 0 || s.expired != 1 || file.Unsent != 0 || file.Confirmed != 1 || file.Expired != 1 ||
+		len(file.UnsentTxIDs) != 0 || file.ExpiredTxIDs[0] != testTxID(2) {
+		t.Errorf("unexpected summary after the transitions %+v", s)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	submittedAfterStr  string
+	submittedBeforeStr string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&submittedAfterStr, "submitted-after", "",
+		"only consider transactions whose first valid round is at or after this time "+
+			"(RFC3339, YYYY-MM-DD or a duration ago such as 7d or 36h)")
+	rootCmd.Flags().StringVar(&submittedBeforeStr, "submitted-before", "",
+		"only consider transactions whose first valid round is before this time "+
+			"(RFC3339, YYYY-MM-DD or a duration ago such as 7d or 36h)")
+}
+
+// parseTimeFlag parses an absolute time (RFC3339 or YYYY-MM-DD, UTC) or a duration before now (e.g. 7d or 36h)
+func parseTimeFlag(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days >= 0 {
+			return time.Now().Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// blockClock maps rounds to the timestamps of their blocks, caching indexer lookups
+type blockClock struct {
+	indexerClient *indexer.Client
+	backoff       *backoffPolicy
+	cache         map[uint64]time.Time
+}
+
+func newBlockClock(indexerClient *indexer.Client, backoff *backoffPolicy) *blockClock {
+	return &blockClock{indexerClient: indexerClient, backoff: backoff, cache: map[uint64]time.Time{}}
+}
+
+// timeOf returns the timestamp of the block of round
+func (c *blockClock) timeOf(round uint64) (time.Time, error) {
+	if t, ok := c.cache[round]; ok {
+		return t, nil
+	}
+	requestID, headers := nextRequest()
+	var block models.Block
+	err := c.backoff.retry(fmt.Sprintf("getting block %d, request %s,", round, requestID), func() error {
+		if err := budget.spend(requestBlock); err != nil {
+			return err
+		}
+		var err error
+		block, err = c.indexerClient.LookupBlock(round).Do(context.Background(), headers...)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed getting block %d, request %s: %v", round, requestID, err)
+	}
+	t := time.Unix(int64(block.Timestamp), 0).UTC()
+	c.cache[round] = t
+	return t, nil
+}
+
+// firstRoundAt returns the first round whose block timestamp is at or after t, using a binary search over the
+// blocks up to latest. It returns latest+1 if t is after the latest block.
+func (c *blockClock) firstRoundAt(t time.Time, latest uint64) (uint64, error) {
+	lo, hi := uint64(1), latest+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		midTime, err := c.timeOf(mid)
+		if err != nil {
+			return 0, err
+		}
+		if midTime.Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// roundWindow is a range of first valid rounds to consider, zero bounds are open
+type roundWindow struct {
+	// first is the smallest first valid round to consider
+	first uint64
+	// before is the first valid round from which transactions are not considered anymore
+	before uint64
+}
+
+// contains returns true if the transaction's first valid round is within the window
+// transactions read from txid lists have no known first valid round and are always within the window
+func (w *roundWindow) contains(rec txRecord) bool {
+	if w == nil || rec.txIDOnly {
+		return true
+	}
+	fv := uint64(rec.stx.Txn.FirstValid)
+	return fv >= w.first && (w.before == 0 || fv < w.before)
+}
+
+// initRoundWindow converts --submitted-after and --submitted-before to a window of first valid rounds
+// it returns nil if neither was set
+func initRoundWindow(indexerClient *indexer.Client, clock *blockClock, backoff *backoffPolicy) (*roundWindow, error) {
+	if submittedAfterStr == "" && submittedBeforeStr == "" {
+		return nil, nil
+	}
+	requestID, headers := nextRequest()
+	var health models.HealthCheckResponse
+	err := backoff.retry(fmt.Sprintf("checking the indexer health, request %s,", requestID), func() error {
+		if err := budget.spend(requestHealth); err != nil {
+			return err
+		}
+		var err error
+		health, err = indexerClient.HealthCheck().Do(context.Background(), headers...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed getting the latest round from the indexer, request %s: %v", requestID, err)
+	}
+	window := &roundWindow{}
+	if submittedAfterStr != "" {
+		after, err := parseTimeFlag(submittedAfterStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --submitted-after: %v", err)
+		}
+		window.first, err = clock.firstRoundAt(after, health.Round)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if submittedBeforeStr != "" {
+		before, err := parseTimeFlag(submittedBeforeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --submitted-before: %v", err)
+		}
+		window.before, err = clock.firstRoundAt(before, health.Round)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if window.first != 0 {
+		logMessage(log.InfoLevel, msgWindowFirst, window.first)
+	}
+	if window.before != 0 {
+		logMessage(log.InfoLevel, msgWindowBefore, window.before)
+	}
+	return window, nil
+}
+
+// filterRoundWindow returns only the groups and individual transactions within the window, and the ones outside it
+// a group is within the window if its first transaction is
+func filterRoundWindow(window *roundWindow, groups map[types.Digest][]txRecord, individualTxs []txRecord) (
+	map[types.Digest][]txRecord, []txRecord, []txRecord) {
+	if window == nil {
+		return groups, individualTxs, nil
+	}
+	inGroups := map[types.Digest][]txRecord{}
+	var inTxs, outside []txRecord
+	for gid, txs := range groups {
+		if window.contains(txs[0]) {
+			inGroups[gid] = txs
+		} else {
+			outside = append(outside, txs...)
+		}
+	}
+	for _, tx := range individualTxs {
+		if window.contains(tx) {
+			inTxs = append(inTxs, tx)
+		} else {
+			outside = append(outside, tx)
+		}
+	}
+	return inGroups, inTxs, outside
+}
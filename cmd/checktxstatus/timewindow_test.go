@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseTimeFlag(t *testing.T) {
+	if parsed, err := parseTimeFlag("2022-03-04"); err != nil || !parsed.Equal(time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v, %v", parsed, err)
+	}
+	if parsed, err := parseTimeFlag("2022-03-04T05:06:07Z"); err != nil || parsed.Hour() != 5 {
+		t.Errorf("unexpected time %v, %v", parsed, err)
+	}
+	if parsed, err := parseTimeFlag("7d"); err != nil || time.Since(parsed) < 7*24*time.Hour-time.Minute {
+		t.Errorf("expected 7 days ago, got %v, %v", parsed, err)
+	}
+	for _, value := range []string{"yesterday", "-3d", "-1h"} {
+		if _, err := parseTimeFlag(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestBlockClockFirstRoundAt(t *testing.T) {
+	requests := 0
+	// the block of every round is 10 seconds after the previous one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		round, err := strconv.Atoi(path.Base(r.URL.Path))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"round":%d,"timestamp":%d}`, round, round*10)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newBlockClock(client, newTestBackoff())
+	for _, test := range []struct{ at, round uint64 }{{0, 1}, {95, 10}, {100, 10}, {10001, 1001}} {
+		round, err := clock.firstRoundAt(time.Unix(int64(test.at), 0), 1000)
+		if err != nil || round != test.round {
+			t.Errorf("expected round %d at %d, got %d, %v", test.round, test.at, round, err)
+		}
+	}
+	before := requests
+	if _, err := clock.firstRoundAt(time.Unix(100, 0), 1000); err != nil || requests != before {
+		t.Errorf("expected the blocks to be cached, got %d more requests, %v", requests-before, err)
+	}
+}
+
+func TestFilterRoundWindow(t *testing.T) {
+	tx := func(index int, firstValid uint64) txRecord {
+		rec := txRecord{index: index}
+		rec.stx.Txn.FirstValid = types.Round(firstValid)
+		return rec
+	}
+	window := &roundWindow{first: 10, before: 20}
+	groups := map[types.Digest][]txRecord{{1}: {tx(0, 10), tx(1, 5)}, {2}: {tx(2, 20), tx(3, 15)}}
+	inGroups, inTxs, outside := filterRoundWindow(window, groups, []txRecord{tx(4, 9), tx(5, 19)})
+	if len(inGroups) != 1 || len(inGroups[types.Digest{1}]) != 2 {
+		t.Errorf("expected only the group whose first transaction is in the window, got %v", inGroups)
+	}
+	if len(inTxs) != 1 || inTxs[0].index != 5 || len(outside) != 3 {
+		t.Errorf("unexpected transactions %v, outside %v", inTxs, outside)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits the rate of the requests to a backend, a nil bucket does not limit them
+type tokenBucket struct {
+	mu sync.Mutex
+	// rate is the number of requests per second, 0 for no limit, and burst the number of tokens the bucket holds
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+	// pausedUntil is when the backend allows requests again after asking to retry after a while
+	pausedUntil time.Time
+}
+
+// newTokenBucket returns a full bucket of rate requests per second, nil for rate 0
+// burst 0 holds the rate rounded up, so a whole second of requests may be sent at once
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate == 0 {
+		return nil
+	}
+	return newPausableBucket(rate, burst)
+}
+
+// newPausableBucket returns a full bucket of rate requests per second, which may be paused even with rate 0
+func newPausableBucket(rate float64, burst int) *tokenBucket {
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until the bucket has a token for a request and takes it
+func (b *tokenBucket) wait() {
+	_ = b.waitContext(context.Background())
+}
+
+// waitContext blocks until the bucket has a token for a request and takes it, or until ctx is done
+// tokens are reserved in order, so concurrent callers wait in turn rather than racing for the next token
+func (b *tokenBucket) waitContext(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	var delay time.Duration
+	if b.rate != 0 {
+		b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		b.tokens--
+		if b.tokens < 0 {
+			delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+		}
+	}
+	if paused := b.pausedUntil.Sub(now); paused > delay {
+		delay = paused
+	}
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// the token is left to the next caller
+		b.mu.Lock()
+		if b.rate != 0 {
+			b.tokens++
+		}
+		b.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pause holds back the requests until the end of d
+func (b *tokenBucket) pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
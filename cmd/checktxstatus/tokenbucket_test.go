@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimitsRate(t *testing.T) {
+	if newTokenBucket(0, 0) != nil {
+		t.Error("expected no bucket without a rate")
+	}
+	bucket := newTokenBucket(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		bucket.wait()
+	}
+	// the burst of 2 is sent at once, the 2 other requests wait 10ms each
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected 4 requests to take about 20ms, took %v", elapsed)
+	}
+	if bucket := newTokenBucket(2.5, 0); bucket.burst != 3 {
+		t.Errorf("expected the burst to default to the rate rounded up, got %d", bucket.burst)
+	}
+}
+
+func TestTokenBucketPause(t *testing.T) {
+	bucket := newPausableBucket(0, 0)
+	bucket.pause(30 * time.Millisecond)
+	start := time.Now()
+	bucket.wait()
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("expected the request to wait for the pause, it waited %v", elapsed)
+	}
+	bucket.pause(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.waitContext(ctx); err == nil {
+		t.Error("expected the wait to end with its context")
+	}
+}
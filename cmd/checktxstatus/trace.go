@@ -0,0 +1,57 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// backends answering lookups, as shown in the debug traces of the lookups
+const (
+	backendIndexer = "indexer"
+	backendHedge   = "hedge-indexer"
+	backendAlgod   = "algod"
+)
+
+// results of looking up a transaction in the checkpoint, the report of --since or the --cache-path cache, before
+// asking a backend
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheSince  = "since"
+	cacheStored = "cached"
+)
+
+// lookupTrace describes how the status of a transaction was looked up
+type lookupTrace struct {
+	// start is the time the first request was sent, zero if no request was sent
+	start time.Time
+	// attempts is the number of requests sent, the first one and its retries
+	attempts int
+	// backend answered the last request, empty if no request was sent
+	backend string
+	// cache is cacheHit or cacheMiss when a --checkpoint is set, cacheSince for a transaction confirmed in the report
+	// of --since, cacheStored for a transaction found in the --cache-path cache, empty otherwise
+	cache string
+}
+
+// traceLookup logs the latency, retries, backend and cache outcome of the lookup of a transaction in debug mode, so
+// slow or flaky subsets of a batch can be found in the logs
+func traceLookup(txid string, trace lookupTrace, found bool, err error) {
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		return
+	}
+	fields := log.Fields{"txid": txid, "found": found}
+	if trace.cache != "" {
+		fields["cache"] = trace.cache
+	}
+	if trace.attempts != 0 {
+		fields["backend"] = trace.backend
+		fields["latency"] = time.Since(trace.start).Round(time.Microsecond).String()
+		fields["retries"] = trace.attempts - 1
+	}
+	entry := log.WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Debug("lookup trace")
+}
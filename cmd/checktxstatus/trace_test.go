@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceLookup(t *testing.T) {
+	defer func(level log.Level, out io.Writer) {
+		log.SetLevel(level)
+		log.SetOutput(out)
+	}(log.GetLevel(), log.StandardLogger().Out)
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetLevel(log.InfoLevel)
+
+	trace := lookupTrace{start: time.Now(), attempts: 3, backend: backendIndexer, cache: cacheMiss}
+	traceLookup(testTxID(1), trace, false, fmt.Errorf("HTTP 503: unavailable"))
+	if out.Len() != 0 {
+		t.Errorf("expected no trace out of debug mode, got %q", out.String())
+	}
+	log.SetLevel(log.DebugLevel)
+	traceLookup(testTxID(1), trace, false, fmt.Errorf("HTTP 503: unavailable"))
+	for _, field := range []string{"backend=indexer", "cache=miss", "retries=2", "found=false", "latency="} {
+		if !strings.Contains(out.String(), field) {
+			t.Errorf("expected %s in the trace, got %q", field, out.String())
+		}
+	}
+	out.Reset()
+	traceLookup(testTxID(2), lookupTrace{cache: cacheHit}, true, nil)
+	if strings.Contains(out.String(), "backend=") || !strings.Contains(out.String(), "cache=hit") {
+		t.Errorf("expected a checkpoint hit without a backend, got %q", out.String())
+	}
+}
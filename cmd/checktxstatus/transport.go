@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/client/v2/common"
+	"net/http"
+	"sync"
+)
+
+// clientTransportHeader names the transport of the requests of the SDK clients, it is removed before they are sent
+const clientTransportHeader = "X-Checktxstatus-Transport"
+
+// the transports of the requests to the indexers and to the algod node
+const (
+	indexerTransport = "indexer"
+	algodTransport   = "algod"
+)
+
+// baseTransport sends the requests of every other client, such as the webhooks, untouched by the rate limits and the
+// injected faults
+var baseTransport = http.DefaultTransport
+
+// clientTransports are the transports of the SDK clients by name, limiting the rate of their requests and injecting
+// the faults of --fault-inject
+var clientTransports = map[string]http.RoundTripper{
+	indexerTransport: &faultInjectingTransport{next: &rateLimitedTransport{next: baseTransport}},
+	algodTransport:   &faultInjectingTransport{next: &rateLimitedTransport{next: baseTransport}},
+}
+
+// installClientRouter routes the requests of the SDK clients to their transports
+var installClientRouter sync.Once
+
+// clientHeaders are the headers of the SDK clients sending their requests through the named transport
+// the SDK sends every request with http.DefaultTransport and takes no other, so it is replaced once by a router
+// sending the tagged requests to their transports and all the others to baseTransport
+func clientHeaders(transport string) []*common.Header {
+	installClientRouter.Do(func() {
+		http.DefaultTransport = clientRouter{}
+	})
+	return append(runHeaders(), &common.Header{Key: clientTransportHeader, Value: transport})
+}
+
+// clientRouter sends the requests tagged with clientTransportHeader through their transports, and the others
+// through baseTransport
+type clientRouter struct{}
+
+func (clientRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, ok := clientTransports[req.Header.Get(clientTransportHeader)]
+	if !ok {
+		return baseTransport.RoundTrip(req)
+	}
+	// a transport must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Del(clientTransportHeader)
+	return transport.RoundTrip(req)
+}
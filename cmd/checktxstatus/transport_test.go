@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"net/http"
+	"testing"
+)
+
+func TestFaultsAreOnlyInjectedInTheSDKClients(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	faultInjectSpec = "lookup-error=1"
+	defer func() {
+		faultInjectSpec, faults = "", nil
+	}()
+	if err := initFaultInjection(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := indexer.MakeClientWithHeaders(server.URL, "", clientHeaders(indexerTransport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.HealthCheck().Do(context.Background()); err == nil {
+		t.Errorf("expected the lookup of the indexer client to fail")
+	}
+	// the other clients send their requests with the default transport too
+	resp, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the request of another client not to fail, got %s", resp.Status)
+	}
+	if requests := server.Requests(); len(requests) != 1 {
+		t.Errorf("expected only the request of the other client to reach the server, got %v", requests)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var txidStream string
+
+// statusTxIDs are the txids given as arguments to the status subcommand, looked up before the ones of --txids
+var statusTxIDs []string
+
+func init() {
+	rootCmd.Flags().StringVar(&txidStream, "txids", "",
+		"look up the txids read line by line from this file, - for stdin, printing the status of every one to "+
+			"stdout as soon as it is known, so the check can sit in the middle of a pipeline; no other input can "+
+			"be given, and the summary is printed to stderr")
+}
+
+// streamedStatus is the status of a streamed txid, a line of the output of --txids with --format json
+type streamedStatus struct {
+	statusResponse
+	// Error is why the txid could not be looked up, the status is empty then
+	Error string `json:"error,omitempty"`
+}
+
+// line formats the status as a line of the output of --txids, e.g. "<txid> confirmed 1200" in text
+func (s streamedStatus) line() string {
+	if outputFormat == summaryJSON {
+		// a struct of strings and numbers always encodes
+		encoded, _ := json.Marshal(s)
+		return string(encoded)
+	}
+	switch {
+	case s.Error != "":
+		return fmt.Sprintf("%s error %s", s.TxID, s.Error)
+	case s.ConfirmedRound != 0:
+		return fmt.Sprintf("%s %s %d", s.TxID, s.Status, s.ConfirmedRound)
+	}
+	return fmt.Sprintf("%s %s", s.TxID, s.Status)
+}
+
+// validateTxIDStream makes sure --txids is the only input, with a summary format it can print
+func validateTxIDStream(args []string) error {
+	if txidStream == "" {
+		return nil
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("--txids is the only input of the run, %s can not be checked too", args[0])
+	}
+	if outputFormat == summaryCSV {
+		return fmt.Errorf("--txids prints the statuses as text or JSON lines, not --format csv")
+	}
+	return nil
+}
+
+// streamTxIDs looks up the txids of the status subcommand and of --txids as they are read, printing their statuses to
+// stdout as they are known, or in the order they were read in deterministic mode; the txids not found by the indexer
+// are looked up in the pending pool of the --algod-addr node, if set
+// invalid txids and failed lookups are printed as errors and do not stop the stream, but fail the run at the end
+func streamTxIDs(lookup *txLookup, summary *runSummary) error {
+	in := io.Reader(strings.NewReader(strings.Join(statusTxIDs, "\n") + "\n"))
+	switch txidStream {
+	case "":
+	case stdioName:
+		in = io.MultiReader(in, os.Stdin)
+	default:
+		file, err := os.Open(txidStream)
+		if err != nil {
+			return fmt.Errorf("error while opening %s: %v", txidStream, err)
+		}
+		defer file.Close()
+		in = io.MultiReader(in, file)
+	}
+	out := newStreamPrinter(os.Stdout)
+	var wg sync.WaitGroup
+	// mu guards failed and the counts of summary, updated by the lookups as they complete
+	var mu sync.Mutex
+	failed := 0
+	scanner := bufio.NewScanner(in)
+	for seq := 0; scanner.Scan(); seq++ {
+		txid := strings.TrimSpace(scanner.Text())
+		if txid == "" {
+			seq--
+			continue
+		}
+		if !txchecker.IsTxID(txid) {
+			mu.Lock()
+			failed++
+			mu.Unlock()
+			out.print(seq, streamedStatus{statusResponse: statusResponse{TxID: txid}, Error: "invalid txid"})
+			continue
+		}
+		lookup.concurrency.acquire()
+		wg.Add(1)
+		go func(seq int, txid string) {
+			defer wg.Done()
+			start := time.Now()
+			status, err := lookupStreamed(lookup, txid)
+			// failed lookups lower the concurrency like slow ones
+			lookup.concurrency.release(time.Since(start), err)
+			mu.Lock()
+			switch {
+			case err != nil:
+				failed++
+			case status.Status == conditionUnsent:
+				summary.unsent++
+			}
+			mu.Unlock()
+			out.print(seq, status)
+		}(seq, txid)
+	}
+	wg.Wait()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error while reading %s: %v", txidStream, err)
+	}
+	if err := out.err(); err != nil {
+		return err
+	}
+	if failed != 0 {
+		return fmt.Errorf("failed to look up %d txids", failed)
+	}
+	return nil
+}
+
+// lookupStreamed looks up the status of a txid, as GET /status/<txid> of serve does, the status of a failed lookup
+// carrying its error
+func lookupStreamed(lookup *txLookup, txid string) (streamedStatus, error) {
+	status := streamedStatus{statusResponse: statusResponse{TxID: txid, Status: conditionUnsent}}
+	rounds := &confirmedRounds{rounds: map[string]uint64{}}
+	l := *lookup
+	l.rounds = rounds
+	sent, err := l.isTxSent(txid)
+	found := []bool{sent}
+	if err == nil {
+		err = l.pending.lookupMissing([]string{txid}, found, l.backoff, l.checkpoint, rounds)
+	}
+	if err != nil {
+		log.Debugf("failed to look up streamed tx %s: %v", txid, err)
+		return streamedStatus{statusResponse: statusResponse{TxID: txid}, Error: err.Error()}, err
+	}
+	if found[0] {
+		status.Status = conditionConfirmed
+		status.ConfirmedRound = rounds.take([]txRecord{{txID: txid}})
+	}
+	return status, nil
+}
+
+// streamPrinter prints the statuses of streamed txids as lines, flushing every one so the next tool of the pipeline
+// gets it at once; in deterministic mode the statuses are held back until the ones of the txids read before them
+// are printed
+type streamPrinter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+	// next is the sequence number of the next status to print in deterministic mode, and held the statuses waiting
+	// for it
+	next     int
+	held     map[int]streamedStatus
+	writeErr error
+}
+
+// newStreamPrinter returns a printer of statuses to w
+func newStreamPrinter(w io.Writer) *streamPrinter {
+	return &streamPrinter{w: bufio.NewWriter(w), held: map[int]streamedStatus{}}
+}
+
+// print prints the status of the txid read at position seq
+func (p *streamPrinter) print(seq int, status streamedStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !deterministic {
+		p.write(status)
+		return
+	}
+	p.held[seq] = status
+	for {
+		status, ok := p.held[p.next]
+		if !ok {
+			return
+		}
+		delete(p.held, p.next)
+		p.next++
+		p.write(status)
+	}
+}
+
+// write writes a status line and flushes it, keeping the first error
+func (p *streamPrinter) write(status streamedStatus) {
+	if p.writeErr != nil {
+		return
+	}
+	_, err := p.w.WriteString(status.line() + "\n")
+	if err == nil {
+		err = p.w.Flush()
+	}
+	if err != nil {
+		p.writeErr = fmt.Errorf("failed to write to stdout: %v", err)
+	}
+}
+
+// err returns the first error writing the statuses
+func (p *streamPrinter) err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.writeErr
+}
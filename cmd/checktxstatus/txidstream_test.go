@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamTxIDsPrintsInTheOrderRead(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(2), 900)
+	dir := testDir(t)
+	input := filepath.Join(dir, "txids")
+	content := testTxID(1) + "\n\n" + testTxID(2) + "\nnot-a-txid\n" + testTxID(3) + "\n"
+	if err := ioutil.WriteFile(input, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "stdout")
+	file, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	defer func(stream string, isDeterministic bool, out *os.File) {
+		txidStream, deterministic, os.Stdout = stream, isDeterministic, out
+	}(txidStream, deterministic, os.Stdout)
+	txidStream, deterministic, os.Stdout = input, true, file
+	lookup := newTestLookup(t, server)
+	lookup.concurrency = newConcurrencyController(4)
+	summary := &runSummary{}
+
+	err = streamTxIDs(lookup, summary)
+	if err == nil || !strings.Contains(err.Error(), "failed to look up 1 txids") {
+		t.Errorf("expected the invalid txid to fail the run, got %v", err)
+	}
+	printed, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := testTxID(1) + " unsent\n" + testTxID(2) + " confirmed 900\nnot-a-txid error invalid txid\n" +
+		testTxID(3) + " unsent\n"
+	if string(printed) != expected {
+		t.Errorf("expected a line per txid in the order read, got %q", printed)
+	}
+	if summary.unsent != 2 {
+		t.Errorf("expected 2 unsent txids in the summary, got %d", summary.unsent)
+	}
+}
+
+func TestValidateTxIDStream(t *testing.T) {
+	defer func(stream string, format string) { txidStream, outputFormat = stream, format }(txidStream, outputFormat)
+	txidStream, outputFormat = stdioName, summaryText
+	if err := validateTxIDStream([]string{"batch.tx"}); err == nil {
+		t.Error("expected --txids with an input file to fail")
+	}
+	outputFormat = summaryCSV
+	if err := validateTxIDStream(nil); err == nil {
+		t.Error("expected --txids with --format csv to fail")
+	}
+}
+
+func TestStreamTxIDsOfTheStatusCommand(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	output := filepath.Join(testDir(t), "stdout")
+	file, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	defer func(txids []string, stream string, isDeterministic bool, out *os.File) {
+		statusTxIDs, txidStream, deterministic, os.Stdout = txids, stream, isDeterministic, out
+	}(statusTxIDs, txidStream, deterministic, os.Stdout)
+	statusTxIDs, txidStream, deterministic, os.Stdout = []string{testTxID(1), testTxID(2)}, "", true, file
+	lookup := newTestLookup(t, server)
+	lookup.concurrency = newConcurrencyController(2)
+
+	if err := streamTxIDs(lookup, &runSummary{}); err != nil {
+		t.Fatal(err)
+	}
+	printed, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := testTxID(1) + " confirmed 900\n" + testTxID(2) + " unsent\n"; string(printed) != expected {
+		t.Errorf("expected the statuses of the txids given as arguments, got %q", printed)
+	}
+}
+
+func TestStreamTxIDsCountsFailuresAndBacksOff(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusBadRequest})
+	defer func(txids []string, adaptive bool, max int, target time.Duration) {
+		statusTxIDs, adaptiveConcurrency, maxConcurrency, targetLatency = txids, adaptive, max, target
+	}(statusTxIDs, adaptiveConcurrency, maxConcurrency, targetLatency)
+	adaptiveConcurrency, maxConcurrency, targetLatency = true, 4, time.Hour
+	statusTxIDs = []string{testTxID(1), testTxID(2), "not-a-txid", testTxID(3), testTxID(4)}
+	lookup := newTestLookup(t, server)
+	lookup.concurrency = newConcurrencyController(2)
+
+	err := streamTxIDs(lookup, &runSummary{})
+	if err == nil || err.Error() != "failed to look up 5 txids" {
+		t.Errorf("expected the invalid txid and the failed lookups to fail the run, got %v", err)
+	}
+	// the lookups are fast, only their failures lower the concurrency
+	if lookup.concurrency.limit != 1 {
+		t.Errorf("expected the failed lookups to halve the concurrency, got %d", lookup.concurrency.limit)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"sort"
+	"strings"
+)
+
+var permissive bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&permissive, "permissive", false,
+		"accept transactions of unknown types or with unknown fields instead of failing")
+}
+
+// stateProofTx is the TxType of state proof transactions
+const stateProofTx = txchecker.StateProofTx
+
+// newTxRecord returns the record of a signed transaction decoded by txchecker
+func newTxRecord(tx txchecker.Transaction) txRecord {
+	return txRecord{stx: tx.SignedTxn, txID: tx.TxID, raw: tx.Raw, unknown: tx.Unknown}
+}
+
+// describeDecodeError returns the error decoding a signed transaction, suggesting --permissive for transactions it
+// accepts
+func describeDecodeError(err error) error {
+	if unmodeled, ok := err.(*txchecker.UnmodeledError); ok {
+		if unmodeled.Err != nil {
+			return fmt.Errorf("%v (use --permissive to accept unknown fields)", unmodeled.Err)
+		}
+		return fmt.Errorf("%v (use --permissive to accept it)", unmodeled)
+	}
+	return err
+}
+
+// encodeTxRecord returns the encoding of the transaction to write to output files, see txchecker.Transaction.Encoding
+// the encodings spilled to keep under --max-memory are read back from the spill file
+func encodeTxRecord(rec txRecord) ([]byte, error) {
+	if rec.spilled.size != 0 {
+		return memory.load(rec.spilled)
+	}
+	return txchecker.Transaction{TxID: rec.txID, Raw: rec.raw}.Encoding(), nil
+}
+
+// txTypesSummary returns a short description of the number of transactions of each type, e.g. "3 pay, 1 stpf"
+func txTypesSummary(records []txRecord) string {
+	counts := map[types.TxType]int{}
+	for _, rec := range records {
+		if rec.txIDOnly {
+			counts["txid-only"]++
+			continue
+		}
+		counts[rec.stx.Txn.Type]++
+	}
+	var txTypes []string
+	for txType := range counts {
+		txTypes = append(txTypes, string(txType))
+	}
+	sort.Strings(txTypes)
+	parts := make([]string, len(txTypes))
+	for i, txType := range txTypes {
+		parts[i] = fmt.Sprintf("%d %s", counts[types.TxType(txType)], txType)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"strings"
+	"testing"
+)
+
+// decodeTestRecord decodes a signed transaction into a record as readTxFile does
+func decodeTestRecord(raw []byte) (txRecord, error) {
+	tx, err := txchecker.DecodeTransaction(raw, permissive)
+	if err != nil {
+		return txRecord{}, describeDecodeError(err)
+	}
+	return newTxRecord(tx), nil
+}
+
+func TestDecodeTxRecordOfModeledType(t *testing.T) {
+	stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{FirstValid: 1}}}
+	raw := msgpack.Encode(stx)
+	rec, err := decodeTestRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.txID != crypto.GetTxID(stx.Txn) || !bytes.Equal(rec.raw, raw) {
+		t.Errorf("expected the txid of the decoded transaction with its encoding, got %+v", rec)
+	}
+}
+
+func TestEncodeTxRecordKeepsOriginalEncoding(t *testing.T) {
+	// a non-canonical encoding, with txn before sig, which re-encoding would reorder
+	txn := msgpack.Encode(types.Transaction{Type: types.PaymentTx, Header: types.Header{FirstValid: 1}})
+	raw := append([]byte{0x82, 0xa3, 't', 'x', 'n'}, txn...)
+	raw = append(append(raw, 0xa3, 's', 'i', 'g', 0xc4, 64), make([]byte, 64)...)
+	raw[len(raw)-1] = 1
+	rec, err := decodeTestRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded, err := encodeTxRecord(rec); err != nil || !bytes.Equal(encoded, raw) {
+		t.Errorf("expected the original encoding %x, got %x", raw, encoded)
+	}
+	if encoded := msgpack.Encode(rec.stx); bytes.Equal(encoded, raw) {
+		t.Errorf("expected re-encoding to differ from the non-canonical encoding")
+	}
+}
+
+func TestDecodeTxRecordKeepsEncodingOfProtocolTypes(t *testing.T) {
+	txn := map[string]interface{}{"type": "stpf", "fv": uint64(1), "sp": []byte{1, 2}}
+	raw := msgpack.Encode(map[string]interface{}{"txn": txn})
+	rec, err := decodeTestRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.stx.Txn.Type != stateProofTx || rec.txID != txchecker.TxIDFromRawTxn(msgpack.Encode(txn)) {
+		t.Errorf("expected the header and txid of the state proof, got %+v", rec)
+	}
+	if encoded, err := encodeTxRecord(rec); err != nil || string(encoded) != string(raw) {
+		t.Error("expected the state proof to be written as it was read")
+	}
+}
+
+func TestDecodeTxRecordUnknownTypeNeedsPermissive(t *testing.T) {
+	defer func() { permissive = false }()
+	raw := msgpack.Encode(map[string]interface{}{"txn": map[string]interface{}{"type": "new", "fv": uint64(1)}})
+	if _, err := decodeTestRecord(raw); err == nil || !strings.Contains(err.Error(), "--permissive") {
+		t.Errorf("expected an unknown transaction type to fail, got %v", err)
+	}
+	permissive = true
+	if _, err := decodeTestRecord(raw); err != nil {
+		t.Errorf("expected an unknown transaction type to be accepted in permissive mode, got %v", err)
+	}
+}
+
+func TestTxTypesSummary(t *testing.T) {
+	records := []txRecord{
+		{stx: types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx}}},
+		{stx: types.SignedTxn{Txn: types.Transaction{Type: stateProofTx}}},
+		{stx: types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx}}},
+	}
+	if summary := txTypesSummary(records); summary != "2 pay, 1 stpf" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
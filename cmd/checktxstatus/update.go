@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releaseKey is the base64 ed25519 public key signing the checksums of the releases, set at build time with
+// -ldflags "-X main.releaseKey=<key>"
+var releaseKey = ""
+
+var (
+	releaseFeed     string
+	updateKey       string
+	updateCheckOnly bool
+	updateForce     bool
+)
+
+const (
+	// releaseChecksumsName is the release asset listing the sha256 checksum of every binary, as written by sha256sum
+	releaseChecksumsName = "checksums.txt"
+	// releaseSignatureName is the release asset holding the base64 ed25519 signature of the checksums and the version
+	// of the release, see releaseSignedPayload
+	releaseSignatureName = "checksums.txt.sig"
+	// maxReleaseAssetSize bounds the size of a downloaded release asset
+	maxReleaseAssetSize = 256 << 20
+)
+
+// updateSharedFlags are the flags of the root command that affect updating
+var updateSharedFlags = []string{"log-level"}
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Replace the running binary with the latest release, after verifying its signed checksum",
+	Example: `  # check whether a newer release exists
+  checktxstatus update --check
+
+  # install the latest release
+  sudo checktxstatus update`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		err := selfUpdate(cmd.OutOrStdout())
+		if err != nil {
+			logError(err)
+			exitCode = 1
+		}
+	},
+}
+
+func init() {
+	updateCmd.Flags().StringVar(&releaseFeed, "release-feed",
+		"https://api.github.com/repos/ori-shem-tov/check-tx-status/releases/latest",
+		"URL of the JSON description of the latest release, in the format of the GitHub releases API")
+	updateCmd.Flags().StringVar(&updateKey, "update-key", "",
+		"base64 ed25519 public key the release checksums must be signed with (default the key built into the binary)")
+	updateCmd.Flags().BoolVar(&updateCheckOnly, "check", false,
+		"only print whether a newer release exists, exiting with 1 if it does")
+	updateCmd.Flags().BoolVar(&updateForce, "force", false,
+		"install the latest release even if the running version can not be compared to it, e.g. for dev builds")
+}
+
+// initUpdateCmd adds the update subcommand
+func initUpdateCmd() {
+	for _, name := range updateSharedFlags {
+		updateCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(updateCmd)
+}
+
+// release is the description of a release in the feed
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file of a release
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// assetURL returns the download URL of the asset named name
+func (r *release) assetURL(name string) (string, error) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no %s", r.TagName, name)
+}
+
+// releaseBinaryName is the name of the release asset built for the running platform
+func releaseBinaryName() string {
+	name := fmt.Sprintf("checktxstatus_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// selfUpdate installs the latest release of the feed in place of the running binary if it is newer
+func selfUpdate(out io.Writer) error {
+	key, err := parseReleaseKey()
+	if err != nil && !updateCheckOnly {
+		return err
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	feed, err := download(client, releaseFeed)
+	if err != nil {
+		return fmt.Errorf("failed reading the release feed: %v", err)
+	}
+	var latest release
+	err = json.Unmarshal(feed, &latest)
+	if err != nil || latest.TagName == "" {
+		return fmt.Errorf("invalid release feed %s: %v", releaseFeed, err)
+	}
+	newer, comparable := versionNewer(latest.TagName, version)
+	if updateCheckOnly {
+		switch {
+		case !comparable:
+			fmt.Fprintf(out, "the latest release is %s, running %s\n", latest.TagName, version)
+		case newer:
+			fmt.Fprintf(out, "%s is available, running %s\n", latest.TagName, version)
+			exitCode = 1
+		default:
+			fmt.Fprintf(out, "%s is up to date\n", version)
+		}
+		return nil
+	}
+	if !comparable && !updateForce {
+		return fmt.Errorf("cannot compare version %s to the latest release %s, use --force to install it",
+			version, latest.TagName)
+	}
+	// a release not newer than the running version is never installed, even with --force, so a compromised feed
+	// can not downgrade to an older release with known flaws
+	if comparable && !newer {
+		fmt.Fprintf(out, "%s is up to date\n", version)
+		return nil
+	}
+
+	checksums, err := downloadAsset(client, &latest, releaseChecksumsName)
+	if err != nil {
+		return err
+	}
+	signature, err := downloadAsset(client, &latest, releaseSignatureName)
+	if err != nil {
+		return err
+	}
+	decodedSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, releaseSignedPayload(latest.TagName, checksums), decodedSignature) {
+		return fmt.Errorf("the checksums of release %s are not signed by the release key for this version, "+
+			"not updating", latest.TagName)
+	}
+	name := releaseBinaryName()
+	want, err := releaseChecksum(checksums, name)
+	if err != nil {
+		return fmt.Errorf("release %s: %v", latest.TagName, err)
+	}
+	binary, err := downloadAsset(client, &latest, name)
+	if err != nil {
+		return err
+	}
+	got := sha256.Sum256(binary)
+	if !bytes.Equal(got[:], want) {
+		return fmt.Errorf("the checksum of %s does not match the signed checksums of release %s, not updating",
+			name, latest.TagName)
+	}
+	executable, err := replaceExecutable(binary)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "updated %s from %s to %s\n", executable, version, latest.TagName)
+	return nil
+}
+
+// releaseSignedPayload returns what the signature of a release signs: its version followed by its checksums, so the
+// signed checksums of a release can not be served as those of another version
+func releaseSignedPayload(tag string, checksums []byte) []byte {
+	return append([]byte("checktxstatus "+tag+"\n"), checksums...)
+}
+
+// parseReleaseKey returns the key of --update-key, or the built-in one
+func parseReleaseKey() (ed25519.PublicKey, error) {
+	encoded := updateKey
+	if encoded == "" {
+		encoded = releaseKey
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("this build has no release key to verify updates with, pass --update-key")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release key %q, expected a base64 ed25519 public key", encoded)
+	}
+	return key, nil
+}
+
+// download reads the body of a successful GET of url
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReleaseAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %v", url, err)
+	}
+	if len(body) > maxReleaseAssetSize {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", url, maxReleaseAssetSize)
+	}
+	return body, nil
+}
+
+// downloadAsset downloads the asset named name of a release
+func downloadAsset(client *http.Client, r *release, name string) ([]byte, error) {
+	url, err := r.assetURL(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := download(client, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed downloading %s: %v", name, err)
+	}
+	return content, nil
+}
+
+// releaseChecksum returns the sha256 checksum of name in a sha256sum listing
+func releaseChecksum(checksums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid checksum of %s", name)
+		}
+		return sum, nil
+	}
+	return nil, fmt.Errorf("no checksum of %s", name)
+}
+
+// versionNewer returns whether version a is newer than version b, and false for comparable if either is not of the
+// form [v]major.minor.patch
+func versionNewer(a string, b string) (newer bool, comparable bool) {
+	parse := func(v string) ([3]int, bool) {
+		var parsed [3]int
+		parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+		if len(parts) != 3 {
+			return parsed, false
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return parsed, false
+			}
+			parsed[i] = n
+		}
+		return parsed, true
+	}
+	parsedA, okA := parse(a)
+	parsedB, okB := parse(b)
+	if !okA || !okB {
+		return false, false
+	}
+	for i := range parsedA {
+		if parsedA[i] != parsedB[i] {
+			return parsedA[i] > parsedB[i], true
+		}
+	}
+	return false, true
+}
+
+// replaceExecutable swaps the running binary for binary, returning its path. The new binary is written next to the
+// old one so the swap is a rename, and the old binary is restored if the swap fails.
+func replaceExecutable(binary []byte) (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed finding the running binary: %v", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", fmt.Errorf("failed finding the running binary: %v", err)
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return "", err
+	}
+	newFilename := executable + ".new"
+	err = ioutil.WriteFile(newFilename, binary, info.Mode().Perm())
+	if err != nil {
+		return "", fmt.Errorf("failed writing the new binary next to %s: %v", executable, err)
+	}
+	// a running binary can be renamed but not overwritten on every platform
+	oldFilename := executable + ".old"
+	err = os.Rename(executable, oldFilename)
+	if err != nil {
+		os.Remove(newFilename)
+		return "", fmt.Errorf("failed moving aside %s: %v", executable, err)
+	}
+	err = os.Rename(newFilename, executable)
+	if err != nil {
+		os.Rename(oldFilename, executable)
+		os.Remove(newFilename)
+		return "", fmt.Errorf("failed installing the new binary at %s: %v", executable, err)
+	}
+	if err := os.Remove(oldFilename); err != nil {
+		log.Debugf("failed removing %s: %v", oldFilename, err)
+	}
+	return executable, nil
+}
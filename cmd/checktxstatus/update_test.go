@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestReleaseFeed serves a release tagged tag of binary, whose checksums are those of signedBinary and signed for
+// the release signedTag, it returns the base64 key of the signature
+func newTestReleaseFeed(t *testing.T, tag, signedTag string, binary, signedBinary []byte) (*httptest.Server, string) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(signedBinary)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  " + releaseBinaryName() + "\n")
+	signature := ed25519.Sign(private, releaseSignedPayload(signedTag, checksums))
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(release{TagName: tag, Assets: []releaseAsset{
+			{Name: releaseChecksumsName, URL: server.URL + "/checksums"},
+			{Name: releaseSignatureName, URL: server.URL + "/signature"},
+			{Name: releaseBinaryName(), URL: server.URL + "/binary"},
+		}})
+	})
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(checksums)
+	})
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(signature)))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	return server, base64.StdEncoding.EncodeToString(public)
+}
+
+func TestSelfUpdateRefusesABinaryNotMatchingTheChecksums(t *testing.T) {
+	server, key := newTestReleaseFeed(t, "v2.0.0", "v2.0.0", []byte("tampered binary"), []byte("binary"))
+	defer server.Close()
+	defer func(feed, key, running string) {
+		releaseFeed, updateKey, version = feed, key, running
+	}(releaseFeed, updateKey, version)
+	releaseFeed, updateKey, version = server.URL+"/latest", key, "v1.0.0"
+
+	var out strings.Builder
+	err := selfUpdate(&out)
+	if err == nil || !strings.Contains(err.Error(), "does not match the signed checksums") {
+		t.Errorf("expected a tampered binary to be rejected, got %v", err)
+	}
+}
+
+func TestSelfUpdateCheckOnly(t *testing.T) {
+	server, _ := newTestReleaseFeed(t, "v2.0.0", "v2.0.0", []byte("binary"), []byte("binary"))
+	defer server.Close()
+	defer func(feed, running string, check bool, code int) {
+		releaseFeed, version, updateCheckOnly, exitCode = feed, running, check, code
+	}(releaseFeed, version, updateCheckOnly, exitCode)
+	releaseFeed, version, updateCheckOnly = server.URL+"/latest", "v1.0.0", true
+
+	var out strings.Builder
+	err := selfUpdate(&out)
+	if err != nil || !strings.Contains(out.String(), "v2.0.0 is available") || exitCode != 1 {
+		t.Errorf("expected the newer release to be reported with exit code 1, got %v, %d: %s", err, exitCode,
+			out.String())
+	}
+}
+
+func TestVersionNewer(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		newer      bool
+		comparable bool
+	}{
+		{"v1.2.10", "v1.2.9", true, true},
+		{"1.3.0", "v1.10.0", false, true},
+		{"v1.0.0", "v1.0.0", false, true},
+		{"v1.0.0", "dev", false, false},
+	}
+	for _, test := range tests {
+		newer, comparable := versionNewer(test.a, test.b)
+		if newer != test.newer || comparable != test.comparable {
+			t.Errorf("expected %s newer than %s to be %v, %v, got %v, %v", test.a, test.b, test.newer,
+				test.comparable, newer, comparable)
+		}
+	}
+}
+
+func TestReleaseChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("binary"))
+	checksums := []byte("00  other\n" + hex.EncodeToString(sum[:]) + " *checktxstatus_linux_amd64\n")
+	got, err := releaseChecksum(checksums, "checktxstatus_linux_amd64")
+	if err != nil || string(got) != string(sum[:]) {
+		t.Errorf("expected the checksum of the binary, got %x, %v", got, err)
+	}
+	if _, err := releaseChecksum(checksums, "checktxstatus_darwin_arm64"); err == nil {
+		t.Error("expected a missing checksum to fail")
+	}
+}
+
+func TestSelfUpdateRefusesAReleaseSignedForAnotherVersion(t *testing.T) {
+	// an older release, validly signed, served under a newer tag
+	server, key := newTestReleaseFeed(t, "v2.0.0", "v1.0.0", []byte("binary"), []byte("binary"))
+	defer server.Close()
+	defer func(feed, key, running string) {
+		releaseFeed, updateKey, version = feed, key, running
+	}(releaseFeed, updateKey, version)
+	releaseFeed, updateKey, version = server.URL+"/latest", key, "v1.5.0"
+
+	err := selfUpdate(ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "not signed by the release key for this version") {
+		t.Errorf("expected the signature of another version to be rejected, got %v", err)
+	}
+}
+
+func TestSelfUpdateNeverDowngrades(t *testing.T) {
+	server, key := newTestReleaseFeed(t, "v1.0.0", "v1.0.0", []byte("binary"), []byte("binary"))
+	defer server.Close()
+	defer func(feed, key, running string, force bool) {
+		releaseFeed, updateKey, version, updateForce = feed, key, running, force
+	}(releaseFeed, updateKey, version, updateForce)
+	releaseFeed, updateKey, version, updateForce = server.URL+"/latest", key, "v1.5.0", true
+
+	var out strings.Builder
+	err := selfUpdate(&out)
+	if err != nil || !strings.Contains(out.String(), "up to date") {
+		t.Errorf("expected the older release not to be installed even with --force, got %v: %s", err, out.String())
+	}
+}
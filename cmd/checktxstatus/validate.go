@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var strict bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&strict, "strict", false,
+		"fail on any input anomaly: unknown fields, zero fees, protocol limit violations, empty signatures, duplicate "+
+			"txids or group IDs not matching their transactions")
+}
+
+// isUnsigned returns true if the signed transaction has no signature, multisig or logicsig
+func isUnsigned(stx types.SignedTxn) bool {
+	return stx.Sig == types.Signature{} && stx.Msig.Blank() && stx.Lsig.Blank()
+}
+
+// isPlaceholder returns true if the transaction is an unsigned placeholder that can never be submitted
+// state proof transactions are unsigned by design
+func isPlaceholder(rec txRecord) bool {
+	return !rec.txIDOnly && isUnsigned(rec.stx) && rec.stx.Txn.Type != stateProofTx
+}
+
+// hasPlaceholder returns true if any of the transactions is an unsigned placeholder
+func hasPlaceholder(txs []txRecord) bool {
+	for _, tx := range txs {
+		if isPlaceholder(tx) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPlaceholders separates unsigned placeholders from the transactions that can be submitted
+// a group with any unsigned member cannot be submitted as a whole, so all of its members are placeholders
+func splitPlaceholders(groups map[types.Digest][]txRecord, individualTxs []txRecord) (
+	map[types.Digest][]txRecord, []txRecord, []txRecord) {
+	signedGroups := map[types.Digest][]txRecord{}
+	var signedTxs, placeholders []txRecord
+	for gid, txs := range groups {
+		if hasPlaceholder(txs) {
+			placeholders = append(placeholders, txs...)
+		} else {
+			signedGroups[gid] = txs
+		}
+	}
+	for _, tx := range individualTxs {
+		if isPlaceholder(tx) {
+			placeholders = append(placeholders, tx)
+		} else {
+			signedTxs = append(signedTxs, tx)
+		}
+	}
+	return signedGroups, signedTxs, placeholders
+}
+
+// findAnomalies returns descriptions of everything suspicious in the transactions read from a file
+func findAnomalies(groups map[types.Digest][]txRecord, individualTxs []txRecord) []string {
+	var anomalies []string
+	seen := map[string]int{}
+	for _, rec := range allRecords(groups, individualTxs) {
+		txn := rec.stx.Txn
+		if firstIndex, ok := seen[rec.txID]; ok {
+			anomalies = append(anomalies, fmt.Sprintf("tx %s at index %d duplicates the one at index %d",
+				rec.txID, rec.index, firstIndex))
+		} else {
+			seen[rec.txID] = rec.index
+		}
+		if rec.txIDOnly {
+			continue
+		}
+		if rec.unknown {
+			anomalies = append(anomalies, fmt.Sprintf("tx %s of type %q has fields unknown to the tool", rec.txID, txn.Type))
+		}
+		if txn.Fee == 0 && txn.Type != stateProofTx {
+			anomalies = append(anomalies, fmt.Sprintf("tx %s has zero fee", rec.txID))
+		}
+		if isPlaceholder(rec) {
+			anomalies = append(anomalies, fmt.Sprintf("tx %s has an empty signature", rec.txID))
+		}
+	}
+	return append(anomalies, findGroupMismatches(groups)...)
+}
+
+// findGroupMismatches returns descriptions of the groups whose ID is not the hash of their transactions, as they are
+// in corrupted files, files mixing up the transactions of different groups or missing some of them; the network
+// would reject them
+func findGroupMismatches(groups map[types.Digest][]txRecord) []string {
+	var mismatches []string
+	for _, txs := range groupsInOrder(groups) {
+		gid := txs[0].stx.Txn.Group
+		txns := make([]types.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			txn := tx.stx.Txn
+			txn.Group = types.Digest{}
+			txns = append(txns, txn)
+		}
+		computed, err := crypto.ComputeGroupID(txns)
+		if err != nil {
+			// groups too large to hash are protocol limit violations
+			continue
+		}
+		if computed != gid {
+			mismatches = append(mismatches, fmt.Sprintf("group %s of tx %s is not the hash of its %d transactions in "+
+				"the file, whose group is %s: the file is corrupted, mixes up groups or misses some of their "+
+				"transactions", groupIDString(gid), txs[0].txID, len(txs), groupIDString(computed)))
+		}
+	}
+	return mismatches
+}
+
+// validateTxs logs the anomalies and protocol limit violations found in the transactions read from filename
+// in strict mode any of them is an error
+func validateTxs(filename string, groups map[types.Digest][]txRecord, individualTxs []txRecord) error {
+	logger := log.WithField("file", filename)
+	violations := findLimitViolations(groups, individualTxs)
+	for _, violation := range violations {
+		logEntryMessage(logger, log.ErrorLevel, msgLimitViolation, violation)
+	}
+	anomalies := findAnomalies(groups, individualTxs)
+	for _, anomaly := range anomalies {
+		logEntryMessage(logger, log.WarnLevel, msgTxAnomaly, anomaly)
+	}
+	if strict && len(violations)+len(anomalies) != 0 {
+		return fmt.Errorf("found %d anomalies in %s while in strict mode", len(violations)+len(anomalies), filename)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	"strings"
+	"testing"
+)
+
+func TestFindAnomalies(t *testing.T) {
+	signed := func(index int, txID string) txRecord {
+		rec := txRecord{txID: txID, index: index}
+		rec.stx.Sig = types.Signature{1}
+		rec.stx.Txn.Fee = 1000
+		return rec
+	}
+	unsigned := signed(1, testTxID(2))
+	unsigned.stx.Sig = types.Signature{}
+	free := signed(2, testTxID(3))
+	free.stx.Txn.Fee = 0
+	unknown := signed(3, testTxID(4))
+	unknown.unknown = true
+	stateProof := txRecord{txID: testTxID(5), index: 4}
+	stateProof.stx.Txn.Type = stateProofTx
+	individualTxs := []txRecord{signed(0, testTxID(1)), unsigned, free, unknown, stateProof, signed(5, testTxID(1))}
+
+	expected := []string{"has an empty signature", "has zero fee", "unknown to the tool",
+		"at index 5 duplicates the one at index 0"}
+	anomalies := findAnomalies(nil, individualTxs)
+	if len(anomalies) != len(expected) {
+		t.Fatalf("expected %d anomalies, got %v", len(expected), anomalies)
+	}
+	for i, anomaly := range anomalies {
+		if !strings.Contains(anomaly, expected[i]) {
+			t.Errorf("expected anomaly %d to be about %q, got %q", i, expected[i], anomaly)
+		}
+	}
+}
+
+func TestValidateTxsFailsOnlyWhenStrict(t *testing.T) {
+	individualTxs := []txRecord{{txID: testTxID(1)}}
+	if err := validateTxs("test", nil, individualTxs); err != nil {
+		t.Errorf("expected anomalies to be only logged, got %v", err)
+	}
+	strict = true
+	defer func() { strict = false }()
+	if err := validateTxs("test", nil, individualTxs); err == nil {
+		t.Error("expected anomalies to fail in strict mode")
+	}
+}
+
+func TestSplitPlaceholders(t *testing.T) {
+	signed := txRecord{txID: testTxID(1)}
+	signed.stx.Sig = types.Signature{1}
+	unsigned := txRecord{txID: testTxID(2)}
+	stateProof := txRecord{txID: testTxID(3)}
+	stateProof.stx.Txn.Type = stateProofTx
+	groups := map[types.Digest][]txRecord{{1}: {signed, signed}, {2}: {signed, unsigned}}
+	signedGroups, signedTxs, placeholders := splitPlaceholders(groups, []txRecord{signed, unsigned, stateProof})
+	if len(signedGroups) != 1 || len(signedGroups[types.Digest{1}]) != 2 {
+		t.Errorf("expected only the fully signed group to be kept, got %v", signedGroups)
+	}
+	if len(signedTxs) != 2 || signedTxs[1].txID != stateProof.txID {
+		t.Errorf("expected the signed transaction and the state proof to be kept, got %v", signedTxs)
+	}
+	if len(placeholders) != 3 {
+		t.Errorf("expected the unsigned transaction and the whole group of another to be skipped, got %v",
+			placeholders)
+	}
+}
+
+func TestFindGroupMismatches(t *testing.T) {
+	var txns []types.Transaction
+	for i := 0; i < 2; i++ {
+		txns = append(txns, types.Transaction{Type: types.PaymentTx, PaymentTxnFields: types.PaymentTxnFields{
+			Amount: types.MicroAlgos(1000 + i)}})
+	}
+	gid, err := crypto.ComputeGroupID(txns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var group []txRecord
+	for i, txn := range txns {
+		rec := txRecord{txID: testTxID(byte(i)), index: i}
+		rec.stx.Txn = txn
+		rec.stx.Txn.Group = gid
+		group = append(group, rec)
+	}
+	if mismatches := findGroupMismatches(map[types.Digest][]txRecord{gid: group}); len(mismatches) != 0 {
+		t.Errorf("expected the group to match its transactions, got %v", mismatches)
+	}
+	// a member missing from the file changes the hash as much as a tampered one
+	if mismatches := findGroupMismatches(map[types.Digest][]txRecord{gid: group[:1]}); len(mismatches) != 1 {
+		t.Errorf("expected the incomplete group to be reported, got %v", mismatches)
+	}
+	group[1].stx.Txn.Amount++
+	if mismatches := findGroupMismatches(map[types.Digest][]txRecord{gid: group}); len(mismatches) != 1 {
+		t.Errorf("expected the tampered group to be reported, got %v", mismatches)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"path/filepath"
+)
+
+// verifySharedFlags are the flags of the root command that affect verifying signatures
+var verifySharedFlags = []string{"log-level", "input-format", "permissive", "shred"}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <file1.tx> <file2.tx> ...",
+	Short: "Verify the signatures, multisigs and logicsigs of the transactions of files offline",
+	Example: `  # make sure the unsent transactions found by a check are still validly signed before resubmitting them
+  checktxstatus verify batch.tx.unsent`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		err := validateInputFormat()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		if len(args) == 0 {
+			logError(newUserError(msgNoInputs))
+			cmd.HelpFunc()(cmd, args)
+			exitCode = 1
+			return
+		}
+
+		for _, filename := range args {
+			err := verifyFile(filepath.Clean(filename), cmd.OutOrStdout())
+			if err != nil {
+				logError(err)
+				exitCode = 1
+			}
+		}
+	},
+}
+
+// initVerifyCmd adds the verify subcommand, sharing the flags of the root command that affect verifying signatures
+// it must be called after the flags of the root command were registered
+func initVerifyCmd() {
+	for _, name := range verifySharedFlags {
+		verifyCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyFile verifies the signatures of the transactions of a file, writing whether each of them is valid to out
+// it fails if any of them is not
+func verifyFile(filename string, out io.Writer) error {
+	batch, err := readBatch(filename, nil)
+	if err != nil {
+		return err
+	}
+	defer zeroizeRecords(allRecords(batch.groups, batch.individual))
+	logger := log.WithField("file", filename)
+	if len(batch.corrupt) != 0 {
+		logEntryMessage(logger, log.WarnLevel, msgCorruptSkipped, len(batch.corrupt))
+	}
+	valid, invalid := 0, 0
+	for _, unit := range unitsInOrder(batch.groups, batch.individual, nil) {
+		for _, tx := range unit {
+			if tx.txIDOnly {
+				return fmt.Errorf("%s lists txids only, it has no signatures to verify", filename)
+			}
+			outcome := "valid"
+			if err := verifyTxRecord(tx); err != nil {
+				outcome = "invalid: " + err.Error()
+				invalid++
+			} else {
+				valid++
+			}
+			fmt.Fprintf(out, "%s %s: %s\n", filename, tx.txID, outcome)
+		}
+	}
+	logEntryMessage(logger, log.InfoLevel, msgVerified, valid, invalid)
+	if invalid != 0 {
+		return fmt.Errorf("%d transactions of %s are not validly signed", invalid, filename)
+	}
+	return nil
+}
+
+// verifyTxRecord verifies that a transaction is signed by its sender, or by its auth address if rekeyed, with exactly
+// one of a signature, a multisig or a logicsig
+// the programs of logicsigs are not evaluated, only the account they sign for is verified
+func verifyTxRecord(rec txRecord) error {
+	stx := rec.stx
+	if stx.Txn.Type == stateProofTx {
+		// state proof transactions are unsigned by design, the state proof they carry is what the network verifies
+		return nil
+	}
+	raw, err := encodeTxRecord(rec)
+	if err != nil {
+		return err
+	}
+	message, err := txchecker.BytesToSign(raw)
+	if err != nil {
+		return fmt.Errorf("failed decoding the transaction: %v", err)
+	}
+	signer := stx.Txn.Sender
+	if stx.AuthAddr != (types.Address{}) {
+		signer = stx.AuthAddr
+	}
+	hasSig, hasMsig, hasLsig := stx.Sig != (types.Signature{}), !stx.Msig.Blank(), !stx.Lsig.Blank()
+	switch {
+	case hasSig && (hasMsig || hasLsig) || hasMsig && hasLsig:
+		return fmt.Errorf("more than one of a signature, a multisig and a logicsig")
+	case hasSig:
+		if !ed25519.Verify(signer[:], message, stx.Sig[:]) {
+			return fmt.Errorf("the signature is not of %s", signer)
+		}
+		return nil
+	case hasMsig:
+		return verifyMultisig(signer, message, stx.Msig)
+	case hasLsig:
+		return verifyLogicSig(signer, stx.Lsig)
+	}
+	return fmt.Errorf("no signature, multisig or logicsig")
+}
+
+// verifyMultisig verifies that a multisig signs message for the multisig account signer
+func verifyMultisig(signer types.Address, message []byte, msig types.MultisigSig) error {
+	account, err := crypto.MultisigAccountFromSig(msig)
+	if err != nil {
+		return fmt.Errorf("invalid multisig: %v", err)
+	}
+	address, err := account.Address()
+	if err != nil {
+		return fmt.Errorf("invalid multisig: %v", err)
+	}
+	if address != signer {
+		return fmt.Errorf("the multisig is of %s, not of %s", address, signer)
+	}
+	for i, subsig := range msig.Subsigs {
+		if subsig.Sig == (types.Signature{}) {
+			continue
+		}
+		if len(subsig.Key) != ed25519.PublicKeySize || !ed25519.Verify(subsig.Key, message, subsig.Sig[:]) {
+			return fmt.Errorf("signature %d of the multisig is not of its key", i)
+		}
+	}
+	if status := newMultisigStatus(msig); !status.complete() {
+		return fmt.Errorf("the multisig has %s", status.describe())
+	}
+	return nil
+}
+
+// verifyLogicSig verifies that a logicsig signs for signer: it is the account of its program, or the program is
+// delegated by the signature or the multisig of signer
+func verifyLogicSig(signer types.Address, lsig types.LogicSig) error {
+	if len(lsig.Logic) == 0 {
+		return fmt.Errorf("the logicsig has no program")
+	}
+	hasSig, hasMsig := lsig.Sig != (types.Signature{}), !lsig.Msig.Blank()
+	message := append([]byte("Program"), lsig.Logic...)
+	switch {
+	case hasSig && hasMsig:
+		return fmt.Errorf("the logicsig has both a signature and a multisig")
+	case hasSig:
+		if !ed25519.Verify(signer[:], message, lsig.Sig[:]) {
+			return fmt.Errorf("the logicsig is not delegated by %s", signer)
+		}
+		return nil
+	case hasMsig:
+		if err := verifyMultisig(signer, message, lsig.Msig); err != nil {
+			return fmt.Errorf("the logicsig is not delegated: %v", err)
+		}
+		return nil
+	}
+	if address := crypto.LogicSigAddress(lsig); address != signer {
+		return fmt.Errorf("the logicsig is the account %s, not %s", address, signer)
+	}
+	return nil
+}
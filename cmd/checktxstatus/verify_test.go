@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	txchecker "github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"testing"
+)
+
+func TestVerifyTxRecord(t *testing.T) {
+	record := func(stxBytes []byte) txRecord {
+		decoded, err := txchecker.DecodeTransaction(stxBytes, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txRecord{stx: decoded.SignedTxn, txID: decoded.TxID, raw: stxBytes}
+	}
+	signer, other := crypto.GenerateAccount(), crypto.GenerateAccount()
+	txn := types.Transaction{Type: types.PaymentTx, Header: types.Header{Sender: signer.Address, Fee: 1000,
+		FirstValid: 1, LastValid: 1000}}
+	_, stxBytes, err := crypto.SignTransaction(signer.PrivateKey, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := record(stxBytes)
+	if err := verifyTxRecord(rec); err != nil {
+		t.Errorf("expected the signature to be valid, got %v", err)
+	}
+	rec.stx.Sig[0]++
+	if err := verifyTxRecord(rec); err == nil {
+		t.Error("expected a tampered signature to be invalid")
+	}
+
+	msig, err := crypto.MultisigAccountWithParams(1, 2, []types.Address{signer.Address, other.Address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn.Sender, _ = msig.Address()
+	_, stxBytes, err = crypto.SignMultisigTransaction(signer.PrivateKey, msig, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTxRecord(record(stxBytes)); err == nil {
+		t.Error("expected a multisig below its threshold to be invalid")
+	}
+	_, stxBytes, err = crypto.AppendMultisigTransaction(other.PrivateKey, msig, stxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTxRecord(record(stxBytes)); err != nil {
+		t.Errorf("expected the multisig to be valid, got %v", err)
+	}
+
+	// an escrow logicsig signs for the account of its program, which is approve
+	lsig := crypto.MakeLogicSigAccountEscrow([]byte{0x01, 0x20, 0x01, 0x01, 0x22}, nil)
+	txn.Sender, _ = lsig.Address()
+	_, stxBytes, err = crypto.SignLogicSigAccountTransaction(lsig, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTxRecord(record(stxBytes)); err != nil {
+		t.Errorf("expected the logicsig to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	watchUnsent      bool
+	watchInterval    time.Duration
+	watchMaxDuration time.Duration
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&watchUnsent, "watch", false,
+		"once all inputs were checked, re-check their unsent transactions every --interval until all of them are "+
+			"confirmed or expired, logging every change of status")
+	rootCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "delay between the re-checks of --watch")
+	rootCmd.Flags().DurationVar(&watchMaxDuration, "max-duration", 0,
+		"stop watching after this long even if transactions are still unsent (0 for no limit)")
+}
+
+// validateWatch makes sure the flags of --watch are consistent
+func validateWatch() error {
+	if !watchUnsent {
+		if watchAlgod {
+			return fmt.Errorf("--watch-algod needs --watch")
+		}
+		return nil
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if asOfRound != 0 {
+		return fmt.Errorf("--watch cannot be combined with --as-of-round, whose statuses never change")
+	}
+	return nil
+}
+
+// watchChunk keeps the unsent transactions of a classified chunk of filename for --watch, stripped of everything
+// but what their lookups need as the records are zeroized once the file is checked, and the severity of the other
+// conditions of the chunk
+func watchChunk(filename string, policy classificationPolicy, classified map[txCondition][]txRecord,
+	result *fileResult) {
+	if !watchUnsent {
+		return
+	}
+	for _, tx := range classified[conditionUnsent] {
+		watched := txRecord{txID: tx.txID, txIDOnly: tx.txIDOnly, file: filename, index: tx.index}
+		watched.stx.Txn.Group = tx.stx.Txn.Group
+		watched.stx.Txn.LastValid = tx.stx.Txn.LastValid
+		result.pending = append(result.pending, watched)
+	}
+	settled := map[txCondition][]txRecord{}
+	for condition, txs := range classified {
+		if condition != conditionUnsent {
+			settled[condition] = txs
+		}
+	}
+	if severity := policy.severity(settled); severity > result.settledSeverity {
+		result.settledSeverity = severity
+	}
+}
+
+// watch re-checks the unsent transactions every --interval until all of them are confirmed or expired, or for
+// --max-duration, updating the summary. It returns the severity of the transactions still unsent or expired.
+// The lookups skip the checkpoint, which recorded the transactions as unsent, but the confirmed ones are recorded in it.
+// With --watch-algod, the indexer is looked up once more and the blocks of the node are followed from then on.
+func (c *checker) watch(pending []txRecord, summary *runSummary) (int, error) {
+	lookup := *c.lookup
+	lookup.checkpoint, lookup.prefetched, lookup.rounds = nil, nil, nil
+	units := submissionUnits(pending)
+	var expiredTxs []txRecord
+	start := time.Now()
+	pause := watchInterval
+	if c.follower != nil {
+		pause = 0
+	}
+	if len(units) != 0 && c.follower != nil {
+		logMessage(log.InfoLevel, msgWatchingBlocks, len(pending))
+	} else if len(units) != 0 {
+		logMessage(log.InfoLevel, msgWatching, len(pending), watchInterval)
+	}
+	following := false
+	for len(units) != 0 {
+		metrics.watch(countTxs(units))
+		if watchMaxDuration > 0 && time.Since(start)+pause > watchMaxDuration {
+			logMessage(log.WarnLevel, msgWatchMaxDuration, countTxs(units), watchMaxDuration)
+			break
+		}
+		round := &indexerRound{indexerClient: c.indexerClient, backoff: c.backoff}
+		var sent []bool
+		var err error
+		switch {
+		case c.follower != nil && following:
+			sent, err = c.follower.next(units)
+			round.round, round.node = c.follower.round, true
+		case c.follower != nil:
+			err = c.follower.start(round)
+			if err == nil {
+				sent, _, err = lookup.lookupUnits(units)
+			}
+			following = true
+		default:
+			if !sleepUnlessInterrupted(watchInterval) {
+				return 0, errInterrupted
+			}
+			sent, _, err = lookup.lookupUnits(units)
+		}
+		if err != nil {
+			return 0, err
+		}
+		var unsent []txRecord
+		for i, unit := range units {
+			if !sent[i] {
+				unsent = append(unsent, unit...)
+				continue
+			}
+			for _, tx := range unit {
+				logTransition(tx, conditionConfirmed, "")
+				metrics.transition(conditionConfirmed)
+				c.lookup.checkpoint.record(tx.txID, true)
+				summary.transition(tx.file, tx.txID, conditionConfirmed)
+			}
+		}
+		unsent, expired, err := round.splitExpired(unsent)
+		if err != nil {
+			return 0, err
+		}
+		for _, tx := range expired {
+			logTransition(tx, conditionExpired, round.expiryDetail(tx))
+			metrics.transition(conditionExpired)
+			summary.transition(tx.file, tx.txID, conditionExpired)
+		}
+		expiredTxs = append(expiredTxs, expired...)
+		units = submissionUnits(unsent)
+	}
+	metrics.watch(0)
+	var unsent []txRecord
+	for _, unit := range units {
+		unsent = append(unsent, unit...)
+	}
+	if len(unsent) == 0 {
+		logMessage(log.InfoLevel, msgWatchDone)
+	}
+	return c.policy.severity(map[txCondition][]txRecord{conditionUnsent: unsent, conditionExpired: expiredTxs}), nil
+}
+
+// logTransition logs the change of status of a watched transaction from unsent
+func logTransition(tx txRecord, condition txCondition, detail string) {
+	logger := log.WithFields(log.Fields{"file": tx.file, "txid": tx.txID})
+	if detail != "" {
+		logEntryMessage(logger, log.InfoLevel, msgWatchChangedDetail, tx.txID, condition, detail)
+		return
+	}
+	logEntryMessage(logger, log.InfoLevel, msgWatchChanged, tx.txID, condition)
+}
+
+// countTxs returns the number of transactions of units
+func countTxs(units [][]txRecord) int {
+	count := 0
+	for _, unit := range units {
+		count += len(unit)
+	}
+	return count
+}
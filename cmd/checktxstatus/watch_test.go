@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchChunkKeepsTheUnsentTransactions(t *testing.T) {
+	defer func(watch bool) { watchUnsent = watch }(watchUnsent)
+	watchUnsent = true
+	unsent := txRecord{txID: testTxID(1), index: 3, raw: []byte{1}}
+	unsent.stx.Txn.LastValid = 2000
+	unsent.stx.Txn.Note = []byte("secret")
+	classified := map[txCondition][]txRecord{
+		conditionUnsent:    {unsent},
+		conditionConfirmed: {{txID: testTxID(2)}},
+	}
+	var result fileResult
+	watchChunk("batch.tx", defaultPolicy(), classified, &result)
+	if len(result.pending) != 1 {
+		t.Fatalf("expected the unsent transaction to be watched, got %+v", result.pending)
+	}
+	watched := result.pending[0]
+	if watched.file != "batch.tx" || watched.index != 3 || watched.stx.Txn.LastValid != 2000 ||
+		watched.stx.Txn.Note != nil || watched.raw != nil {
+		t.Errorf("expected only what the lookups need to be kept, got %+v", watched)
+	}
+}
+
+func TestWatchUntilConfirmedOrExpired(t *testing.T) {
+	defer func(watch bool, interval time.Duration) {
+		watchUnsent, watchInterval = watch, interval
+	}(watchUnsent, watchInterval)
+	watchUnsent, watchInterval = true, time.Millisecond
+	var mu sync.Mutex
+	lookups := map[string]int{}
+	// testTxID(1) is confirmed on its second lookup, testTxID(2) is never confirmed and expired at round 1000
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			fmt.Fprint(w, `{"db-available":true,"is-migrating":false,"message":"1000","round":1000}`)
+			return
+		}
+		txID := path.Base(r.URL.Path)
+		mu.Lock()
+		lookups[txID]++
+		count := lookups[txID]
+		mu.Unlock()
+		if txID != testTxID(1) || count < 2 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"current-round":1000,"transaction":{"id":%q,"confirmed-round":999}}`, txID)
+	}))
+	defer server.Close()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := newNotFoundRules("idx", []int{404}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := &txLookup{indexerClient: client, notFound: notFound, backoff: newTestBackoff(),
+		concurrency: newConcurrencyController(2)}
+	c := &checker{indexerClient: client, lookup: lookup, backoff: newTestBackoff(), policy: defaultPolicy()}
+	pending := []txRecord{{txID: testTxID(1), file: "a.tx"}, {txID: testTxID(2), file: "b.tx"}}
+	pending[0].stx.Txn.LastValid = 2000
+	pending[1].stx.Txn.LastValid = 999
+	summary := &runSummary{unsent: 2}
+
+	severity, err := c.watch(pending, summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.unsent != 0 || summary.expired != 1 {
+		t.Errorf("expected a confirmed and an expired transaction, got %d unsent and %d expired", summary.unsent,
+			summary.expired)
+	}
+	expected := defaultPolicy().severity(map[txCondition][]txRecord{conditionExpired: {pending[1]}})
+	if severity != expected {
+		t.Errorf("expected the severity of the expired transaction %d, got %d", expected, severity)
+	}
+	if lookups[testTxID(1)] != 2 || lookups[testTxID(2)] != 1 {
+		t.Errorf("expected the watched transactions to be looked up until settled, got %v", lookups)
+	}
+}
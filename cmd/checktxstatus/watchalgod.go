@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var watchAlgod bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&watchAlgod, "watch-algod", false,
+		"make --watch follow the blocks and the pending pool of the --algod-addr node instead of re-checking the "+
+			"indexer every --interval, updating the statuses seconds after the transactions are committed rather "+
+			"than once the indexer ingested them")
+}
+
+// blockFollower follows the blocks committed by an algod node and its pending pool for --watch-algod
+// its requests are made one at a time, so unlike the lookups it needs no concurrency controller, but the node is
+// paused like the indexers when it answers 429 with Retry-After
+type blockFollower struct {
+	client  *algod.Client
+	backoff *backoffPolicy
+	// round is the last round whose block was read
+	round uint64
+	// pooled are the watched transactions seen in the pending pool, logged once
+	pooled map[string]bool
+}
+
+// initBlockFollower returns the follower of the node of the pending pool if --watch-algod is set, nil otherwise
+func initBlockFollower(pending *pendingPool, backoff *backoffPolicy) (*blockFollower, error) {
+	if !watchAlgod {
+		return nil, nil
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("--watch-algod needs the algod node to follow, please supply --algod-addr")
+	}
+	limitIndexer(algodAddress, 0, 0)
+	return &blockFollower{client: pending.client, backoff: backoff, pooled: map[string]bool{}}, nil
+}
+
+// start makes the follower read the blocks after the current round of the indexer
+// it must be called before the indexer is looked up a last time, so no transaction committed since the check of the
+// files is missed whatever the lag of the indexer
+func (f *blockFollower) start(round *indexerRound) error {
+	latest, err := round.latest()
+	if err != nil {
+		return err
+	}
+	f.round = latest
+	logMessage(log.InfoLevel, msgFollowingBlocks, latest)
+	return nil
+}
+
+// next waits for the node to commit blocks after the last one read and reads them, logging the watched transactions
+// that entered the pending pool meanwhile. It returns which units had their transactions committed in the blocks.
+func (f *blockFollower) next(units [][]txRecord) ([]bool, error) {
+	latest, err := f.waitForBlock()
+	if err != nil {
+		return nil, err
+	}
+	committed := map[string]bool{}
+	for f.round < latest {
+		block, err := f.block(f.round + 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, stib := range block.Payset {
+			committed[blockTxID(block, stib)] = true
+		}
+		f.round++
+	}
+	f.logPooled(units)
+	sent := make([]bool, len(units))
+	for i, unit := range units {
+		sent[i] = true
+		for _, tx := range unit {
+			if !committed[tx.txID] {
+				sent[i] = false
+			}
+		}
+	}
+	return sent, nil
+}
+
+// waitForBlock waits for the node to commit a block after the last one read, or for the node to give up waiting
+// after a minute, and returns the last round of the node
+func (f *blockFollower) waitForBlock() (uint64, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-runInterrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	requestID, headers := nextRequest()
+	log.Debugf("waiting for a block after round %d, request %s", f.round, requestID)
+	var latest uint64
+	err := f.backoff.retry(fmt.Sprintf("waiting for a block after round %d, request %s,", f.round, requestID),
+		func() error {
+			start := time.Now()
+			status, err := f.client.StatusAfterBlock(f.round).Do(ctx, headers...)
+			if isInterrupted() {
+				return errInterrupted
+			}
+			bundle.observe(backendAlgod, time.Since(start), err)
+			latest = status.LastRound
+			return err
+		})
+	if err == errInterrupted {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed waiting for a block after round %d, algod request %s: %v", f.round, requestID,
+			err)
+	}
+	return latest, nil
+}
+
+// block returns the block of round
+func (f *blockFollower) block(round uint64) (types.Block, error) {
+	requestID, headers := nextRequest()
+	log.Debugf("reading the block of round %d, request %s", round, requestID)
+	var block types.Block
+	err := f.backoff.retry(fmt.Sprintf("reading the block of round %d, request %s,", round, requestID), func() error {
+		start := time.Now()
+		var err error
+		block, err = f.client.Block(round).Do(context.Background(), headers...)
+		bundle.observe(backendAlgod, time.Since(start), err)
+		return err
+	})
+	if err != nil {
+		return types.Block{}, fmt.Errorf("failed reading the block of round %d, algod request %s: %v", round,
+			requestID, err)
+	}
+	return block, nil
+}
+
+// logPooled logs the watched transactions that entered the pending pool of the node since the last call
+// failing to read the pool only delays the logs, so it is not an error
+func (f *blockFollower) logPooled(units [][]txRecord) {
+	requestID, headers := nextRequest()
+	start := time.Now()
+	_, pool, err := f.client.PendingTransactions().Do(context.Background(), headers...)
+	bundle.observe(backendAlgod, time.Since(start), err)
+	if err != nil {
+		logMessage(log.WarnLevel, msgPendingPoolFailed, requestID, err)
+		return
+	}
+	pooled := map[string]bool{}
+	for _, stx := range pool {
+		pooled[crypto.TransactionIDString(stx.Txn)] = true
+	}
+	for _, unit := range units {
+		for _, tx := range unit {
+			if pooled[tx.txID] && !f.pooled[tx.txID] {
+				f.pooled[tx.txID] = true
+				logEntryMessage(log.WithFields(log.Fields{"file": tx.file, "txid": tx.txID}), log.InfoLevel,
+					msgPendingPoolEntered, tx.txID)
+			}
+		}
+	}
+}
+
+// blockTxID returns the txid of a transaction of a block, whose genesis ID and hash are stripped in the block when
+// they are the ones of the block
+func blockTxID(block types.Block, stib types.SignedTxnInBlock) string {
+	txn := stib.Txn
+	if stib.HasGenesisID {
+		txn.GenesisID = block.GenesisID
+	}
+	if stib.HasGenesisHash {
+		txn.GenesisHash = block.GenesisHash
+	}
+	return crypto.TransactionIDString(txn)
+}
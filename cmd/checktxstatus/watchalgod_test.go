@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"testing"
+)
+
+func TestBlockFollowerNext(t *testing.T) {
+	server := checkertest.NewServer()
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stxs []types.SignedTxn
+	for i := 0; i < 2; i++ {
+		stxs = append(stxs, types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{
+			Fee: types.MicroAlgos(1000 + i), FirstValid: 990, LastValid: 1990, GenesisID: checkertest.GenesisID}}})
+	}
+	if _, err := client.SendRawTransaction(msgpack.Encode(stxs[1])).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.Commit(1001, stxs[0])
+	follower := &blockFollower{client: client, backoff: newTestLookup(t, server).backoff, round: 1000,
+		pooled: map[string]bool{}}
+	units := [][]txRecord{{{txID: crypto.TransactionIDString(stxs[0].Txn)}},
+		{{txID: crypto.TransactionIDString(stxs[1].Txn)}}}
+	sent, err := follower.next(units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sent[0] || sent[1] || follower.round != 1001 {
+		t.Errorf("expected the committed transaction sent at round 1001, got %v at round %d", sent, follower.round)
+	}
+	if !follower.pooled[units[1][0].txID] {
+		t.Error("expected the submitted transaction to be seen in the pending pool")
+	}
+}
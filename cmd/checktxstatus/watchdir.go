@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	watchDirPattern string
+	watchDirSettle  time.Duration
+)
+
+// checkSharedFlags are the flags of the root command that affect checking, shared by the subcommands checking files
+var checkSharedFlags = []string{
+	"log-level", "network", "idx-addr", "idx-tkn", "idx-not-found-status", "idx-not-found-body", "idx-not-found-empty",
+	"hedge-idx-addr", "hedge-idx-tkn", "hedge-percentile", "algod-addr", "algod-tkn", "input-format", "permissive",
+	"strict", "policy", "group-policy", "check-all-group-members", "overrides", "submitted-after", "submitted-before",
+	"concurrency", "adaptive-concurrency", "max-concurrency", "target-latency", "backoff", "backoff-base",
+	"backoff-max", "retries", "max-requests", "max-request-cost", "request-cost", "budget-slowdown", "rate-limit",
+	"rate-burst", "fault-inject", "cache-path", "cache-negative-ttl", "cache-batch", "cache-flush", "checkpoint",
+	"checkpoint-batch", "checkpoint-flush", "resume", "since", "report", "split-unsent-by-group", "age-recipient",
+	"gpg-recipient", "allowlist", "denylist", "max-amount", "confirm-large", "max-file-size", "max-txns", "max-memory",
+	"yes", "address-book", "nfd", "nfd-api", "top-senders", "check-fees", "simulate-unsent", "preview-logicsigs",
+	"stream-chunk", "fsync-interval", "decode-workers", "prefetch", "max-buffered-txns", "index-files-above",
+	"deterministic", "graph", "metrics-listen", "redact", "compress", "state-backend", "state-prefix",
+	"state-cache-ttl",
+}
+
+// the subdirectories of the watched directory the checked files are moved to
+const (
+	watchDirDone   = "done"
+	watchDirUnsent = "unsent"
+)
+
+var watchDirCmd = &cobra.Command{
+	Use: "watch-dir <dir>",
+	Short: "Check the transactions files dropped into a directory, moving them to done/ and their unsent " +
+		"transactions to unsent/",
+	Example: `  # check the batches dropped into /var/spool/txs until interrupted
+  checktxstatus watch-dir --idx-addr http://localhost:8980 --yes /var/spool/txs`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configErr := loadConfig(cmd)
+		setLogger(logLevelStr)
+		if configErr != nil {
+			logError(configErr)
+			exitCode = 1
+			return
+		}
+		policy, err := loadPolicy(policyFile)
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		// the metrics add up over the files checked
+		stopMetrics, err := startMetrics()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		defer stopMetrics()
+		alerts, err := parseAlerting(alertRuleValues, alertNotifiers)
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		state, err := openSharedState()
+		if err == nil && state != nil && stateTTL <= 0 {
+			err = fmt.Errorf("--state-ttl must be positive")
+		}
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		defer state.leave()
+		dir := filepath.Clean(args[0])
+		dashboard, stopDashboard, err := startDashboard(dir)
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		defer stopDashboard()
+		w := &dirWatcher{
+			cmd:          cmd,
+			dir:          dir,
+			unsentBucket: policy.Conditions[conditionUnsent].Bucket,
+			events:       map[string]time.Time{},
+			dashboard:    dashboard,
+			alerts:       alerts,
+			state:        state,
+		}
+		err = w.run()
+		if err != nil {
+			logError(err)
+			exitCode = 1
+			return
+		}
+		exitCode = 0
+	},
+}
+
+func init() {
+	watchDirCmd.Flags().StringVar(&watchDirPattern, "pattern", "*.tx",
+		"glob of the names of the files checked, the others are ignored")
+	watchDirCmd.Flags().DurationVar(&watchDirSettle, "settle", 2*time.Second,
+		"time without changes after which a dropped file is considered completely written and is checked")
+}
+
+// initWatchDirCmd adds the watch-dir subcommand, sharing the flags of the root command that affect checking
+// it must be called after the flags of the root command were registered
+func initWatchDirCmd() {
+	for _, name := range checkSharedFlags {
+		watchDirCmd.Flags().AddFlag(rootCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(watchDirCmd)
+}
+
+// dirWatcher checks the files dropped into a directory, one at a time
+type dirWatcher struct {
+	cmd *cobra.Command
+	dir string
+	// unsentBucket is the bucket of the unsent transactions in the policy, its outputs are moved to unsent/
+	unsentBucket string
+	// events are the times of the last changes of the files not checked yet
+	events map[string]time.Time
+	// dashboard shows the checks, nil without --dashboard-listen
+	dashboard *dashboard
+	// alerts evaluates the --alert rules after every check, nil without them
+	alerts *alerting
+	// state locks the files being checked and partitions them among the instances sharing it, nil without
+	// --state-backend
+	state *sharedState
+}
+
+// run checks the files already in the directory, then the ones dropped into it until interrupted
+func (w *dirWatcher) run() error {
+	for _, sub := range []string{watchDirDone, watchDirUnsent} {
+		err := os.MkdirAll(filepath.Join(w.dir, sub), 0700)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", filepath.Join(w.dir, sub), err)
+		}
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %v", w.dir, err)
+	}
+	// no need to check error on close, nothing is written
+	defer watcher.Close()
+	err = watcher.Add(w.dir)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %v", w.dir, err)
+	}
+	err = w.state.heartbeat(time.Now())
+	if err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("error while listing %s: %v", w.dir, err)
+	}
+	for _, entry := range entries {
+		w.changed(filepath.Join(w.dir, entry.Name()))
+	}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	ticker := time.NewTicker(watchDirSettle / 4)
+	defer ticker.Stop()
+	logMessage(log.InfoLevel, msgWatchdirWatching, w.dir, watchDirPattern)
+	for {
+		select {
+		case <-interrupted:
+			logMessage(log.InfoLevel, msgWatchdirStopped, w.dir)
+			return nil
+		case event := <-watcher.Events:
+			if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				w.changed(event.Name)
+			}
+		case err := <-watcher.Errors:
+			return fmt.Errorf("failed watching %s: %v", w.dir, err)
+		case <-ticker.C:
+			if err := w.state.heartbeat(time.Now()); err != nil {
+				logMessage(log.WarnLevel, msgStateHeartbeatFailed, err)
+			}
+			w.checkSettled()
+		}
+	}
+}
+
+// changed records a change of a file of the directory, if it matches --pattern
+func (w *dirWatcher) changed(path string) {
+	if match, _ := filepath.Match(watchDirPattern, filepath.Base(path)); !match {
+		return
+	}
+	if stat, err := os.Stat(path); err != nil || !stat.Mode().IsRegular() {
+		return
+	}
+	w.events[path] = time.Now()
+	w.dashboard.setWaiting(len(w.events))
+}
+
+// checkSettled checks the files that did not change for --settle, in the order of their names
+// with --state-backend, only the files partitioned to the instance are checked, once it locked them; the others wait
+// for their instance to move them, or to leave so they are partitioned again
+func (w *dirWatcher) checkSettled() {
+	var settled []string
+	for path, last := range w.events {
+		if time.Since(last) >= watchDirSettle {
+			settled = append(settled, path)
+		}
+	}
+	sort.Strings(settled)
+	for _, path := range settled {
+		if _, err := os.Stat(path); err != nil {
+			// moved by another instance
+			delete(w.events, path)
+			w.dashboard.setWaiting(len(w.events))
+			continue
+		}
+		if !w.state.owns(filepath.Base(path)) {
+			continue
+		}
+		unlock, ok, err := w.state.lock(filepath.Base(path))
+		if err != nil {
+			logMessage(log.ErrorLevel, msgWatchdirLockFailed, path, err)
+			continue
+		}
+		if !ok {
+			log.Debugf("%s is being checked by another instance", path)
+			continue
+		}
+		delete(w.events, path)
+		w.dashboard.setWaiting(len(w.events))
+		// another instance may have checked it before it was locked
+		if _, err := os.Stat(path); err == nil {
+			w.check(path)
+		}
+		unlock()
+	}
+}
+
+// outputDir returns the subdirectory an output of the file base is moved to: unsent/ for the outputs of the unsent
+// bucket, whether compressed, encrypted or split by group, and done/ for all the others
+func (w *dirWatcher) outputDir(base string, output string) string {
+	if w.unsentBucket != "" && trimCompression(trimEncryption(output)) == base+"."+w.unsentBucket {
+		return watchDirUnsent
+	}
+	return watchDirDone
+}
+
+// check checks a file and moves it along with its outputs, leaving it in place if the check failed
+func (w *dirWatcher) check(path string) {
+	logger := log.WithField("file", path)
+	logEntryMessage(logger, log.InfoLevel, msgWatchdirChecking)
+	exitCode = 0
+	run := dashboardRun{File: filepath.Base(path), Started: time.Now()}
+	manifest, summary := runCheck(w.cmd, []string{path})
+	run.Duration = time.Since(run.Started).Round(time.Millisecond)
+	run.Status, run.Error, run.Unsent, run.Expired = summary.status(), summary.err, summary.unsent, summary.expired
+	run.Waiting = len(w.events)
+	w.alerts.evaluate(path, summary, time.Now())
+	defer func() {
+		w.dashboard.record(run)
+	}()
+	if manifest == nil || manifest.Error != "" {
+		logEntryMessage(logger, log.ErrorLevel, msgWatchdirCheckFailed, path)
+		return
+	}
+	moves := map[string]string{path: filepath.Join(w.dir, watchDirDone, filepath.Base(path))}
+	// the sidecar index of a large input follows it
+	if _, err := os.Stat(indexFilename(path)); err == nil {
+		moves[indexFilename(path)] = indexFilename(moves[path])
+	}
+	for _, output := range manifest.Outputs {
+		// outputs are moved as a whole, e.g. the directory of the unsent groups of --split-unsent-by-group
+		rel, err := filepath.Rel(w.dir, output.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		top := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+		if !strings.HasPrefix(top, filepath.Base(path)+".") {
+			continue
+		}
+		moves[filepath.Join(w.dir, top)] = filepath.Join(w.dir, w.outputDir(filepath.Base(path), top), top)
+	}
+	sources := make([]string, 0, len(moves))
+	for source := range moves {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		err := os.Rename(source, moves[source])
+		if err != nil {
+			logEntryMessage(logger, log.ErrorLevel, msgWatchdirMoveFailed, source, err)
+			continue
+		}
+		logEntryMessage(logger, log.InfoLevel, msgWatchdirMoved, source, moves[source])
+		if source != path {
+			rel, _ := filepath.Rel(w.dir, moves[source])
+			run.Outputs = append(run.Outputs, rel)
+		}
+	}
+}
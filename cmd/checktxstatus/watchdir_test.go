@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchDirChangedMatchesThePattern(t *testing.T) {
+	dir := testDir(t)
+	for _, name := range []string{"batch.tx", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.tx"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	w := &dirWatcher{dir: dir, events: map[string]time.Time{}}
+	for _, name := range []string{"batch.tx", "notes.txt", "dir.tx", "missing.tx"} {
+		w.changed(filepath.Join(dir, name))
+	}
+	if _, ok := w.events[filepath.Join(dir, "batch.tx")]; !ok || len(w.events) != 1 {
+		t.Errorf("expected only the regular files matching --pattern to be checked, got %v", w.events)
+	}
+}
+
+func TestWatchDirMovesTheCheckedFile(t *testing.T) {
+	// the indexer is at round 500 and knows of no transaction
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			fmt.Fprint(w, `{"db-available":true,"is-migrating":false,"message":"500","round":500}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	defer func(addr string, code int) { indexerAddress, exitCode = addr, code }(indexerAddress, exitCode)
+	indexerAddress = server.URL
+	dir := testDir(t)
+	_, raw := testSignedPayment(1000, types.Digest{})
+	path := filepath.Join(dir, "batch.tx")
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	w := &dirWatcher{cmd: rootCmd, dir: dir, unsentBucket: "unsent", events: map[string]time.Time{}}
+	for _, sub := range []string{watchDirDone, watchDirUnsent} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.check(path)
+	for _, moved := range []string{
+		filepath.Join(dir, watchDirDone, "batch.tx"),
+		filepath.Join(dir, watchDirUnsent, "batch.tx.unsent"),
+	} {
+		if _, err := os.Stat(moved); err != nil {
+			t.Errorf("expected %s, got %v", moved, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the checked file to be moved, got %v", err)
+	}
+}
+
+func TestWatchDirMovesOnlyTheUnsentOutputsToUnsent(t *testing.T) {
+	w := &dirWatcher{unsentBucket: "unsent"}
+	for output, dir := range map[string]string{
+		"batch.tx.unsent":         watchDirUnsent,
+		"batch.tx.unsent.zst.age": watchDirUnsent,
+		"batch.tx.confirmed":      watchDirDone,
+		"batch.tx.unsent-old":     watchDirDone,
+		"batch.tx.report.json":    watchDirDone,
+	} {
+		if sub := w.outputDir("batch.tx", output); sub != dir {
+			t.Errorf("expected %s to be moved to %s, got %s", output, dir, sub)
+		}
+	}
+	// a policy writing no unsent bucket has no output to move to unsent/
+	w.unsentBucket = ""
+	if sub := w.outputDir("batch.tx", "batch.tx.expired"); sub != watchDirDone {
+		t.Errorf("expected the outputs to be moved to %s without an unsent bucket, got %s", watchDirDone, sub)
+	}
+}
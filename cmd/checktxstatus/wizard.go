@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"filippo.io/age"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactively set up the network, indexer and output preferences and write them to a config file",
+	Example: `  # write ~/.checktxstatus.yaml
+  checktxstatus init
+
+  # write a config file used with --config
+  checktxstatus init --config testnet.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogger(logLevelStr)
+		initRunID()
+		filename, err := wizardConfigFilename()
+		if err != nil {
+			logError(err)
+			return
+		}
+		w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stderr}
+		config, err := w.run(filename)
+		if err != nil {
+			logError(err)
+			return
+		}
+		if config == nil {
+			return
+		}
+		encoded, err := yaml.Marshal(config)
+		if err != nil {
+			logMessage(log.ErrorLevel, msgWizardEncodeFailed, err)
+			return
+		}
+		header := "# written by checktxstatus init, any flag can be set here by its name\n"
+		err = writeFileAtomic(filename, append([]byte(header), encoded...))
+		if err != nil {
+			logMessage(log.ErrorLevel, msgWizardWriteFailed, filename, err)
+			return
+		}
+		fmt.Fprintf(w.out, "wrote %s, runs use it for the flags not given on the command line\n", filename)
+	},
+}
+
+// initInitCmd adds the init subcommand
+func initInitCmd() {
+	rootCmd.AddCommand(initCmd)
+}
+
+// wizardConfigFilename returns the config file the wizard writes: --config, or the default one
+func wizardConfigFilename() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed finding the home directory, pass --config: %v", err)
+	}
+	return filepath.Join(home, defaultConfigName), nil
+}
+
+// wizard asks the user for the settings of a config file
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// prompt asks a question, returning the trimmed answer or def if the answer is empty
+func (w *wizard) prompt(question string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	answer, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("setup aborted: %v", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// promptSecret asks for a secret, without echoing it when stdin is a terminal
+func (w *wizard) promptSecret(question string) (string, error) {
+	if !isInteractive() {
+		return w.prompt(question, "")
+	}
+	fmt.Fprintf(w.out, "%s: ", question)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(w.out)
+	if err != nil {
+		return "", fmt.Errorf("setup aborted: %v", err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// promptYes asks a yes or no question
+func (w *wizard) promptYes(question string, def bool) (bool, error) {
+	defAnswer := "y/N"
+	if def {
+		defAnswer = "Y/n"
+	}
+	for {
+		answer, err := w.prompt(question, defAnswer)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		case strings.ToLower(defAnswer):
+			return def, nil
+		}
+		fmt.Fprintln(w.out, "please answer y or n")
+	}
+}
+
+// run asks for the settings and returns the config to write, nil if the user chose not to write it
+func (w *wizard) run(filename string) (yaml.MapSlice, error) {
+	if _, err := os.Stat(filename); err == nil {
+		overwrite, err := w.promptYes(fmt.Sprintf("%s exists, overwrite it?", filename), false)
+		if err != nil || !overwrite {
+			return nil, err
+		}
+	}
+	var names []string
+	for _, preset := range networkPresets {
+		names = append(names, preset.name)
+	}
+	var network *networkPreset
+	for network == nil {
+		answer, err := w.prompt(fmt.Sprintf("network (%s or other)", strings.Join(names, ", ")), "mainnet")
+		if err != nil {
+			return nil, err
+		}
+		if answer == "other" {
+			network = &networkPreset{}
+			break
+		}
+		for i := range networkPresets {
+			if networkPresets[i].name == answer {
+				network = &networkPresets[i]
+			}
+		}
+		if network == nil {
+			fmt.Fprintf(w.out, "unknown network %q\n", answer)
+		}
+	}
+
+	var address, token string
+	for {
+		var err error
+		address, err = w.prompt("indexer address", network.indexer)
+		if err != nil {
+			return nil, err
+		}
+		token, err = w.promptSecret("indexer API token (empty for public indexers)")
+		if err != nil {
+			return nil, err
+		}
+		err = testIndexer(address, token, network.genesisID)
+		if err == nil {
+			fmt.Fprintf(w.out, "connected to %s\n", address)
+			break
+		}
+		fmt.Fprintf(w.out, "connection test failed: %v\n", err)
+		retry, err := w.promptYes("enter the indexer again?", true)
+		if err != nil {
+			return nil, err
+		}
+		if retry {
+			continue
+		}
+		keep, err := w.promptYes("keep it anyway?", false)
+		if err != nil || !keep {
+			return nil, err
+		}
+		break
+	}
+	config := yaml.MapSlice{{Key: "idx-addr", Value: address}}
+	if token != "" {
+		config = append(config, yaml.MapItem{Key: "idx-tkn", Value: token})
+	}
+
+	for {
+		answer, err := w.prompt("concurrent lookups", "1")
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 {
+			if n > 1 {
+				config = append(config, yaml.MapItem{Key: "concurrency", Value: n})
+			}
+			break
+		}
+		fmt.Fprintln(w.out, "please answer a number of at least 1")
+	}
+	for {
+		recipient, err := w.prompt("age recipient to encrypt outputs to (empty to not encrypt)", "")
+		if err != nil {
+			return nil, err
+		}
+		if recipient == "" {
+			break
+		}
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			fmt.Fprintf(w.out, "invalid age recipient: %v\n", err)
+			continue
+		}
+		config = append(config, yaml.MapItem{Key: "age-recipient", Value: []string{recipient}})
+		break
+	}
+	split, err := w.promptYes("write every unsent group to its own file?", false)
+	if err != nil {
+		return nil, err
+	}
+	if split {
+		config = append(config, yaml.MapItem{Key: "split-unsent-by-group", Value: true})
+	}
+	return config, nil
+}
+
+// testIndexer checks an indexer is healthy, accepts the token and serves the network of genesisID if not empty
+func testIndexer(address string, token string, genesisID string) error {
+	client, err := indexer.MakeClientWithHeaders(address, token, clientHeaders(indexerTransport))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	health, err := client.HealthCheck().Do(ctx)
+	if err != nil {
+		if fix := indexerFix(err, "the indexer address and token"); fix != "" {
+			return fmt.Errorf("%v, %s", err, fix)
+		}
+		return err
+	}
+	if !health.DbAvailable || health.IsMigrating {
+		return fmt.Errorf("the indexer is unhealthy: %s", health.Message)
+	}
+	block, err := client.LookupBlock(0).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if genesisID != "" && block.GenesisId != genesisID {
+		return fmt.Errorf("the indexer serves %s, not %s", block.GenesisId, genesisID)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWizardWritesAnswers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			fmt.Fprint(w, `{"db-available":true,"round":1000}`)
+		case "/v2/blocks/0":
+			fmt.Fprint(w, `{"genesis-id":"devnet-v1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	// an unknown network, then other, the indexer, no token, an invalid then a valid concurrency, no encryption
+	// and split outputs
+	answers := []string{"localnet", "other", server.URL, "", "zero", "4", "", "y"}
+	var out bytes.Buffer
+	w := &wizard{in: bufio.NewReader(strings.NewReader(strings.Join(answers, "\n") + "\n")), out: &out}
+	config, err := w.run(filepath.Join(testDir(t), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := yaml.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("idx-addr: %s\nconcurrency: 4\nsplit-unsent-by-group: true\n", server.URL)
+	if string(encoded) != expected {
+		t.Errorf("expected config %q, got %q", expected, encoded)
+	}
+	for _, prompt := range []string{`unknown network "localnet"`, "connected to", "at least 1"} {
+		if !strings.Contains(out.String(), prompt) {
+			t.Errorf("expected the wizard to print %q, got:\n%s", prompt, out.String())
+		}
+	}
+}
+
+func TestTestIndexerChecksNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			fmt.Fprint(w, `{"db-available":true,"round":1000}`)
+		case "/v2/blocks/0":
+			fmt.Fprint(w, `{"genesis-id":"testnet-v1.0"}`)
+		}
+	}))
+	defer server.Close()
+	if err := testIndexer(server.URL, "", "testnet-v1.0"); err != nil {
+		t.Errorf("expected the indexer of the network to pass, got %v", err)
+	}
+	if err := testIndexer(server.URL, "", "mainnet-v1.0"); err == nil {
+		t.Error("expected the indexer of another network to fail")
+	}
+}
@@ -3,8 +3,16 @@ module github.com/ori-shem-tov/check-tx-status
 go 1.14
 
 require (
+	filippo.io/age v1.0.0
 	github.com/algorand/go-algorand-sdk v1.14.1
+	github.com/algorand/go-codec/codec v1.1.8
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/klauspost/compress v1.13.6
 	github.com/sirupsen/logrus v1.8.1
-	github.com/spf13/cobra v0.0.3
+	github.com/spf13/cobra v1.1.3
 	github.com/spf13/pflag v1.0.5
+	github.com/spf13/viper v1.7.1
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/term v0.0.0-20210615171337-6886f2dfbf5b
+	gopkg.in/yaml.v2 v2.4.0
 )
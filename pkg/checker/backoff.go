@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BackoffKind is a strategy of the delays between retries
+type BackoffKind string
+
+const (
+	// BackoffExponential doubles the delay after every retry
+	BackoffExponential BackoffKind = "exponential"
+	// BackoffDecorrelatedJitter picks a random delay between the base delay and three times the previous delay,
+	// spreading the retries of concurrent clients
+	BackoffDecorrelatedJitter BackoffKind = "decorrelated-jitter"
+	// BackoffFixed waits the base delay before every retry
+	BackoffFixed BackoffKind = "fixed"
+)
+
+// Backoff retries requests failing with transient errors, a nil Backoff never retries
+type Backoff struct {
+	Kind BackoffKind
+	// Base is the delay before the first retry, Max the maximal delay between retries, unlimited if 0
+	Base time.Duration
+	Max  time.Duration
+	// Retries is the number of times a failed request is retried
+	Retries int
+	// Rand draws the jittered delays, it is seeded with the time if nil
+	Rand *rand.Rand
+	// Sleep waits between retries, time.Sleep if nil
+	Sleep func(time.Duration)
+	// Transient decides which errors are retried, IsTransient if nil
+	Transient func(err error) bool
+
+	once   sync.Once
+	randMu sync.Mutex
+}
+
+// Delay returns the delay before the given retry (starting at 1), prev is the delay before the previous retry
+func (b *Backoff) Delay(retry int, prev time.Duration) time.Duration {
+	var d time.Duration
+	switch b.Kind {
+	case BackoffFixed:
+		d = b.Base
+	case BackoffDecorrelatedJitter:
+		if prev < b.Base {
+			prev = b.Base
+		}
+		b.once.Do(func() {
+			if b.Rand == nil {
+				b.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+			}
+		})
+		b.randMu.Lock()
+		d = b.Base + time.Duration(b.Rand.Int63n(int64(3*prev-b.Base)+1))
+		b.randMu.Unlock()
+	default:
+		d = b.Base
+		for i := 1; i < retry && (b.Max == 0 || d < b.Max); i++ {
+			d *= 2
+		}
+	}
+	if b.Max != 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not transient, or the retries run out
+// onRetry, if not nil, is called with every failure that is retried and the delay before retrying it
+func (b *Backoff) Retry(fn func() error, onRetry func(retry int, delay time.Duration, err error)) error {
+	err := fn()
+	if b == nil {
+		return err
+	}
+	transient, sleep := b.Transient, b.Sleep
+	if transient == nil {
+		transient = IsTransient
+	}
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	var d time.Duration
+	for retry := 1; err != nil && transient(err) && retry <= b.Retries; retry++ {
+		d = b.Delay(retry, d)
+		if onRetry != nil {
+			onRetry(retry, d, err)
+		}
+		sleep(d)
+		err = fn()
+	}
+	return err
+}
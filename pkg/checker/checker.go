@@ -0,0 +1,266 @@
+// Package checker checks whether signed Algorand transactions were submitted to the blockchain, so Go programs can
+// embed the checks of the checktxstatus CLI without shelling out to it.
+//
+// A typical use reads a file of signed transactions, filters out the ones found by an indexer and writes the rest
+// to a file, from which they can be resubmitted:
+//
+//	batch, err := checker.ReadTxFile("txns.tx", checker.ReadOptions{})
+//	c := checker.Checker{Client: indexerClient, Backoff: &checker.Backoff{Base: time.Second, Retries: 3}}
+//	groups, err := c.FilterUnsentGroups(ctx, batch.Groups)
+//	txs, err := c.FilterUnsentTxs(ctx, batch.Individual)
+//	err = checker.WriteTxsToFile("txns.tx.unsent", append(checker.Flatten(groups), txs...))
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Batch holds the transactions of an input, separated to groups and individual transactions
+type Batch struct {
+	// Groups are the groups of transactions, in the order of their first transaction in the input
+	Groups [][]Transaction
+	// Individual are the transactions which are not part of a group, in input order
+	Individual []Transaction
+}
+
+// ReadOptions set how an input is read
+type ReadOptions struct {
+	// Format is the format of the input, which is detected if empty or FormatAuto
+	Format Format
+	// Lenient accepts transactions of unknown types or with unknown fields, instead of failing
+	Lenient bool
+	// Corrupt is called with the records that can not be decoded as transactions and the decoding error, they are
+	// skipped if it returns nil, reading fails with the decoding error if Corrupt is nil
+	Corrupt func(pos Position, err error) error
+	// Decompress makes ReadTxFile decompress the files compressed with gzip or zstd, detected by their magic numbers
+	Decompress bool
+}
+
+// Position locates a record in an input
+type Position struct {
+	// Index is the position of the record among the records of the input, starting at 0
+	Index int
+	// Offset and Size locate the encoding of a signed transaction in the input, Size is 0 for txids
+	Offset int64
+	Size   int
+}
+
+// ReadTxFile reads and decodes the transactions of a file
+// it assumes groups of transactions appear consecutively and does not validate them
+func ReadTxFile(filename string, options ReadOptions) (*Batch, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %v", filename, err)
+	}
+	// no need to check error on close when reading file
+	defer file.Close()
+
+	var reader io.Reader = file
+	if options.Decompress {
+		decompressed, _, err := Decompress(bufio.NewReader(file))
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %s: %v", filename, err)
+		}
+		// no need to check error on close when reading file
+		defer decompressed.Close()
+		reader = decompressed
+	}
+
+	batch := &Batch{}
+	groupPositions := map[types.Digest]int{}
+	err = ReadTransactions(bufio.NewReaderSize(reader, SniffSize), options, func(tx Transaction, _ Position) error {
+		gid := tx.SignedTxn.Txn.Group
+		if (gid == types.Digest{}) {
+			batch.Individual = append(batch.Individual, tx)
+			return nil
+		}
+		position, ok := groupPositions[gid]
+		if !ok {
+			position = len(batch.Groups)
+			groupPositions[gid] = position
+			batch.Groups = append(batch.Groups, nil)
+		}
+		batch.Groups[position] = append(batch.Groups[position], tx)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while reading %s: %v", filename, err)
+	}
+	return batch, nil
+}
+
+// ReadTransactions decodes the transactions of an input, calling add with every transaction and its position
+// transactions read from a txid list only have a TxID
+func ReadTransactions(r *bufio.Reader, options ReadOptions, add func(tx Transaction, pos Position) error) error {
+	format := options.Format
+	if format == "" || format == FormatAuto {
+		var err error
+		format, err = DetectFormat(r)
+		if err != nil {
+			return err
+		}
+	}
+	var pos Position
+	return ReadRecords(r, format, options.Lenient, func(raw []byte) error {
+		pos.Size = len(raw)
+		current := pos
+		pos.Index++
+		pos.Offset += int64(pos.Size)
+		tx, err := DecodeTransaction(raw, options.Lenient)
+		if err != nil && options.Corrupt != nil {
+			return options.Corrupt(current, err)
+		}
+		if err != nil {
+			return err
+		}
+		return add(tx, current)
+	}, func(txid string) error {
+		current := Position{Index: pos.Index}
+		pos.Index++
+		return add(Transaction{TxID: txid}, current)
+	})
+}
+
+// Checker looks up transactions in an indexer
+type Checker struct {
+	Client *indexer.Client
+	// NotFound decides which errors of lookups mean a transaction does not exist, DefaultNotFound if nil
+	NotFound *NotFoundRules
+	// Backoff retries the lookups failing with transient errors, they are not retried if nil
+	Backoff *Backoff
+	// OnRetry, if not nil, is called with every failed lookup before it is retried
+	OnRetry func(txid string, retry int, delay time.Duration, err error)
+	// Lookup, if not nil, looks up a transaction instead of Client, e.g. to add headers or hedge slow lookups
+	Lookup func(ctx context.Context, txid string) (models.TransactionResponse, error)
+	// GroupPolicy derives the status of a group from its transactions, GroupPolicyFirst if empty
+	GroupPolicy GroupPolicy
+}
+
+// IsSent returns true if the indexer found the transaction
+func (c *Checker) IsSent(ctx context.Context, txid string) (bool, error) {
+	sent, _, err := c.Status(ctx, txid)
+	if err == ErrEmptyResponse {
+		return false, fmt.Errorf("%v to the lookup of tx %s", err, txid)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed getting status of tx %s: %v", txid, err)
+	}
+	return sent, nil
+}
+
+// Status looks up a transaction, retrying transient failures, and returns whether the indexer found it and its
+// confirmed round if so
+// the errors meaning the transaction does not exist are not errors, other errors are returned as they are, an empty
+// successful response being ErrEmptyResponse
+func (c *Checker) Status(ctx context.Context, txid string) (sent bool, round uint64, err error) {
+	notFound := c.NotFound
+	if notFound == nil {
+		notFound = DefaultNotFound
+	}
+	lookup := c.Lookup
+	if lookup == nil {
+		lookup = func(ctx context.Context, txid string) (models.TransactionResponse, error) {
+			return c.Client.LookupTransaction(txid).Do(ctx)
+		}
+	}
+	var onRetry func(retry int, delay time.Duration, err error)
+	if c.OnRetry != nil {
+		onRetry = func(retry int, delay time.Duration, err error) {
+			c.OnRetry(txid, retry, delay, err)
+		}
+	}
+	err = c.Backoff.Retry(func() error {
+		resp, err := lookup(ctx, txid)
+		if err == io.EOF || err == nil && resp.Transaction.Id == "" {
+			err = ErrEmptyResponse
+		}
+		if err != nil && notFound.IsNotFound(err) {
+			sent, round = false, 0
+			return nil
+		}
+		sent, round = err == nil, resp.Transaction.ConfirmedRound
+		return err
+	}, onRetry)
+	if err != nil {
+		return false, 0, err
+	}
+	return sent, round, nil
+}
+
+// FilterUnsentGroups returns the groups that were not sent according to GroupPolicy, in order
+func (c *Checker) FilterUnsentGroups(ctx context.Context, groups [][]Transaction) ([][]Transaction, error) {
+	units := make([][]string, len(groups))
+	for i, group := range groups {
+		for _, tx := range group {
+			units[i] = append(units[i], tx.TxID)
+		}
+	}
+	found, _, err := LookupUnits(units, c.GroupPolicy, false, func(txids []string, _ []int) ([]bool, error) {
+		sent := make([]bool, len(txids))
+		for i, txid := range txids {
+			var err error
+			sent[i], err = c.IsSent(ctx, txid)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return sent, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var unsent [][]Transaction
+	for i, group := range groups {
+		if len(group) != 0 && found[i] < c.GroupPolicy.Quorum(len(group)) {
+			unsent = append(unsent, group)
+		}
+	}
+	return unsent, nil
+}
+
+// FilterUnsentTxs returns the individual transactions that were not sent, in order
+func (c *Checker) FilterUnsentTxs(ctx context.Context, txs []Transaction) ([]Transaction, error) {
+	var unsent []Transaction
+	for _, tx := range txs {
+		sent, err := c.IsSent(ctx, tx.TxID)
+		if err != nil {
+			return nil, err
+		}
+		if !sent {
+			unsent = append(unsent, tx)
+		}
+	}
+	return unsent, nil
+}
+
+// Flatten returns the transactions of groups, in order
+func Flatten(groups [][]Transaction) []Transaction {
+	var txs []Transaction
+	for _, group := range groups {
+		txs = append(txs, group...)
+	}
+	return txs
+}
+
+// WriteTxsToFile writes transactions to a file readable by its owner only, as their encodings so the file can be
+// submitted with goal clerk rawsend; transactions read from a txid list are written as a txid list
+func WriteTxsToFile(filename string, txs []Transaction) error {
+	var content []byte
+	for _, tx := range txs {
+		content = append(content, tx.Encoding()...)
+	}
+	err := ioutil.WriteFile(filename, content, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write txs to %s: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package checker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base32"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checkertest"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTxID returns a valid txid made of n
+func testTxID(n byte) string {
+	var digest [32]byte
+	digest[0] = n
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(digest[:])
+}
+
+// newTestChecker returns a checker of a fake indexer, which must be closed once done
+func newTestChecker(t *testing.T) (*checker.Checker, *checkertest.Server) {
+	server := checkertest.NewServer()
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return &checker.Checker{Client: client}, server
+}
+
+func TestFilterUnsentTxIDList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "txids.txt")
+	err = ioutil.WriteFile(input, []byte(testTxID(1)+"\n"+testTxID(2)+"\n"+testTxID(3)+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, server := newTestChecker(t)
+	defer server.Close()
+	server.Confirm(testTxID(2), 900)
+
+	batch, err := checker.ReadTxFile(input, checker.ReadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsent, err := c.FilterUnsentTxs(context.Background(), batch.Individual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := input + ".unsent"
+	err = checker.WriteTxsToFile(output, unsent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := testTxID(1) + "\n" + testTxID(3) + "\n"; string(content) != expected {
+		t.Errorf("expected the unsent txids %q, got %q", expected, content)
+	}
+}
+
+func TestFilterUnsentGroupsLooksUpFirstTransaction(t *testing.T) {
+	c, server := newTestChecker(t)
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	groups := [][]checker.Transaction{
+		{{TxID: testTxID(1)}, {TxID: testTxID(2)}},
+		{{TxID: testTxID(3)}, {TxID: testTxID(4)}},
+	}
+	unsent, err := c.FilterUnsentGroups(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsent) != 1 || unsent[0][0].TxID != testTxID(3) {
+		t.Errorf("expected the second group to be unsent, got %v", unsent)
+	}
+	if requests := server.Requests(); len(requests) != 2 {
+		t.Errorf("expected a lookup per group, got %v", requests)
+	}
+}
+
+func TestIsSentFailsOnErrors(t *testing.T) {
+	c, server := newTestChecker(t)
+	defer server.Close()
+	server.Script(
+		checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusServiceUnavailable, Times: 1},
+		checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusNotFound, Body: `{"message":"proxy"}`,
+			Times: 1},
+	)
+	_, err := c.IsSent(context.Background(), testTxID(1))
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Errorf("expected a 503 to fail the lookup, got %v", err)
+	}
+	// a 404 means not found unless the not found rules tell otherwise
+	c.NotFound = &checker.NotFoundRules{Bodies: []*regexp.Regexp{regexp.MustCompile("no transaction found")}}
+	_, err = c.IsSent(context.Background(), testTxID(1))
+	if err == nil {
+		t.Errorf("expected a 404 of a proxy to fail the lookup")
+	}
+	sent, err := c.IsSent(context.Background(), testTxID(1))
+	if err != nil || sent {
+		t.Errorf("expected the transaction to be unsent, got %v, %v", sent, err)
+	}
+}
+
+func TestIsSentHonorsContext(t *testing.T) {
+	c, server := newTestChecker(t)
+	defer server.Close()
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.IsSent(ctx, testTxID(1))
+	if err == nil {
+		t.Errorf("expected the lookup to time out")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the lookup to stop at the deadline, it took %s", time.Since(start))
+	}
+}
+
+func TestIsSentRetriesWithBackoff(t *testing.T) {
+	c, server := newTestChecker(t)
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: http.StatusServiceUnavailable, Times: 2})
+	retries := 0
+	c.Backoff = &checker.Backoff{Kind: checker.BackoffFixed, Base: time.Millisecond, Retries: 3}
+	c.OnRetry = func(txid string, retry int, delay time.Duration, err error) {
+		retries++
+	}
+	sent, round, err := c.Status(context.Background(), testTxID(1))
+	if err != nil || !sent || round != 900 {
+		t.Errorf("expected the transaction to be found at round 900 after the retries, got %v, %d, %v", sent,
+			round, err)
+	}
+	if retries != 2 {
+		t.Errorf("expected 2 retries, got %d", retries)
+	}
+}
+
+func TestFilterUnsentGroupsWithQuorum(t *testing.T) {
+	c, server := newTestChecker(t)
+	defer server.Close()
+	c.GroupPolicy = checker.GroupPolicyQuorum
+	// the first transaction of the first group is missing, but a majority of the group was found
+	server.Confirm(testTxID(2), 900)
+	server.Confirm(testTxID(3), 900)
+	server.Confirm(testTxID(4), 900)
+	groups := [][]checker.Transaction{
+		{{TxID: testTxID(1)}, {TxID: testTxID(2)}, {TxID: testTxID(3)}},
+		{{TxID: testTxID(4)}, {TxID: testTxID(5)}, {TxID: testTxID(6)}},
+	}
+	unsent, err := c.FilterUnsentGroups(context.Background(), groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsent) != 1 || unsent[0][0].TxID != testTxID(4) {
+		t.Errorf("expected the second group to be unsent, got %v", unsent)
+	}
+}
+
+func TestLookupUnitsAll(t *testing.T) {
+	units := [][]string{{testTxID(1), testTxID(2), testTxID(3)}, {testTxID(4)}}
+	var lookedUp []string
+	found, looked, err := checker.LookupUnits(units, checker.GroupPolicyFirst, true,
+		func(txids []string, _ []int) ([]bool, error) {
+			lookedUp = append(lookedUp, txids...)
+			return make([]bool, len(txids)), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lookedUp) != 4 || looked[0] != 3 || looked[1] != 1 || found[0] != 0 {
+		t.Errorf("expected every transaction to be looked up whatever the policy, got %v, %v", lookedUp, looked)
+	}
+}
+
+func TestReadRecordsDecodesBase32AddressesOfJSON(t *testing.T) {
+	sender := "FYD3GWODP4ZI4EZA5HTXWF7MPYBCR3HXC4YUYKAXAZWGU2X2KKVWRTFAM4"
+	input := `{"txn": {"type": "pay", "snd": "` + sender + `", "fee": 1000, "fv": 1, "lv": 2, "amt": 5}}`
+	var txs []checker.Transaction
+	err := checker.ReadRecords(bufio.NewReader(strings.NewReader(input)), checker.FormatJSON, false,
+		func(raw []byte) error {
+			tx, err := checker.DecodeTransaction(raw, false)
+			txs = append(txs, tx)
+			return err
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].SignedTxn.Txn.Sender.String() != sender || txs[0].SignedTxn.Txn.Amount != 5 {
+		t.Errorf("expected the payment of %s to be decoded, got %+v", sender, txs)
+	}
+}
+
+func TestReadTxFileDecompresses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "txids.txt.zst")
+	file, err := os.Create(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w, err := checker.Compress(file, checker.CompressionZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(testTxID(1) + "\n" + testTxID(2) + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := checker.ReadTxFile(input, checker.ReadOptions{Decompress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Individual) != 2 || batch.Individual[1].TxID != testTxID(2) {
+		t.Errorf("expected the 2 txids of the compressed file, got %+v", batch.Individual)
+	}
+}
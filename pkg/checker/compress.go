@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+)
+
+// Compression is the compression of an input or output file
+type Compression string
+
+const (
+	// CompressionNone is an uncompressed file
+	CompressionNone Compression = ""
+	// CompressionGzip is a gzip stream, usually named .gz
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd is a zstd stream, usually named .zst
+	CompressionZstd Compression = "zstd"
+)
+
+// the magic numbers starting gzip and zstd streams
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompressedReader reads a stream through a decompressor, closing the decompressor when closed
+type decompressedReader struct {
+	io.Reader
+	close func() error
+}
+
+func (d *decompressedReader) Close() error {
+	return d.close()
+}
+
+// Decompress returns a reader of r decompressing it if it is compressed with gzip or zstd, which is detected by its
+// magic number, and the compression detected
+// closing the reader releases the decompressor, not r
+func Decompress(r *bufio.Reader) (io.ReadCloser, Compression, error) {
+	// a stream shorter than the magic numbers is not compressed
+	magic, _ := r.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress: %v", err)
+		}
+		return gz, CompressionGzip, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		// a single decoding goroutine, the transactions are decoded as they are read anyway
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress: %v", err)
+		}
+		return &decompressedReader{Reader: zr, close: func() error {
+			zr.Close()
+			return nil
+		}}, CompressionZstd, nil
+	}
+	return ioutil.NopCloser(r), CompressionNone, nil
+}
+
+// Compress returns a writer compressing what is written to it into w, which must be closed to complete the stream
+// closing the writer does not close w
+func Compress(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return nil, fmt.Errorf("unknown compression %q, expected gzip or zstd", compression)
+}
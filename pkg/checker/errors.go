@@ -0,0 +1,69 @@
+package checker
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// ErrEmptyResponse is the error of lookups answered by an empty successful response, which some proxies return for
+// transactions not found
+var ErrEmptyResponse = errors.New("empty response from the indexer")
+
+// httpErrorRegexp matches the errors the SDK returns for unsuccessful HTTP responses
+var httpErrorRegexp = regexp.MustCompile(`^HTTP (\d+): `)
+
+// HTTPStatus returns the HTTP status and the body of the unsuccessful response that err of the SDK was returned for,
+// ok is false if err is not the error of an HTTP response
+func HTTPStatus(err error) (status int, body string, ok bool) {
+	msg := err.Error()
+	match := httpErrorRegexp.FindStringSubmatch(msg)
+	if match == nil {
+		return 0, "", false
+	}
+	status, _ = strconv.Atoi(match[1])
+	return status, msg[len(match[0]):], true
+}
+
+// NotFoundRules decide which errors of lookups mean that a looked up transaction does not exist
+// any other error is fatal, so misconfigured infrastructure fails the check instead of misclassifying transactions
+type NotFoundRules struct {
+	// Statuses are HTTP status codes meaning not found
+	Statuses map[int]bool
+	// Bodies match the bodies of error responses meaning not found
+	Bodies []*regexp.Regexp
+	// Empty is set when an empty successful response means not found
+	Empty bool
+}
+
+// DefaultNotFound are the rules of an indexer answering lookups of transactions not found with HTTP 404
+var DefaultNotFound = &NotFoundRules{Statuses: map[int]bool{404: true}}
+
+// IsNotFound returns true if err, returned by a lookup, means the looked up transaction does not exist
+func (r *NotFoundRules) IsNotFound(err error) bool {
+	status, body, ok := HTTPStatus(err)
+	if !ok {
+		// empty successful responses fail decoding with io.EOF
+		return r.Empty && (err == io.EOF || err == ErrEmptyResponse)
+	}
+	if r.Statuses[status] {
+		return true
+	}
+	for _, re := range r.Bodies {
+		if re.MatchString(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient returns true if a request failing with err may succeed when retried: network errors, 429 (too many
+// requests) and 5xx responses
+func IsTransient(err error) bool {
+	if err == io.EOF || err == ErrEmptyResponse {
+		return false
+	}
+	status, _, ok := HTTPStatus(err)
+	return !ok || status == 429 || status >= 500
+}
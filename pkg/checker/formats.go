@@ -0,0 +1,270 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	stdjson "encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/algorand/go-codec/codec"
+	"io"
+	"strings"
+)
+
+// Format is the encoding of an input file
+type Format string
+
+const (
+	// FormatAuto detects the format of an input from its first bytes
+	FormatAuto Format = "auto"
+	// FormatMsgpack is a concatenation of msgpack-encoded signed transactions, as written by goal
+	FormatMsgpack Format = "msgpack"
+	// FormatJSON is a JSON array or a stream of JSON-encoded signed transactions
+	FormatJSON Format = "json"
+	// FormatBase64 is a file of lines of base64-encoded msgpack signed transactions
+	FormatBase64 Format = "base64"
+	// FormatTxIDs is a file of txids, one per line, which can be checked but not resubmitted
+	FormatTxIDs Format = "txids"
+)
+
+// SniffSize is the number of bytes looked at when detecting the format of an input, readers passed to DetectFormat
+// should buffer at least as many
+const SniffSize = 4096
+
+// ErrUndetectedFormat is returned by DetectFormat when the input is of no known format
+var ErrUndetectedFormat = fmt.Errorf("unable to detect the input format")
+
+// DetectFormat detects the format of an input from its first bytes without consuming them
+func DetectFormat(r *bufio.Reader) (Format, error) {
+	head, err := r.Peek(SniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	if len(head) == 0 {
+		return FormatMsgpack, nil
+	}
+	if trimmed := bytes.TrimLeft(head, " \t\r\n"); len(trimmed) != 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return FormatJSON, nil
+	}
+	firstLine := head
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		firstLine = head[:i]
+	}
+	firstLine = bytes.TrimSpace(firstLine)
+	if IsTxID(string(firstLine)) {
+		return FormatTxIDs, nil
+	}
+	// the first line may be cut when longer than SniffSize, so only its alphabet is checked
+	if len(firstLine) != 0 && len(bytes.Trim(firstLine, base64Alphabet)) == 0 {
+		return FormatBase64, nil
+	}
+	// signed transactions are msgpack maps: fixmap, map 16 or map 32
+	if head[0]&0xf0 == 0x80 || head[0] == 0xde || head[0] == 0xdf {
+		return FormatMsgpack, nil
+	}
+	return "", ErrUndetectedFormat
+}
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+// IsTxID returns true if s is a txid: the base32 encoding of a 32 bytes digest, without padding
+func IsTxID(s string) bool {
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	return err == nil && len(decoded) == len(types.Digest{})
+}
+
+// ReadRecords decodes an input of the given format, calling add with every msgpack-encoded signed transaction, or
+// addTxID with every txid of a txid list. Lenient decoding of JSON inputs accepts unknown fields.
+func ReadRecords(r *bufio.Reader, format Format, lenient bool, add func(raw []byte) error,
+	addTxID func(txid string) error) error {
+	switch format {
+	case FormatMsgpack:
+		return readMsgpackRecords(r, add)
+	case FormatJSON:
+		return readJSONRecords(r, lenient, add)
+	case FormatBase64:
+		return readLines(r, func(line string) error {
+			decoded, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				return fmt.Errorf("invalid base64: %v", err)
+			}
+			return readMsgpackRecords(bytes.NewReader(decoded), add)
+		})
+	case FormatTxIDs:
+		return readLines(r, func(line string) error {
+			if !IsTxID(line) {
+				return fmt.Errorf("%q is not a txid", line)
+			}
+			return addTxID(line)
+		})
+	}
+	return fmt.Errorf("unknown input format %q", format)
+}
+
+// readMsgpackRecords calls add with every msgpack-encoded signed transaction of a concatenation of them
+func readMsgpackRecords(r io.Reader, add func(raw []byte) error) error {
+	dec := msgpack.NewDecoder(r)
+	for {
+		var raw codec.Raw
+		err := dec.Decode(&raw) // read next encoded transaction into raw
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = add(raw)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONRecords calls add with the msgpack encoding of every signed transaction of a JSON array or stream
+// JSON inputs can hold only the transaction types and fields modeled by the SDK, their addresses may be in base32 as
+// printed by goal clerk inspect
+func readJSONRecords(r io.Reader, lenient bool, add func(raw []byte) error) error {
+	decode := json.Decode
+	if lenient {
+		decode = json.LenientDecode
+	}
+	addValue := func(value stdjson.RawMessage) error {
+		value, err := base64Addresses(value)
+		if err != nil {
+			return err
+		}
+		var stx types.SignedTxn
+		err = decode(value, &stx)
+		if err != nil {
+			return err
+		}
+		return add(msgpack.Encode(stx))
+	}
+	dec := stdjson.NewDecoder(r)
+	for {
+		var value stdjson.RawMessage
+		err := dec.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(value); len(trimmed) == 0 || trimmed[0] != '[' {
+			err = addValue(value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		var values []stdjson.RawMessage
+		err = stdjson.Unmarshal(value, &values)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			err = addValue(value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// addressFields are the fields of transactions holding addresses, which goal prints in base32
+var addressFields = []string{"snd", "rcv", "close", "asnd", "arcv", "aclose", "fadd", "rekey", "apat"}
+
+// assetParamsAddressFields are the fields of asset params holding addresses
+var assetParamsAddressFields = []string{"m", "r", "f", "c"}
+
+// base64Addresses returns a JSON signed transaction with its base32 addresses, as printed by goal clerk inspect,
+// encoded in base64 like the other byte fields, so the SDK can decode it
+func base64Addresses(value stdjson.RawMessage) (stdjson.RawMessage, error) {
+	var stx map[string]stdjson.RawMessage
+	if err := stdjson.Unmarshal(value, &stx); err != nil {
+		return nil, err
+	}
+	var txn map[string]stdjson.RawMessage
+	if err := stdjson.Unmarshal(stx["txn"], &txn); err != nil || txn == nil {
+		// the SDK reports the invalid transaction
+		return value, nil
+	}
+	changed := base64AddressFields(stx, []string{"sgnr"})
+	if base64AddressFields(txn, addressFields) {
+		changed = true
+	}
+	var params map[string]stdjson.RawMessage
+	err := stdjson.Unmarshal(txn["apar"], &params)
+	if err == nil && base64AddressFields(params, assetParamsAddressFields) {
+		txn["apar"], _ = stdjson.Marshal(params)
+		changed = true
+	}
+	if !changed {
+		return value, nil
+	}
+	stx["txn"], _ = stdjson.Marshal(txn)
+	return stdjson.Marshal(stx)
+}
+
+// base64AddressFields encodes the base32 addresses of the fields of object, or of their elements if they are arrays,
+// in base64, it returns true if any was
+func base64AddressFields(object map[string]stdjson.RawMessage, fields []string) bool {
+	changed := false
+	for _, field := range fields {
+		var addr string
+		if err := stdjson.Unmarshal(object[field], &addr); err == nil {
+			if encoded, ok := base64Address(addr); ok {
+				object[field], _ = stdjson.Marshal(encoded)
+				changed = true
+			}
+			continue
+		}
+		var addrs []string
+		if err := stdjson.Unmarshal(object[field], &addrs); err != nil {
+			continue
+		}
+		converted := false
+		for i, addr := range addrs {
+			if encoded, ok := base64Address(addr); ok {
+				addrs[i], converted = encoded, true
+			}
+		}
+		if converted {
+			object[field], _ = stdjson.Marshal(addrs)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// base64Address returns the base64 encoding of the bytes of a base32 address, false if addr is not one
+func base64Address(addr string) (string, bool) {
+	if len(addr) != 58 {
+		return "", false
+	}
+	decoded, err := types.DecodeAddress(addr)
+	if err != nil {
+		return "", false
+	}
+	return base64.StdEncoding.EncodeToString(decoded[:]), true
+}
+
+// readLines calls fn with every non-empty line, with surrounding whitespace removed
+func readLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,93 @@
+package checker
+
+// GroupPolicy is how the status of a group is derived from the statuses of its transactions
+type GroupPolicy string
+
+const (
+	// GroupPolicyFirst derives the status of a group from its first transaction, one lookup per group, as groups are
+	// submitted atomically
+	GroupPolicyFirst GroupPolicy = "first"
+	// GroupPolicyAll considers a group sent only if all its transactions are found
+	GroupPolicyAll GroupPolicy = "all"
+	// GroupPolicyQuorum considers a group sent if a majority of its transactions are found
+	GroupPolicyQuorum GroupPolicy = "quorum"
+)
+
+// Quorum returns the number of transactions of a unit of n transactions that must be found for it to be sent
+func (p GroupPolicy) Quorum(n int) int {
+	switch p {
+	case GroupPolicyAll:
+		return n
+	case GroupPolicyQuorum:
+		return n/2 + 1
+	}
+	return 1
+}
+
+// UnitLookup looks up a round of LookupUnits, it is called with the txids of the round and the index of the unit of
+// every txid, and returns whether every transaction was found
+type UnitLookup func(txids []string, owners []int) ([]bool, error)
+
+// LookupUnits looks up the transactions of units, groups or individual transactions, according to a group policy,
+// and returns the number of transactions of every unit found and looked up
+// the first round looks up the fewest transactions of every unit that may decide it, the second one looks up the
+// rest of the transactions of the units still undecided
+// with GroupPolicyFirst the first transaction of a unit decides it, so there is no second round
+// with all every transaction of every unit is looked up in the first round, e.g. to detect partially sent groups
+func LookupUnits(units [][]string, policy GroupPolicy, all bool, lookup UnitLookup) (found []int, looked []int,
+	err error) {
+	found = make([]int, len(units))
+	looked = make([]int, len(units))
+	var txids []string
+	var owners []int
+	for i, unit := range units {
+		looked[i] = policy.Quorum(len(unit))
+		if all {
+			looked[i] = len(unit)
+		}
+		for _, txid := range unit[:looked[i]] {
+			txids = append(txids, txid)
+			owners = append(owners, i)
+		}
+	}
+	if err := lookupRound(txids, owners, found, lookup); err != nil {
+		return nil, nil, err
+	}
+
+	if policy == GroupPolicyFirst || policy == "" {
+		return found, looked, nil
+	}
+	txids, owners = nil, nil
+	for i, unit := range units {
+		quorum := policy.Quorum(len(unit))
+		if found[i] >= quorum || found[i]+len(unit)-looked[i] < quorum {
+			continue
+		}
+		for _, txid := range unit[looked[i]:] {
+			txids = append(txids, txid)
+			owners = append(owners, i)
+		}
+		looked[i] = len(unit)
+	}
+	if err := lookupRound(txids, owners, found, lookup); err != nil {
+		return nil, nil, err
+	}
+	return found, looked, nil
+}
+
+// lookupRound looks up a round of LookupUnits, counting the transactions found in the units owning them
+func lookupRound(txids []string, owners []int, found []int, lookup UnitLookup) error {
+	if len(txids) == 0 {
+		return nil
+	}
+	sent, err := lookup(txids, owners)
+	if err != nil {
+		return err
+	}
+	for j, isSent := range sent {
+		if isSent {
+			found[owners[j]]++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"crypto/sha512"
+	"encoding/base32"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/algorand/go-codec/codec"
+)
+
+const (
+	// StateProofTx is the TxType of state proof transactions
+	StateProofTx types.TxType = "stpf"
+	// HeartbeatTx is the TxType of heartbeat transactions
+	HeartbeatTx types.TxType = "hb"
+)
+
+// modeledTxTypes are the transaction types fully modeled by the SDK, which can be decoded into types.SignedTxn
+// without losing any field
+var modeledTxTypes = map[types.TxType]bool{
+	types.PaymentTx:         true,
+	types.KeyRegistrationTx: true,
+	types.AssetConfigTx:     true,
+	types.AssetTransferTx:   true,
+	types.AssetFreezeTx:     true,
+	types.ApplicationCallTx: true,
+}
+
+// protocolTxTypes are transaction types known to the protocol but not modeled by the SDK
+// only their common header is decoded, their txid is computed from their original encoding
+var protocolTxTypes = map[types.TxType]bool{
+	StateProofTx: true,
+	HeartbeatTx:  true,
+}
+
+// Transaction is a signed transaction read from an input
+type Transaction struct {
+	// SignedTxn is the decoded transaction, which may not hold all the fields of transactions not modeled by the SDK
+	SignedTxn types.SignedTxn
+	TxID      string
+	// Raw is the original encoding of the signed transaction, nil for transactions read from a txid list
+	Raw []byte
+	// Unknown is set for transactions of unknown types or with unknown fields, decoded leniently
+	Unknown bool
+}
+
+// TxIDOnly returns true if the transaction was read from a txid list, so it can be checked but not resubmitted
+func (t Transaction) TxIDOnly() bool {
+	return t.Raw == nil
+}
+
+// Encoding returns the encoding of the transaction in output files: its original encoding, byte for byte, or its
+// txid on a line of a txid list if it was read from one
+func (t Transaction) Encoding() []byte {
+	if t.TxIDOnly() {
+		return []byte(t.TxID + "\n")
+	}
+	return t.Raw
+}
+
+// UnmodeledError is returned when decoding a transaction of an unknown type, or with unknown fields, strictly
+type UnmodeledError struct {
+	// Type is the type of the transaction, which is known if only its fields are not
+	Type types.TxType
+	// Err is the error of decoding the transaction strictly, nil if its type is unknown
+	Err error
+}
+
+func (e *UnmodeledError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("unknown transaction type %q", e.Type)
+}
+
+// DecodeTransaction decodes a single msgpack-encoded signed transaction
+// the transaction keeps raw as its encoding, so raw must not be modified afterwards
+// transactions of types (or with fields) not modeled by the SDK are decoded partially; unknown transaction types and
+// unknown fields are accepted only if lenient, otherwise an *UnmodeledError is returned
+func DecodeTransaction(raw []byte, lenient bool) (Transaction, error) {
+	var stx types.SignedTxn
+	strictErr := msgpack.Decode(raw, &stx)
+	if strictErr == nil && modeledTxTypes[stx.Txn.Type] {
+		return Transaction{SignedTxn: stx, TxID: crypto.GetTxID(stx.Txn), Raw: raw}, nil
+	}
+
+	var envelope struct {
+		Txn codec.Raw `codec:"txn"`
+	}
+	err := codec.NewDecoderBytes(raw, msgpack.LenientCodecHandle).Decode(&envelope)
+	if err != nil {
+		return Transaction{}, err
+	}
+	stx = types.SignedTxn{}
+	err = codec.NewDecoderBytes(raw, msgpack.LenientCodecHandle).Decode(&stx)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	unknown := false
+	switch {
+	case protocolTxTypes[stx.Txn.Type]:
+	case lenient:
+		unknown = true
+	case modeledTxTypes[stx.Txn.Type]:
+		return Transaction{}, &UnmodeledError{Type: stx.Txn.Type, Err: strictErr}
+	default:
+		return Transaction{}, &UnmodeledError{Type: stx.Txn.Type}
+	}
+
+	return Transaction{
+		SignedTxn: stx,
+		TxID:      TxIDFromRawTxn(envelope.Txn),
+		Raw:       raw,
+		Unknown:   unknown,
+	}, nil
+}
+
+// TxIDFromRawTxn computes the txid of a msgpack-encoded transaction
+// it assumes the encoding is canonical, as it is when produced by goal or the SDKs
+func TxIDFromRawTxn(rawTxn []byte) string {
+	digest := sha512.Sum512_256(append([]byte("TX"), rawTxn...))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(digest[:])
+}
+
+// BytesToSign returns the bytes signed by the signature of a msgpack-encoded signed transaction: its transaction as
+// encoded, with the fields not modeled by the SDK, prefixed by "TX"
+func BytesToSign(raw []byte) ([]byte, error) {
+	var envelope struct {
+		Txn codec.Raw `codec:"txn"`
+	}
+	err := codec.NewDecoderBytes(raw, msgpack.LenientCodecHandle).Decode(&envelope)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("TX"), envelope.Txn...), nil
+}
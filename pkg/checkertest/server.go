@@ -0,0 +1,431 @@
+// Package checkertest runs an in-process fake indexer and algod node with scriptable responses, to test the flows
+// built on package checker, and the ones of checktxstatus, without a network.
+//
+// The server answers the requests of both APIs on the same address. Transactions are unsent until confirmed, and
+// rules inject latency and failures into the responses, e.g. an indexer rate limiting the first lookup:
+//
+//	server := checkertest.NewServer()
+//	defer server.Close()
+//	server.Confirm(txid, 1200)
+//	server.Script(checkertest.Rule{Path: "/v2/transactions/", Status: 429, RetryAfter: time.Second, Times: 1})
+//	client, err := indexer.MakeClient(server.URL, "")
+package checkertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/ori-shem-tov/check-tx-status/pkg/checker"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GenesisID is the genesis ID of the network of the server
+const GenesisID = "checkertest-v1"
+
+// Rule changes the responses to the requests it matches, the first rule applying to a request is the one used
+type Rule struct {
+	// Method and Path match the method and a prefix of the path of requests, empty ones match all requests
+	Method string
+	Path   string
+	// Latency delays the response
+	Latency time.Duration
+	// Status answers with an error status instead of the response, e.g. 404, 429 or 503, 0 keeps the response
+	Status int
+	// Body is the body of the error response, a JSON message naming the status by default
+	Body string
+	// RetryAfter sets the Retry-After header of the error response, in seconds
+	RetryAfter time.Duration
+	// After skips the first matching requests, Every applies the rule to one of every Every of the next ones, so the
+	// responses flap, and Times stops applying it after that many responses; zeros apply it to all of them
+	After int
+	Every int
+	Times int
+}
+
+// rule is a scripted rule and the requests it matched
+type rule struct {
+	Rule
+	matched int
+	applied int
+}
+
+// applies counts a request to method and path, returning whether the rule applies to it
+func (r *rule) applies(method string, path string) bool {
+	if r.Method != "" && r.Method != method || !strings.HasPrefix(path, r.Path) {
+		return false
+	}
+	r.matched++
+	n := r.matched - r.After
+	if n <= 0 || r.Every > 1 && (n-1)%r.Every != 0 || r.Times > 0 && r.applied >= r.Times {
+		return false
+	}
+	r.applied++
+	return true
+}
+
+// Server is a fake indexer and algod node
+type Server struct {
+	// URL is the address of the indexer and of the algod node, e.g. http://127.0.0.1:41283
+	URL    string
+	server *httptest.Server
+
+	mu    sync.Mutex
+	round uint64
+	// confirmed are the rounds of the transactions found by the indexer
+	confirmed map[string]uint64
+	// pooled are the transactions in the pending pool of the node, with their pool errors if dropped
+	pooled map[string]string
+	// pool are the signed transactions submitted to the pending pool, listed by the node while pooled
+	pool map[string]types.SignedTxn
+	// blocks are the signed transactions committed by rounds, the blocks of the other rounds are empty
+	blocks map[uint64][]types.SignedTxn
+	// submitted are the bodies of the submissions, in order
+	submitted [][]byte
+	rules     []*rule
+	requests  []string
+}
+
+// NewServer starts a server at round 1000, with no transactions, which must be closed once done
+func NewServer() *Server {
+	s := &Server{round: 1000, confirmed: map[string]uint64{}, pooled: map[string]string{},
+		pool: map[string]types.SignedTxn{}, blocks: map[uint64][]types.SignedTxn{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close stops the server, waiting for the requests in progress
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetRound sets the latest round of the indexer and of the node
+func (s *Server) SetRound(round uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.round = round
+}
+
+// Confirm makes the indexer find a transaction, confirmed in round
+func (s *Server) Confirm(txid string, round uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.confirmed[txid] = round
+	delete(s.pooled, txid)
+}
+
+// Commit commits signed transactions in the block of round, confirming them and advancing the latest round to round
+// if behind, e.g. to wake the clients waiting for a block after the latest round
+func (s *Server) Commit(round uint64, stxs ...types.SignedTxn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stx := range stxs {
+		txid := crypto.TransactionIDString(stx.Txn)
+		s.confirmed[txid] = round
+		delete(s.pooled, txid)
+		delete(s.pool, txid)
+	}
+	s.blocks[round] = append(s.blocks[round], stxs...)
+	if round > s.round {
+		s.round = round
+	}
+}
+
+// Pend puts a transaction in the pending pool of the node, the indexer does not find it
+func (s *Server) Pend(txid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pooled[txid] = ""
+}
+
+// Drop makes the node report a transaction as dropped from its pending pool with poolError
+func (s *Server) Drop(txid string, poolError string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pooled[txid] = poolError
+}
+
+// Script adds rules, after the ones added before
+func (s *Server) Script(rules ...Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range rules {
+		s.rules = append(s.rules, &rule{Rule: r})
+	}
+}
+
+// Requests returns the requests received so far, as the method and the path, e.g. "GET /health"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Submitted returns the bodies of the submissions received so far, every one the concatenation of the
+// msgpack-encoded signed transactions of a group or of an individual transaction
+func (s *Server) Submitted() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.submitted...)
+}
+
+// serve answers a request as scripted, or as an indexer or a node would
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	var applied *rule
+	for _, candidate := range s.rules {
+		if candidate.applies(r.Method, r.URL.Path) {
+			applied = candidate
+			break
+		}
+	}
+	s.mu.Unlock()
+	if applied != nil {
+		if applied.Latency > 0 {
+			if !sleep(r.Context(), applied.Latency) {
+				return
+			}
+		}
+		if applied.Status != 0 {
+			if applied.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(applied.RetryAfter.Seconds())))
+			}
+			body := applied.Body
+			if body == "" {
+				body = fmt.Sprintf(`{"message":%q}`, http.StatusText(applied.Status))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(applied.Status)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+	}
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && path == "/v2/transactions":
+		s.submit(w, r)
+	case path == "/health":
+		round := s.currentRound()
+		writeJSON(w, http.StatusOK, models.HealthCheck{DbAvailable: true, Message: strconv.FormatUint(round, 10),
+			Round: round, Version: "2.15.0"})
+	case path == "/versions":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"genesis_id": GenesisID, "versions": []string{"v2"}})
+	case path == "/v2/status":
+		writeJSON(w, http.StatusOK, models.NodeStatus{LastRound: s.currentRound()})
+	case strings.HasPrefix(path, "/v2/status/wait-for-block-after/"):
+		round, err := strconv.ParseUint(strings.TrimPrefix(path, "/v2/status/wait-for-block-after/"), 10, 64)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, "invalid round")
+			return
+		}
+		s.waitForBlock(r.Context(), round)
+		writeJSON(w, http.StatusOK, models.NodeStatus{LastRound: s.currentRound()})
+	case path == "/v2/transactions/pending":
+		s.pendingPool(w)
+	case path == "/v2/transactions/params":
+		writeJSON(w, http.StatusOK, models.TransactionParametersResponse{ConsensusVersion: "future", Fee: 0,
+			GenesisHash: make([]byte, 32), GenesisId: GenesisID, LastRound: s.currentRound(), MinFee: 1000})
+	case strings.HasPrefix(path, "/v2/transactions/pending/"):
+		s.pending(w, strings.TrimPrefix(path, "/v2/transactions/pending/"))
+	case strings.HasPrefix(path, "/v2/transactions/"):
+		s.lookup(w, strings.TrimPrefix(path, "/v2/transactions/"))
+	case strings.HasPrefix(path, "/v2/blocks/"):
+		round, err := strconv.ParseUint(strings.TrimPrefix(path, "/v2/blocks/"), 10, 64)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, "invalid round")
+			return
+		}
+		if r.URL.Query().Get("format") == "msgpack" {
+			s.block(w, round)
+			return
+		}
+		writeJSON(w, http.StatusOK, models.Block{Round: round, Timestamp: uint64(BlockTime(round).Unix()),
+			GenesisId: GenesisID, GenesisHash: make([]byte, 32)})
+	case strings.HasPrefix(path, "/v2/accounts/"):
+		writeJSON(w, http.StatusOK, models.AccountResponse{CurrentRound: s.currentRound(), Account: models.Account{
+			Address: strings.TrimPrefix(path, "/v2/accounts/"), Status: "Offline"}})
+	default:
+		writeMessage(w, http.StatusNotFound, "unknown path "+path)
+	}
+}
+
+// currentRound returns the latest round
+func (s *Server) currentRound() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.round
+}
+
+// BlockTime returns the timestamp of the block of a round, 4 seconds after the one of the previous round
+func BlockTime(round uint64) time.Time {
+	return time.Unix(1600000000+int64(round)*4, 0).UTC()
+}
+
+// lookup answers the indexer lookup of a transaction
+func (s *Server) lookup(w http.ResponseWriter, txid string) {
+	s.mu.Lock()
+	round, ok := s.confirmed[txid]
+	current := s.round
+	s.mu.Unlock()
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "no transaction found for transaction id: "+txid)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.TransactionResponse{CurrentRound: current, Transaction: models.Transaction{
+		Id: txid, ConfirmedRound: round, RoundTime: uint64(BlockTime(round).Unix())}})
+}
+
+// pending answers the lookup of a transaction in the pending pool of the node, which also knows of the confirmed
+// transactions
+func (s *Server) pending(w http.ResponseWriter, txid string) {
+	s.mu.Lock()
+	poolError, pooled := s.pooled[txid]
+	round, confirmed := s.confirmed[txid]
+	s.mu.Unlock()
+	if !pooled && !confirmed {
+		writeMessage(w, http.StatusNotFound, "txn does not exist")
+		return
+	}
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(msgpack.Encode(map[string]interface{}{"pool-error": poolError, "confirmed-round": round}))
+}
+
+// waitForBlock waits for the latest round to pass round, for a minute at most as the node does, or for the request to
+// be canceled
+func (s *Server) waitForBlock(ctx context.Context, round uint64) {
+	deadline := time.Now().Add(time.Minute)
+	for s.currentRound() <= round && time.Now().Before(deadline) {
+		if !sleep(ctx, 10*time.Millisecond) {
+			return
+		}
+	}
+}
+
+// pendingPool answers the listing of the pending pool of the node with the submitted transactions still pooled
+func (s *Server) pendingPool(w http.ResponseWriter) {
+	s.mu.Lock()
+	var pool []types.SignedTxn
+	for txid, stx := range s.pool {
+		if s.pooled[txid] == "" {
+			pool = append(pool, stx)
+		}
+	}
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(msgpack.Encode(models.PendingTransactionsResponse{TopTransactions: pool,
+		TotalTransactions: uint64(len(pool))}))
+}
+
+// block answers the node request of the block of round in msgpack, stripping the genesis of its transactions as the
+// node does
+func (s *Server) block(w http.ResponseWriter, round uint64) {
+	s.mu.Lock()
+	stxs := s.blocks[round]
+	current := s.round
+	s.mu.Unlock()
+	if round > current {
+		writeMessage(w, http.StatusNotFound, "ledger does not have entry")
+		return
+	}
+	block := types.Block{BlockHeader: types.BlockHeader{Round: types.Round(round), GenesisID: GenesisID,
+		TimeStamp: BlockTime(round).Unix()}}
+	for _, stx := range stxs {
+		stib := types.SignedTxnInBlock{SignedTxnWithAD: types.SignedTxnWithAD{SignedTxn: stx}}
+		if stx.Txn.GenesisID == block.GenesisID {
+			stib.Txn.GenesisID, stib.HasGenesisID = "", true
+		}
+		if stx.Txn.GenesisHash == block.GenesisHash {
+			stib.Txn.GenesisHash, stib.HasGenesisHash = types.Digest{}, true
+		}
+		block.Payset = append(block.Payset, stib)
+	}
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(msgpack.Encode(models.BlockResponse{Block: block}))
+}
+
+// submit puts the transactions of a submission in the pending pool, answering with the txid of the first one
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var txids []string
+	var stxs []types.SignedTxn
+	err = checker.ReadRecords(bufio.NewReader(bytes.NewReader(body)), checker.FormatMsgpack, true,
+		func(raw []byte) error {
+			tx, err := checker.DecodeTransaction(raw, true)
+			if err != nil {
+				return err
+			}
+			txids = append(txids, tx.TxID)
+			stxs = append(stxs, tx.SignedTxn)
+			return nil
+		}, nil)
+	if err != nil || len(txids) == 0 {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid submission: %v", err))
+		return
+	}
+	s.mu.Lock()
+	s.submitted = append(s.submitted, body)
+	for _, txid := range txids {
+		if _, ok := s.confirmed[txid]; ok {
+			s.mu.Unlock()
+			writeMessage(w, http.StatusBadRequest, "TransactionPool.Remember: transaction already in ledger: "+txid)
+			return
+		}
+	}
+	for i, txid := range txids {
+		s.pooled[txid] = ""
+		s.pool[txid] = stxs[i]
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, models.PostTransactionsResponse{Txid: txids[0]})
+}
+
+// sleep waits for d, returning false if the request was canceled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, response interface{}) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(encoded)
+}
+
+// writeMessage writes an error response with a message, as the indexer and the node do
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct {
+		Message string `json:"message"`
+	}{Message: message})
+}
@@ -0,0 +1,196 @@
+package checkertest
+
+import (
+	"context"
+	"encoding/base32"
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTxID returns a valid txid made of n
+func testTxID(n byte) string {
+	var digest [32]byte
+	digest[0] = n
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(digest[:])
+}
+
+func TestLookup(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Confirm(testTxID(1), 900)
+	client, err := indexer.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.LookupTransaction(testTxID(1)).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Transaction.Id != testTxID(1) || resp.Transaction.ConfirmedRound != 900 || resp.CurrentRound != 1000 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	_, err = client.LookupTransaction(testTxID(2)).Do(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "HTTP 404: ") {
+		t.Errorf("expected a 404 for an unsent transaction, got %v", err)
+	}
+}
+
+func TestRulesFlap(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Script(Rule{Path: "/health", Status: http.StatusServiceUnavailable, After: 1, Every: 2, Times: 2})
+	var statuses []int
+	for i := 0; i < 7; i++ {
+		resp, err := http.Get(server.URL + "/health")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	expected := []int{200, 503, 200, 503, 200, 200, 200}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Fatalf("expected statuses %v, got %v", expected, statuses)
+		}
+	}
+	if requests := server.Requests(); len(requests) != 7 || requests[0] != "GET /health" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestRulesRetryAfterAndLatency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Script(
+		Rule{Method: http.MethodGet, Path: "/v2/transactions/", Status: http.StatusTooManyRequests,
+			RetryAfter: 2 * time.Second, Times: 1},
+		Rule{Path: "/v2/transactions/", Latency: 100 * time.Millisecond},
+	)
+	resp, err := http.Get(server.URL + "/v2/transactions/" + testTxID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("expected a 429 with Retry-After 2, got %d with %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	start := time.Now()
+	resp, err = http.Get(server.URL + "/v2/transactions/" + testTxID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("expected a delayed 404, got %d after %s", resp.StatusCode, time.Since(start))
+	}
+}
+
+func TestPendingPool(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Pend(testTxID(1))
+	server.Drop(testTxID(2), "txn dead")
+	server.Confirm(testTxID(3), 990)
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		txid      string
+		poolError string
+		round     uint64
+	}{
+		{testTxID(1), "", 0},
+		{testTxID(2), "txn dead", 0},
+		{testTxID(3), "", 990},
+	} {
+		resp, _, err := client.PendingTransactionInformation(c.txid).Do(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.PoolError != c.poolError || resp.ConfirmedRound != c.round {
+			t.Errorf("expected pool error %q and round %d for %s, got %+v", c.poolError, c.round, c.txid, resp)
+		}
+	}
+	_, _, err = client.PendingTransactionInformation(testTxID(4)).Do(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "HTTP 404: ") {
+		t.Errorf("expected a 404 for an unknown transaction, got %v", err)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{
+		Fee: 1000, FirstValid: 990, LastValid: 1990, GenesisID: GenesisID}}}
+	raw := msgpack.Encode(stx)
+	txid := crypto.TransactionIDString(stx.Txn)
+	sent, err := client.SendRawTransaction(raw).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != txid {
+		t.Errorf("expected txid %s, got %s", txid, sent)
+	}
+	if submitted := server.Submitted(); len(submitted) != 1 || string(submitted[0]) != string(raw) {
+		t.Errorf("expected the submission to be recorded, got %v", submitted)
+	}
+	resp, _, err := client.PendingTransactionInformation(txid).Do(context.Background())
+	if err != nil || resp.PoolError != "" {
+		t.Errorf("expected the submitted transaction to be pending, got %+v, %v", resp, err)
+	}
+	server.Confirm(txid, 1001)
+	_, err = client.SendRawTransaction(raw).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already in ledger") {
+		t.Errorf("expected resubmitting a confirmed transaction to fail, got %v", err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client, err := algod.MakeClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stx := types.SignedTxn{Txn: types.Transaction{Type: types.PaymentTx, Header: types.Header{
+		Fee: 1000, FirstValid: 990, LastValid: 1990, GenesisID: GenesisID}}}
+	txid := crypto.TransactionIDString(stx.Txn)
+	if _, err := client.SendRawTransaction(msgpack.Encode(stx)).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, pool, err := client.PendingTransactions().Do(context.Background())
+	if err != nil || len(pool) != 1 || crypto.TransactionIDString(pool[0].Txn) != txid {
+		t.Errorf("expected the submitted transaction in the pending pool, got %v, %v", pool, err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.Commit(1002, stx)
+	}()
+	status, err := client.StatusAfterBlock(1000).Do(context.Background())
+	if err != nil || status.LastRound != 1002 {
+		t.Fatalf("expected to wait for round 1002, got %+v, %v", status, err)
+	}
+	block, err := client.Block(1002).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Payset) != 1 || !block.Payset[0].HasGenesisID || block.Payset[0].Txn.GenesisID != "" {
+		t.Errorf("expected the committed transaction stripped of its genesis ID, got %+v", block.Payset)
+	}
+	if _, pool, _ := client.PendingTransactions().Do(context.Background()); len(pool) != 0 {
+		t.Errorf("expected the committed transaction to leave the pending pool, got %v", pool)
+	}
+}